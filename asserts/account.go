@@ -35,17 +35,17 @@ type Account struct {
 
 // AccountID returns the account-id of the account.
 func (acc *Account) AccountID() string {
-	return acc.Header("account-id")
+	return acc.HeaderString("account-id")
 }
 
 // Username returns the user name for the account.
 func (acc *Account) Username() string {
-	return acc.Header("username")
+	return acc.HeaderString("username")
 }
 
 // DisplayName returns the human-friendly name for the account.
 func (acc *Account) DisplayName() string {
-	return acc.Header("display-name")
+	return acc.HeaderString("display-name")
 }
 
 // IsCertified returns true if the authority has confidence in the account's name.
@@ -59,16 +59,16 @@ func (acc *Account) Timestamp() time.Time {
 }
 
 func assembleAccount(assert assertionBase) (Assertion, error) {
-	_, err := checkNotEmpty(assert.headers, "display-name")
+	_, err := checkNotEmptyString(assert.headers, "display-name")
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = checkNotEmpty(assert.headers, "validation")
+	validation, err := checkNotEmptyString(assert.headers, "validation")
 	if err != nil {
 		return nil, err
 	}
-	certified := assert.headers["validation"] == accountValidationCertified
+	certified := validation == accountValidationCertified
 
 	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
 	if err != nil {
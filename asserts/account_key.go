@@ -28,14 +28,15 @@ import (
 // belonging to the account.
 type AccountKey struct {
 	assertionBase
-	since  time.Time
-	until  time.Time
-	pubKey PublicKey
+	since   time.Time
+	until   time.Time
+	revoked bool
+	pubKey  PublicKey
 }
 
 // AccountID returns the account-id of this account-key.
 func (ak *AccountKey) AccountID() string {
-	return ak.Header("account-id")
+	return ak.HeaderString("account-id")
 }
 
 // Since returns the time when the account key starts being valid.
@@ -48,6 +49,13 @@ func (ak *AccountKey) Until() time.Time {
 	return ak.until
 }
 
+// Revoked returns true if the account key has been revoked. Assertions
+// signed by a revoked key, directly or through other keys, are not
+// valid anymore.
+func (ak *AccountKey) Revoked() bool {
+	return ak.revoked
+}
+
 // PublicKeyID returns the key id (as used to match signatures to signing keys) for the account key.
 func (ak *AccountKey) PublicKeyID() string {
 	return ak.pubKey.ID()
@@ -73,14 +81,14 @@ func checkPublicKey(ab *assertionBase, fingerprintName, keyIDName string) (Publi
 	if err != nil {
 		return nil, err
 	}
-	fp, err := checkNotEmpty(ab.headers, fingerprintName)
+	fp, err := checkNotEmptyString(ab.headers, fingerprintName)
 	if err != nil {
 		return nil, err
 	}
 	if fp != pubKey.Fingerprint() {
 		return nil, fmt.Errorf("public key does not match provided fingerprint")
 	}
-	keyID, err := checkNotEmpty(ab.headers, keyIDName)
+	keyID, err := checkNotEmptyString(ab.headers, keyIDName)
 	if err != nil {
 		return nil, err
 	}
@@ -102,12 +110,106 @@ func assembleAccountKey(assert assertionBase) (Assertion, error) {
 	if !until.After(since) {
 		return nil, fmt.Errorf("invalid 'since' and 'until' times (no gap after 'since' till 'until')")
 	}
+	revoked, err := checkOptionalBool(assert.headers, "revoked")
+	if err != nil {
+		return nil, err
+	}
 	pubk, err := checkPublicKey(&assert, "public-key-fingerprint", "public-key-id")
 	if err != nil {
 		return nil, err
 	}
 	// ignore extra headers for future compatibility
 	return &AccountKey{
+		assertionBase: assert,
+		since:         since,
+		until:         until,
+		revoked:       revoked,
+		pubKey:        pubk,
+	}, nil
+}
+
+// AccountKeyRequest holds an account-key-request assertion, which is
+// a self-signed request to associate a public key with an account
+// through an account-key assertion.
+type AccountKeyRequest struct {
+	assertionBase
+	since  time.Time
+	until  time.Time
+	pubKey PublicKey
+}
+
+// AccountID returns the account-id of this account-key-request.
+func (akr *AccountKeyRequest) AccountID() string {
+	return akr.HeaderString("account-id")
+}
+
+// Since returns the time when the requested account key starts being valid.
+func (akr *AccountKeyRequest) Since() time.Time {
+	return akr.since
+}
+
+// Until returns the time when the requested account key stops being valid.
+// A zero time means the key is valid forever.
+func (akr *AccountKeyRequest) Until() time.Time {
+	return akr.until
+}
+
+// PublicKeyID returns the key id of the requested account key.
+func (akr *AccountKeyRequest) PublicKeyID() string {
+	return akr.pubKey.ID()
+}
+
+// PublicKeyFingerprint returns the fingerprint of the requested account key.
+func (akr *AccountKeyRequest) PublicKeyFingerprint() string {
+	return akr.pubKey.Fingerprint()
+}
+
+// Verify checks that the request is signed by the private key matching
+// the public key it carries.
+func (akr *AccountKeyRequest) Verify() error {
+	content, signature := akr.Signature()
+	sig, err := decodeSignature(signature)
+	if err != nil {
+		return err
+	}
+	if sig.KeyID() != akr.pubKey.ID() {
+		return fmt.Errorf("account-key-request is not signed with the requested key")
+	}
+	err = akr.pubKey.verify(content, sig)
+	if err != nil {
+		return fmt.Errorf("failed signature verification: %v", err)
+	}
+	return nil
+}
+
+func assembleAccountKeyRequest(assert assertionBase) (Assertion, error) {
+	accountID, err := checkNotEmptyString(assert.headers, "account-id")
+	if err != nil {
+		return nil, err
+	}
+	if assert.AuthorityID() != accountID {
+		return nil, fmt.Errorf("authority-id and account-id must match, account-key-request is self-signed: %q != %q", assert.AuthorityID(), accountID)
+	}
+	since, err := checkRFC3339Date(assert.headers, "since")
+	if err != nil {
+		return nil, err
+	}
+	var until time.Time
+	if _, ok := assert.headers["until"]; ok {
+		until, err = checkRFC3339Date(assert.headers, "until")
+		if err != nil {
+			return nil, err
+		}
+		if !until.After(since) {
+			return nil, fmt.Errorf("invalid 'since' and 'until' times (no gap after 'since' till 'until')")
+		}
+	}
+	pubk, err := checkPublicKey(&assert, "public-key-fingerprint", "public-key-id")
+	if err != nil {
+		return nil, err
+	}
+	// ignore extra headers for future compatibility
+	return &AccountKeyRequest{
 		assertionBase: assert,
 		since:         since,
 		until:         until,
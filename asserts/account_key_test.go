@@ -84,6 +84,12 @@ func (aks *accountKeySuite) TestDecodeOK(c *C) {
 	c.Check(accKey.PublicKeyID(), Equals, aks.keyid)
 	c.Check(accKey.Since(), Equals, aks.since)
 	c.Check(accKey.Until(), Equals, aks.until)
+	c.Check(accKey.Revoked(), Equals, false)
+
+	revoked := strings.Replace(encoded, aks.untilLine, aks.untilLine+"revoked: true\n", 1)
+	a, err = asserts.Decode([]byte(revoked))
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.AccountKey).Revoked(), Equals, true)
 }
 
 const (
@@ -117,6 +123,7 @@ func (aks *accountKeySuite) TestDecodeInvalidHeaders(c *C) {
 		{aks.untilLine, "until: \n", `"until" header should not be empty`},
 		{aks.untilLine, "until: " + aks.since.Format(time.RFC3339) + "\n", `invalid 'since' and 'until' times \(no gap after 'since' till 'until'\)`},
 		{aks.untilLine, "until: \n", `"until" header should not be empty`},
+		{aks.untilLine, aks.untilLine + "revoked: yes\n", `"revoked" header must be 'true' or 'false'`},
 	}
 
 	for _, test := range invalidHeaderTests {
@@ -205,7 +212,7 @@ func (aks *accountKeySuite) openDB(c *C) *asserts.Database {
 func (aks *accountKeySuite) TestAccountKeyCheck(c *C) {
 	trustedKey := testPrivKey0
 
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id":           "canonical",
 		"account-id":             "acc-id1",
 		"public-key-id":          aks.keyid,
@@ -225,7 +232,7 @@ func (aks *accountKeySuite) TestAccountKeyCheck(c *C) {
 func (aks *accountKeySuite) TestAccountKeyAddAndFind(c *C) {
 	trustedKey := testPrivKey0
 
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id":           "canonical",
 		"account-id":             "acc-id1",
 		"public-key-id":          aks.keyid,
@@ -274,3 +281,85 @@ func (aks *accountKeySuite) TestPublicKeyIsValidAt(c *C) {
 	c.Check(asserts.AccountKeyIsKeyValidAt(accKey, aks.until.AddDate(0, -1, 0)), Equals, true)
 	c.Check(asserts.AccountKeyIsKeyValidAt(accKey, aks.until.AddDate(0, 1, 0)), Equals, false)
 }
+
+func (aks *accountKeySuite) TestAccountKeyRequestDecodeOK(c *C) {
+	encoded := "type: account-key-request\n" +
+		"authority-id: acc-id1\n" +
+		"account-id: acc-id1\n" +
+		"public-key-id: " + aks.keyid + "\n" +
+		"public-key-fingerprint: " + aks.fp + "\n" +
+		aks.sinceLine +
+		fmt.Sprintf("body-length: %v", len(aks.pubKeyBody)) + "\n\n" +
+		aks.pubKeyBody + "\n\n" +
+		"openpgp c2ln"
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.AccountKeyRequestType)
+	akr := a.(*asserts.AccountKeyRequest)
+	c.Check(akr.AccountID(), Equals, "acc-id1")
+	c.Check(akr.PublicKeyFingerprint(), Equals, aks.fp)
+	c.Check(akr.PublicKeyID(), Equals, aks.keyid)
+	c.Check(akr.Since(), Equals, aks.since)
+	c.Check(akr.Until().IsZero(), Equals, true)
+
+	withUntil := strings.Replace(encoded, aks.sinceLine, aks.sinceLine+aks.untilLine, 1)
+	a, err = asserts.Decode([]byte(withUntil))
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.AccountKeyRequest).Until(), Equals, aks.until)
+}
+
+const (
+	accKeyReqErrPrefix = "assertion account-key-request: "
+)
+
+func (aks *accountKeySuite) TestAccountKeyRequestDecodeInvalidHeaders(c *C) {
+	encoded := "type: account-key-request\n" +
+		"authority-id: acc-id1\n" +
+		"account-id: acc-id1\n" +
+		"public-key-id: " + aks.keyid + "\n" +
+		"public-key-fingerprint: " + aks.fp + "\n" +
+		aks.sinceLine +
+		aks.untilLine +
+		fmt.Sprintf("body-length: %v", len(aks.pubKeyBody)) + "\n\n" +
+		aks.pubKeyBody + "\n\n" +
+		"openpgp c2ln"
+
+	invalidHeaderTests := []struct{ original, invalid, expectedErr string }{
+		{"account-id: acc-id1\n", "", `"account-id" header is mandatory`},
+		{"account-id: acc-id1\n", "account-id: acc-id2\n", `authority-id and account-id must match, account-key-request is self-signed: "acc-id1" != "acc-id2"`},
+		{"public-key-id: " + aks.keyid + "\n", "", `"public-key-id" header is mandatory`},
+		{"public-key-fingerprint: " + aks.fp + "\n", "public-key-fingerprint: 00\n", `public key does not match provided fingerprint`},
+		{aks.sinceLine, "", `"since" header is mandatory`},
+		{aks.sinceLine, "since: 12:30\n", `"since" header is not a RFC3339 date: .*`},
+		{aks.untilLine, "until: \n", `"until" header should not be empty`},
+		{aks.untilLine, "until: " + aks.since.Format(time.RFC3339) + "\n", `invalid 'since' and 'until' times \(no gap after 'since' till 'until'\)`},
+	}
+
+	for _, test := range invalidHeaderTests {
+		invalid := strings.Replace(encoded, test.original, test.invalid, 1)
+		_, err := asserts.Decode([]byte(invalid))
+		c.Check(err, ErrorMatches, accKeyReqErrPrefix+test.expectedErr)
+	}
+}
+
+func (aks *accountKeySuite) TestAccountKeyRequestVerify(c *C) {
+	headers := map[string]interface{}{
+		"authority-id":           "acc-id1",
+		"account-id":             "acc-id1",
+		"public-key-id":          aks.keyid,
+		"public-key-fingerprint": aks.fp,
+		"since":                  aks.since.Format(time.RFC3339),
+	}
+	akr, err := asserts.AssembleAndSignInTest(asserts.AccountKeyRequestType, headers, []byte(aks.pubKeyBody), testPrivKey1)
+	c.Assert(err, IsNil)
+
+	err = akr.(*asserts.AccountKeyRequest).Verify()
+	c.Check(err, IsNil)
+
+	// signed by another key
+	akr, err = asserts.AssembleAndSignInTest(asserts.AccountKeyRequestType, headers, []byte(aks.pubKeyBody), testPrivKey2)
+	c.Assert(err, IsNil)
+
+	err = akr.(*asserts.AccountKeyRequest).Verify()
+	c.Check(err, ErrorMatches, `account-key-request is not signed with the requested key`)
+}
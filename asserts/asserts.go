@@ -24,11 +24,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"unicode/utf8"
 )
 
 // AssertionType describes a known assertion type with its name and metadata.
@@ -44,25 +42,29 @@ type AssertionType struct {
 
 // Understood assertion types.
 var (
-	AccountType         = &AssertionType{"account", []string{"account-id"}, assembleAccount}
-	AccountKeyType      = &AssertionType{"account-key", []string{"account-id", "public-key-id"}, assembleAccountKey}
-	ModelType           = &AssertionType{"model", []string{"series", "brand-id", "model"}, assembleModel}
-	SerialType          = &AssertionType{"serial", []string{"brand-id", "model", "serial"}, assembleSerial}
-	SnapDeclarationType = &AssertionType{"snap-declaration", []string{"series", "snap-id"}, assembleSnapDeclaration}
-	SnapBuildType       = &AssertionType{"snap-build", []string{"series", "snap-id", "snap-digest"}, assembleSnapBuild}
-	SnapRevisionType    = &AssertionType{"snap-revision", []string{"series", "snap-id", "snap-digest"}, assembleSnapRevision}
+	AccountType           = &AssertionType{"account", []string{"account-id"}, assembleAccount}
+	AccountKeyType        = &AssertionType{"account-key", []string{"account-id", "public-key-id"}, assembleAccountKey}
+	AccountKeyRequestType = &AssertionType{"account-key-request", []string{"public-key-id"}, assembleAccountKeyRequest}
+	ModelType             = &AssertionType{"model", []string{"series", "brand-id", "model"}, assembleModel}
+	SerialType            = &AssertionType{"serial", []string{"brand-id", "model", "serial"}, assembleSerial}
+	SnapDeclarationType   = &AssertionType{"snap-declaration", []string{"series", "snap-id"}, assembleSnapDeclaration}
+	SnapBuildType         = &AssertionType{"snap-build", []string{"series", "snap-id", "snap-digest"}, assembleSnapBuild}
+	SnapRevisionType      = &AssertionType{"snap-revision", []string{"series", "snap-id", "snap-digest"}, assembleSnapRevision}
+	ValidationType        = &AssertionType{"validation", []string{"series", "snap-id", "approved-snap-id", "approved-revision"}, assembleValidation}
 
 // ...
 )
 
 var typeRegistry = map[string]*AssertionType{
-	AccountType.Name:         AccountType,
-	AccountKeyType.Name:      AccountKeyType,
-	ModelType.Name:           ModelType,
-	SerialType.Name:          SerialType,
-	SnapDeclarationType.Name: SnapDeclarationType,
-	SnapBuildType.Name:       SnapBuildType,
-	SnapRevisionType.Name:    SnapRevisionType,
+	AccountType.Name:           AccountType,
+	AccountKeyType.Name:        AccountKeyType,
+	AccountKeyRequestType.Name: AccountKeyRequestType,
+	ModelType.Name:             ModelType,
+	SerialType.Name:            SerialType,
+	SnapDeclarationType.Name:   SnapDeclarationType,
+	SnapBuildType.Name:         SnapBuildType,
+	SnapRevisionType.Name:      SnapRevisionType,
+	ValidationType.Name:        ValidationType,
 }
 
 // Type returns the AssertionType with name or nil
@@ -70,6 +72,35 @@ func Type(name string) *AssertionType {
 	return typeRegistry[name]
 }
 
+// Ref expresses a reference to an assertion through its type and
+// primary key.
+type Ref struct {
+	Type       *AssertionType
+	PrimaryKey []string
+}
+
+// Unique returns a unique string representing the reference that can
+// be used as a key in maps.
+func (ref *Ref) Unique() string {
+	return fmt.Sprintf("%s/%s", ref.Type.Name, strings.Join(ref.PrimaryKey, "/"))
+}
+
+func (ref *Ref) String() string {
+	return fmt.Sprintf("%s %v", ref.Type.Name, ref.PrimaryKey)
+}
+
+// Resolve resolves the reference using the given find function.
+func (ref *Ref) Resolve(find func(assertType *AssertionType, headers map[string]string) (Assertion, error)) (Assertion, error) {
+	if len(ref.PrimaryKey) != len(ref.Type.PrimaryKey) {
+		return nil, fmt.Errorf("%q assertion reference primary key has the wrong length (expected %v): %v", ref.Type.Name, ref.Type.PrimaryKey, ref.PrimaryKey)
+	}
+	headers := make(map[string]string, len(ref.PrimaryKey))
+	for i, name := range ref.Type.PrimaryKey {
+		headers[name] = ref.PrimaryKey[i]
+	}
+	return find(ref.Type, headers)
+}
+
 // Assertion represents an assertion through its general elements.
 type Assertion interface {
 	// Type returns the type of this assertion
@@ -79,17 +110,29 @@ type Assertion interface {
 	// AuthorityID returns the authority that signed this assertion
 	AuthorityID() string
 
-	// Header retrieves the header with name
-	Header(name string) string
+	// Header retrieves the header with name, its value is either a
+	// string, a list ([]interface{}) or a map (map[string]interface{})
+	// of such values
+	Header(name string) interface{}
+
+	// HeaderString retrieves the string value of header with name or ""
+	HeaderString(name string) string
 
 	// Headers returns the complete headers
-	Headers() map[string]string
+	Headers() map[string]interface{}
 
 	// Body returns the body of this assertion
 	Body() []byte
 
 	// Signature returns the signed content and its unprocessed signature
 	Signature() (content, signature []byte)
+
+	// Ref returns a reference to this assertion
+	Ref() *Ref
+
+	// Prerequisites returns references to the prerequisite assertions
+	// for the validity of this one, other than the signing account-key
+	Prerequisites() []*Ref
 }
 
 // MediaType is the media type for enconded assertions on the wire.
@@ -98,7 +141,7 @@ const MediaType = "application/x.ubuntu.assertion"
 // assertionBase is the concrete base to hold representation data for actual assertions.
 type assertionBase struct {
 	// TODO: worth having a type *AssertionType cache field now?
-	headers map[string]string
+	headers map[string]interface{}
 	body    []byte
 	// parsed revision
 	revision int
@@ -110,7 +153,7 @@ type assertionBase struct {
 
 // Type returns the assertion type.
 func (ab *assertionBase) Type() *AssertionType {
-	return Type(ab.headers["type"])
+	return Type(ab.HeaderString("type"))
 }
 
 // Revision returns the assertion revision.
@@ -120,21 +163,28 @@ func (ab *assertionBase) Revision() int {
 
 // AuthorityID returns the authority-id a.k.a the signer id of the assertion.
 func (ab *assertionBase) AuthorityID() string {
-	return ab.headers["authority-id"]
+	return ab.HeaderString("authority-id")
 }
 
 // Header returns the value of an header by name.
-func (ab *assertionBase) Header(name string) string {
-	return ab.headers[name]
+func (ab *assertionBase) Header(name string) interface{} {
+	v := ab.headers[name]
+	if v == nil {
+		return nil
+	}
+	return copyHeader(v)
+}
+
+// HeaderString returns the value of a string header by name, or ""
+// if the header is missing or not a string.
+func (ab *assertionBase) HeaderString(name string) string {
+	s, _ := ab.headers[name].(string)
+	return s
 }
 
 // Headers returns the complete headers.
-func (ab *assertionBase) Headers() map[string]string {
-	res := make(map[string]string, len(ab.headers))
-	for name, v := range ab.headers {
-		res[name] = v
-	}
-	return res
+func (ab *assertionBase) Headers() map[string]interface{} {
+	return copyHeaders(ab.headers)
 }
 
 // Body returns the body of the assertion.
@@ -147,74 +197,33 @@ func (ab *assertionBase) Signature() (content, signature []byte) {
 	return ab.content, ab.signature
 }
 
+// Ref returns a reference to the assertion.
+func (ab *assertionBase) Ref() *Ref {
+	assertType := ab.Type()
+	primKey := make([]string, len(assertType.PrimaryKey))
+	for i, name := range assertType.PrimaryKey {
+		primKey[i] = ab.HeaderString(name)
+	}
+	return &Ref{
+		Type:       assertType,
+		PrimaryKey: primKey,
+	}
+}
+
+// Prerequisites returns references to the prerequisite assertions for
+// the validity of this one, by default none.
+func (ab *assertionBase) Prerequisites() []*Ref {
+	return nil
+}
+
 // sanity check
 var _ Assertion = (*assertionBase)(nil)
 
 var (
 	nl   = []byte("\n")
 	nlnl = []byte("\n\n")
-
-	// for basic sanity checking of header names
-	headerNameSanity = regexp.MustCompile("^[a-z][a-z0-9-]*[a-z0-9]$")
 )
 
-func parseHeaders(head []byte) (map[string]string, error) {
-	if !utf8.Valid(head) {
-		return nil, fmt.Errorf("header is not utf8")
-	}
-	headers := make(map[string]string)
-	lines := strings.Split(string(head), "\n")
-	for i := 0; i < len(lines); {
-		entry := lines[i]
-		i++
-		nameValueSplit := strings.Index(entry, ":")
-		if nameValueSplit == -1 {
-			return nil, fmt.Errorf("header entry missing ':' separator: %q", entry)
-		}
-		name := entry[:nameValueSplit]
-		if !headerNameSanity.MatchString(name) {
-			return nil, fmt.Errorf("invalid header name: %q", name)
-		}
-
-		afterSplit := nameValueSplit + 1
-		if afterSplit == len(entry) {
-			// multiline value
-			size := 0
-			j := i
-			for j < len(lines) {
-				iline := lines[j]
-				if len(iline) == 0 || iline[0] != ' ' {
-					break
-				}
-				size += len(iline)
-				j++
-			}
-			if j == i {
-				return nil, fmt.Errorf("empty multiline header value: %q", entry)
-			}
-
-			valueBuf := bytes.NewBuffer(make([]byte, 0, size-1))
-			valueBuf.WriteString(lines[i][1:])
-			i++
-			for i < j {
-				valueBuf.WriteByte('\n')
-				valueBuf.WriteString(lines[i][1:])
-				i++
-			}
-
-			headers[name] = valueBuf.String()
-			continue
-		}
-
-		if entry[afterSplit] != ' ' {
-			return nil, fmt.Errorf("header entry should have a space or newline (multiline) before value: %q", entry)
-		}
-
-		headers[name] = entry[afterSplit+1:]
-	}
-	return headers, nil
-}
-
 // Decode parses a serialized assertion.
 //
 // The expected serialisation format looks like:
@@ -235,6 +244,18 @@ func parseHeaders(head []byte) (map[string]string, error) {
 //
 //   NAME ":\n"  1-space indented VALUE
 //
+// Header values can also be lists or maps of such values, a list
+// entry looks like:
+//
+//   NAME ":\n" "  - " VALUE ("\n  - " VALUE)*
+//
+// and a map entry like:
+//
+//   NAME ":\n" "  " KEY ": " VALUE ("\n  " KEY ": " VALUE)*
+//
+// where nested multiline, list or map values are indented relative
+// to their own entry following the same rules.
+//
 // The following headers are mandatory:
 //
 //   type
@@ -249,7 +270,7 @@ func parseHeaders(head []byte) (map[string]string, error) {
 //   revision (a positive int)
 //   body-length (expected to be equal to the length of BODY)
 //
-// Typically list values in headers are expected to be comma separated.
+// Older assertions may still carry list values as comma separated strings.
 // Times are expected to be in the RFC3339 format: "2006-01-02T15:04:05Z07:00".
 func Decode(serializedAssertion []byte) (Assertion, error) {
 	// copy to get an independent backstorage that can't be mutated later
@@ -456,7 +477,7 @@ func (d *Decoder) Decode() (Assertion, error) {
 	return Assemble(headers, finalBody, finalContent, finalSig)
 }
 
-func checkRevision(headers map[string]string) (int, error) {
+func checkRevision(headers map[string]interface{}) (int, error) {
 	revision, err := checkInteger(headers, "revision", 0)
 	if err != nil {
 		return -1, err
@@ -468,7 +489,7 @@ func checkRevision(headers map[string]string) (int, error) {
 }
 
 // Assemble assembles an assertion from its components.
-func Assemble(headers map[string]string, body, content, signature []byte) (Assertion, error) {
+func Assemble(headers map[string]interface{}, body, content, signature []byte) (Assertion, error) {
 	length, err := checkInteger(headers, "body-length", 0)
 	if err != nil {
 		return nil, fmt.Errorf("assertion: %v", err)
@@ -477,11 +498,11 @@ func Assemble(headers map[string]string, body, content, signature []byte) (Asser
 		return nil, fmt.Errorf("assertion body length and declared body-length don't match: %v != %v", len(body), length)
 	}
 
-	if _, err := checkNotEmpty(headers, "authority-id"); err != nil {
+	if _, err := checkNotEmptyString(headers, "authority-id"); err != nil {
 		return nil, fmt.Errorf("assertion: %v", err)
 	}
 
-	typ, err := checkNotEmpty(headers, "type")
+	typ, err := checkNotEmptyString(headers, "type")
 	if err != nil {
 		return nil, fmt.Errorf("assertion: %v", err)
 	}
@@ -518,37 +539,25 @@ func Assemble(headers map[string]string, body, content, signature []byte) (Asser
 	return assert, nil
 }
 
-func writeHeader(buf *bytes.Buffer, headers map[string]string, name string) {
-	buf.WriteByte('\n')
-	buf.WriteString(name)
-	value := headers[name]
-	if strings.IndexRune(value, '\n') != -1 {
-		// multiline value => quote by 1-space indenting
-		buf.WriteString(":\n ")
-		value = strings.Replace(value, "\n", "\n ", -1)
-	} else {
-		buf.WriteString(": ")
-	}
-	buf.WriteString(value)
-}
-
-func assembleAndSign(assertType *AssertionType, headers map[string]string, body []byte, privKey PrivateKey) (Assertion, error) {
+func assembleAndSign(assertType *AssertionType, headers map[string]interface{}, body []byte, privKey PrivateKey) (Assertion, error) {
 	err := checkAssertType(assertType)
 	if err != nil {
 		return nil, err
 	}
 
-	finalHeaders := make(map[string]string, len(headers))
-	for name, value := range headers {
-		finalHeaders[name] = value
+	err = checkHeaders(headers)
+	if err != nil {
+		return nil, err
 	}
+
+	finalHeaders := copyHeaders(headers)
 	bodyLength := len(body)
 	finalBody := make([]byte, bodyLength)
 	copy(finalBody, body)
 	finalHeaders["type"] = assertType.Name
 	finalHeaders["body-length"] = strconv.Itoa(bodyLength)
 
-	if _, err := checkNotEmpty(finalHeaders, "authority-id"); err != nil {
+	if _, err := checkNotEmptyString(finalHeaders, "authority-id"); err != nil {
 		return nil, err
 	}
 
@@ -560,9 +569,9 @@ func assembleAndSign(assertType *AssertionType, headers map[string]string, body
 	buf := bytes.NewBufferString("type: ")
 	buf.WriteString(assertType.Name)
 
-	writeHeader(buf, finalHeaders, "authority-id")
+	appendEntry(buf, "authority-id:", finalHeaders["authority-id"], 0)
 	if revision > 0 {
-		writeHeader(buf, finalHeaders, "revision")
+		appendEntry(buf, "revision:", finalHeaders["revision"], 0)
 	} else {
 		delete(finalHeaders, "revision")
 	}
@@ -576,7 +585,7 @@ func assembleAndSign(assertType *AssertionType, headers map[string]string, body
 		if _, err := checkPrimaryKey(finalHeaders, primKey); err != nil {
 			return nil, err
 		}
-		writeHeader(buf, finalHeaders, primKey)
+		appendEntry(buf, primKey+":", finalHeaders[primKey], 0)
 		written[primKey] = true
 	}
 
@@ -589,12 +598,12 @@ func assembleAndSign(assertType *AssertionType, headers map[string]string, body
 	}
 	sort.Strings(otherKeys)
 	for _, k := range otherKeys {
-		writeHeader(buf, finalHeaders, k)
+		appendEntry(buf, k+":", finalHeaders[k], 0)
 	}
 
 	// body-length and body
 	if bodyLength > 0 {
-		writeHeader(buf, finalHeaders, "body-length")
+		appendEntry(buf, "body-length:", finalHeaders["body-length"], 0)
 	} else {
 		delete(finalHeaders, "body-length")
 	}
@@ -56,7 +56,7 @@ func (as *assertsSuite) TestDecodeEmptyBodyAllDefaults(c *C) {
 	c.Check(ok, Equals, true)
 	c.Check(a.Revision(), Equals, 0)
 	c.Check(a.Body(), IsNil)
-	c.Check(a.Header("header1"), Equals, "")
+	c.Check(a.Header("header1"), IsNil)
 	c.Check(a.AuthorityID(), Equals, "auth-id1")
 }
 
@@ -136,6 +136,13 @@ func (as *assertsSuite) TestDecodeHeaderParsingErrors(c *C) {
 		{"foo: a\nbar:>\n\n", `header entry should have a space or newline \(multiline\) before value: "bar:>"`},
 		{"foo: a\nbar:\n\n", `empty multiline header value: "bar:"`},
 		{"foo: a\nbar:\nbaz: x\n\n", `empty multiline header value: "bar:"`},
+		{"foo: a\nfoo: b\n\n", `repeated header: "foo"`},
+		{"foo:\n  - a\n  b\n\n", `expected list entry: "  b"`},
+		{"foo:\n  - a\n  -b\n\n", `list entry should have a space or newline \(multiline\) before value: "  -b"`},
+		{"foo:\n  -\nbar: x\n\n", `empty multiline list entry: "  -"`},
+		{"foo:\n  a: x\n   b: y\n\n", `invalid header name: " b"`},
+		{"foo:\n  a: x\n  a: y\n\n", `repeated header: "a"`},
+		{"foo:\n  a: x\n  - y\n\n", `header entry missing ':' separator: "- y"`},
 	}
 
 	for _, test := range headerParsingErrorsTests {
@@ -167,6 +174,8 @@ func (as *assertsSuite) TestDecodeInvalid(c *C) {
 		{"revision: 0\n", "revision: -10\n", "assertion: revision should be positive: -10"},
 		{"primary-key: abc\n", "", `assertion test-only: "primary-key" header is mandatory`},
 		{"primary-key: abc\n", "primary-key: a/c\n", `assertion test-only: "primary-key" primary key header cannot contain '/'`},
+		{"primary-key: abc\n", "primary-key:\n  - abc\n", `assertion test-only: "primary-key" header must be a string`},
+		{"revision: 0\n", "revision:\n  a: 1\n", `assertion: "revision" header must be a string`},
 	}
 
 	for _, test := range invalidAssertTests {
@@ -391,7 +400,7 @@ func (as *assertsSuite) TestEncoderSingleDecodeOK(c *C) {
 }
 
 func (as *assertsSuite) TestSignFormatSanityEmptyBody(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "auth-id1",
 		"primary-key":  "0",
 	}
@@ -403,7 +412,7 @@ func (as *assertsSuite) TestSignFormatSanityEmptyBody(c *C) {
 }
 
 func (as *assertsSuite) TestSignFormatSanityNonEmptyBody(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "auth-id1",
 		"primary-key":  "0",
 	}
@@ -418,7 +427,7 @@ func (as *assertsSuite) TestSignFormatSanityNonEmptyBody(c *C) {
 }
 
 func (as *assertsSuite) TestSignFormatSanitySupportMultilineHeaderValues(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "auth-id1",
 		"primary-key":  "0",
 	}
@@ -448,6 +457,130 @@ func (as *assertsSuite) TestSignFormatSanitySupportMultilineHeaderValues(c *C) {
 	}
 }
 
+const exampleNestedHeaders = "type: test-only\n" +
+	"authority-id: auth-id1\n" +
+	"primary-key: abc\n" +
+	"list:\n" +
+	"  - one\n" +
+	"  -\n" +
+	"   multi\n" +
+	"   line\n" +
+	"  -\n" +
+	"    - nested\n" +
+	"  -\n" +
+	"    x: 1\n" +
+	"map:\n" +
+	"  a: one\n" +
+	"  b:\n" +
+	"   multi\n" +
+	"   line\n" +
+	"  c:\n" +
+	"    - x\n" +
+	"    - y\n" +
+	"  d:\n" +
+	"    e: f\n" +
+	"multiline:\n" +
+	" - not a list\n" +
+	" a: not a map" +
+	"\n\n" +
+	"openpgp c2ln"
+
+func (as *assertsSuite) TestDecodeNestedHeaders(c *C) {
+	a, err := asserts.Decode([]byte(exampleNestedHeaders))
+	c.Assert(err, IsNil)
+	c.Check(a.Header("list"), DeepEquals, []interface{}{
+		"one",
+		"multi\nline",
+		[]interface{}{"nested"},
+		map[string]interface{}{"x": "1"},
+	})
+	c.Check(a.Header("map"), DeepEquals, map[string]interface{}{
+		"a": "one",
+		"b": "multi\nline",
+		"c": []interface{}{"x", "y"},
+		"d": map[string]interface{}{"e": "f"},
+	})
+	c.Check(a.Header("multiline"), Equals, "- not a list\na: not a map")
+	c.Check(a.HeaderString("multiline"), Equals, "- not a list\na: not a map")
+	c.Check(a.HeaderString("list"), Equals, "")
+	c.Check(a.HeaderString("header1"), Equals, "")
+}
+
+func (as *assertsSuite) TestSignFormatNestedHeaderValues(c *C) {
+	headers := map[string]interface{}{
+		"authority-id": "auth-id1",
+		"primary-key":  "0",
+		"list": []interface{}{
+			"one",
+			"",
+			"multi\nline\n",
+			[]interface{}{"nested", []interface{}{"deeper"}},
+			map[string]interface{}{"x": "1", "y": []interface{}{"2"}},
+		},
+		"map": map[string]interface{}{
+			"a": "one",
+			"b": "\nmulti\nline",
+			"c": []interface{}{"x", "y"},
+			"d": map[string]interface{}{"e": map[string]interface{}{"f": "g"}},
+		},
+	}
+
+	a, err := asserts.AssembleAndSignInTest(asserts.TestOnlyType, headers, nil, testPrivKey1)
+	c.Assert(err, IsNil)
+
+	decoded, err := asserts.Decode(asserts.Encode(a))
+	c.Assert(err, IsNil)
+	c.Check(decoded.Headers(), DeepEquals, a.Headers())
+	c.Check(decoded.Header("list"), DeepEquals, headers["list"])
+	c.Check(decoded.Header("map"), DeepEquals, headers["map"])
+}
+
+func (as *assertsSuite) TestSignFormatFlatHeadersUnchanged(c *C) {
+	headers := map[string]interface{}{
+		"authority-id": "auth-id1",
+		"primary-key":  "0",
+		"header1":      "value1",
+		"multiline":    "a\nb",
+	}
+	a, err := asserts.AssembleAndSignInTest(asserts.TestOnlyType, headers, nil, testPrivKey1)
+	c.Assert(err, IsNil)
+
+	cont, _ := a.Signature()
+	c.Check(string(cont), Equals, "type: test-only\n"+
+		"authority-id: auth-id1\n"+
+		"primary-key: 0\n"+
+		"header1: value1\n"+
+		"multiline:\n"+
+		" a\n"+
+		" b")
+}
+
+func (as *assertsSuite) TestSignFormatInvalidHeaderValues(c *C) {
+	tests := []struct {
+		value       interface{}
+		expectedErr string
+	}{
+		{[]interface{}{}, `header "h": empty lists are not supported`},
+		{map[string]interface{}{}, `header "h": empty maps are not supported`},
+		{[]interface{}{"a", []interface{}{}}, `header "h": empty lists are not supported`},
+		{map[string]interface{}{"A": "a"}, `header "h": invalid map entry key: "A"`},
+		{42, `header "h": header values must be strings or nested lists or maps with strings as the only scalars: 42`},
+		{[]interface{}{nil}, `header "h": header values must be strings or nested lists or maps with strings as the only scalars: <nil>`},
+		{" - a\nb", `header "h": multiline value starts ambiguously: " - a"`},
+		{" a: b\nc", `header "h": multiline value starts ambiguously: " a: b"`},
+	}
+
+	for _, test := range tests {
+		headers := map[string]interface{}{
+			"authority-id": "auth-id1",
+			"primary-key":  "0",
+			"h":            test.value,
+		}
+		_, err := asserts.AssembleAndSignInTest(asserts.TestOnlyType, headers, nil, testPrivKey1)
+		c.Check(err, ErrorMatches, test.expectedErr)
+	}
+}
+
 func (as *assertsSuite) TestHeaders(c *C) {
 	encoded := []byte("type: test-only\n" +
 		"authority-id: auth-id2\n" +
@@ -463,7 +596,7 @@ func (as *assertsSuite) TestHeaders(c *C) {
 	c.Assert(err, IsNil)
 
 	hs := a.Headers()
-	c.Check(hs, DeepEquals, map[string]string{
+	c.Check(hs, DeepEquals, map[string]interface{}{
 		"type":         "test-only",
 		"authority-id": "auth-id2",
 		"primary-key":  "abc",
@@ -494,6 +627,20 @@ func (as *assertsSuite) TestHeadersReturnsCopy(c *C) {
 	c.Check(a.Header("primary-key"), Equals, "xyz")
 }
 
+func (as *assertsSuite) TestNestedHeadersReturnCopies(c *C) {
+	a, err := asserts.Decode([]byte(exampleNestedHeaders))
+	c.Assert(err, IsNil)
+
+	hs := a.Headers()
+	hs["list"].([]interface{})[0] = "mutated"
+	delete(hs["map"].(map[string]interface{}), "a")
+	l := a.Header("list").([]interface{})
+	l[0] = "mutated"
+
+	c.Check(a.Header("list").([]interface{})[0], Equals, "one")
+	c.Check(a.Header("map").(map[string]interface{})["a"], Equals, "one")
+}
+
 func (as *assertsSuite) TestAssembleRoundtrip(c *C) {
 	encoded := []byte("type: test-only\n" +
 		"authority-id: auth-id2\n" +
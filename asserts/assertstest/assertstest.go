@@ -0,0 +1,153 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package assertstest provides helpers for testing code that involves assertions.
+package assertstest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/openpgp/packet"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+// GenerateKey generates a private/public key pair of the given bits. It panics on error.
+func GenerateKey(bits int) asserts.PrivateKey {
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		panic(fmt.Errorf("failed to create private key: %v", err))
+	}
+	return asserts.OpenPGPPrivateKey(packet.NewRSAPrivateKey(time.Now(), priv))
+}
+
+// SigningDB embeds a signing assertion database with a default private key and assigned authority id.
+// Sign will use the assigned authority id.
+type SigningDB struct {
+	AuthorityID string
+	KeyID       string
+
+	*asserts.Database
+}
+
+// NewSigningDB creates a test signing assertion db with the given defaults. It panics on error.
+func NewSigningDB(authorityID string, privKey asserts.PrivateKey) *SigningDB {
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+	})
+	if err != nil {
+		panic(err)
+	}
+	err = db.ImportKey(authorityID, privKey)
+	if err != nil {
+		panic(err)
+	}
+	return &SigningDB{
+		AuthorityID: authorityID,
+		KeyID:       privKey.PublicKey().ID(),
+		Database:    db,
+	}
+}
+
+// Sign signs an assertion of the given type with the default key,
+// setting the authority-id header if it is missing.
+func (db *SigningDB) Sign(assertType *asserts.AssertionType, headers map[string]interface{}, body []byte) (asserts.Assertion, error) {
+	h := make(map[string]interface{}, len(headers)+1)
+	for k, v := range headers {
+		h[k] = v
+	}
+	if authorityID, _ := h["authority-id"].(string); authorityID == "" {
+		h["authority-id"] = db.AuthorityID
+	}
+	return db.Database.Sign(assertType, h, body, db.KeyID)
+}
+
+// NewAccount creates an account assertion for accountID signed by db. It panics on error.
+func NewAccount(db *SigningDB, accountID string, otherHeaders map[string]interface{}) *asserts.Account {
+	headers := map[string]interface{}{
+		"account-id":   accountID,
+		"username":     accountID,
+		"display-name": accountID,
+		"validation":   "unproven",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+	for k, v := range otherHeaders {
+		headers[k] = v
+	}
+	a, err := db.Sign(asserts.AccountType, headers, nil)
+	if err != nil {
+		panic(err)
+	}
+	return a.(*asserts.Account)
+}
+
+// NewAccountKey creates an account-key assertion for the public key of
+// accountID signed by db. It panics on error.
+func NewAccountKey(db *SigningDB, accountID string, pubKey asserts.PublicKey, otherHeaders map[string]interface{}) *asserts.AccountKey {
+	body, err := asserts.EncodePublicKey(pubKey)
+	if err != nil {
+		panic(err)
+	}
+	now := time.Now().UTC()
+	headers := map[string]interface{}{
+		"account-id":             accountID,
+		"public-key-id":          pubKey.ID(),
+		"public-key-fingerprint": pubKey.Fingerprint(),
+		"since":                  now.Format(time.RFC3339),
+		"until":                  now.AddDate(10, 0, 0).Format(time.RFC3339),
+	}
+	for k, v := range otherHeaders {
+		headers[k] = v
+	}
+	a, err := db.Sign(asserts.AccountKeyType, headers, body)
+	if err != nil {
+		panic(err)
+	}
+	return a.(*asserts.AccountKey)
+}
+
+// StoreStack realises a store-like set of founding trusted assertions and signing setup.
+type StoreStack struct {
+	// TrustedKey is the self-signed root account-key to be trusted
+	TrustedKey *asserts.AccountKey
+	// RootSigning signs with the root key
+	RootSigning *SigningDB
+	// StoreAccountKey is the account-key of the store key, signed by the root key
+	StoreAccountKey *asserts.AccountKey
+
+	// the embedded SigningDB signs with the store key
+	*SigningDB
+}
+
+// NewStoreStack creates a new store assertion stack using the given private keys.
+func NewStoreStack(authorityID string, rootPrivKey, storePrivKey asserts.PrivateKey) *StoreStack {
+	rootSigning := NewSigningDB(authorityID, rootPrivKey)
+	trustedKey := NewAccountKey(rootSigning, authorityID, rootPrivKey.PublicKey(), nil)
+	storeAccKey := NewAccountKey(rootSigning, authorityID, storePrivKey.PublicKey(), nil)
+
+	return &StoreStack{
+		TrustedKey:      trustedKey,
+		RootSigning:     rootSigning,
+		StoreAccountKey: storeAccKey,
+		SigningDB:       NewSigningDB(authorityID, storePrivKey),
+	}
+}
@@ -0,0 +1,65 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstest_test
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+)
+
+func TestAssertsTest(t *testing.T) { TestingT(t) }
+
+type helperSuite struct{}
+
+var _ = Suite(&helperSuite{})
+
+func (s *helperSuite) TestStoreStack(c *C) {
+	rootPrivKey := assertstest.GenerateKey(752)
+	storePrivKey := assertstest.GenerateKey(752)
+
+	store := assertstest.NewStoreStack("super", rootPrivKey, storePrivKey)
+	c.Check(store.TrustedKey.AccountID(), Equals, "super")
+	c.Check(store.TrustedKey.PublicKeyID(), Equals, rootPrivKey.PublicKey().ID())
+	c.Check(store.StoreAccountKey.PublicKeyID(), Equals, storePrivKey.PublicKey().ID())
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		Trusted:        []asserts.Assertion{store.TrustedKey},
+	})
+	c.Assert(err, IsNil)
+
+	err = db.Add(store.StoreAccountKey)
+	c.Assert(err, IsNil)
+
+	acct := assertstest.NewAccount(store.SigningDB, "devel1", nil)
+	c.Check(acct.AuthorityID(), Equals, "super")
+	c.Check(acct.Username(), Equals, "devel1")
+	err = db.Add(acct)
+	c.Assert(err, IsNil)
+
+	keyID, err := asserts.SignatureKeyID(acct)
+	c.Assert(err, IsNil)
+	c.Check(keyID, Equals, store.KeyID)
+}
@@ -218,8 +218,8 @@ func (db *Database) PublicKey(authorityID string, keyID string) (PublicKey, erro
 
 // Sign assembles an assertion with the provided information and signs it
 // with the private key from `headers["authority-id"]` that has the provided key id.
-func (db *Database) Sign(assertType *AssertionType, headers map[string]string, body []byte, keyID string) (Assertion, error) {
-	authorityID, err := checkNotEmpty(headers, "authority-id")
+func (db *Database) Sign(assertType *AssertionType, headers map[string]interface{}, body []byte, keyID string) (Assertion, error) {
+	authorityID, err := checkNotEmptyString(headers, "authority-id")
 	if err != nil {
 		return nil, err
 	}
@@ -246,6 +246,54 @@ func (db *Database) findAccountKey(authorityID, keyID string) (*AccountKey, erro
 	return nil, ErrNotFound
 }
 
+// maxSigningChain bounds the length of the chains of account-keys
+// followed when checking for revocations.
+const maxSigningChain = 16
+
+// isRevoked returns whether the assertion was signed, directly or
+// through a chain of account-keys, with a key that has been revoked.
+// Trusted assertions are never revoked.
+func (db *Database) isRevoked(assert Assertion) (bool, error) {
+	for i := 0; i < maxSigningChain; i++ {
+		ref := assert.Ref()
+		if _, err := db.trusted.Get(ref.Type, ref.PrimaryKey); err == nil {
+			return false, nil
+		}
+		keyID, err := SignatureKeyID(assert)
+		if err != nil {
+			return false, err
+		}
+		accKey, err := db.findAccountKey(assert.AuthorityID(), keyID)
+		if err == ErrNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if accKey.Revoked() {
+			return true, nil
+		}
+		if accKey.Ref().Unique() == ref.Unique() {
+			// self-signed
+			return false, nil
+		}
+		assert = accKey
+	}
+	return false, fmt.Errorf("cannot check %s for revocations: too long chain of signing keys", assert.Ref())
+}
+
+// SignatureKeyID returns the id of the key that signed the assertion,
+// which together with its authority-id identifies the account-key
+// needed to check it.
+func SignatureKeyID(assert Assertion) (string, error) {
+	_, signature := assert.Signature()
+	sig, err := decodeSignature(signature)
+	if err != nil {
+		return "", err
+	}
+	return sig.KeyID(), nil
+}
+
 // Check tests whether the assertion is properly signed and consistent with all the stored knowledge.
 func (db *Database) Check(assert Assertion) error {
 	_, signature := assert.Signature()
@@ -261,6 +309,16 @@ func (db *Database) Check(assert Assertion) error {
 	if err != nil {
 		return fmt.Errorf("error finding matching public key for signature: %v", err)
 	}
+	if accKey.Revoked() {
+		return fmt.Errorf("assertion is signed with revoked public key %q from %q", sig.KeyID(), assert.AuthorityID())
+	}
+	revoked, err := db.isRevoked(accKey)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return fmt.Errorf("assertion is signed with public key %q from %q that was signed with a revoked key", sig.KeyID(), assert.AuthorityID())
+	}
 
 	now := time.Now()
 	for _, checker := range db.checkers {
@@ -284,7 +342,7 @@ func (db *Database) Add(assert Assertion) error {
 
 	keyValues := make([]string, len(assertType.PrimaryKey))
 	for i, k := range assertType.PrimaryKey {
-		keyVal := assert.Header(k)
+		keyVal := assert.HeaderString(k)
 		if keyVal == "" {
 			return fmt.Errorf("missing primary key header: %v", k)
 		}
@@ -305,7 +363,7 @@ func (db *Database) Add(assert Assertion) error {
 func searchMatch(assert Assertion, expectedHeaders map[string]string) bool {
 	// check non-primary-key headers as well
 	for expectedKey, expectedValue := range expectedHeaders {
-		if assert.Header(expectedKey) != expectedValue {
+		if assert.HeaderString(expectedKey) != expectedValue {
 			return false
 		}
 	}
@@ -314,7 +372,8 @@ func searchMatch(assert Assertion, expectedHeaders map[string]string) bool {
 
 // Find an assertion based on arbitrary headers.
 // Provided headers must contain the primary key for the assertion type.
-// It returns ErrNotFound if the assertion cannot be found.
+// It returns ErrNotFound if the assertion cannot be found, or if it
+// has been invalidated by the revocation of a key that signed it.
 func (db *Database) Find(assertionType *AssertionType, headers map[string]string) (Assertion, error) {
 	err := checkAssertType(assertionType)
 	if err != nil {
@@ -345,10 +404,19 @@ func (db *Database) Find(assertionType *AssertionType, headers map[string]string
 		return nil, ErrNotFound
 	}
 
+	revoked, err := db.isRevoked(assert)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrNotFound
+	}
+
 	return assert, nil
 }
 
-// FindMany finds assertions based on arbitrary headers.
+// FindMany finds assertions based on arbitrary headers, leaving out
+// the ones invalidated by the revocation of a key that signed them.
 // It returns ErrNotFound if no assertion can be found.
 func (db *Database) FindMany(assertionType *AssertionType, headers map[string]string) ([]Assertion, error) {
 	err := checkAssertType(assertionType)
@@ -357,8 +425,16 @@ func (db *Database) FindMany(assertionType *AssertionType, headers map[string]st
 	}
 	res := []Assertion{}
 
+	var revokedErr error
 	foundCb := func(assert Assertion) {
-		res = append(res, assert)
+		revoked, err := db.isRevoked(assert)
+		if err != nil {
+			revokedErr = err
+			return
+		}
+		if !revoked {
+			res = append(res, assert)
+		}
 	}
 
 	for _, bs := range db.backstores {
@@ -366,6 +442,9 @@ func (db *Database) FindMany(assertionType *AssertionType, headers map[string]st
 		if err != nil {
 			return nil, err
 		}
+		if revokedErr != nil {
+			return nil, revokedErr
+		}
 	}
 
 	if len(res) == 0 {
@@ -35,6 +35,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 )
 
 func Test(t *testing.T) { TestingT(t) }
@@ -59,7 +60,7 @@ func (opens *openSuite) TestOpenDatabasePanicOnUnsetBackstores(c *C) {
 }
 
 func (opens *openSuite) TestOpenDatabaseTrustedAccount(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"account-id":   "trusted",
 		"display-name": "Trusted",
@@ -87,7 +88,7 @@ func (opens *openSuite) TestOpenDatabaseTrustedAccount(c *C) {
 }
 
 func (opens *openSuite) TestOpenDatabaseTrustedWrongType(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "0",
 	}
@@ -202,7 +203,7 @@ func (chks *checkSuite) SetUpTest(c *C) {
 	chks.bs, err = asserts.OpenFSBackstore(topDir)
 	c.Assert(err, IsNil)
 
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "0",
 	}
@@ -310,7 +311,7 @@ func (safs *signAddFindSuite) SetUpTest(c *C) {
 }
 
 func (safs *signAddFindSuite) TestSign(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "a",
 	}
@@ -321,8 +322,21 @@ func (safs *signAddFindSuite) TestSign(c *C) {
 	c.Check(err, IsNil)
 }
 
+func (safs *signAddFindSuite) TestSignatureKeyID(c *C) {
+	headers := map[string]interface{}{
+		"authority-id": "canonical",
+		"primary-key":  "a",
+	}
+	a1, err := safs.signingDB.Sign(asserts.TestOnlyType, headers, nil, safs.signingKeyID)
+	c.Assert(err, IsNil)
+
+	keyID, err := asserts.SignatureKeyID(a1)
+	c.Assert(err, IsNil)
+	c.Check(keyID, Equals, safs.signingKeyID)
+}
+
 func (safs *signAddFindSuite) TestSignEmptyKeyID(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "a",
 	}
@@ -332,7 +346,7 @@ func (safs *signAddFindSuite) TestSignEmptyKeyID(c *C) {
 }
 
 func (safs *signAddFindSuite) TestSignMissingAuthorityId(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"primary-key": "a",
 	}
 	a1, err := safs.signingDB.Sign(asserts.TestOnlyType, headers, nil, safs.signingKeyID)
@@ -341,7 +355,7 @@ func (safs *signAddFindSuite) TestSignMissingAuthorityId(c *C) {
 }
 
 func (safs *signAddFindSuite) TestSignMissingPrimaryKey(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 	}
 	a1, err := safs.signingDB.Sign(asserts.TestOnlyType, headers, nil, safs.signingKeyID)
@@ -350,7 +364,7 @@ func (safs *signAddFindSuite) TestSignMissingPrimaryKey(c *C) {
 }
 
 func (safs *signAddFindSuite) TestSignPrimaryKeyWithSlash(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "baz/9000",
 	}
@@ -360,7 +374,7 @@ func (safs *signAddFindSuite) TestSignPrimaryKeyWithSlash(c *C) {
 }
 
 func (safs *signAddFindSuite) TestSignNoPrivateKey(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "a",
 	}
@@ -370,7 +384,7 @@ func (safs *signAddFindSuite) TestSignNoPrivateKey(c *C) {
 }
 
 func (safs *signAddFindSuite) TestSignUnknownType(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 	}
 	a1, err := safs.signingDB.Sign(&asserts.AssertionType{Name: "xyz", PrimaryKey: nil}, headers, nil, safs.signingKeyID)
@@ -379,7 +393,7 @@ func (safs *signAddFindSuite) TestSignUnknownType(c *C) {
 }
 
 func (safs *signAddFindSuite) TestSignNonPredefinedType(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 	}
 	a1, err := safs.signingDB.Sign(&asserts.AssertionType{Name: "test-only", PrimaryKey: nil}, headers, nil, safs.signingKeyID)
@@ -388,7 +402,7 @@ func (safs *signAddFindSuite) TestSignNonPredefinedType(c *C) {
 }
 
 func (safs *signAddFindSuite) TestSignBadRevision(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "a",
 		"revision":     "zzz",
@@ -399,7 +413,7 @@ func (safs *signAddFindSuite) TestSignBadRevision(c *C) {
 }
 
 func (safs *signAddFindSuite) TestSignAssemblerError(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "a",
 		"count":        "zzz",
@@ -410,7 +424,7 @@ func (safs *signAddFindSuite) TestSignAssemblerError(c *C) {
 }
 
 func (safs *signAddFindSuite) TestAddSuperseding(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "a",
 	}
@@ -446,7 +460,7 @@ func (safs *signAddFindSuite) TestAddSuperseding(c *C) {
 }
 
 func (safs *signAddFindSuite) TestFindNotFound(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "a",
 	}
@@ -478,7 +492,7 @@ func (safs *signAddFindSuite) TestFindPrimaryLeftOut(c *C) {
 }
 
 func (safs *signAddFindSuite) TestFindMany(c *C) {
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "a",
 		"other":        "other-x",
@@ -488,7 +502,7 @@ func (safs *signAddFindSuite) TestFindMany(c *C) {
 	err = safs.db.Add(aa)
 	c.Assert(err, IsNil)
 
-	headers = map[string]string{
+	headers = map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "b",
 		"other":        "other-y",
@@ -498,7 +512,7 @@ func (safs *signAddFindSuite) TestFindMany(c *C) {
 	err = safs.db.Add(ab)
 	c.Assert(err, IsNil)
 
-	headers = map[string]string{
+	headers = map[string]interface{}{
 		"authority-id": "canonical",
 		"primary-key":  "c",
 		"other":        "other-x",
@@ -513,7 +527,7 @@ func (safs *signAddFindSuite) TestFindMany(c *C) {
 	})
 	c.Assert(err, IsNil)
 	c.Assert(res, HasLen, 2)
-	primKeys := []string{res[0].Header("primary-key"), res[1].Header("primary-key")}
+	primKeys := []string{res[0].HeaderString("primary-key"), res[1].HeaderString("primary-key")}
 	sort.Strings(primKeys)
 	c.Check(primKeys, DeepEquals, []string{"a", "c"})
 
@@ -549,7 +563,7 @@ func (safs *signAddFindSuite) TestFindFindsTrustedAccountKeys(c *C) {
 	c.Assert(err, IsNil)
 
 	now := time.Now().UTC()
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id":           "canonical",
 		"account-id":             "acc-id1",
 		"public-key-id":          pk1.PublicKey().ID(),
@@ -586,7 +600,7 @@ func (safs *signAddFindSuite) TestDontLetAddConfusinglyAssertionClashingWithTrus
 	c.Assert(err, IsNil)
 
 	now := time.Now().UTC()
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id":           "canonical",
 		"account-id":             "canonical",
 		"public-key-id":          safs.signingKeyID,
@@ -698,3 +712,99 @@ ZF5jSvRDLgI=`
 	err = db.Check(a)
 	c.Check(err, IsNil)
 }
+
+type revocationSuite struct {
+	storeSigning *assertstest.StoreStack
+	devSigning   *assertstest.SigningDB
+	devKey       *asserts.AccountKey
+	db           *asserts.Database
+}
+
+var _ = Suite(&revocationSuite{})
+
+func (rs *revocationSuite) SetUpTest(c *C) {
+	rs.storeSigning = assertstest.NewStoreStack("canonical", testPrivKey0, testPrivKey1)
+	rs.devSigning = assertstest.NewSigningDB("dev1", testPrivKey2)
+	rs.devKey = assertstest.NewAccountKey(rs.storeSigning.SigningDB, "dev1", testPrivKey2.PublicKey(), nil)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		Trusted:        []asserts.Assertion{rs.storeSigning.TrustedKey},
+	})
+	c.Assert(err, IsNil)
+	rs.db = db
+	for _, a := range []asserts.Assertion{rs.storeSigning.StoreAccountKey, rs.devKey} {
+		err := db.Add(a)
+		c.Assert(err, IsNil)
+	}
+}
+
+func (rs *revocationSuite) signSnapBuild(c *C, snapID string) asserts.Assertion {
+	snapBuild, err := rs.devSigning.Sign(asserts.SnapBuildType, map[string]interface{}{
+		"series":      "16",
+		"snap-id":     snapID,
+		"snap-digest": "sha512-digest",
+		"grade":       "devel",
+		"snap-size":   "1025",
+		"timestamp":   time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, IsNil)
+	return snapBuild
+}
+
+func (rs *revocationSuite) checkInvalidated(c *C, snapBuild asserts.Assertion) {
+	_, err := snapBuild.Ref().Resolve(rs.db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+	_, err = rs.db.FindMany(asserts.SnapBuildType, map[string]string{
+		"series": "16",
+	})
+	c.Check(err, Equals, asserts.ErrNotFound)
+}
+
+func (rs *revocationSuite) TestRevokedKey(c *C) {
+	snapBuild := rs.signSnapBuild(c, "snap-id-1")
+	err := rs.db.Add(snapBuild)
+	c.Assert(err, IsNil)
+
+	_, err = snapBuild.Ref().Resolve(rs.db.Find)
+	c.Assert(err, IsNil)
+
+	revokedKey := assertstest.NewAccountKey(rs.storeSigning.SigningDB, "dev1", testPrivKey2.PublicKey(), map[string]interface{}{
+		"revision": "1",
+		"revoked":  "true",
+	})
+	err = rs.db.Add(revokedKey)
+	c.Assert(err, IsNil)
+
+	// the revoked key itself can still be found
+	a, err := rs.devKey.Ref().Resolve(rs.db.Find)
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.AccountKey).Revoked(), Equals, true)
+
+	rs.checkInvalidated(c, snapBuild)
+
+	err = rs.db.Add(rs.signSnapBuild(c, "snap-id-2"))
+	c.Check(err, ErrorMatches, `assertion is signed with revoked public key ".*" from "dev1"`)
+}
+
+func (rs *revocationSuite) TestRevokedSigningChain(c *C) {
+	snapBuild := rs.signSnapBuild(c, "snap-id-1")
+	err := rs.db.Add(snapBuild)
+	c.Assert(err, IsNil)
+
+	// revoke the store key that signed the developer key
+	revokedStoreKey := assertstest.NewAccountKey(rs.storeSigning.RootSigning, "canonical", testPrivKey1.PublicKey(), map[string]interface{}{
+		"revision": "1",
+		"revoked":  "true",
+	})
+	err = rs.db.Add(revokedStoreKey)
+	c.Assert(err, IsNil)
+
+	_, err = rs.devKey.Ref().Resolve(rs.db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+	rs.checkInvalidated(c, snapBuild)
+
+	err = rs.db.Add(rs.signSnapBuild(c, "snap-id-2"))
+	c.Check(err, ErrorMatches, `assertion is signed with public key ".*" from "dev1" that was signed with a revoked key`)
+}
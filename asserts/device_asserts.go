@@ -35,42 +35,42 @@ type Model struct {
 
 // BrandID returns the brand identifier. Same as the authority id.
 func (mod *Model) BrandID() string {
-	return mod.Header("brand-id")
+	return mod.HeaderString("brand-id")
 }
 
 // Model returns the model name identifier.
 func (mod *Model) Model() string {
-	return mod.Header("model")
+	return mod.HeaderString("model")
 }
 
 // Series returns the series of the core software the model uses.
 func (mod *Model) Series() string {
-	return mod.Header("series")
+	return mod.HeaderString("series")
 }
 
 // Core returns the core snap the model uses.
 func (mod *Model) Core() string {
-	return mod.Header("core")
+	return mod.HeaderString("core")
 }
 
 // Architecture returns the archicteture the model is based on.
 func (mod *Model) Architecture() string {
-	return mod.Header("architecture")
+	return mod.HeaderString("architecture")
 }
 
 // Gadget returns the gadget snap the model uses.
 func (mod *Model) Gadget() string {
-	return mod.Header("gadget")
+	return mod.HeaderString("gadget")
 }
 
 // Kernel returns the kernel snap the model uses.
 func (mod *Model) Kernel() string {
-	return mod.Header("kernel")
+	return mod.HeaderString("kernel")
 }
 
 // Store returns the snap store the model uses.
 func (mod *Model) Store() string {
-	return mod.Header("store")
+	return mod.HeaderString("store")
 }
 
 // AllowedModes returns which ones of the "classic" and "developer" modes are allowed for the model.
@@ -86,7 +86,7 @@ func (mod *Model) RequiredSnaps() []string {
 // Class returns which class the model belongs to defining policies for
 // additional software installation.
 func (mod *Model) Class() string {
-	return mod.Header("class")
+	return mod.HeaderString("class")
 }
 
 // Timestamp returns the time when the model assertion was issued.
@@ -106,24 +106,24 @@ var _ consistencyChecker = (*Model)(nil)
 var modelMandatory = []string{"core", "architecture", "gadget", "kernel", "store", "class"}
 
 func assembleModel(assert assertionBase) (Assertion, error) {
-	if assert.headers["brand-id"] != assert.headers["authority-id"] {
-		return nil, fmt.Errorf("authority-id and brand-id must match, model assertions are expected to be signed by the brand: %q != %q", assert.headers["authority-id"], assert.headers["brand-id"])
+	if assert.HeaderString("brand-id") != assert.HeaderString("authority-id") {
+		return nil, fmt.Errorf("authority-id and brand-id must match, model assertions are expected to be signed by the brand: %q != %q", assert.HeaderString("authority-id"), assert.HeaderString("brand-id"))
 	}
 
 	for _, mandatory := range modelMandatory {
-		if _, err := checkNotEmpty(assert.headers, mandatory); err != nil {
+		if _, err := checkNotEmptyString(assert.headers, mandatory); err != nil {
 			return nil, err
 		}
 	}
 
 	// TODO: check 'class' value already here? fundamental policy derives from it
 
-	allowedModes, err := checkCommaSepList(assert.headers, "allowed-modes")
+	allowedModes, err := checkStringList(assert.headers, "allowed-modes")
 	if err != nil {
 		return nil, err
 	}
 
-	requiredSnaps, err := checkCommaSepList(assert.headers, "required-snaps")
+	requiredSnaps, err := checkStringList(assert.headers, "required-snaps")
 	if err != nil {
 		return nil, err
 	}
@@ -152,18 +152,18 @@ type Serial struct {
 
 // BrandID returns the brand identifier of the device.
 func (ser *Serial) BrandID() string {
-	return ser.Header("brand-id")
+	return ser.HeaderString("brand-id")
 }
 
 // Model returns the model name identifier of the device.
 func (ser *Serial) Model() string {
-	return ser.Header("model")
+	return ser.HeaderString("model")
 }
 
 // Serial returns the serial identifier of the device, together with
 // brand id and model they form the unique identifier of the device.
 func (ser *Serial) Serial() string {
-	return ser.Header("serial")
+	return ser.HeaderString("serial")
 }
 
 // DeviceKey returns the public key of the device.
@@ -181,7 +181,7 @@ func (ser *Serial) Timestamp() time.Time {
 func assembleSerial(assert assertionBase) (Assertion, error) {
 	// TODO: authority-id can only == canonical or brand-id
 
-	encodedKey, err := checkNotEmpty(assert.headers, "device-key")
+	encodedKey, err := checkNotEmptyString(assert.headers, "device-key")
 	if err != nil {
 		return nil, err
 	}
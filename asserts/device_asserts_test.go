@@ -82,6 +82,15 @@ func (mods *modelSuite) TestDecodeOK(c *C) {
 	c.Check(model.RequiredSnaps(), DeepEquals, []string{"foo", "bar"})
 }
 
+func (mods *modelSuite) TestDecodeRequiredSnapsList(c *C) {
+	encoded := strings.Replace(modelExample, "TSLINE", mods.tsLine, 1)
+	encoded = strings.Replace(encoded, "required-snaps: foo, bar\n", "required-snaps:\n  - foo\n  - bar\n", 1)
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	model := a.(*asserts.Model)
+	c.Check(model.RequiredSnaps(), DeepEquals, []string{"foo", "bar"})
+}
+
 const (
 	modelErrPrefix = "assertion model: "
 )
@@ -112,6 +121,7 @@ func (mods *modelSuite) TestDecodeInvalid(c *C) {
 		{"allowed-modes: \n", "allowed-modes: ,\n", `empty entry in comma separated "allowed-modes" header: ","`},
 		{"required-snaps: foo, bar\n", "", `"required-snaps" header is mandatory`},
 		{"required-snaps: foo, bar\n", "required-snaps: foo,\n", `empty entry in comma separated "required-snaps" header: "foo,"`},
+		{"required-snaps: foo, bar\n", "required-snaps:\n  - foo\n  - \n", `"required-snaps" header must be a list of non-empty strings`},
 		{"class: fixed\n", "", `"class" header is mandatory`},
 		{"class: fixed\n", "class: \n", `"class" header should not be empty`},
 		{mods.tsLine, "", `"timestamp" header is mandatory`},
@@ -61,7 +61,7 @@ func EncoderAppend(enc *Encoder, encoded []byte) error {
 func makeAccountKeyForTest(authorityID string, openPGPPubKey PublicKey, validYears int) *AccountKey {
 	return &AccountKey{
 		assertionBase: assertionBase{
-			headers: map[string]string{
+			headers: map[string]interface{}{
 				"type":          "account-key",
 				"authority-id":  authorityID,
 				"account-id":    authorityID,
@@ -111,6 +111,15 @@ var TestOnly2Type = &AssertionType{"test-only-2", []string{"pk1", "pk2"}, assemb
 func init() {
 	typeRegistry[TestOnlyType.Name] = TestOnlyType
 	typeRegistry[TestOnly2Type.Name] = TestOnly2Type
+	indexedHeaders[TestOnlyType.Name] = []string{"header1"}
+}
+
+func MockIndexCompactThreshold(n int) (restore func()) {
+	old := indexCompactThreshold
+	indexCompactThreshold = n
+	return func() {
+		indexCompactThreshold = old
+	}
 }
 
 // AccountKeyIsKeyValidAt exposes isKeyValidAt on AccountKey for tests
@@ -0,0 +1,134 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// the timeout for a whole exchange with a signing service, signing
+// can involve hardware so be generous
+var signingServiceTimeout = 60 * time.Second
+
+// SigningServiceRequest is a request sent to a signing service.
+//
+// Its Action is either "get-public-key" or "sign", to get
+// respectively the exported OpenPGP public key packet of the key of
+// the authority with the key id or a detached OpenPGP signature
+// packet of Content by that key, using SHA512.
+type SigningServiceRequest struct {
+	Action      string `json:"action"`
+	AuthorityID string `json:"authority-id"`
+	KeyID       string `json:"key-id"`
+	Content     []byte `json:"content,omitempty"`
+}
+
+// SigningServiceResponse is the response of a signing service to a
+// SigningServiceRequest, either with the requested public key or
+// signature or with an error.
+type SigningServiceResponse struct {
+	PublicKey []byte `json:"public-key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type signingServiceKeypairManager struct {
+	socketPath string
+}
+
+// NewSigningServiceKeypairManager creates a new key pair manager
+// delegating to a signing service listening on the unix socket at
+// socketPath, so that private keys never leave the service.
+//
+// The service is expected to read one JSON encoded
+// SigningServiceRequest per connection and to reply with a JSON
+// encoded SigningServiceResponse.
+//
+// Importing keys through the keypair manager interface is not
+// supported.
+func NewSigningServiceKeypairManager(socketPath string) KeypairManager {
+	return &signingServiceKeypairManager{
+		socketPath: socketPath,
+	}
+}
+
+func (sskm *signingServiceKeypairManager) do(req *SigningServiceRequest) (*SigningServiceResponse, error) {
+	conn, err := net.DialTimeout("unix", sskm.socketPath, signingServiceTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(signingServiceTimeout))
+
+	err = json.NewEncoder(conn).Encode(req)
+	if err != nil {
+		return nil, err
+	}
+	var resp SigningServiceResponse
+	err = json.NewDecoder(conn).Decode(&resp)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode response: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	return &resp, nil
+}
+
+func (sskm *signingServiceKeypairManager) Put(authorityID string, privKey PrivateKey) error {
+	return fmt.Errorf("cannot import private key into signing service")
+}
+
+func (sskm *signingServiceKeypairManager) Get(authorityID, keyID string) (PrivateKey, error) {
+	resp, err := sskm.do(&SigningServiceRequest{
+		Action:      "get-public-key",
+		AuthorityID: authorityID,
+		KeyID:       keyID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get key %q from signing service: %v", keyID, err)
+	}
+
+	sign := func(fingerprint string, content []byte) ([]byte, error) {
+		resp, err := sskm.do(&SigningServiceRequest{
+			Action:      "sign",
+			AuthorityID: authorityID,
+			KeyID:       keyID,
+			Content:     content,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot sign using signing service: %v", err)
+		}
+		return resp.Signature, nil
+	}
+	privKey, err := newExtPGPPrivateKey(bytes.NewBuffer(resp.PublicKey), "signing service", sign)
+	if err != nil {
+		return nil, fmt.Errorf("cannot use signing service key %q: %v", keyID, err)
+	}
+	gotID := privKey.PublicKey().ID()
+	if gotID != keyID {
+		return nil, fmt.Errorf("got wrong key from signing service, expected %q: %s", keyID, gotID)
+	}
+	return privKey, nil
+}
@@ -0,0 +1,274 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"golang.org/x/crypto/openpgp/packet"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+var (
+	strongTestPrivKeyOnce sync.Once
+	strongTestPrivKey     asserts.PrivateKey
+)
+
+// getStrongTestPrivKey returns a shared 4096 bits key, as required for
+// externally held keys, generating it on first use.
+func getStrongTestPrivKey(c *C) asserts.PrivateKey {
+	strongTestPrivKeyOnce.Do(func() {
+		var err error
+		strongTestPrivKey, err = asserts.GenerateKey()
+		c.Assert(err, IsNil)
+	})
+	return strongTestPrivKey
+}
+
+// openpgpSign produces a detached OpenPGP signature packet of content.
+func openpgpSign(privKey asserts.PrivateKey, content []byte) ([]byte, error) {
+	privk := asserts.PrivateKeyPacket(privKey)
+	sig := new(packet.Signature)
+	sig.PubKeyAlgo = privk.PubKeyAlgo
+	sig.Hash = crypto.SHA512
+	sig.CreationTime = time.Now()
+	sig.IssuerKeyId = &privk.KeyId
+
+	h := crypto.SHA512.New()
+	h.Write(content)
+	err := sig.Sign(h, privk, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	err = sig.Serialize(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type signingServiceKeypairMgrSuite struct {
+	privKey    asserts.PrivateKey
+	socketPath string
+	listener   net.Listener
+	mu         sync.Mutex
+	requests   []*asserts.SigningServiceRequest
+	respond    func(req *asserts.SigningServiceRequest) *asserts.SigningServiceResponse
+	keypairMgr asserts.KeypairManager
+}
+
+var _ = Suite(&signingServiceKeypairMgrSuite{})
+
+func (sskms *signingServiceKeypairMgrSuite) SetUpSuite(c *C) {
+	sskms.privKey = getStrongTestPrivKey(c)
+}
+
+func (sskms *signingServiceKeypairMgrSuite) SetUpTest(c *C) {
+	sskms.socketPath = filepath.Join(c.MkDir(), "signing.socket")
+	l, err := net.Listen("unix", sskms.socketPath)
+	c.Assert(err, IsNil)
+	sskms.listener = l
+	sskms.requests = nil
+	sskms.respond = sskms.serve
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			var req asserts.SigningServiceRequest
+			err = json.NewDecoder(conn).Decode(&req)
+			if err == nil {
+				sskms.mu.Lock()
+				sskms.requests = append(sskms.requests, &req)
+				sskms.mu.Unlock()
+				json.NewEncoder(conn).Encode(sskms.respond(&req))
+			}
+			conn.Close()
+		}
+	}()
+	sskms.keypairMgr = asserts.NewSigningServiceKeypairManager(sskms.socketPath)
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TearDownTest(c *C) {
+	sskms.listener.Close()
+}
+
+// serve implements a well behaved signing service holding privKey for "auth-id1"
+func (sskms *signingServiceKeypairMgrSuite) serve(req *asserts.SigningServiceRequest) *asserts.SigningServiceResponse {
+	if req.AuthorityID != "auth-id1" || req.KeyID != sskms.privKey.PublicKey().ID() {
+		return &asserts.SigningServiceResponse{Error: "no such key"}
+	}
+	switch req.Action {
+	case "get-public-key":
+		buf := new(bytes.Buffer)
+		err := asserts.PrivateKeyPacket(sskms.privKey).PublicKey.Serialize(buf)
+		if err != nil {
+			return &asserts.SigningServiceResponse{Error: err.Error()}
+		}
+		return &asserts.SigningServiceResponse{PublicKey: buf.Bytes()}
+	case "sign":
+		sig, err := openpgpSign(sskms.privKey, req.Content)
+		if err != nil {
+			return &asserts.SigningServiceResponse{Error: err.Error()}
+		}
+		return &asserts.SigningServiceResponse{Signature: sig}
+	}
+	return &asserts.SigningServiceResponse{Error: "unknown action"}
+}
+
+func (sskms *signingServiceKeypairMgrSuite) receivedRequests() []*asserts.SigningServiceRequest {
+	sskms.mu.Lock()
+	defer sskms.mu.Unlock()
+	return sskms.requests
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TestGetPublicKeyLooksGood(c *C) {
+	keyID := sskms.privKey.PublicKey().ID()
+	got, err := sskms.keypairMgr.Get("auth-id1", keyID)
+	c.Assert(err, IsNil)
+	c.Check(got.PublicKey().Fingerprint(), Equals, sskms.privKey.PublicKey().Fingerprint())
+	c.Check(sskms.receivedRequests(), DeepEquals, []*asserts.SigningServiceRequest{
+		{Action: "get-public-key", AuthorityID: "auth-id1", KeyID: keyID},
+	})
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TestGetNotFound(c *C) {
+	got, err := sskms.keypairMgr.Get("auth-id1", "ffffffffffffffff")
+	c.Check(err, ErrorMatches, `cannot get key "ffffffffffffffff" from signing service: no such key`)
+	c.Check(got, IsNil)
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TestGetWrongKey(c *C) {
+	keyID := sskms.privKey.PublicKey().ID()
+	sskms.respond = func(req *asserts.SigningServiceRequest) *asserts.SigningServiceResponse {
+		req.KeyID = keyID
+		return sskms.serve(req)
+	}
+	got, err := sskms.keypairMgr.Get("auth-id1", "ffffffffffffffff")
+	c.Check(err, ErrorMatches, `got wrong key from signing service, expected "ffffffffffffffff": `+keyID)
+	c.Check(got, IsNil)
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TestGetWeakKey(c *C) {
+	sskms.privKey = testPrivKey1
+	defer func() { sskms.privKey = getStrongTestPrivKey(c) }()
+
+	got, err := sskms.keypairMgr.Get("auth-id1", testPrivKey1.PublicKey().ID())
+	c.Check(err, ErrorMatches, `cannot use signing service key ".*": need at least 4096 bits key, got 752`)
+	c.Check(got, IsNil)
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TestServiceUnavailable(c *C) {
+	sskms.listener.Close()
+
+	got, err := sskms.keypairMgr.Get("auth-id1", sskms.privKey.PublicKey().ID())
+	c.Check(err, ErrorMatches, `cannot get key ".*" from signing service: .*`)
+	c.Check(got, IsNil)
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TestPut(c *C) {
+	err := sskms.keypairMgr.Put("auth-id1", testPrivKey1)
+	c.Check(err, ErrorMatches, "cannot import private key into signing service")
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TestUseInSigning(c *C) {
+	keyID := sskms.privKey.PublicKey().ID()
+	signDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: sskms.keypairMgr,
+	})
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"authority-id": "auth-id1",
+		"primary-key":  "0",
+	}
+	a, err := signDB.Sign(asserts.TestOnlyType, headers, nil, keyID)
+	c.Assert(err, IsNil)
+	reqs := sskms.receivedRequests()
+	c.Assert(reqs, HasLen, 2)
+	c.Check(reqs[1].Action, Equals, "sign")
+	c.Check(reqs[1].KeyID, Equals, keyID)
+
+	checkDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		Trusted:        []asserts.Assertion{asserts.BootstrapAccountKeyForTest("auth-id1", sskms.privKey.PublicKey())},
+	})
+	c.Assert(err, IsNil)
+	err = checkDB.Check(a)
+	c.Check(err, IsNil)
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TestSignBadSignature(c *C) {
+	keyID := sskms.privKey.PublicKey().ID()
+	sskms.respond = func(req *asserts.SigningServiceRequest) *asserts.SigningServiceResponse {
+		if req.Action == "sign" {
+			// signature of something else
+			sig, err := openpgpSign(sskms.privKey, []byte("other"))
+			c.Assert(err, IsNil)
+			return &asserts.SigningServiceResponse{Signature: sig}
+		}
+		return sskms.serve(req)
+	}
+	signDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: sskms.keypairMgr,
+	})
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"authority-id": "auth-id1",
+		"primary-key":  "0",
+	}
+	_, err = signDB.Sign(asserts.TestOnlyType, headers, nil, keyID)
+	c.Check(err, ErrorMatches, "cannot sign assertion: bad signing service produced signature: it does not verify: .*")
+}
+
+func (sskms *signingServiceKeypairMgrSuite) TestSignError(c *C) {
+	keyID := sskms.privKey.PublicKey().ID()
+	sskms.respond = func(req *asserts.SigningServiceRequest) *asserts.SigningServiceResponse {
+		if req.Action == "sign" {
+			return &asserts.SigningServiceResponse{Error: "token locked"}
+		}
+		return sskms.serve(req)
+	}
+	signDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: sskms.keypairMgr,
+	})
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"authority-id": "auth-id1",
+		"primary-key":  "0",
+	}
+	_, err = signDB.Sign(asserts.TestOnlyType, headers, nil, keyID)
+	c.Check(err, ErrorMatches, "cannot sign assertion: cannot sign using signing service: token locked")
+}
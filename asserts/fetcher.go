@@ -0,0 +1,207 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"fmt"
+)
+
+// maxFetchDepth bounds the length of the chains of prerequisites that
+// a Fetcher will follow.
+const maxFetchDepth = 16
+
+type fetchProgress int
+
+const (
+	fetchNotSeen fetchProgress = iota
+	fetchRetrieved
+	fetchSaved
+)
+
+// A Fetcher helps fetching assertions and their prerequisites.
+type Fetcher interface {
+	// Fetch retrieves the assertion indicated by the given reference,
+	// unless it is already known, and saves it after its
+	// prerequisites, retrieving those as needed.
+	Fetch(*Ref) error
+	// Save saves the given assertion after its prerequisites,
+	// retrieving those as needed.
+	Save(Assertion) error
+}
+
+// prerequisites returns references to all the prerequisites of the
+// assertion, starting with the account-key that signed it.
+func prerequisites(a Assertion) ([]*Ref, error) {
+	keyID, err := SignatureKeyID(a)
+	if err != nil {
+		return nil, err
+	}
+	signingKey := &Ref{
+		Type:       AccountKeyType,
+		PrimaryKey: []string{a.AuthorityID(), keyID},
+	}
+	return append([]*Ref{signingKey}, a.Prerequisites()...), nil
+}
+
+type fetcher struct {
+	db       RODatabase
+	retrieve func(*Ref) (Assertion, error)
+	save     func(Assertion) error
+
+	fetched map[string]fetchProgress
+}
+
+// NewFetcher creates a Fetcher which will consult the database db for
+// the assertions already known, retrieve the missing ones with
+// retrieve, and save them with save, which will be called on
+// prerequisites before the assertions that need them.
+func NewFetcher(db RODatabase, retrieve func(*Ref) (Assertion, error), save func(Assertion) error) Fetcher {
+	return &fetcher{
+		db:       db,
+		retrieve: retrieve,
+		save:     save,
+		fetched:  make(map[string]fetchProgress),
+	}
+}
+
+func (f *fetcher) chase(ref *Ref, a Assertion, depth int) error {
+	u := ref.Unique()
+	switch f.fetched[u] {
+	case fetchSaved:
+		return nil
+	case fetchRetrieved:
+		return fmt.Errorf("cannot fetch %s: circular prerequisites", ref)
+	}
+	if a == nil {
+		_, err := ref.Resolve(f.db.Find)
+		if err == nil {
+			f.fetched[u] = fetchSaved
+			return nil
+		}
+		if err != ErrNotFound {
+			return err
+		}
+		a, err = f.retrieve(ref)
+		if err != nil {
+			return fmt.Errorf("cannot fetch %s: %v", ref, err)
+		}
+	}
+	if depth >= maxFetchDepth {
+		return fmt.Errorf("cannot fetch %s: too many levels of prerequisites", ref)
+	}
+	f.fetched[u] = fetchRetrieved
+
+	prereqs, err := prerequisites(a)
+	if err == nil {
+		for _, prereq := range prereqs {
+			if err = f.chase(prereq, nil, depth+1); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = f.save(a)
+	}
+	if err != nil {
+		// allow to retry, for example while saving other assertions
+		delete(f.fetched, u)
+		return err
+	}
+	f.fetched[u] = fetchSaved
+	return nil
+}
+
+// Fetch retrieves the assertion indicated by the given reference and
+// saves it together with its prerequisites.
+func (f *fetcher) Fetch(ref *Ref) error {
+	return f.chase(ref, nil, 0)
+}
+
+// Save saves the given assertion together with its prerequisites.
+func (f *fetcher) Save(a Assertion) error {
+	return f.chase(a.Ref(), a, 0)
+}
+
+type prereqCollector struct {
+	db        RODatabase
+	collected map[string]fetchProgress
+	res       []Assertion
+}
+
+func (pc *prereqCollector) collect(a Assertion, depth int) error {
+	ref := a.Ref()
+	u := ref.Unique()
+	switch pc.collected[u] {
+	case fetchSaved:
+		return nil
+	case fetchRetrieved:
+		return fmt.Errorf("cannot collect prerequisites of %s: circular prerequisites", ref)
+	}
+	if depth >= maxFetchDepth {
+		return fmt.Errorf("cannot collect prerequisites of %s: too many levels of prerequisites", ref)
+	}
+	pc.collected[u] = fetchRetrieved
+
+	prereqs, err := prerequisites(a)
+	if err != nil {
+		return err
+	}
+	if prereqs[0].Unique() == u {
+		// self-signed account-keys can only be trusted, leave them out
+		pc.collected[u] = fetchSaved
+		return nil
+	}
+	for _, prereq := range prereqs {
+		if pc.collected[prereq.Unique()] == fetchSaved {
+			continue
+		}
+		p, err := prereq.Resolve(pc.db.Find)
+		if err == ErrNotFound {
+			return fmt.Errorf("cannot find %s, prerequisite of %s", prereq, ref)
+		}
+		if err != nil {
+			return err
+		}
+		if err := pc.collect(p, depth+1); err != nil {
+			return err
+		}
+	}
+
+	pc.res = append(pc.res, a)
+	pc.collected[u] = fetchSaved
+	return nil
+}
+
+// WithPrerequisites returns the given assertions each preceded by its
+// prerequisites, recursively, as found in db. The result can be added
+// in order to another database. Each assertion is included only once
+// and self-signed account-keys, which can only be trusted, are left out.
+func WithPrerequisites(db RODatabase, assertions []Assertion) ([]Assertion, error) {
+	pc := &prereqCollector{
+		db:        db,
+		collected: make(map[string]fetchProgress),
+	}
+	for _, a := range assertions {
+		if err := pc.collect(a, 0); err != nil {
+			return nil, err
+		}
+	}
+	return pc.res, nil
+}
@@ -0,0 +1,277 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+)
+
+type fetcherSuite struct {
+	storeSigning *assertstest.StoreStack
+}
+
+var _ = Suite(&fetcherSuite{})
+
+func (s *fetcherSuite) SetUpTest(c *C) {
+	s.storeSigning = assertstest.NewStoreStack("canonical", testPrivKey0, testPrivKey1)
+}
+
+func (s *fetcherSuite) snapAsserts(c *C) (snapDecl, snapRev asserts.Assertion) {
+	now := time.Now().Format(time.RFC3339)
+	snapDecl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "snap-id-1",
+		"snap-name":    "foo",
+		"publisher-id": "dev1",
+		"gates":        "",
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, IsNil)
+	snapRev, err = s.storeSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"series":        "16",
+		"snap-id":       "snap-id-1",
+		"snap-digest":   "sha512-digest",
+		"snap-size":     "1000",
+		"snap-revision": "1",
+		"developer-id":  "dev1",
+		"timestamp":     now,
+	}, nil)
+	c.Assert(err, IsNil)
+	return snapDecl, snapRev
+}
+
+func (s *fetcherSuite) openDB(c *C) *asserts.Database {
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		Trusted:        []asserts.Assertion{s.storeSigning.TrustedKey},
+	})
+	c.Assert(err, IsNil)
+	return db
+}
+
+func retrieveFrom(retrieved *[]string, available ...asserts.Assertion) func(*asserts.Ref) (asserts.Assertion, error) {
+	return func(ref *asserts.Ref) (asserts.Assertion, error) {
+		*retrieved = append(*retrieved, ref.Unique())
+		for _, a := range available {
+			if a.Ref().Unique() == ref.Unique() {
+				return a, nil
+			}
+		}
+		return nil, fmt.Errorf("not available")
+	}
+}
+
+func saveTo(db *asserts.Database, saved *[]string) func(asserts.Assertion) error {
+	return func(a asserts.Assertion) error {
+		*saved = append(*saved, a.Ref().Unique())
+		return db.Add(a)
+	}
+}
+
+func (s *fetcherSuite) TestRef(c *C) {
+	_, snapRev := s.snapAsserts(c)
+
+	ref := snapRev.Ref()
+	c.Check(ref.Type, Equals, asserts.SnapRevisionType)
+	c.Check(ref.PrimaryKey, DeepEquals, []string{"16", "snap-id-1", "sha512-digest"})
+	c.Check(ref.Unique(), Equals, "snap-revision/16/snap-id-1/sha512-digest")
+	c.Check(ref.String(), Equals, "snap-revision [16 snap-id-1 sha512-digest]")
+
+	db := s.openDB(c)
+	err := db.Add(s.storeSigning.StoreAccountKey)
+	c.Assert(err, IsNil)
+
+	a, err := s.storeSigning.StoreAccountKey.Ref().Resolve(db.Find)
+	c.Assert(err, IsNil)
+	c.Check(a.Ref().Unique(), Equals, s.storeSigning.StoreAccountKey.Ref().Unique())
+
+	_, err = ref.Resolve(db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+
+	_, err = (&asserts.Ref{Type: asserts.AccountType}).Resolve(db.Find)
+	c.Check(err, ErrorMatches, `"account" assertion reference primary key has the wrong length \(expected \[account-id\]\): \[\]`)
+}
+
+func (s *fetcherSuite) TestFetch(c *C) {
+	snapDecl, snapRev := s.snapAsserts(c)
+	dev1Acct := assertstest.NewAccount(s.storeSigning.SigningDB, "dev1", nil)
+
+	db := s.openDB(c)
+	var retrieved, saved []string
+	f := asserts.NewFetcher(db, retrieveFrom(&retrieved, s.storeSigning.StoreAccountKey, dev1Acct, snapDecl, snapRev), saveTo(db, &saved))
+
+	err := f.Fetch(snapRev.Ref())
+	c.Assert(err, IsNil)
+
+	expected := []string{
+		s.storeSigning.StoreAccountKey.Ref().Unique(),
+		"snap-declaration/16/snap-id-1",
+		"account/dev1",
+		"snap-revision/16/snap-id-1/sha512-digest",
+	}
+	c.Check(saved, DeepEquals, []string{expected[0], expected[2], expected[1], expected[3]})
+	c.Check(retrieved, DeepEquals, []string{expected[3], expected[0], expected[1], expected[2]})
+
+	_, err = snapRev.Ref().Resolve(db.Find)
+	c.Check(err, IsNil)
+
+	// already known assertions are neither retrieved nor saved again
+	retrieved, saved = nil, nil
+	f = asserts.NewFetcher(db, retrieveFrom(&retrieved), saveTo(db, &saved))
+	err = f.Fetch(snapRev.Ref())
+	c.Assert(err, IsNil)
+	c.Check(retrieved, HasLen, 0)
+	c.Check(saved, HasLen, 0)
+}
+
+func (s *fetcherSuite) TestSave(c *C) {
+	snapDecl, _ := s.snapAsserts(c)
+	dev1Acct := assertstest.NewAccount(s.storeSigning.SigningDB, "dev1", nil)
+
+	db := s.openDB(c)
+	err := db.Add(s.storeSigning.StoreAccountKey)
+	c.Assert(err, IsNil)
+
+	var retrieved, saved []string
+	f := asserts.NewFetcher(db, retrieveFrom(&retrieved, dev1Acct), saveTo(db, &saved))
+
+	err = f.Save(snapDecl)
+	c.Assert(err, IsNil)
+	c.Check(retrieved, DeepEquals, []string{"account/dev1"})
+	c.Check(saved, DeepEquals, []string{"account/dev1", "snap-declaration/16/snap-id-1"})
+}
+
+func (s *fetcherSuite) TestFetchMissing(c *C) {
+	_, snapRev := s.snapAsserts(c)
+
+	db := s.openDB(c)
+	var retrieved, saved []string
+	f := asserts.NewFetcher(db, retrieveFrom(&retrieved, s.storeSigning.StoreAccountKey), saveTo(db, &saved))
+
+	err := f.Save(snapRev)
+	c.Check(err, ErrorMatches, `cannot fetch snap-declaration \[16 snap-id-1\]: not available`)
+	c.Check(saved, DeepEquals, []string{s.storeSigning.StoreAccountKey.Ref().Unique()})
+}
+
+func (s *fetcherSuite) TestFetchCircular(c *C) {
+	// a self-signed key which is not trusted
+	otherSigning := assertstest.NewSigningDB("other", testPrivKey2)
+	otherKey := assertstest.NewAccountKey(otherSigning, "other", testPrivKey2.PublicKey(), nil)
+
+	db := s.openDB(c)
+	var retrieved, saved []string
+	f := asserts.NewFetcher(db, retrieveFrom(&retrieved, otherKey), saveTo(db, &saved))
+
+	err := f.Fetch(otherKey.Ref())
+	c.Check(err, ErrorMatches, `cannot fetch account-key \[other .*\]: circular prerequisites`)
+	c.Check(saved, HasLen, 0)
+}
+
+func (s *fetcherSuite) TestFetchTooDeep(c *C) {
+	// a chain of keys each signing the next one
+	db := s.openDB(c)
+	var available []asserts.Assertion
+	signing := s.storeSigning.SigningDB
+	var last asserts.Assertion
+	for i := 0; i < 20; i++ {
+		privKey := assertstest.GenerateKey(752)
+		acctKey := assertstest.NewAccountKey(signing, "canonical", privKey.PublicKey(), nil)
+		available = append(available, acctKey)
+		signing = assertstest.NewSigningDB("canonical", privKey)
+		last = acctKey
+	}
+	available = append(available, s.storeSigning.StoreAccountKey)
+
+	var retrieved, saved []string
+	f := asserts.NewFetcher(db, retrieveFrom(&retrieved, available...), saveTo(db, &saved))
+
+	err := f.Fetch(last.Ref())
+	c.Check(err, ErrorMatches, `cannot fetch account-key \[canonical .*\]: too many levels of prerequisites`)
+	c.Check(saved, HasLen, 0)
+}
+
+func (s *fetcherSuite) TestFetchRetryAfterFailure(c *C) {
+	snapDecl, snapRev := s.snapAsserts(c)
+	dev1Acct := assertstest.NewAccount(s.storeSigning.SigningDB, "dev1", nil)
+
+	db := s.openDB(c)
+	var retrieved, saved []string
+	available := []asserts.Assertion{s.storeSigning.StoreAccountKey, snapDecl, snapRev}
+	f := asserts.NewFetcher(db, retrieveFrom(&retrieved, available...), saveTo(db, &saved))
+
+	err := f.Save(snapDecl)
+	c.Check(err, ErrorMatches, `cannot fetch account \[dev1\]: not available`)
+
+	// the failure is reported again, not mistaken for a cycle
+	err = f.Save(snapRev)
+	c.Check(err, ErrorMatches, `cannot fetch account \[dev1\]: not available`)
+
+	available = append(available, dev1Acct)
+	f = asserts.NewFetcher(db, retrieveFrom(&retrieved, available...), saveTo(db, &saved))
+	err = f.Save(snapRev)
+	c.Check(err, IsNil)
+}
+
+func (s *fetcherSuite) TestWithPrerequisites(c *C) {
+	snapDecl, snapRev := s.snapAsserts(c)
+	dev1Acct := assertstest.NewAccount(s.storeSigning.SigningDB, "dev1", nil)
+
+	db := s.openDB(c)
+	for _, a := range []asserts.Assertion{s.storeSigning.StoreAccountKey, dev1Acct, snapDecl, snapRev} {
+		c.Assert(db.Add(a), IsNil)
+	}
+
+	res, err := asserts.WithPrerequisites(db, []asserts.Assertion{snapRev, snapDecl})
+	c.Assert(err, IsNil)
+	var refs []string
+	for _, a := range res {
+		refs = append(refs, a.Ref().Unique())
+	}
+	// the trusted self-signed key is left out
+	c.Check(refs, DeepEquals, []string{
+		s.storeSigning.StoreAccountKey.Ref().Unique(),
+		"account/dev1",
+		"snap-declaration/16/snap-id-1",
+		"snap-revision/16/snap-id-1/sha512-digest",
+	})
+
+	// the result can be added in order to another database
+	db2 := s.openDB(c)
+	for _, a := range res {
+		c.Check(db2.Add(a), IsNil)
+	}
+}
+
+func (s *fetcherSuite) TestWithPrerequisitesMissing(c *C) {
+	snapDecl, _ := s.snapAsserts(c)
+
+	db := s.openDB(c)
+	c.Assert(db.Add(s.storeSigning.StoreAccountKey), IsNil)
+
+	_, err := asserts.WithPrerequisites(db, []asserts.Assertion{snapDecl})
+	c.Check(err, ErrorMatches, `cannot find account \[dev1\], prerequisite of snap-declaration \[16 snap-id-1\]`)
+}
@@ -102,3 +102,37 @@ func (fsbss *fsBackstoreSuite) TestPutOldRevision(c *C) {
 	c.Check(err, ErrorMatches, `revision 0 is older than current revision 1`)
 	c.Check(err, DeepEquals, &asserts.RevisionError{Current: 1, Used: 0})
 }
+
+func (fsbss *fsBackstoreSuite) TestSearchNestedHeaders(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	a, err := asserts.Decode([]byte("type: test-only\n" +
+		"authority-id: auth-id1\n" +
+		"primary-key: foo\n" +
+		"flat: value\n" +
+		"list:\n" +
+		"  - one\n" +
+		"  - two\n" +
+		"map:\n" +
+		"  x: y\n" +
+		"\n" +
+		"openpgp c2ln"))
+	c.Assert(err, IsNil)
+
+	err = bs.Put(asserts.TestOnlyType, a)
+	c.Assert(err, IsNil)
+
+	var found []asserts.Assertion
+	err = bs.Search(asserts.TestOnlyType, map[string]string{
+		"primary-key": "foo",
+		"flat":        "value",
+	}, func(a asserts.Assertion) {
+		found = append(found, a)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 1)
+	c.Check(found[0].Headers(), DeepEquals, a.Headers())
+	c.Check(asserts.Encode(found[0]), DeepEquals, asserts.Encode(a))
+}
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
@@ -53,11 +54,15 @@ func runGPGImpl(homedir string, input []byte, args ...string) ([]byte, error) {
 
 var runGPG = runGPGImpl
 
-type gpgKeypairManager struct {
+// A GPGKeypairManager is a manager for key pairs backed by a local
+// GnuPG setup. Besides the KeypairManager interface it supports
+// generating and looking up keys by name, a key name being the real
+// name of the key user id.
+type GPGKeypairManager struct {
 	homedir string
 }
 
-func (gkm *gpgKeypairManager) gpg(input []byte, args ...string) ([]byte, error) {
+func (gkm *GPGKeypairManager) gpg(input []byte, args ...string) ([]byte, error) {
 	return runGPG(gkm.homedir, input, args...)
 }
 
@@ -67,18 +72,18 @@ func (gkm *gpgKeypairManager) gpg(input []byte, args ...string) ([]byte, error)
 // Importing keys through the keypair manager interface is not
 // suppored.
 // Main purpose is allowing signing using keys from a GPG setup.
-func NewGPGKeypairManager(homedir string) KeypairManager {
-	return &gpgKeypairManager{
+func NewGPGKeypairManager(homedir string) *GPGKeypairManager {
+	return &GPGKeypairManager{
 		homedir: homedir,
 	}
 }
 
-func (gkm *gpgKeypairManager) Put(authorityID string, privKey PrivateKey) error {
+func (gkm *GPGKeypairManager) Put(authorityID string, privKey PrivateKey) error {
 	// NOTE: we don't need this initially at least and this keypair mgr is not for general arbitrary usage
 	return fmt.Errorf("cannot import private key into GPG keyring")
 }
 
-func (gkm *gpgKeypairManager) Get(authorityID, keyID string) (PrivateKey, error) {
+func (gkm *GPGKeypairManager) Get(authorityID, keyID string) (PrivateKey, error) {
 	out, err := gkm.gpg(nil, "--batch", "--export", "--export-options", "export-minimal,export-clean,no-export-attributes", "0x"+keyID)
 	if err != nil {
 		return nil, err
@@ -99,10 +104,135 @@ func (gkm *gpgKeypairManager) Get(authorityID, keyID string) (PrivateKey, error)
 	return privKey, nil
 }
 
-func (gkm *gpgKeypairManager) sign(fingerprint string, content []byte) ([]byte, error) {
+func (gkm *GPGKeypairManager) sign(fingerprint string, content []byte) ([]byte, error) {
 	out, err := gkm.gpg(content, "--personal-digest-preferences", "SHA512", "--default-key", "0x"+fingerprint, "--detach-sign")
 	if err != nil {
 		return nil, fmt.Errorf("cannot sign using GPG: %v", err)
 	}
 	return out, nil
 }
+
+// ExternalKeyInfo holds information about a key managed externally
+// to the assertion database, e.g. in GnuPG.
+type ExternalKeyInfo struct {
+	Name string
+	ID   string
+}
+
+// unescapeColons undoes the \xHH escaping used by gpg in --with-colons output.
+func unescapeColons(field string) string {
+	if !strings.Contains(field, `\x`) {
+		return field
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) && field[i+1] == 'x' {
+			if b, err := strconv.ParseUint(field[i+2:i+4], 16, 8); err == nil {
+				buf.WriteByte(byte(b))
+				i += 3
+				continue
+			}
+		}
+		buf.WriteByte(field[i])
+	}
+	return buf.String()
+}
+
+// List returns the name and key id of the secret keys in the GPG
+// keyring, in keyring order.
+func (gkm *GPGKeypairManager) List() ([]ExternalKeyInfo, error) {
+	out, err := gkm.gpg(nil, "--batch", "--list-secret-keys", "--fixed-list-mode", "--with-colons", "--with-fingerprint")
+	if err != nil {
+		return nil, err
+	}
+	var res []ExternalKeyInfo
+	// index of the primary key entry being filled, -1 while in a subkey or before any key
+	cur := -1
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, ":")
+		switch fields[0] {
+		case "sec":
+			res = append(res, ExternalKeyInfo{})
+			cur = len(res) - 1
+		case "ssb":
+			cur = -1
+		case "fpr":
+			if cur == -1 || res[cur].ID != "" || len(fields) < 10 {
+				continue
+			}
+			fpr := strings.ToLower(fields[9])
+			if len(fpr) != 40 {
+				return nil, fmt.Errorf("unexpected fingerprint format in GPG output: %q", fields[9])
+			}
+			// the key id of a v4 OpenPGP key is the low 64 bits of its fingerprint
+			res[cur].ID = fpr[24:]
+		case "uid":
+			if cur == -1 || res[cur].Name != "" || len(fields) < 10 {
+				continue
+			}
+			res[cur].Name = unescapeColons(fields[9])
+		}
+	}
+	return res, nil
+}
+
+func (gkm *GPGKeypairManager) findByName(name string) (*ExternalKeyInfo, error) {
+	keys, err := gkm.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.Name == name {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetByName looks up a private key by name and returns it.
+func (gkm *GPGKeypairManager) GetByName(name string) (PrivateKey, error) {
+	key, err := gkm.findByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("cannot find key named %q in GPG keyring", name)
+	}
+	return gkm.Get("", key.ID)
+}
+
+var generateKeyTemplate = `Key-Type: RSA
+Key-Length: 4096
+Key-Usage: sign
+Name-Real: %s
+`
+
+// Generate creates a new 4096 bits RSA key in the GPG keyring with the
+// given name, protected by passphrase unless passphrase is empty.
+func (gkm *GPGKeypairManager) Generate(passphrase, name string) error {
+	if name == "" || strings.ContainsAny(name, "\n\r") {
+		return fmt.Errorf("invalid key name: %q", name)
+	}
+	key, err := gkm.findByName(name)
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		return fmt.Errorf("key named %q already exists in GPG keyring", name)
+	}
+	if strings.ContainsAny(passphrase, "\n\r") {
+		return fmt.Errorf("passphrase cannot contain newlines")
+	}
+	params := fmt.Sprintf(generateKeyTemplate, name)
+	if passphrase != "" {
+		params += fmt.Sprintf("Passphrase: %s\n", passphrase)
+	} else {
+		params += "%no-protection\n"
+	}
+	params += "%commit\n"
+	_, err = gkm.gpg([]byte(params), "--batch", "--gen-key")
+	if err != nil {
+		return err
+	}
+	return nil
+}
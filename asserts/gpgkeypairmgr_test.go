@@ -40,7 +40,7 @@ import (
 
 type gpgKeypairMgrSuite struct {
 	homedir    string
-	keypairMgr asserts.KeypairManager
+	keypairMgr *asserts.GPGKeypairManager
 }
 
 var _ = Suite(&gpgKeypairMgrSuite{})
@@ -154,7 +154,7 @@ func (gkms *gpgKeypairMgrSuite) TestUseInSigning(c *C) {
 	c.Assert(err, IsNil)
 
 	now := time.Now().UTC()
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id":           "trusted",
 		"account-id":             "trusted",
 		"public-key-id":          trustedKey.PublicKey().ID(),
@@ -169,7 +169,7 @@ func (gkms *gpgKeypairMgrSuite) TestUseInSigning(c *C) {
 
 	devKey, err := gkms.keypairMgr.Get("dev1", testKeyID)
 	c.Assert(err, IsNil)
-	headers = map[string]string{
+	headers = map[string]interface{}{
 		"authority-id":           "trusted",
 		"account-id":             "dev1-id",
 		"public-key-id":          devKey.PublicKey().ID(),
@@ -196,7 +196,7 @@ func (gkms *gpgKeypairMgrSuite) TestUseInSigning(c *C) {
 	err = checkDB.Add(devAccKey)
 	c.Assert(err, IsNil)
 
-	headers = map[string]string{
+	headers = map[string]interface{}{
 		"authority-id": "dev1-id",
 		"series":       "16",
 		"snap-id":      "snap-id-1",
@@ -337,7 +337,7 @@ func (gkms *gpgKeypairMgrSuite) TestUseInSigningBrokenSignature(c *C) {
 	})
 	c.Assert(err, IsNil)
 
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "dev1-id",
 		"series":       "16",
 		"snap-id":      "snap-id-1",
@@ -395,7 +395,7 @@ func (gkms *gpgKeypairMgrSuite) TestUseInSigningFailure(c *C) {
 	})
 	c.Assert(err, IsNil)
 
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "dev1-id",
 		"series":       "16",
 		"snap-id":      "snap-id-1",
@@ -408,3 +408,95 @@ func (gkms *gpgKeypairMgrSuite) TestUseInSigningFailure(c *C) {
 	_, err = signDB.Sign(asserts.SnapBuildType, headers, nil, testKeyID)
 	c.Check(err, ErrorMatches, "cannot sign assertion: cannot sign using GPG: boom")
 }
+
+func (gkms *gpgKeypairMgrSuite) TestList(c *C) {
+	keys, err := gkms.keypairMgr.List()
+	c.Assert(err, IsNil)
+	c.Check(keys, DeepEquals, []asserts.ExternalKeyInfo{
+		{Name: " (test)", ID: testKeyID},
+	})
+}
+
+func (gkms *gpgKeypairMgrSuite) TestListParsing(c *C) {
+	mockGPG := func(prev asserts.GPGRunner, homedir string, input []byte, args ...string) ([]byte, error) {
+		c.Check(args[1], Equals, "--list-secret-keys")
+		return []byte(`sec:u:4096:1:B52CF438A2D078F7:1464555764:::u:::escaESCA:::+:::23::0:
+fpr:::::::::42A3050D365C10D5C093ABEEB52CF438A2D078F7:
+uid:u::::1464555764::10DF0DF36675F5202C9D5EC8E1D3511A92567F41::key\x3aone::::::::::0:
+uid:u::::1464555764::20DF0DF36675F5202C9D5EC8E1D3511A92567F41::other::::::::::0:
+ssb:u:4096:1:0000000000000001:1464555764::::::e:::+:::23:
+fpr:::::::::FFFF050D365C10D5C093ABEE0000000000000001:
+sec:u:4096:1:56BFD291E7FED4E5:1464555764:::u:::escaESCA:::+:::23::0:
+fpr:::::::::FFFF050D365C10D5C093ABEE56BFD291E7FED4E5:
+uid:u::::1464555764::30DF0DF36675F5202C9D5EC8E1D3511A92567F41::two::::::::::0:
+`), nil
+	}
+	restore := asserts.MockRunGPG(mockGPG)
+	defer restore()
+
+	keys, err := gkms.keypairMgr.List()
+	c.Assert(err, IsNil)
+	c.Check(keys, DeepEquals, []asserts.ExternalKeyInfo{
+		{Name: "key:one", ID: testKeyID},
+		{Name: "two", ID: dsaKeyID},
+	})
+}
+
+func (gkms *gpgKeypairMgrSuite) TestGenerateAndGetByName(c *C) {
+	err := gkms.keypairMgr.Generate("", "my-key")
+	c.Assert(err, IsNil)
+
+	keys, err := gkms.keypairMgr.List()
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 2)
+	c.Check(keys[1].Name, Equals, "my-key")
+
+	privKey, err := gkms.keypairMgr.GetByName("my-key")
+	c.Assert(err, IsNil)
+	c.Check(privKey.PublicKey().ID(), Equals, keys[1].ID)
+
+	err = gkms.keypairMgr.Generate("", "my-key")
+	c.Check(err, ErrorMatches, `key named "my-key" already exists in GPG keyring`)
+}
+
+func (gkms *gpgKeypairMgrSuite) TestGenerateParams(c *C) {
+	var params string
+	mockGPG := func(prev asserts.GPGRunner, homedir string, input []byte, args ...string) ([]byte, error) {
+		if args[1] == "--list-secret-keys" {
+			return nil, nil
+		}
+		c.Check(args, DeepEquals, []string{"--batch", "--gen-key"})
+		params = string(input)
+		return nil, nil
+	}
+	restore := asserts.MockRunGPG(mockGPG)
+	defer restore()
+
+	err := gkms.keypairMgr.Generate("secret", "my-key")
+	c.Assert(err, IsNil)
+	c.Check(params, Equals, `Key-Type: RSA
+Key-Length: 4096
+Key-Usage: sign
+Name-Real: my-key
+Passphrase: secret
+%commit
+`)
+
+	err = gkms.keypairMgr.Generate("", "my-key")
+	c.Assert(err, IsNil)
+	c.Check(params, Matches, `(?s).*\n%no-protection\n%commit\n`)
+}
+
+func (gkms *gpgKeypairMgrSuite) TestGenerateInvalid(c *C) {
+	err := gkms.keypairMgr.Generate("", "")
+	c.Check(err, ErrorMatches, `invalid key name: ""`)
+	err = gkms.keypairMgr.Generate("", "a\nb")
+	c.Check(err, ErrorMatches, `invalid key name: "a\\nb"`)
+	err = gkms.keypairMgr.Generate("x\ny", "my-key")
+	c.Check(err, ErrorMatches, `passphrase cannot contain newlines`)
+}
+
+func (gkms *gpgKeypairMgrSuite) TestGetByNameNotFound(c *C) {
+	_, err := gkms.keypairMgr.GetByName("missing")
+	c.Check(err, ErrorMatches, `cannot find key named "missing" in GPG keyring`)
+}
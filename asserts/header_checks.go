@@ -28,16 +28,20 @@ import (
 
 // common checks used when decoding/assembling assertions
 
-func checkExists(headers map[string]string, name string) (string, error) {
+func checkExistsString(headers map[string]interface{}, name string) (string, error) {
 	value, ok := headers[name]
 	if !ok {
 		return "", fmt.Errorf("%q header is mandatory", name)
 	}
-	return value, nil
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%q header must be a string", name)
+	}
+	return s, nil
 }
 
-func checkNotEmpty(headers map[string]string, name string) (string, error) {
-	value, err := checkExists(headers, name)
+func checkNotEmptyString(headers map[string]interface{}, name string) (string, error) {
+	value, err := checkExistsString(headers, name)
 	if err != nil {
 		return "", err
 	}
@@ -47,8 +51,21 @@ func checkNotEmpty(headers map[string]string, name string) (string, error) {
 	return value, nil
 }
 
-func checkPrimaryKey(headers map[string]string, primKey string) (string, error) {
-	value, err := checkNotEmpty(headers, primKey)
+// use "" if missing
+func checkOptionalString(headers map[string]interface{}, name string) (string, error) {
+	value, ok := headers[name]
+	if !ok {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%q header must be a string", name)
+	}
+	return s, nil
+}
+
+func checkPrimaryKey(headers map[string]interface{}, primKey string) (string, error) {
+	value, err := checkNotEmptyString(headers, primKey)
 	if err != nil {
 		return "", err
 	}
@@ -76,11 +93,14 @@ func checkAssertType(assertType *AssertionType) error {
 }
 
 // use 'defl' default if missing
-func checkInteger(headers map[string]string, name string, defl int) (int, error) {
-	valueStr, ok := headers[name]
-	if !ok {
+func checkInteger(headers map[string]interface{}, name string, defl int) (int, error) {
+	if _, ok := headers[name]; !ok {
 		return defl, nil
 	}
+	valueStr, err := checkExistsString(headers, name)
+	if err != nil {
+		return -1, err
+	}
 	value, err := strconv.Atoi(valueStr)
 	if err != nil {
 		return -1, fmt.Errorf("%q header is not an integer: %v", name, valueStr)
@@ -88,8 +108,24 @@ func checkInteger(headers map[string]string, name string, defl int) (int, error)
 	return value, nil
 }
 
-func checkRFC3339Date(headers map[string]string, name string) (time.Time, error) {
-	dateStr, err := checkNotEmpty(headers, name)
+// use false if missing
+func checkOptionalBool(headers map[string]interface{}, name string) (bool, error) {
+	value, ok := headers[name]
+	if !ok {
+		return false, nil
+	}
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("%q header must be 'true' or 'false'", name)
+	}
+}
+
+func checkRFC3339Date(headers map[string]interface{}, name string) (time.Time, error) {
+	dateStr, err := checkNotEmptyString(headers, name)
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -100,8 +136,8 @@ func checkRFC3339Date(headers map[string]string, name string) (time.Time, error)
 	return date, nil
 }
 
-func checkUint(headers map[string]string, name string, bitSize int) (uint64, error) {
-	valueStr, err := checkNotEmpty(headers, name)
+func checkUint(headers map[string]interface{}, name string, bitSize int) (uint64, error) {
+	valueStr, err := checkNotEmptyString(headers, name)
 	if err != nil {
 		return 0, err
 	}
@@ -113,12 +149,32 @@ func checkUint(headers map[string]string, name string, bitSize int) (uint64, err
 	return value, nil
 }
 
-func checkCommaSepList(headers map[string]string, name string) ([]string, error) {
-	listStr, ok := headers[name]
+// checkStringList accepts either a proper list of strings or, for
+// backward compatibility, a comma separated list in a string.
+func checkStringList(headers map[string]interface{}, name string) ([]string, error) {
+	value, ok := headers[name]
 	if !ok {
 		return nil, fmt.Errorf("%q header is mandatory", name)
 	}
+	switch x := value.(type) {
+	case string:
+		return checkCommaSepList(name, x)
+	case []interface{}:
+		res := make([]string, len(x))
+		for i, elem := range x {
+			s, ok := elem.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("%q header must be a list of non-empty strings", name)
+			}
+			res[i] = s
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("%q header must be a list of strings", name)
+	}
+}
 
+func checkCommaSepList(name, listStr string) ([]string, error) {
 	// XXX: we likely don't need this much white-space flexibility,
 	// just supporting newline after , could be enough
 
@@ -0,0 +1,296 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// for basic sanity checking of header names
+	headerNameSanity = regexp.MustCompile("^[a-z](?:[a-z0-9-]*[a-z0-9])?$")
+)
+
+// header values are either strings, lists ([]interface{}) or maps
+// (map[string]interface{}) of such values, nested values are indented
+// by 2 spaces relative to their entry, multiline strings by 1 space
+// as always
+
+func parseHeaders(head []byte) (map[string]interface{}, error) {
+	if !utf8.Valid(head) {
+		return nil, fmt.Errorf("header is not utf8")
+	}
+	lines := strings.Split(string(head), "\n")
+	return parseMapEntries(lines, "")
+}
+
+// nestedBlock returns the end of the block of lines following
+// lines[i-1] that are more indented than pfx.
+func nestedBlock(lines []string, i int, pfx string) int {
+	nestedPfx := pfx + " "
+	j := i
+	for j < len(lines) && strings.HasPrefix(lines[j], nestedPfx) {
+		j++
+	}
+	return j
+}
+
+func parseMapEntries(lines []string, pfx string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for i := 0; i < len(lines); {
+		entry := lines[i]
+		i++
+		if !strings.HasPrefix(entry, pfx) {
+			return nil, fmt.Errorf("map entry is not correctly indented: %q", entry)
+		}
+		entry = entry[len(pfx):]
+		nameValueSplit := strings.Index(entry, ":")
+		if nameValueSplit == -1 {
+			return nil, fmt.Errorf("header entry missing ':' separator: %q", entry)
+		}
+		name := entry[:nameValueSplit]
+		if !headerNameSanity.MatchString(name) {
+			return nil, fmt.Errorf("invalid header name: %q", name)
+		}
+		if _, ok := m[name]; ok {
+			return nil, fmt.Errorf("repeated header: %q", name)
+		}
+
+		afterSplit := nameValueSplit + 1
+		if afterSplit == len(entry) {
+			// multiline or nested value
+			j := nestedBlock(lines, i, pfx)
+			if j == i {
+				return nil, fmt.Errorf("empty multiline header value: %q", entry)
+			}
+			value, err := parseNested(lines[i:j], pfx)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = value
+			i = j
+			continue
+		}
+
+		if entry[afterSplit] != ' ' {
+			return nil, fmt.Errorf("header entry should have a space or newline (multiline) before value: %q", entry)
+		}
+
+		m[name] = entry[afterSplit+1:]
+	}
+	return m, nil
+}
+
+func parseListEntries(lines []string, pfx string) ([]interface{}, error) {
+	entryPfx := pfx + "-"
+	var l []interface{}
+	for i := 0; i < len(lines); {
+		entry := lines[i]
+		i++
+		if !strings.HasPrefix(entry, entryPfx) {
+			return nil, fmt.Errorf("expected list entry: %q", entry)
+		}
+		afterDash := len(entryPfx)
+		if afterDash == len(entry) {
+			// multiline or nested value
+			j := nestedBlock(lines, i, pfx)
+			if j == i {
+				return nil, fmt.Errorf("empty multiline list entry: %q", entry)
+			}
+			value, err := parseNested(lines[i:j], pfx)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, value)
+			i = j
+			continue
+		}
+
+		if entry[afterDash] != ' ' {
+			return nil, fmt.Errorf("list entry should have a space or newline (multiline) before value: %q", entry)
+		}
+
+		l = append(l, entry[afterDash+1:])
+	}
+	return l, nil
+}
+
+func isListEntry(line, pfx string) bool {
+	if !strings.HasPrefix(line, pfx+"-") {
+		return false
+	}
+	afterDash := len(pfx) + 1
+	return afterDash == len(line) || line[afterDash] == ' '
+}
+
+func isMapEntry(line, pfx string) bool {
+	if !strings.HasPrefix(line, pfx) {
+		return false
+	}
+	entry := line[len(pfx):]
+	nameValueSplit := strings.Index(entry, ":")
+	if nameValueSplit == -1 || !headerNameSanity.MatchString(entry[:nameValueSplit]) {
+		return false
+	}
+	afterSplit := nameValueSplit + 1
+	return afterSplit == len(entry) || entry[afterSplit] == ' '
+}
+
+// parseNested parses the non-empty block of lines nested under an
+// entry indented by pfx.
+func parseNested(lines []string, pfx string) (interface{}, error) {
+	nestedPfx := pfx + "  "
+	switch {
+	case isListEntry(lines[0], nestedPfx):
+		return parseListEntries(lines, nestedPfx)
+	case isMapEntry(lines[0], nestedPfx):
+		return parseMapEntries(lines, nestedPfx)
+	}
+
+	// multiline string value
+	unquote := len(pfx) + 1
+	size := 0
+	for _, line := range lines {
+		size += len(line) - unquote + 1
+	}
+	valueBuf := bytes.NewBuffer(make([]byte, 0, size-1))
+	valueBuf.WriteString(lines[0][unquote:])
+	for _, line := range lines[1:] {
+		valueBuf.WriteByte('\n')
+		valueBuf.WriteString(line[unquote:])
+	}
+	return valueBuf.String(), nil
+}
+
+// appendEntry appends the entry for a value introduced by intro (e.g.
+// "NAME:" or "-" with their indentation) at baseIndent.
+func appendEntry(buf *bytes.Buffer, intro string, v interface{}, baseIndent int) {
+	buf.WriteByte('\n')
+	buf.WriteString(intro)
+	switch x := v.(type) {
+	case string:
+		if strings.IndexRune(x, '\n') != -1 {
+			// multiline value => quote by 1-space indenting
+			quote := "\n" + strings.Repeat(" ", baseIndent+1)
+			buf.WriteString(quote)
+			buf.WriteString(strings.Replace(x, "\n", quote, -1))
+		} else {
+			buf.WriteByte(' ')
+			buf.WriteString(x)
+		}
+	case []interface{}:
+		entryPfx := strings.Repeat(" ", baseIndent+2)
+		for _, elem := range x {
+			appendEntry(buf, entryPfx+"-", elem, baseIndent+2)
+		}
+	case map[string]interface{}:
+		entryPfx := strings.Repeat(" ", baseIndent+2)
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			appendEntry(buf, entryPfx+k+":", x[k], baseIndent+2)
+		}
+	default:
+		panic(fmt.Sprintf("internal error: unexpected header value type: %T", v))
+	}
+}
+
+// checkHeaders checks that headers can be encoded and parsed back
+// unambiguously.
+func checkHeaders(headers map[string]interface{}) error {
+	for name, value := range headers {
+		if err := checkHeader(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkHeader(name string, value interface{}) error {
+	switch x := value.(type) {
+	case string:
+		if strings.IndexRune(x, '\n') == -1 {
+			return nil
+		}
+		// the first line of a multiline value cannot be confused
+		// with a nested list or map entry
+		firstLine := " " + strings.SplitN(x, "\n", 2)[0]
+		if isListEntry(firstLine, "  ") || isMapEntry(firstLine, "  ") {
+			return fmt.Errorf("header %q: multiline value starts ambiguously: %q", name, firstLine[1:])
+		}
+		return nil
+	case []interface{}:
+		if len(x) == 0 {
+			return fmt.Errorf("header %q: empty lists are not supported", name)
+		}
+		for _, elem := range x {
+			if err := checkHeader(name, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		if len(x) == 0 {
+			return fmt.Errorf("header %q: empty maps are not supported", name)
+		}
+		for k, elem := range x {
+			if !headerNameSanity.MatchString(k) {
+				return fmt.Errorf("header %q: invalid map entry key: %q", name, k)
+			}
+			if err := checkHeader(name, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("header %q: header values must be strings or nested lists or maps with strings as the only scalars: %v", name, value)
+	}
+}
+
+func copyHeader(value interface{}) interface{} {
+	switch x := value.(type) {
+	case []interface{}:
+		res := make([]interface{}, len(x))
+		for i, elem := range x {
+			res[i] = copyHeader(elem)
+		}
+		return res
+	case map[string]interface{}:
+		return copyHeaders(x)
+	default:
+		return value
+	}
+}
+
+func copyHeaders(headers map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(headers))
+	for name, value := range headers {
+		res[name] = copyHeader(value)
+	}
+	return res
+}
@@ -0,0 +1,516 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/snapcore/snapd/osutil"
+)
+
+// an indexed filesystem based backstore for assertions: all the
+// assertions are appended to a single log file, an index of their
+// primary keys, commonly searched headers and locations in the log
+// is kept in memory and compacted to disk periodically
+
+const (
+	indexedAssertionsLayoutVersion = "v0"
+	indexedAssertionsRoot          = "asserts-idx-" + indexedAssertionsLayoutVersion
+	idxLogFname                    = "assertions.log"
+	idxIndexFname                  = "index.json"
+)
+
+// write the index to disk every indexCompactThreshold appended assertions
+var indexCompactThreshold = 64
+
+// indexedHeaders lists per assertion type the headers besides the
+// primary key ones that are commonly searched for and worth indexing
+var indexedHeaders = map[string][]string{
+	AccountType.Name:         {"username"},
+	SnapDeclarationType.Name: {"snap-name", "publisher-id"},
+	SnapRevisionType.Name:    {"snap-revision", "developer-id"},
+}
+
+type idxEntry struct {
+	Revision int               `json:"revision"`
+	Offset   int64             `json:"offset"`
+	Length   int               `json:"length"`
+	Headers  map[string]string `json:"headers"`
+}
+
+type idxIndex struct {
+	Version string `json:"version"`
+	LogSize int64  `json:"log-size"`
+	// assertion type name -> primary key -> entry
+	Entries map[string]map[string]*idxEntry `json:"entries"`
+}
+
+type indexedBackstore struct {
+	top       string
+	mu        sync.RWMutex
+	idx       *idxIndex
+	unindexed int
+}
+
+// OpenIndexedBackstore opens an indexed filesystem backed assertions
+// backstore under path. On first use it migrates the assertions
+// stored by a filesystem backstore (see OpenFSBackstore) under the
+// same path, leaving those in place.
+func OpenIndexedBackstore(path string) (Backstore, error) {
+	top := filepath.Join(path, indexedAssertionsRoot)
+	if !osutil.IsDirectory(top) {
+		err := migrateToIndexed(path, top)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := ensureTop(top)
+	if err != nil {
+		return nil, err
+	}
+	ibs := &indexedBackstore{top: top}
+	err = ibs.load()
+	if err != nil {
+		return nil, err
+	}
+	return ibs, nil
+}
+
+func newIdxIndex() *idxIndex {
+	return &idxIndex{
+		Version: indexedAssertionsLayoutVersion,
+		Entries: make(map[string]map[string]*idxEntry),
+	}
+}
+
+// migrateToIndexed imports into a new indexed backstore at top the
+// assertions of the filesystem backstore under path if there is one.
+func migrateToIndexed(path, top string) error {
+	newTop := top + ".new"
+	if err := os.RemoveAll(newTop); err != nil {
+		return fmt.Errorf("cannot create assert storage root: %v", err)
+	}
+	if err := ensureTop(newTop); err != nil {
+		return err
+	}
+	ibs := &indexedBackstore{top: newTop, idx: newIdxIndex()}
+
+	if osutil.IsDirectory(filepath.Join(path, assertionsRoot)) {
+		bs, err := OpenFSBackstore(path)
+		if err != nil {
+			return err
+		}
+		fsbs := bs.(*filesystemBackstore)
+
+		typeNames := make([]string, 0, len(typeRegistry))
+		for name := range typeRegistry {
+			typeNames = append(typeNames, name)
+		}
+		sort.Strings(typeNames)
+		var log bytes.Buffer
+		for _, name := range typeNames {
+			assertType := typeRegistry[name]
+			diskPattern := make([]string, len(assertType.PrimaryKey)+1)
+			for i := range assertType.PrimaryKey {
+				diskPattern[i] = "*"
+			}
+			diskPattern[len(assertType.PrimaryKey)] = activeFname
+			err := fsbs.search(assertType, diskPattern, func(a Assertion) {
+				encoded := Encode(a)
+				ibs.addEntry(assertType, a, int64(log.Len()), len(encoded))
+				log.Write(formatRecord(encoded))
+			})
+			if err != nil {
+				return fmt.Errorf("cannot migrate assertions to indexed storage: %v", err)
+			}
+		}
+		if log.Len() > 0 {
+			err = atomicWriteEntry(log.Bytes(), false, newTop, idxLogFname)
+			if err != nil {
+				return fmt.Errorf("cannot migrate assertions to indexed storage: %v", err)
+			}
+			ibs.idx.LogSize = int64(log.Len())
+		}
+	}
+
+	if err := ibs.writeIndex(); err != nil {
+		return err
+	}
+	if err := os.Rename(newTop, top); err != nil {
+		return fmt.Errorf("cannot migrate assertions to indexed storage: %v", err)
+	}
+	return nil
+}
+
+func (ibs *indexedBackstore) logPath() string {
+	return filepath.Join(ibs.top, idxLogFname)
+}
+
+// load reads the index and brings it up to date with the log,
+// discarding a partially written last record if there is one.
+func (ibs *indexedBackstore) load() error {
+	idx := newIdxIndex()
+	data, err := readEntry(ibs.top, idxIndexFname)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("broken assertion storage, cannot read index: %v", err)
+	}
+	if err == nil {
+		err := json.Unmarshal(data, idx)
+		if err != nil || idx.Version != indexedAssertionsLayoutVersion {
+			// rebuild from the log
+			idx = newIdxIndex()
+		}
+	}
+
+	logSize := int64(0)
+	finfo, err := os.Stat(ibs.logPath())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("broken assertion storage, cannot read log: %v", err)
+	}
+	if err == nil {
+		logSize = finfo.Size()
+	}
+	if idx.LogSize > logSize {
+		// the index does not match the log, rebuild it
+		idx = newIdxIndex()
+	}
+	ibs.idx = idx
+
+	replayed, err := ibs.replay()
+	if err != nil {
+		return err
+	}
+	if replayed > 0 {
+		return ibs.writeIndex()
+	}
+	return nil
+}
+
+// replay indexes the records of the log after the end of the part
+// covered by the index.
+func (ibs *indexedBackstore) replay() (int, error) {
+	f, err := os.OpenFile(ibs.logPath(), os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("broken assertion storage, cannot read log: %v", err)
+	}
+	defer f.Close()
+
+	offset := ibs.idx.LogSize
+	if _, err := f.Seek(offset, 0); err != nil {
+		return 0, fmt.Errorf("broken assertion storage, cannot read log: %v", err)
+	}
+	rd := bufio.NewReader(f)
+	replayed := 0
+	for {
+		encoded, recordSize, err := readRecord(rd)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			// partially written last record, drop it
+			if err := f.Truncate(offset); err != nil {
+				return 0, fmt.Errorf("broken assertion storage, cannot truncate log: %v", err)
+			}
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("broken assertion storage, cannot read log at offset %d: %v", offset, err)
+		}
+		a, err := Decode(encoded)
+		if err != nil {
+			return 0, fmt.Errorf("broken assertion storage, cannot decode assertion at offset %d: %v", offset, err)
+		}
+		ibs.addEntry(a.Type(), a, offset, len(encoded))
+		offset += recordSize
+		replayed++
+	}
+	ibs.idx.LogSize = offset
+	return replayed, nil
+}
+
+// log records are the length of the encoded assertion in decimal
+// followed by a newline, the encoded assertion and another newline
+
+func readRecord(rd *bufio.Reader) (encoded []byte, recordSize int64, err error) {
+	lengthLine, err := rd.ReadString('\n')
+	if err == io.EOF {
+		if lengthLine == "" {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	length, err := strconv.Atoi(lengthLine[:len(lengthLine)-1])
+	if err != nil || length <= 0 {
+		return nil, 0, fmt.Errorf("invalid record length: %q", lengthLine)
+	}
+	record := make([]byte, length+1)
+	_, err = io.ReadFull(rd, record)
+	if err == io.EOF {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if record[length] != '\n' {
+		return nil, 0, fmt.Errorf("record is not terminated by newline")
+	}
+	return record[:length], int64(len(lengthLine) + length + 1), nil
+}
+
+func formatRecord(encoded []byte) []byte {
+	record := make([]byte, 0, len(encoded)+16)
+	record = strconv.AppendInt(record, int64(len(encoded)), 10)
+	record = append(record, '\n')
+	record = append(record, encoded...)
+	return append(record, '\n')
+}
+
+func (ibs *indexedBackstore) appendRecord(encoded []byte) (offset int64, err error) {
+	f, err := os.OpenFile(ibs.logPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0664)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	offset = ibs.idx.LogSize
+	record := formatRecord(encoded)
+	_, err = f.Write(record)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		// don't leave a partial record behind
+		f.Truncate(offset)
+		return 0, err
+	}
+	ibs.idx.LogSize += int64(len(record))
+	return offset, nil
+}
+
+func (ibs *indexedBackstore) writeIndex() error {
+	data, err := json.Marshal(ibs.idx)
+	if err != nil {
+		return fmt.Errorf("broken assertion storage, cannot write index: %v", err)
+	}
+	err = atomicWriteEntry(data, false, ibs.top, idxIndexFname)
+	if err != nil {
+		return fmt.Errorf("broken assertion storage, cannot write index: %v", err)
+	}
+	ibs.unindexed = 0
+	return nil
+}
+
+func primaryKeyOf(assertType *AssertionType, assert Assertion) []string {
+	key := make([]string, len(assertType.PrimaryKey))
+	for i, k := range assertType.PrimaryKey {
+		key[i] = assert.HeaderString(k)
+	}
+	return key
+}
+
+func (ibs *indexedBackstore) addEntry(assertType *AssertionType, assert Assertion, offset int64, length int) {
+	byKey := ibs.idx.Entries[assertType.Name]
+	if byKey == nil {
+		byKey = make(map[string]*idxEntry)
+		ibs.idx.Entries[assertType.Name] = byKey
+	}
+	key := strings.Join(primaryKeyOf(assertType, assert), "/")
+	cur := byKey[key]
+	if cur != nil && cur.Revision > assert.Revision() {
+		return
+	}
+	headers := make(map[string]string)
+	for _, k := range assertType.PrimaryKey {
+		headers[k] = assert.HeaderString(k)
+	}
+	for _, k := range indexedHeaders[assertType.Name] {
+		if v, ok := assert.Header(k).(string); ok {
+			headers[k] = v
+		}
+	}
+	byKey[key] = &idxEntry{
+		Revision: assert.Revision(),
+		Offset:   offset,
+		Length:   length,
+		Headers:  headers,
+	}
+}
+
+func (ibs *indexedBackstore) put(assertType *AssertionType, assert Assertion) error {
+	key := strings.Join(primaryKeyOf(assertType, assert), "/")
+	if cur := ibs.idx.Entries[assertType.Name][key]; cur != nil {
+		curRev := cur.Revision
+		rev := assert.Revision()
+		if curRev >= rev {
+			return &RevisionError{Current: curRev, Used: rev}
+		}
+	}
+	encoded := Encode(assert)
+	offset, err := ibs.appendRecord(encoded)
+	if err != nil {
+		return fmt.Errorf("broken assertion storage, cannot write assertion: %v", err)
+	}
+	ibs.addEntry(assertType, assert, offset, len(encoded))
+	ibs.unindexed++
+	return nil
+}
+
+func (ibs *indexedBackstore) Put(assertType *AssertionType, assert Assertion) error {
+	ibs.mu.Lock()
+	defer ibs.mu.Unlock()
+
+	err := ibs.put(assertType, assert)
+	if err != nil {
+		return err
+	}
+	if ibs.unindexed >= indexCompactThreshold {
+		return ibs.writeIndex()
+	}
+	return nil
+}
+
+// guarantees that result assertion is of the expected type (both in the AssertionType and go type sense)
+func (ibs *indexedBackstore) readAssertion(f *os.File, assertType *AssertionType, entry *idxEntry) (Assertion, error) {
+	encoded := make([]byte, entry.Length)
+	// skip the record length line
+	lengthLine := strconv.Itoa(entry.Length) + "\n"
+	_, err := f.ReadAt(encoded, entry.Offset+int64(len(lengthLine)))
+	if err != nil {
+		return nil, fmt.Errorf("broken assertion storage, cannot read assertion: %v", err)
+	}
+	assert, err := Decode(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("broken assertion storage, cannot decode assertion: %v", err)
+	}
+	if assert.Type() != assertType {
+		return nil, fmt.Errorf("assertion that is not of type %q indexed as such", assertType.Name)
+	}
+	// because of Decode() construction assert has also the expected go type
+	return assert, nil
+}
+
+func (ibs *indexedBackstore) openLog() (*os.File, error) {
+	f, err := os.Open(ibs.logPath())
+	if err != nil {
+		return nil, fmt.Errorf("broken assertion storage, cannot read log: %v", err)
+	}
+	return f, nil
+}
+
+func (ibs *indexedBackstore) Get(assertType *AssertionType, key []string) (Assertion, error) {
+	ibs.mu.RLock()
+	defer ibs.mu.RUnlock()
+
+	entry := ibs.idx.Entries[assertType.Name][strings.Join(key, "/")]
+	if entry == nil {
+		return nil, ErrNotFound
+	}
+	f, err := ibs.openLog()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ibs.readAssertion(f, assertType, entry)
+}
+
+func (ibs *indexedBackstore) Search(assertType *AssertionType, headers map[string]string, foundCb func(Assertion)) error {
+	ibs.mu.RLock()
+	defer ibs.mu.RUnlock()
+
+	byKey := ibs.idx.Entries[assertType.Name]
+	if len(byKey) == 0 {
+		return nil
+	}
+
+	// only the headers that are not indexed need to be matched
+	// against the retrieved assertions
+	unindexed := make(map[string]string)
+	for k, v := range headers {
+		unindexed[k] = v
+	}
+	for _, k := range assertType.PrimaryKey {
+		delete(unindexed, k)
+	}
+	for _, k := range indexedHeaders[assertType.Name] {
+		delete(unindexed, k)
+	}
+
+	var candidates []*idxEntry
+	for _, entry := range byKey {
+		if indexMatch(entry, headers, unindexed) {
+			candidates = append(candidates, entry)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	// retrieve in log order
+	sort.Sort(byOffset(candidates))
+
+	f, err := ibs.openLog()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, entry := range candidates {
+		a, err := ibs.readAssertion(f, assertType, entry)
+		if err != nil {
+			return err
+		}
+		if searchMatch(a, unindexed) {
+			foundCb(a)
+		}
+	}
+	return nil
+}
+
+func indexMatch(entry *idxEntry, headers, unindexed map[string]string) bool {
+	for k, v := range headers {
+		if _, ok := unindexed[k]; ok {
+			continue
+		}
+		if entry.Headers[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+type byOffset []*idxEntry
+
+func (entries byOffset) Len() int           { return len(entries) }
+func (entries byOffset) Swap(i, j int)      { entries[i], entries[j] = entries[j], entries[i] }
+func (entries byOffset) Less(i, j int) bool { return entries[i].Offset < entries[j].Offset }
@@ -0,0 +1,313 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+type idxBackstoreSuite struct {
+	topDir string
+}
+
+var _ = Suite(&idxBackstoreSuite{})
+
+func (ibss *idxBackstoreSuite) SetUpTest(c *C) {
+	ibss.topDir = filepath.Join(c.MkDir(), "asserts-db")
+}
+
+func makeTestOnly(c *C, pk string, revision int, otherHeaders string) asserts.Assertion {
+	a, err := asserts.Decode([]byte(fmt.Sprintf("type: test-only\n"+
+		"authority-id: auth-id1\n"+
+		"primary-key: %s\n"+
+		"revision: %d\n"+
+		"%s"+
+		"\n"+
+		"openpgp c2ln", pk, revision, otherHeaders)))
+	c.Assert(err, IsNil)
+	return a
+}
+
+func (ibss *idxBackstoreSuite) TestOpenOK(c *C) {
+	// ensure umask is clean when creating the DB dir
+	oldUmask := syscall.Umask(0)
+	defer syscall.Umask(oldUmask)
+
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Check(err, IsNil)
+	c.Check(bs, NotNil)
+
+	info, err := os.Stat(filepath.Join(ibss.topDir, "asserts-idx-v0"))
+	c.Assert(err, IsNil)
+	c.Assert(info.IsDir(), Equals, true)
+	c.Check(info.Mode().Perm(), Equals, os.FileMode(0775))
+	_, err = os.Stat(filepath.Join(ibss.topDir, "asserts-idx-v0", "index.json"))
+	c.Check(err, IsNil)
+	// no fs backstore layout is created
+	_, err = os.Stat(filepath.Join(ibss.topDir, "asserts-v0"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (ibss *idxBackstoreSuite) TestOpenWorldWritableFail(c *C) {
+	// make it world-writable
+	oldUmask := syscall.Umask(0)
+	os.MkdirAll(filepath.Join(ibss.topDir, "asserts-idx-v0"), 0777)
+	syscall.Umask(oldUmask)
+
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, ErrorMatches, "assert storage root unexpectedly world-writable: .*")
+	c.Check(bs, IsNil)
+}
+
+func (ibss *idxBackstoreSuite) TestPutAndGet(c *C) {
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+
+	a := makeTestOnly(c, "foo", 0, "")
+	err = bs.Put(asserts.TestOnlyType, a)
+	c.Assert(err, IsNil)
+
+	got, err := bs.Get(asserts.TestOnlyType, []string{"foo"})
+	c.Assert(err, IsNil)
+	c.Check(asserts.Encode(got), DeepEquals, asserts.Encode(a))
+
+	got, err = bs.Get(asserts.TestOnlyType, []string{"bar"})
+	c.Check(err, Equals, asserts.ErrNotFound)
+	c.Check(got, IsNil)
+}
+
+func (ibss *idxBackstoreSuite) TestPutOldRevision(c *C) {
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "foo", 1, ""))
+	c.Assert(err, IsNil)
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "foo", 0, ""))
+	c.Check(err, ErrorMatches, `revision 0 is older than current revision 1`)
+	c.Check(err, DeepEquals, &asserts.RevisionError{Current: 1, Used: 0})
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "foo", 1, ""))
+	c.Check(err, ErrorMatches, "revision 1 is already the current revision")
+}
+
+func (ibss *idxBackstoreSuite) TestSearch(c *C) {
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+
+	a1 := makeTestOnly(c, "one", 0, "header1: a\nother: other1\n")
+	a2 := makeTestOnly(c, "two", 0, "header1: b\nother: other2\n")
+	a3 := makeTestOnly(c, "three", 0, "header1: a\nother: other2\n")
+	for _, a := range []asserts.Assertion{a1, a2, a3} {
+		err = bs.Put(asserts.TestOnlyType, a)
+		c.Assert(err, IsNil)
+	}
+
+	search := func(headers map[string]string) []string {
+		var found []string
+		err := bs.Search(asserts.TestOnlyType, headers, func(a asserts.Assertion) {
+			found = append(found, a.HeaderString("primary-key"))
+		})
+		c.Assert(err, IsNil)
+		return found
+	}
+
+	// results come in log order
+	c.Check(search(nil), DeepEquals, []string{"one", "two", "three"})
+	c.Check(search(map[string]string{"primary-key": "two"}), DeepEquals, []string{"two"})
+	// indexed header
+	c.Check(search(map[string]string{"header1": "a"}), DeepEquals, []string{"one", "three"})
+	// not indexed header
+	c.Check(search(map[string]string{"other": "other2"}), DeepEquals, []string{"two", "three"})
+	c.Check(search(map[string]string{"header1": "a", "other": "other2"}), DeepEquals, []string{"three"})
+	c.Check(search(map[string]string{"primary-key": "two", "other": "other1"}), HasLen, 0)
+	c.Check(search(map[string]string{"header1": "c"}), HasLen, 0)
+
+	var found []asserts.Assertion
+	err = bs.Search(asserts.TestOnly2Type, nil, func(a asserts.Assertion) {
+		found = append(found, a)
+	})
+	c.Assert(err, IsNil)
+	c.Check(found, HasLen, 0)
+}
+
+func (ibss *idxBackstoreSuite) TestSearchLatestRevision(c *C) {
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "foo", 0, "header1: a\n"))
+	c.Assert(err, IsNil)
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "foo", 1, "header1: b\n"))
+	c.Assert(err, IsNil)
+
+	var found []asserts.Assertion
+	cb := func(a asserts.Assertion) {
+		found = append(found, a)
+	}
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"header1": "a"}, cb)
+	c.Assert(err, IsNil)
+	c.Check(found, HasLen, 0)
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"header1": "b"}, cb)
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 1)
+	c.Check(found[0].Revision(), Equals, 1)
+}
+
+func (ibss *idxBackstoreSuite) TestReopen(c *C) {
+	restore := asserts.MockIndexCompactThreshold(2)
+	defer restore()
+
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+	// the first two are covered by the index on disk, the last two
+	// only by the log
+	for i := 0; i < 4; i++ {
+		err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, fmt.Sprintf("pk%d", i), 0, "header1: x\n"))
+		c.Assert(err, IsNil)
+	}
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "pk0", 1, "header1: y\n"))
+	c.Assert(err, IsNil)
+
+	bs, err = asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+
+	a, err := bs.Get(asserts.TestOnlyType, []string{"pk3"})
+	c.Assert(err, IsNil)
+	c.Check(a.HeaderString("primary-key"), Equals, "pk3")
+
+	var found []string
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"header1": "x"}, func(a asserts.Assertion) {
+		found = append(found, a.HeaderString("primary-key"))
+	})
+	c.Assert(err, IsNil)
+	c.Check(found, DeepEquals, []string{"pk1", "pk2", "pk3"})
+
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "pk0", 1, ""))
+	c.Check(err, DeepEquals, &asserts.RevisionError{Current: 1, Used: 1})
+}
+
+func (ibss *idxBackstoreSuite) TestReopenRebuildsBrokenIndex(c *C) {
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "foo", 0, ""))
+	c.Assert(err, IsNil)
+
+	err = ioutil.WriteFile(filepath.Join(ibss.topDir, "asserts-idx-v0", "index.json"), []byte("{garbage"), 0644)
+	c.Assert(err, IsNil)
+
+	bs, err = asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+	a, err := bs.Get(asserts.TestOnlyType, []string{"foo"})
+	c.Assert(err, IsNil)
+	c.Check(a.HeaderString("primary-key"), Equals, "foo")
+}
+
+func (ibss *idxBackstoreSuite) TestReopenDropsPartialRecord(c *C) {
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "foo", 0, ""))
+	c.Assert(err, IsNil)
+
+	logPath := filepath.Join(ibss.topDir, "asserts-idx-v0", "assertions.log")
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("100\ntype: test-only\n"))
+	c.Assert(err, IsNil)
+	f.Close()
+
+	bs, err = asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "bar", 0, ""))
+	c.Assert(err, IsNil)
+
+	bs, err = asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+	for _, pk := range []string{"foo", "bar"} {
+		a, err := bs.Get(asserts.TestOnlyType, []string{pk})
+		c.Assert(err, IsNil)
+		c.Check(a.HeaderString("primary-key"), Equals, pk)
+	}
+}
+
+func (ibss *idxBackstoreSuite) TestMigrateFromFSBackstore(c *C) {
+	fsbs, err := asserts.OpenFSBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+	for _, pk := range []string{"one", "two"} {
+		err = fsbs.Put(asserts.TestOnlyType, makeTestOnly(c, pk, 3, "header1: a\n"))
+		c.Assert(err, IsNil)
+	}
+	a2, err := asserts.Decode([]byte("type: test-only-2\n" +
+		"authority-id: auth-id1\n" +
+		"pk1: a\n" +
+		"pk2: x" +
+		"\n\n" +
+		"openpgp c2ln"))
+	c.Assert(err, IsNil)
+	err = fsbs.Put(asserts.TestOnly2Type, a2)
+	c.Assert(err, IsNil)
+
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+
+	var found []string
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"header1": "a"}, func(a asserts.Assertion) {
+		c.Check(a.Revision(), Equals, 3)
+		found = append(found, a.HeaderString("primary-key"))
+	})
+	c.Assert(err, IsNil)
+	c.Check(found, DeepEquals, []string{"one", "two"})
+
+	a, err := bs.Get(asserts.TestOnly2Type, []string{"a", "x"})
+	c.Assert(err, IsNil)
+	c.Check(asserts.Encode(a), DeepEquals, asserts.Encode(a2))
+
+	err = bs.Put(asserts.TestOnlyType, makeTestOnly(c, "one", 3, ""))
+	c.Check(err, DeepEquals, &asserts.RevisionError{Current: 3, Used: 3})
+
+	// the old layout is left alone and not migrated again
+	_, err = os.Stat(filepath.Join(ibss.topDir, "asserts-v0"))
+	c.Check(err, IsNil)
+	err = fsbs.Put(asserts.TestOnlyType, makeTestOnly(c, "three", 0, ""))
+	c.Assert(err, IsNil)
+	bs, err = asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, IsNil)
+	_, err = bs.Get(asserts.TestOnlyType, []string{"three"})
+	c.Check(err, Equals, asserts.ErrNotFound)
+}
+
+func (ibss *idxBackstoreSuite) TestMigrateWorldWritableFail(c *C) {
+	// make the old layout world-writable
+	oldUmask := syscall.Umask(0)
+	os.MkdirAll(filepath.Join(ibss.topDir, "asserts-v0"), 0777)
+	syscall.Umask(oldUmask)
+
+	bs, err := asserts.OpenIndexedBackstore(ibss.topDir)
+	c.Assert(err, ErrorMatches, "assert storage root unexpectedly world-writable: .*")
+	c.Check(bs, IsNil)
+	_, err = os.Stat(filepath.Join(ibss.topDir, "asserts-idx-v0"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
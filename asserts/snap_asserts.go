@@ -20,6 +20,7 @@
 package asserts
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -34,22 +35,22 @@ type SnapDeclaration struct {
 
 // Series returns the series for which the snap is being declared.
 func (snapdcl *SnapDeclaration) Series() string {
-	return snapdcl.Header("series")
+	return snapdcl.HeaderString("series")
 }
 
 // SnapID returns the snap id of the declared snap.
 func (snapdcl *SnapDeclaration) SnapID() string {
-	return snapdcl.Header("snap-id")
+	return snapdcl.HeaderString("snap-id")
 }
 
 // SnapName returns the declared snap name.
 func (snapdcl *SnapDeclaration) SnapName() string {
-	return snapdcl.Header("snap-name")
+	return snapdcl.HeaderString("snap-name")
 }
 
 // PublisherID returns the identifier of the publisher of the declared snap.
 func (snapdcl *SnapDeclaration) PublisherID() string {
-	return snapdcl.Header("publisher-id")
+	return snapdcl.HeaderString("publisher-id")
 }
 
 // Gates returns the list of snap-ids gated by this snap.
@@ -62,20 +63,27 @@ func (snapdcl *SnapDeclaration) Timestamp() time.Time {
 	return snapdcl.timestamp
 }
 
+// Prerequisites returns references to this snap-declaration's prerequisite assertions.
+func (snapdcl *SnapDeclaration) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: AccountType, PrimaryKey: []string{snapdcl.PublisherID()}},
+	}
+}
+
 // XXX: consistency check is signed by canonical
 
 func assembleSnapDeclaration(assert assertionBase) (Assertion, error) {
-	_, err := checkExists(assert.headers, "snap-name")
+	_, err := checkExistsString(assert.headers, "snap-name")
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = checkNotEmpty(assert.headers, "publisher-id")
+	_, err = checkNotEmptyString(assert.headers, "publisher-id")
 	if err != nil {
 		return nil, err
 	}
 
-	gates, err := checkCommaSepList(assert.headers, "gates")
+	gates, err := checkStringList(assert.headers, "gates")
 	if err != nil {
 		return nil, err
 	}
@@ -102,18 +110,18 @@ type SnapBuild struct {
 
 // Series returns the series for which the snap was built.
 func (snapbld *SnapBuild) Series() string {
-	return snapbld.Header("series")
+	return snapbld.HeaderString("series")
 }
 
 // SnapID returns the snap id of the snap.
 func (snapbld *SnapBuild) SnapID() string {
-	return snapbld.Header("snap-id")
+	return snapbld.HeaderString("snap-id")
 }
 
 // SnapDigest returns the digest of the snap. The digest is prefixed with the
 // algorithm used to generate it.
 func (snapbld *SnapBuild) SnapDigest() string {
-	return snapbld.Header("snap-digest")
+	return snapbld.HeaderString("snap-digest")
 }
 
 // SnapSize returns the size of the snap.
@@ -123,7 +131,7 @@ func (snapbld *SnapBuild) SnapSize() uint64 {
 
 // Grade returns the grade of the snap: devel|stable
 func (snapbld *SnapBuild) Grade() string {
-	return snapbld.Header("grade")
+	return snapbld.HeaderString("grade")
 }
 
 // Timestamp returns the time when the snap-build assertion was created.
@@ -131,10 +139,17 @@ func (snapbld *SnapBuild) Timestamp() time.Time {
 	return snapbld.timestamp
 }
 
+// Prerequisites returns references to this snap-build's prerequisite assertions.
+func (snapbld *SnapBuild) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: SnapDeclarationType, PrimaryKey: []string{snapbld.Series(), snapbld.SnapID()}},
+	}
+}
+
 func assembleSnapBuild(assert assertionBase) (Assertion, error) {
 	// TODO: more parsing/checking of snap-digest
 
-	_, err := checkNotEmpty(assert.headers, "grade")
+	_, err := checkNotEmptyString(assert.headers, "grade")
 	if err != nil {
 		return nil, err
 	}
@@ -169,18 +184,18 @@ type SnapRevision struct {
 // Series returns the series of the snap submitted to and acknowledged by the
 // store.
 func (snaprev *SnapRevision) Series() string {
-	return snaprev.Header("series")
+	return snaprev.HeaderString("series")
 }
 
 // SnapID returns the snap id of the snap.
 func (snaprev *SnapRevision) SnapID() string {
-	return snaprev.Header("snap-id")
+	return snaprev.HeaderString("snap-id")
 }
 
 // SnapDigest returns the digest of the snap submitted to and acknowledged by
 // the store. The digest is prefixed with the algorithm used to generate it.
 func (snaprev *SnapRevision) SnapDigest() string {
-	return snaprev.Header("snap-digest")
+	return snaprev.HeaderString("snap-digest")
 }
 
 // SnapSize returns the size in bytes of the snap submitted to the store.
@@ -196,7 +211,7 @@ func (snaprev *SnapRevision) SnapRevision() uint64 {
 // DeveloperID returns the id of the developer that submitted this build of the
 // snap.
 func (snaprev *SnapRevision) DeveloperID() string {
-	return snaprev.Header("developer-id")
+	return snaprev.HeaderString("developer-id")
 }
 
 // Timestamp returns the time when the snap-revision was issued.
@@ -204,6 +219,14 @@ func (snaprev *SnapRevision) Timestamp() time.Time {
 	return snaprev.timestamp
 }
 
+// Prerequisites returns references to this snap-revision's prerequisite assertions.
+func (snaprev *SnapRevision) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: SnapDeclarationType, PrimaryKey: []string{snaprev.Series(), snaprev.SnapID()}},
+		{Type: AccountType, PrimaryKey: []string{snaprev.DeveloperID()}},
+	}
+}
+
 // Implement further consistency checks.
 func (snaprev *SnapRevision) checkConsistency(db RODatabase, acck *AccountKey) error {
 	return nil
@@ -225,7 +248,7 @@ func assembleSnapRevision(assert assertionBase) (Assertion, error) {
 		return nil, err
 	}
 
-	_, err = checkNotEmpty(assert.headers, "developer-id")
+	_, err = checkNotEmptyString(assert.headers, "developer-id")
 	if err != nil {
 		return nil, err
 	}
@@ -242,3 +265,104 @@ func assembleSnapRevision(assert assertionBase) (Assertion, error) {
 		timestamp:     timestamp,
 	}, nil
 }
+
+// Validation holds a validation assertion, describing that a
+// combination of (snap-id, approved-snap-id, approved-revision) has
+// been validated for the series, meaning that the publisher of the
+// gating snap with snap-id approves the snap with approved-snap-id at
+// approved-revision to go together with it.
+type Validation struct {
+	assertionBase
+	approvedRevision uint64
+	revoked          bool
+	timestamp        time.Time
+}
+
+// Series returns the series for which the validation holds.
+func (validation *Validation) Series() string {
+	return validation.HeaderString("series")
+}
+
+// SnapID returns the ID of the gating snap.
+func (validation *Validation) SnapID() string {
+	return validation.HeaderString("snap-id")
+}
+
+// ApprovedSnapID returns the ID of the gated snap.
+func (validation *Validation) ApprovedSnapID() string {
+	return validation.HeaderString("approved-snap-id")
+}
+
+// ApprovedRevision returns the approved revision of the gated snap.
+func (validation *Validation) ApprovedRevision() uint64 {
+	return validation.approvedRevision
+}
+
+// Revoked returns true if the validation has been revoked.
+func (validation *Validation) Revoked() bool {
+	return validation.revoked
+}
+
+// Timestamp returns the time when the validation was issued.
+func (validation *Validation) Timestamp() time.Time {
+	return validation.timestamp
+}
+
+// Prerequisites returns references to this validation's prerequisite assertions.
+func (validation *Validation) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: SnapDeclarationType, PrimaryKey: []string{validation.Series(), validation.SnapID()}},
+		{Type: SnapDeclarationType, PrimaryKey: []string{validation.Series(), validation.ApprovedSnapID()}},
+	}
+}
+
+// Implement further consistency checks.
+func (validation *Validation) checkConsistency(db RODatabase, acck *AccountKey) error {
+	a, err := db.Find(SnapDeclarationType, map[string]string{
+		"series":  validation.Series(),
+		"snap-id": validation.SnapID(),
+	})
+	if err == ErrNotFound {
+		return fmt.Errorf("validation assertion for snap-id %q does not have a matching snap-declaration", validation.SnapID())
+	}
+	if err != nil {
+		return err
+	}
+	gatingDecl := a.(*SnapDeclaration)
+	if gatingDecl.PublisherID() != validation.AuthorityID() {
+		return fmt.Errorf("validation assertion for snap-id %q is not signed by the publisher of the gating snap %q", validation.SnapID(), gatingDecl.PublisherID())
+	}
+	for _, gated := range gatingDecl.Gates() {
+		if gated == validation.ApprovedSnapID() {
+			return nil
+		}
+	}
+	return fmt.Errorf("validation assertion for snap-id %q approves snap-id %q which is not gated by it", validation.SnapID(), validation.ApprovedSnapID())
+}
+
+// sanity
+var _ consistencyChecker = (*Validation)(nil)
+
+func assembleValidation(assert assertionBase) (Assertion, error) {
+	approvedRevision, err := checkUint(assert.headers, "approved-revision", 64)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := checkOptionalBool(assert.headers, "revoked")
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Validation{
+		assertionBase:    assert,
+		approvedRevision: approvedRevision,
+		revoked:          revoked,
+		timestamp:        timestamp,
+	}, nil
+}
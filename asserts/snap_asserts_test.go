@@ -33,6 +33,7 @@ var (
 	_ = Suite(&snapDeclSuite{})
 	_ = Suite(&snapBuildSuite{})
 	_ = Suite(&snapRevSuite{})
+	_ = Suite(&validationSuite{})
 )
 
 type snapDeclSuite struct {
@@ -70,6 +71,26 @@ func (sds *snapDeclSuite) TestDecodeOK(c *C) {
 	c.Check(snapDecl.Gates(), DeepEquals, []string{"snap-id-3", "snap-id-4"})
 }
 
+func (sds *snapDeclSuite) TestDecodeGatesList(c *C) {
+	encoded := "type: snap-declaration\n" +
+		"authority-id: canonical\n" +
+		"series: 16\n" +
+		"snap-id: snap-id-1\n" +
+		"snap-name: first\n" +
+		"publisher-id: dev-id1\n" +
+		"gates:\n" +
+		"  - snap-id-3\n" +
+		"  - snap-id-4\n" +
+		sds.tsLine +
+		"body-length: 0" +
+		"\n\n" +
+		"openpgp c2ln"
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	snapDecl := a.(*asserts.SnapDeclaration)
+	c.Check(snapDecl.Gates(), DeepEquals, []string{"snap-id-3", "snap-id-4"})
+}
+
 func (sds *snapDeclSuite) TestEmptySnapName(c *C) {
 	encoded := "type: snap-declaration\n" +
 		"authority-id: canonical\n" +
@@ -118,6 +139,9 @@ func (sds *snapDeclSuite) TestDecodeInvalid(c *C) {
 		{sds.tsLine, "timestamp: 12:30\n", `"timestamp" header is not a RFC3339 date: .*`},
 		{"gates: snap-id-3,snap-id-4\n", "", `\"gates\" header is mandatory`},
 		{"gates: snap-id-3,snap-id-4\n", "gates: foo,\n", `empty entry in comma separated "gates" header: "foo,"`},
+		{"gates: snap-id-3,snap-id-4\n", "gates:\n  - foo\n  -\n    - bar\n", `"gates" header must be a list of non-empty strings`},
+		{"gates: snap-id-3,snap-id-4\n", "gates:\n  foo: bar\n", `"gates" header must be a list of strings`},
+		{"snap-name: first\n", "snap-name:\n  - first\n", `"snap-name" header must be a string`},
 	}
 
 	for _, test := range invalidTests {
@@ -224,7 +248,7 @@ func makeSignAndCheckDbWithAccountKey(c *C, accountID string) (signingKeyID stri
 	c.Assert(err, IsNil)
 	accPubKeyBody := string(pubKeyEncoded)
 
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id":           "canonical",
 		"account-id":             accountID,
 		"public-key-id":          accKeyID,
@@ -255,7 +279,7 @@ func makeSignAndCheckDbWithAccountKey(c *C, accountID string) (signingKeyID stri
 func (sbs *snapBuildSuite) TestSnapBuildCheck(c *C) {
 	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
 
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "dev-id1",
 		"series":       "16",
 		"snap-id":      "snap-id-1",
@@ -274,7 +298,7 @@ func (sbs *snapBuildSuite) TestSnapBuildCheck(c *C) {
 func (sbs *snapBuildSuite) TestSnapBuildCheckInconsistentTimestamp(c *C) {
 	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
 
-	headers := map[string]string{
+	headers := map[string]interface{}{
 		"authority-id": "dev-id1",
 		"series":       "16",
 		"snap-id":      "snap-id-1",
@@ -317,8 +341,8 @@ func (srs *snapRevSuite) makeValidEncoded() string {
 		"openpgp c2ln"
 }
 
-func (srs *snapRevSuite) makeHeaders(overrides map[string]string) map[string]string {
-	headers := map[string]string{
+func (srs *snapRevSuite) makeHeaders(overrides map[string]interface{}) map[string]interface{} {
+	headers := map[string]interface{}{
 		"authority-id":  "store-id1",
 		"series":        "16",
 		"snap-id":       "snap-id-1",
@@ -401,7 +425,7 @@ func (srs *snapRevSuite) TestSnapRevisionCheck(c *C) {
 func (srs *snapRevSuite) TestSnapRevisionCheckInconsistentTimestamp(c *C) {
 	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "store-id1")
 
-	headers := srs.makeHeaders(map[string]string{
+	headers := srs.makeHeaders(map[string]interface{}{
 		"timestamp": "2013-01-01T14:00:00Z",
 	})
 	snapRev, err := accSignDB.Sign(asserts.SnapRevisionType, headers, nil, signingKeyID)
@@ -422,8 +446,178 @@ func (srs *snapRevSuite) TestPrimaryKey(c *C) {
 
 	_, err = db.Find(asserts.SnapRevisionType, map[string]string{
 		"series":      "16",
-		"snap-id":     headers["snap-id"],
-		"snap-digest": headers["snap-digest"],
+		"snap-id":     headers["snap-id"].(string),
+		"snap-digest": headers["snap-digest"].(string),
 	})
 	c.Assert(err, IsNil)
 }
+
+type validationSuite struct {
+	ts     time.Time
+	tsLine string
+}
+
+func (vs *validationSuite) SetUpSuite(c *C) {
+	vs.ts = time.Now().Truncate(time.Second).UTC()
+	vs.tsLine = "timestamp: " + vs.ts.Format(time.RFC3339) + "\n"
+}
+
+func (vs *validationSuite) makeValidEncoded() string {
+	return "type: validation\n" +
+		"authority-id: dev-id1\n" +
+		"series: 16\n" +
+		"snap-id: snap-id-1\n" +
+		"approved-snap-id: snap-id-2\n" +
+		"approved-revision: 42\n" +
+		"revision: 1\n" +
+		vs.tsLine +
+		"body-length: 0" +
+		"\n\n" +
+		"openpgp c2ln"
+}
+
+func (vs *validationSuite) makeHeaders(overrides map[string]interface{}) map[string]interface{} {
+	headers := map[string]interface{}{
+		"authority-id":      "dev-id1",
+		"series":            "16",
+		"snap-id":           "snap-id-1",
+		"approved-snap-id":  "snap-id-2",
+		"approved-revision": "42",
+		"revision":          "1",
+		"timestamp":         "2015-11-25T20:00:00Z",
+	}
+	for k, v := range overrides {
+		headers[k] = v
+	}
+	return headers
+}
+
+func (vs *validationSuite) TestDecodeOK(c *C) {
+	encoded := vs.makeValidEncoded()
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.ValidationType)
+	validation := a.(*asserts.Validation)
+	c.Check(validation.AuthorityID(), Equals, "dev-id1")
+	c.Check(validation.Timestamp(), Equals, vs.ts)
+	c.Check(validation.Series(), Equals, "16")
+	c.Check(validation.SnapID(), Equals, "snap-id-1")
+	c.Check(validation.ApprovedSnapID(), Equals, "snap-id-2")
+	c.Check(validation.ApprovedRevision(), Equals, uint64(42))
+	c.Check(validation.Revoked(), Equals, false)
+	c.Check(validation.Revision(), Equals, 1)
+
+	revoked := strings.Replace(encoded, vs.tsLine, vs.tsLine+"revoked: true\n", 1)
+	a, err = asserts.Decode([]byte(revoked))
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.Validation).Revoked(), Equals, true)
+}
+
+const (
+	validationErrPrefix = "assertion validation: "
+)
+
+func (vs *validationSuite) TestDecodeInvalid(c *C) {
+	encoded := vs.makeValidEncoded()
+	invalidTests := []struct{ original, invalid, expectedErr string }{
+		{"series: 16\n", "", `"series" header is mandatory`},
+		{"snap-id: snap-id-1\n", "", `"snap-id" header is mandatory`},
+		{"approved-snap-id: snap-id-2\n", "", `"approved-snap-id" header is mandatory`},
+		{"approved-snap-id: snap-id-2\n", "approved-snap-id: \n", `"approved-snap-id" header should not be empty`},
+		{"approved-revision: 42\n", "", `"approved-revision" header is mandatory`},
+		{"approved-revision: 42\n", "approved-revision: z\n", `"approved-revision" header is not an unsigned integer: z`},
+		{vs.tsLine, vs.tsLine + "revoked: maybe\n", `"revoked" header must be 'true' or 'false'`},
+		{vs.tsLine, "", `"timestamp" header is mandatory`},
+		{vs.tsLine, "timestamp: 12:30\n", `"timestamp" header is not a RFC3339 date: .*`},
+	}
+
+	for _, test := range invalidTests {
+		invalid := strings.Replace(encoded, test.original, test.invalid, 1)
+		_, err := asserts.Decode([]byte(invalid))
+		c.Check(err, ErrorMatches, validationErrPrefix+test.expectedErr)
+	}
+}
+
+func (vs *validationSuite) addGatingDecl(c *C, db *asserts.Database, publisherID, gates string) {
+	headers := map[string]interface{}{
+		"authority-id": "canonical",
+		"series":       "16",
+		"snap-id":      "snap-id-1",
+		"snap-name":    "gating",
+		"publisher-id": publisherID,
+		"gates":        gates,
+		"timestamp":    "2015-11-25T20:00:00Z",
+	}
+	snapDecl, err := asserts.AssembleAndSignInTest(asserts.SnapDeclarationType, headers, nil, testPrivKey0)
+	c.Assert(err, IsNil)
+	err = db.Add(snapDecl)
+	c.Assert(err, IsNil)
+}
+
+func (vs *validationSuite) TestValidationCheck(c *C) {
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
+	vs.addGatingDecl(c, db, "dev-id1", "snap-id-3,snap-id-2")
+
+	validation, err := accSignDB.Sign(asserts.ValidationType, vs.makeHeaders(nil), nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(validation)
+	c.Assert(err, IsNil)
+}
+
+func (vs *validationSuite) TestValidationCheckNoGatingDecl(c *C) {
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
+
+	validation, err := accSignDB.Sign(asserts.ValidationType, vs.makeHeaders(nil), nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(validation)
+	c.Assert(err, ErrorMatches, `validation assertion violates other knowledge: validation assertion for snap-id "snap-id-1" does not have a matching snap-declaration`)
+}
+
+func (vs *validationSuite) TestValidationCheckWrongAuthority(c *C) {
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
+	vs.addGatingDecl(c, db, "dev-id2", "snap-id-2")
+
+	validation, err := accSignDB.Sign(asserts.ValidationType, vs.makeHeaders(nil), nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(validation)
+	c.Assert(err, ErrorMatches, `validation assertion violates other knowledge: validation assertion for snap-id "snap-id-1" is not signed by the publisher of the gating snap "dev-id2"`)
+}
+
+func (vs *validationSuite) TestValidationCheckNotGated(c *C) {
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
+	vs.addGatingDecl(c, db, "dev-id1", "snap-id-3")
+
+	validation, err := accSignDB.Sign(asserts.ValidationType, vs.makeHeaders(nil), nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(validation)
+	c.Assert(err, ErrorMatches, `validation assertion violates other knowledge: validation assertion for snap-id "snap-id-1" approves snap-id "snap-id-2" which is not gated by it`)
+}
+
+func (vs *validationSuite) TestValidationCheckInconsistentTimestamp(c *C) {
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
+	vs.addGatingDecl(c, db, "dev-id1", "snap-id-2")
+
+	headers := vs.makeHeaders(map[string]interface{}{
+		"timestamp": "2013-01-01T14:00:00Z",
+	})
+	validation, err := accSignDB.Sign(asserts.ValidationType, headers, nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(validation)
+	c.Assert(err, ErrorMatches, "validation assertion timestamp outside of signing key validity")
+}
+
+func (vs *validationSuite) TestPrerequisites(c *C) {
+	encoded := vs.makeValidEncoded()
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+
+	prereqs := a.Prerequisites()
+	c.Assert(prereqs, HasLen, 2)
+	c.Check(prereqs[0].Unique(), Equals, "snap-declaration/16/snap-id-1")
+	c.Check(prereqs[1].Unique(), Equals, "snap-declaration/16/snap-id-2")
+}
@@ -27,7 +27,7 @@ import (
 )
 
 func openDatabaseAt(path string, cfg *DatabaseConfig) (*Database, error) {
-	bs, err := OpenFSBackstore(path)
+	bs, err := OpenIndexedBackstore(path)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,116 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// A TokenKey is a key held by a signing token, like a PKCS#11 device
+// or a HSM, that never exposes its private part but can sign
+// digests.
+type TokenKey interface {
+	// Sign signs digests as crypto.Signer, the public key must be
+	// a *rsa.PublicKey.
+	crypto.Signer
+
+	// CreationTime returns the creation time of the key as recorded
+	// in its OpenPGP public key packet, it contributes to its
+	// fingerprint and id.
+	CreationTime() time.Time
+}
+
+// A SigningToken gives access to the keys held by a token, like a
+// session with a PKCS#11 device.
+type SigningToken interface {
+	// FindKeys returns the keys on the token usable by the authority.
+	FindKeys(authorityID string) ([]TokenKey, error)
+}
+
+type tokenKeypairManager struct {
+	token SigningToken
+}
+
+// NewTokenKeypairManager creates a new key pair manager backed by the
+// given signing token.
+// Importing keys through the keypair manager interface is not
+// supported.
+func NewTokenKeypairManager(token SigningToken) KeypairManager {
+	return &tokenKeypairManager{
+		token: token,
+	}
+}
+
+func (tkm *tokenKeypairManager) Put(authorityID string, privKey PrivateKey) error {
+	return fmt.Errorf("cannot import private key into signing token")
+}
+
+func (tkm *tokenKeypairManager) Get(authorityID, keyID string) (PrivateKey, error) {
+	keys, err := tkm.token.FindKeys(authorityID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find key %q in signing token: %v", keyID, err)
+	}
+	for _, key := range keys {
+		rsaPubKey, ok := key.Public().(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		pubKey := packet.NewRSAPublicKey(key.CreationTime(), rsaPubKey)
+		if OpenPGPPublicKey(pubKey).ID() != keyID {
+			continue
+		}
+		bitLen := rsaPubKey.N.BitLen()
+		if bitLen < 4096 {
+			return nil, fmt.Errorf("cannot use signing token key %q: need at least 4096 bits key, got %d", keyID, bitLen)
+		}
+		return tokenPrivateKey{openpgpPrivateKey{packet.NewSignerPrivateKey(key.CreationTime(), key)}}, nil
+	}
+	return nil, fmt.Errorf("cannot find key %q in signing token", keyID)
+}
+
+type tokenPrivateKey struct {
+	openpgpPrivateKey
+}
+
+func (tpk tokenPrivateKey) keyEncode(w io.Writer) error {
+	return fmt.Errorf("cannot access external private key to encode it")
+}
+
+func (tpk tokenPrivateKey) keyFormat() string {
+	return ""
+}
+
+func (tpk tokenPrivateKey) sign(content []byte) (*packet.Signature, error) {
+	sig, err := tpk.openpgpPrivateKey.sign(content)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign using signing token: %v", err)
+	}
+	err = tpk.PublicKey().verify(content, openpgpSignature{sig})
+	if err != nil {
+		return nil, fmt.Errorf("bad signing token produced signature: it does not verify: %v", err)
+	}
+	return sig, nil
+}
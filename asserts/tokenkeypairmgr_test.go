@@ -0,0 +1,165 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+type testTokenKey struct {
+	privKey      asserts.PrivateKey
+	signed       int
+	corruptSigns bool
+}
+
+func (k *testTokenKey) Public() crypto.PublicKey {
+	return &k.rsaKey().PublicKey
+}
+
+func (k *testTokenKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	k.signed++
+	if k.corruptSigns {
+		digest = append([]byte(nil), digest...)
+		digest[0] ^= 0xff
+	}
+	return k.rsaKey().Sign(rand, digest, opts)
+}
+
+func (k *testTokenKey) CreationTime() time.Time {
+	return asserts.PrivateKeyPacket(k.privKey).CreationTime
+}
+
+func (k *testTokenKey) rsaKey() *rsa.PrivateKey {
+	return asserts.PrivateKeyPacket(k.privKey).PrivateKey.(*rsa.PrivateKey)
+}
+
+type testToken struct {
+	keys map[string][]asserts.TokenKey
+	err  error
+}
+
+func (t *testToken) FindKeys(authorityID string) ([]asserts.TokenKey, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return t.keys[authorityID], nil
+}
+
+type tokenKeypairMgrSuite struct {
+	key        *testTokenKey
+	token      *testToken
+	keypairMgr asserts.KeypairManager
+}
+
+var _ = Suite(&tokenKeypairMgrSuite{})
+
+func (tkms *tokenKeypairMgrSuite) SetUpTest(c *C) {
+	tkms.key = &testTokenKey{privKey: getStrongTestPrivKey(c)}
+	tkms.token = &testToken{
+		keys: map[string][]asserts.TokenKey{
+			"auth-id1": {&testTokenKey{privKey: testPrivKey1}, tkms.key},
+		},
+	}
+	tkms.keypairMgr = asserts.NewTokenKeypairManager(tkms.token)
+}
+
+func (tkms *tokenKeypairMgrSuite) TestGetPublicKeyLooksGood(c *C) {
+	pubKey := tkms.key.privKey.PublicKey()
+	got, err := tkms.keypairMgr.Get("auth-id1", pubKey.ID())
+	c.Assert(err, IsNil)
+	c.Check(got.PublicKey().ID(), Equals, pubKey.ID())
+	c.Check(got.PublicKey().Fingerprint(), Equals, pubKey.Fingerprint())
+}
+
+func (tkms *tokenKeypairMgrSuite) TestGetNotFound(c *C) {
+	got, err := tkms.keypairMgr.Get("auth-id1", "ffffffffffffffff")
+	c.Check(err, ErrorMatches, `cannot find key "ffffffffffffffff" in signing token`)
+	c.Check(got, IsNil)
+
+	got, err = tkms.keypairMgr.Get("auth-id2", tkms.key.privKey.PublicKey().ID())
+	c.Check(err, ErrorMatches, `cannot find key ".*" in signing token`)
+	c.Check(got, IsNil)
+}
+
+func (tkms *tokenKeypairMgrSuite) TestGetTokenError(c *C) {
+	tkms.token.err = fmt.Errorf("token not present")
+	got, err := tkms.keypairMgr.Get("auth-id1", "ffffffffffffffff")
+	c.Check(err, ErrorMatches, `cannot find key "ffffffffffffffff" in signing token: token not present`)
+	c.Check(got, IsNil)
+}
+
+func (tkms *tokenKeypairMgrSuite) TestGetWeakKey(c *C) {
+	got, err := tkms.keypairMgr.Get("auth-id1", testPrivKey1.PublicKey().ID())
+	c.Check(err, ErrorMatches, `cannot use signing token key ".*": need at least 4096 bits key, got 752`)
+	c.Check(got, IsNil)
+}
+
+func (tkms *tokenKeypairMgrSuite) TestPut(c *C) {
+	err := tkms.keypairMgr.Put("auth-id1", testPrivKey1)
+	c.Check(err, ErrorMatches, "cannot import private key into signing token")
+}
+
+func (tkms *tokenKeypairMgrSuite) TestUseInSigning(c *C) {
+	pubKey := tkms.key.privKey.PublicKey()
+	signDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: tkms.keypairMgr,
+	})
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"authority-id": "auth-id1",
+		"primary-key":  "0",
+	}
+	a, err := signDB.Sign(asserts.TestOnlyType, headers, nil, pubKey.ID())
+	c.Assert(err, IsNil)
+	c.Check(tkms.key.signed, Equals, 1)
+
+	checkDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		Trusted:        []asserts.Assertion{asserts.BootstrapAccountKeyForTest("auth-id1", pubKey)},
+	})
+	c.Assert(err, IsNil)
+	err = checkDB.Check(a)
+	c.Check(err, IsNil)
+}
+
+func (tkms *tokenKeypairMgrSuite) TestSignBadSignature(c *C) {
+	tkms.key.corruptSigns = true
+	signDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: tkms.keypairMgr,
+	})
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"authority-id": "auth-id1",
+		"primary-key":  "0",
+	}
+	_, err = signDB.Sign(asserts.TestOnlyType, headers, nil, tkms.key.privKey.PublicKey().ID())
+	c.Check(err, ErrorMatches, "cannot sign assertion: bad signing token produced signature: it does not verify: .*")
+}
@@ -0,0 +1,93 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// AppOptions represent the options of the Apps call.
+type AppOptions struct {
+	// If Service is true, only return apps that are services.
+	Service bool
+}
+
+// Apps returns information about the apps of the given snaps, or of all
+// active snaps when no names are given. Names are either snap names or
+// <snap>.<app> pairs.
+func (client *Client) Apps(names []string, opts AppOptions) ([]*AppInfo, error) {
+	query := url.Values{}
+	if len(names) > 0 {
+		query.Set("names", strings.Join(names, ","))
+	}
+	if opts.Service {
+		query.Set("select", "service")
+	}
+
+	var apps []*AppInfo
+	_, err := client.doSync("GET", "/v2/apps", query, nil, nil, &apps)
+	return apps, err
+}
+
+// StartOptions represent the options of the Start call.
+type StartOptions struct {
+	// Enable the services so they also start on boot.
+	Enable bool `json:"enable,omitempty"`
+}
+
+// StopOptions represent the options of the Stop call.
+type StopOptions struct {
+	// Disable the services so they no longer start on boot.
+	Disable bool `json:"disable,omitempty"`
+}
+
+type appInstruction struct {
+	Action string   `json:"action"`
+	Names  []string `json:"names"`
+	StartOptions
+	StopOptions
+}
+
+func (client *Client) doAppAction(inst *appInstruction) (changeID string, err error) {
+	b, err := json.Marshal(inst)
+	if err != nil {
+		return "", err
+	}
+	return client.doAsync("POST", "/v2/apps", nil, nil, bytes.NewReader(b))
+}
+
+// Start starts the given services, or all services when no names are given.
+func (client *Client) Start(names []string, opts StartOptions) (changeID string, err error) {
+	return client.doAppAction(&appInstruction{Action: "start", Names: names, StartOptions: opts})
+}
+
+// Stop stops the given services, or all services when no names are given.
+func (client *Client) Stop(names []string, opts StopOptions) (changeID string, err error) {
+	return client.doAppAction(&appInstruction{Action: "stop", Names: names, StopOptions: opts})
+}
+
+// Restart restarts the given services, or all services when no names are
+// given.
+func (client *Client) Restart(names []string) (changeID string, err error) {
+	return client.doAppAction(&appInstruction{Action: "restart", Names: names})
+}
@@ -0,0 +1,85 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientApps(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{"snap": "foo", "name": "web", "daemon": "simple", "enabled": true, "active": true},
+			{"snap": "foo", "name": "worker", "daemon": "forking"}
+		]
+	}`
+	apps, err := cs.cli.Apps([]string{"foo", "bar.baz"}, client.AppOptions{Service: true})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/apps")
+	c.Check(cs.req.URL.Query().Get("names"), check.Equals, "foo,bar.baz")
+	c.Check(cs.req.URL.Query().Get("select"), check.Equals, "service")
+	c.Check(apps, check.DeepEquals, []*client.AppInfo{
+		{Snap: "foo", Name: "web", Daemon: "simple", Enabled: true, Active: true},
+		{Snap: "foo", Name: "worker", Daemon: "forking"},
+	})
+}
+
+func (cs *clientSuite) TestClientAppsNoOptions(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": []}`
+	_, err := cs.cli.Apps(nil, client.AppOptions{})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.RawQuery, check.Equals, "")
+}
+
+func (cs *clientSuite) TestClientAppActions(c *check.C) {
+	for _, t := range []struct {
+		op       func() (string, error)
+		expected map[string]interface{}
+	}{
+		{func() (string, error) { return cs.cli.Start([]string{"foo"}, client.StartOptions{Enable: true}) },
+			map[string]interface{}{"action": "start", "names": []interface{}{"foo"}, "enable": true}},
+		{func() (string, error) { return cs.cli.Stop([]string{"foo.web"}, client.StopOptions{Disable: true}) },
+			map[string]interface{}{"action": "stop", "names": []interface{}{"foo.web"}, "disable": true}},
+		{func() (string, error) { return cs.cli.Restart(nil) },
+			map[string]interface{}{"action": "restart", "names": nil}},
+	} {
+		cs.rsp = `{
+			"type": "async",
+			"status-code": 202,
+			"result": { },
+			"change": "42"
+		}`
+		id, err := t.op()
+		c.Assert(err, check.IsNil)
+		c.Check(id, check.Equals, "42")
+		c.Check(cs.req.Method, check.Equals, "POST")
+		c.Check(cs.req.URL.Path, check.Equals, "/v2/apps")
+		var body map[string]interface{}
+		err = json.NewDecoder(cs.req.Body).Decode(&body)
+		c.Check(err, check.IsNil)
+		c.Check(body, check.DeepEquals, t.expected)
+	}
+}
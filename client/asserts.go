@@ -33,7 +33,8 @@ import (
 // Ack tries to add an assertion to the system assertion
 // database. To succeed the assertion must be valid, its signature
 // verified with a known public key and the assertion consistent with
-// and its prerequisite in the database.
+// and its prerequisite in the database. b can also hold a stream of
+// assertions, which are added after their prerequisites.
 func (client *Client) Ack(b []byte) error {
 	var rsp interface{}
 	if _, err := client.doSync("POST", "/v2/assertions", nil, nil, bytes.NewReader(b), &rsp); err != nil {
@@ -45,6 +46,17 @@ func (client *Client) Ack(b []byte) error {
 
 // Known queries assertions with type assertTypeName and matching assertion headers.
 func (client *Client) Known(assertTypeName string, headers map[string]string) ([]asserts.Assertion, error) {
+	return client.known(assertTypeName, headers, false)
+}
+
+// KnownWithPrerequisites queries assertions like Known but returns
+// them each preceded by its prerequisites, as a bundle that can be
+// acked in order on another system.
+func (client *Client) KnownWithPrerequisites(assertTypeName string, headers map[string]string) ([]asserts.Assertion, error) {
+	return client.known(assertTypeName, headers, true)
+}
+
+func (client *Client) known(assertTypeName string, headers map[string]string, export bool) ([]asserts.Assertion, error) {
 	path := fmt.Sprintf("/v2/assertions/%s", assertTypeName)
 	q := url.Values{}
 
@@ -53,6 +65,9 @@ func (client *Client) Known(assertTypeName string, headers map[string]string) ([
 			q.Set(k, v)
 		}
 	}
+	if export {
+		q.Set("export", "true")
+	}
 
 	response, err := client.raw("GET", path, q, nil, nil)
 	if err != nil {
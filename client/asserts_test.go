@@ -65,6 +65,19 @@ func (cs *clientSuite) TestClientAssertsCallsEndpointWithFilter(c *C) {
 	})
 }
 
+func (cs *clientSuite) TestClientAssertsWithPrerequisitesCallsEndpoint(c *C) {
+	_, _ = cs.cli.KnownWithPrerequisites("snap-revision", map[string]string{
+		"snap-id": "snap-id-1",
+	})
+	u, err := url.ParseRequestURI(cs.req.URL.String())
+	c.Assert(err, IsNil)
+	c.Check(u.Path, Equals, "/v2/assertions/snap-revision")
+	c.Check(u.Query(), DeepEquals, url.Values{
+		"snap-id": []string{"snap-id-1"},
+		"export":  []string{"true"},
+	})
+}
+
 func (cs *clientSuite) TestClientAssertsHttpError(c *C) {
 	cs.err = errors.New("fail")
 	_, err := cs.cli.Known("snap-build", nil)
@@ -231,6 +231,7 @@ const (
 	ErrorKindTwoFactorRequired = "two-factor-required"
 	ErrorKindTwoFactorFailed   = "two-factor-failed"
 	ErrorKindLoginRequired     = "login-required"
+	ErrorKindRefreshPostponed  = "refresh-postponed"
 )
 
 // IsTwoFactorError returns whether the given error is due to problems
@@ -22,6 +22,7 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"net/url"
 )
 
 // Plug represents the potential of a given snap to connect to a slot.
@@ -64,6 +65,23 @@ type Interfaces struct {
 	Slots []Slot `json:"slots"`
 }
 
+// Connection describes a connection between a plug and a slot.
+type Connection struct {
+	Plug      PlugRef `json:"plug"`
+	Slot      SlotRef `json:"slot"`
+	Interface string  `json:"interface"`
+	// Manual is set for connections made on request rather than automatically.
+	Manual bool `json:"manual,omitempty"`
+}
+
+// Connections contains information about established connections and the
+// plugs and slots of one or all snaps.
+type Connections struct {
+	Established []Connection `json:"established"`
+	Plugs       []Plug       `json:"plugs"`
+	Slots       []Slot       `json:"slots"`
+}
+
 // InterfaceAction represents an action performed on the interface system.
 type InterfaceAction struct {
 	Action string `json:"action"`
@@ -77,6 +95,17 @@ func (client *Client) Interfaces() (interfaces Interfaces, err error) {
 	return
 }
 
+// Connections returns the connections, plugs and slots of the given snap, or
+// of all the snaps if snapName is empty.
+func (client *Client) Connections(snapName string) (conns Connections, err error) {
+	query := url.Values{}
+	if snapName != "" {
+		query.Set("snap", snapName)
+	}
+	_, err = client.doSync("GET", "/v2/connections", query, nil, nil, &conns)
+	return
+}
+
 // performInterfaceAction performs a single action on the interface system.
 func (client *Client) performInterfaceAction(sa *InterfaceAction) (changeID string, err error) {
 	b, err := json.Marshal(sa)
@@ -104,3 +133,28 @@ func (client *Client) Disconnect(plugSnapName, plugName, slotSnapName, slotName
 		Slots:  []Slot{{Snap: slotSnapName, Name: slotName}},
 	})
 }
+
+// InterfaceDrift describes a single disagreement between the interface
+// connections recorded by snapd, the interface repository and the security
+// files on disk.
+type InterfaceDrift struct {
+	Kind    string `json:"kind"`
+	Snap    string `json:"snap,omitempty"`
+	Backend string `json:"backend,omitempty"`
+	Detail  string `json:"detail"`
+}
+
+// CheckInterfaces checks the consistency of the interface system.
+func (client *Client) CheckInterfaces() (drift []InterfaceDrift, err error) {
+	_, err = client.doSync("GET", "/v2/interfaces/check", nil, nil, nil, &drift)
+	return
+}
+
+// RepairInterfaces repairs the inconsistencies reported by CheckInterfaces.
+func (client *Client) RepairInterfaces() (changeID string, err error) {
+	b, err := json.Marshal(map[string]string{"action": "repair"})
+	if err != nil {
+		return "", err
+	}
+	return client.doAsync("POST", "/v2/interfaces/check", nil, nil, bytes.NewReader(b))
+}
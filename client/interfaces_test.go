@@ -168,3 +168,97 @@ func (cs *clientSuite) TestClientDisconnect(c *check.C) {
 		},
 	})
 }
+
+func (cs *clientSuite) TestClientCheckInterfaces(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{"kind": "missing-connection", "detail": "consumer:plug producer:slot"},
+			{"kind": "changed-file", "snap": "consumer", "backend": "apparmor", "detail": "snap.consumer.app"}
+		]
+	}`
+	drift, err := cs.cli.CheckInterfaces()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/interfaces/check")
+	c.Check(drift, check.DeepEquals, []client.InterfaceDrift{
+		{Kind: "missing-connection", Detail: "consumer:plug producer:slot"},
+		{Kind: "changed-file", Snap: "consumer", Backend: "apparmor", Detail: "snap.consumer.app"},
+	})
+}
+
+func (cs *clientSuite) TestClientRepairInterfaces(c *check.C) {
+	cs.rsp = `{
+		"type": "async",
+                "status-code": 202,
+		"result": { },
+                "change": "foo"
+	}`
+	id, err := cs.cli.RepairInterfaces()
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "foo")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/interfaces/check")
+	var body map[string]interface{}
+	decoder := json.NewDecoder(cs.req.Body)
+	err = decoder.Decode(&body)
+	c.Check(err, check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{"action": "repair"})
+}
+
+func (cs *clientSuite) TestClientConnections(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"established": [
+				{
+					"plug": {"snap": "keyboard-lights", "plug": "capslock-led"},
+					"slot": {"snap": "canonical-pi2", "slot": "pin-13"},
+					"interface": "bool-file",
+					"manual": true
+				}
+			],
+			"plugs": [
+				{
+					"snap": "keyboard-lights",
+					"plug": "capslock-led",
+					"interface": "bool-file",
+					"connections": [
+						{"snap": "canonical-pi2", "slot": "pin-13"}
+					]
+				}
+			],
+			"slots": []
+		}
+	}`
+	conns, err := cs.cli.Connections("keyboard-lights")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/connections")
+	c.Check(cs.req.URL.Query().Get("snap"), check.Equals, "keyboard-lights")
+	c.Check(conns, check.DeepEquals, client.Connections{
+		Established: []client.Connection{{
+			Plug:      client.PlugRef{Snap: "keyboard-lights", Name: "capslock-led"},
+			Slot:      client.SlotRef{Snap: "canonical-pi2", Name: "pin-13"},
+			Interface: "bool-file",
+			Manual:    true,
+		}},
+		Plugs: []client.Plug{{
+			Snap:      "keyboard-lights",
+			Name:      "capslock-led",
+			Interface: "bool-file",
+			Connections: []client.SlotRef{
+				{Snap: "canonical-pi2", Name: "pin-13"},
+			},
+		}},
+		Slots: []client.Slot{},
+	})
+}
+
+func (cs *clientSuite) TestClientConnectionsAllSnaps(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {"established": [], "plugs": [], "slots": []}}`
+	_, err := cs.cli.Connections("")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/connections")
+	c.Check(cs.req.URL.RawQuery, check.Equals, "")
+}
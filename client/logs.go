@@ -0,0 +1,106 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Log holds a single log entry of a snap service.
+type Log struct {
+	Timestamp string `json:"timestamp"`
+	SID       string `json:"sid"`
+	PID       string `json:"pid"`
+	Message   string `json:"message"`
+}
+
+func (l Log) String() string {
+	return fmt.Sprintf("%s %s[%s]: %s", l.Timestamp, l.SID, l.PID, l.Message)
+}
+
+// LogOptions represent the options of the Logs call.
+type LogOptions struct {
+	// N is the number of past entries to get, or all of them if negative.
+	N int
+	// Follow keeps getting new entries as they are logged.
+	Follow bool
+}
+
+// Logs returns a channel on which the log entries of the given services,
+// or of all services when no names are given, are delivered. The channel
+// is closed when the server stops sending entries.
+func (client *Client) Logs(names []string, opts LogOptions) (<-chan Log, error) {
+	query := url.Values{}
+	if len(names) > 0 {
+		query.Set("names", strings.Join(names, ","))
+	}
+	if opts.N < 0 {
+		query.Set("n", "all")
+	} else {
+		query.Set("n", strconv.Itoa(opts.N))
+	}
+	if opts.Follow {
+		query.Set("follow", "true")
+	}
+
+	rsp, err := client.raw("GET", "/v2/logs", query, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot communicate with server: %v", err)
+	}
+	if rsp.StatusCode != http.StatusOK {
+		defer rsp.Body.Close()
+		return nil, parseError(rsp)
+	}
+
+	ch := make(chan Log)
+	go func() {
+		defer rsp.Body.Close()
+		defer close(ch)
+		decodeLogs(rsp.Body, ch)
+	}()
+	return ch, nil
+}
+
+// decodeLogs reads a JSON text sequence of logs, as described in RFC 7464,
+// delivering each log on ch. Records that cannot be decoded are skipped.
+func decodeLogs(r io.Reader, ch chan<- Log) {
+	buf := bufio.NewReader(r)
+	for {
+		record, err := buf.ReadBytes('\n')
+		record = bytes.TrimLeft(record, "\x1e")
+		if len(bytes.TrimSpace(record)) > 0 {
+			var log Log
+			if json.Unmarshal(record, &log) == nil {
+				ch <- log
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
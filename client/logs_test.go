@@ -0,0 +1,70 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientLogs(c *check.C) {
+	cs.rsp = "\x1e{\"timestamp\":\"1970-01-01T00:00:00.000042Z\",\"sid\":\"foo.web\",\"pid\":\"7\",\"message\":\"hello\"}\n" +
+		"\x1e{garbage}\n" +
+		"\x1e{\"timestamp\":\"1970-01-01T00:00:00.000044Z\",\"sid\":\"foo.web\",\"pid\":\"7\",\"message\":\"bye\"}\n"
+	ch, err := cs.cli.Logs([]string{"foo.web", "bar"}, client.LogOptions{N: -1, Follow: true})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/logs")
+	c.Check(cs.req.URL.Query().Get("names"), check.Equals, "foo.web,bar")
+	c.Check(cs.req.URL.Query().Get("n"), check.Equals, "all")
+	c.Check(cs.req.URL.Query().Get("follow"), check.Equals, "true")
+
+	var logs []client.Log
+	for log := range ch {
+		logs = append(logs, log)
+	}
+	c.Check(logs, check.DeepEquals, []client.Log{
+		{Timestamp: "1970-01-01T00:00:00.000042Z", SID: "foo.web", PID: "7", Message: "hello"},
+		{Timestamp: "1970-01-01T00:00:00.000044Z", SID: "foo.web", PID: "7", Message: "bye"},
+	})
+	c.Check(logs[0].String(), check.Equals, "1970-01-01T00:00:00.000042Z foo.web[7]: hello")
+}
+
+func (cs *clientSuite) TestClientLogsDefaults(c *check.C) {
+	cs.rsp = ""
+	ch, err := cs.cli.Logs(nil, client.LogOptions{})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.RawQuery, check.Equals, "n=0")
+	for range ch {
+		c.Fatal("unexpected log")
+	}
+}
+
+func (cs *clientSuite) TestClientLogsError(c *check.C) {
+	cs.status = 400
+	cs.header = http.Header{"Content-Type": {"application/json"}}
+	cs.rsp = `{"type": "error", "result": {"message": "app \"foo.cli\" is not a service"}}`
+	_, err := cs.cli.Logs([]string{"foo.cli"}, client.LogOptions{N: 5})
+	c.Check(err, check.ErrorMatches, `app "foo.cli" is not a service`)
+	c.Check(cs.req.URL.Query().Get("n"), check.Equals, "5")
+}
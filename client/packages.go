@@ -49,11 +49,20 @@ type Snap struct {
 	TryMode       bool          `json:"trymode"`
 	Apps          []AppInfo     `json:"apps"`
 
+	Resources *snap.Resources `json:"resources,omitempty"`
+
 	Prices map[string]float64 `json:"prices"`
 }
 
+// AppInfo holds the data for an app of a snap. Snap, Enabled and Active
+// are only filled in when listing apps.
 type AppInfo struct {
-	Name string `json:"name"`
+	Snap      string          `json:"snap,omitempty"`
+	Name      string          `json:"name"`
+	Daemon    string          `json:"daemon,omitempty"`
+	Enabled   bool            `json:"enabled,omitempty"`
+	Active    bool            `json:"active,omitempty"`
+	Resources *snap.Resources `json:"resources,omitempty"`
 }
 
 // Statuses and types a snap may have.
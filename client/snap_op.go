@@ -31,8 +31,10 @@ import (
 )
 
 type SnapOptions struct {
-	Channel string `json:"channel,omitempty"`
-	DevMode bool   `json:"devmode,omitempty"`
+	Channel   string `json:"channel,omitempty"`
+	DevMode   bool   `json:"devmode,omitempty"`
+	KillApps  bool   `json:"kill-apps,omitempty"`
+	Dangerous bool   `json:"dangerous,omitempty"`
 }
 
 type actionData struct {
@@ -130,6 +132,7 @@ func sendSnapFile(snapPath string, snapFile *os.File, pw *io.PipeWriter, mw *mul
 		mw.WriteField("snap-path", action.SnapPath),
 		mw.WriteField("channel", action.Channel),
 		mw.WriteField("devmode", strconv.FormatBool(action.DevMode)),
+		mw.WriteField("dangerous", strconv.FormatBool(action.Dangerous)),
 	}
 	for _, err := range errs {
 		if err != nil {
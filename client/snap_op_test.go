@@ -118,6 +118,25 @@ func (cs *clientSuite) TestClientOpSnap(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientOpRefreshKillApps(c *check.C) {
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	_, err := cs.cli.Refresh(pkgName, &client.SnapOptions{KillApps: true})
+	c.Assert(err, check.IsNil)
+
+	var jsonBody map[string]interface{}
+	err = json.NewDecoder(cs.req.Body).Decode(&jsonBody)
+	c.Assert(err, check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action":    "refresh",
+		"name":      pkgName,
+		"kill-apps": true,
+	})
+}
+
 func (cs *clientSuite) TestClientOpInstallPath(c *check.C) {
 	cs.rsp = `{
 		"change": "66b3",
@@ -145,6 +164,24 @@ func (cs *clientSuite) TestClientOpInstallPath(c *check.C) {
 	c.Check(id, check.Equals, "66b3")
 }
 
+func (cs *clientSuite) TestClientOpInstallPathDangerous(c *check.C) {
+	cs.rsp = `{
+		"change": "66b3",
+		"status-code": 202,
+		"type": "async"
+	}`
+	snap := filepath.Join(c.MkDir(), "foo.snap")
+	err := ioutil.WriteFile(snap, []byte("snap-data"), 0644)
+	c.Assert(err, check.IsNil)
+
+	_, err = cs.cli.InstallPath(snap, &client.SnapOptions{Dangerous: true})
+	c.Assert(err, check.IsNil)
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(body), check.Matches, "(?s).*Content-Disposition: form-data; name=\"dangerous\"\r\n\r\ntrue\r\n.*")
+}
+
 func formToMap(c *check.C, mr *multipart.Reader) map[string]string {
 	formData := map[string]string{}
 	for {
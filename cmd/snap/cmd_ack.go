@@ -43,6 +43,13 @@ will replace.
 To succeed the assertion must be valid, its signature verified with a known
 public key and the assertion consistent with and its prerequisite in the
 database.
+
+The assertion file can also contain several assertions, for example the
+assertion of interest together with its prerequisites as exported by
+'snap known --export'. They are added in dependency order, and
+prerequisites not already known to the system or present in the file are
+fetched from the store. If some of the assertions cannot be added the
+others are still added, and the failing ones are reported.
 `)
 
 func init() {
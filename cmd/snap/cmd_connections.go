@@ -0,0 +1,93 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/i18n"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type cmdConnections struct {
+	Positionals struct {
+		Snap string `positional-arg-name:"<snap>" description:"snap name"`
+	} `positional-args:"true"`
+}
+
+var shortConnectionsHelp = i18n.G("Lists the connections of a snap")
+var longConnectionsHelp = i18n.G(`
+The connections command lists the connections established between plugs and
+slots, and the plugs and slots that are not connected.
+
+$ snap connections <snap>
+
+Lists only the connections, plugs and slots of the specified snap.
+`)
+
+func init() {
+	addCommand("connections", shortConnectionsHelp, longConnectionsHelp, func() flags.Commander {
+		return &cmdConnections{}
+	})
+}
+
+// plugOrSlotName returns the snap:name form of a plug or slot, abbreviated
+// to :name for the OS snap as in the output of "snap interfaces".
+func plugOrSlotName(snap, name string) string {
+	if snap == "ubuntu-core" {
+		return ":" + name
+	}
+	return snap + ":" + name
+}
+
+func (x *cmdConnections) Execute(args []string) error {
+	conns, err := Client().Connections(x.Positionals.Snap)
+	if err != nil {
+		return err
+	}
+	if len(conns.Established) == 0 && len(conns.Plugs) == 0 && len(conns.Slots) == 0 {
+		return fmt.Errorf(i18n.G("no connections found"))
+	}
+	w := tabWriter()
+	defer w.Flush()
+	fmt.Fprintln(w, i18n.G("Interface\tPlug\tSlot\tNotes"))
+	for _, conn := range conns.Established {
+		notes := "-"
+		if conn.Manual {
+			notes = "manual"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", conn.Interface,
+			plugOrSlotName(conn.Plug.Snap, conn.Plug.Name),
+			plugOrSlotName(conn.Slot.Snap, conn.Slot.Name), notes)
+	}
+	// Display visual indicator for disconnected plugs and slots.
+	for _, plug := range conns.Plugs {
+		if len(plug.Connections) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t-\t-\n", plug.Interface, plugOrSlotName(plug.Snap, plug.Name))
+		}
+	}
+	for _, slot := range conns.Slots {
+		if len(slot.Connections) == 0 {
+			fmt.Fprintf(w, "%s\t-\t%s\t-\n", slot.Interface, plugOrSlotName(slot.Snap, slot.Name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	. "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestConnectionsHelp(c *C) {
+	msg := `Usage:
+  snap.test [OPTIONS] connections [<snap>]
+
+The connections command lists the connections established between plugs and
+slots, and the plugs and slots that are not connected.
+
+$ snap connections <snap>
+
+Lists only the connections, plugs and slots of the specified snap.
+
+Application Options:
+      --version     print the version and exit
+
+Help Options:
+  -h, --help        Show this help message
+
+[connections command arguments]
+  <snap>:           snap name
+`
+	rest, err := Parser().ParseArgs([]string{"connections", "--help"})
+	c.Assert(err.Error(), Equals, msg)
+	c.Assert(rest, DeepEquals, []string{})
+}
+
+func (s *SnapSuite) TestConnectionsOfSnap(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query().Get("snap"), Equals, "keyboard-lights")
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type": "sync",
+			"result": client.Connections{
+				Established: []client.Connection{{
+					Plug:      client.PlugRef{Snap: "keyboard-lights", Name: "capslock-led"},
+					Slot:      client.SlotRef{Snap: "canonical-pi2", Name: "pin-13"},
+					Interface: "bool-file",
+					Manual:    true,
+				}, {
+					Plug:      client.PlugRef{Snap: "keyboard-lights", Name: "network"},
+					Slot:      client.SlotRef{Snap: "ubuntu-core", Name: "network"},
+					Interface: "network",
+				}},
+				Plugs: []client.Plug{{
+					Snap:        "keyboard-lights",
+					Name:        "capslock-led",
+					Interface:   "bool-file",
+					Connections: []client.SlotRef{{Snap: "canonical-pi2", Name: "pin-13"}},
+				}, {
+					Snap:        "keyboard-lights",
+					Name:        "network",
+					Interface:   "network",
+					Connections: []client.SlotRef{{Snap: "ubuntu-core", Name: "network"}},
+				}, {
+					Snap:      "keyboard-lights",
+					Name:      "numlock-led",
+					Interface: "bool-file",
+				}},
+				Slots: []client.Slot{{
+					Snap:      "keyboard-lights",
+					Name:      "brightness",
+					Interface: "bool-file",
+				}},
+			},
+		})
+	})
+	rest, err := Parser().ParseArgs([]string{"connections", "keyboard-lights"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	expectedStdout := "" +
+		"Interface  Plug                          Slot                        Notes\n" +
+		"bool-file  keyboard-lights:capslock-led  canonical-pi2:pin-13        manual\n" +
+		"network    keyboard-lights:network       :network                    -\n" +
+		"bool-file  keyboard-lights:numlock-led   -                           -\n" +
+		"bool-file  -                             keyboard-lights:brightness  -\n"
+	c.Assert(s.Stdout(), Equals, expectedStdout)
+	c.Assert(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestConnectionsNothingFound(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query().Get("snap"), Equals, "")
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": client.Connections{},
+		})
+	})
+	_, err := Parser().ParseArgs([]string{"connections"})
+	c.Assert(err, ErrorMatches, "no connections found")
+}
@@ -0,0 +1,79 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdCreateKey struct {
+	Positional struct {
+		KeyName string `positional-arg-name:"<key-name>" description:"name of key to create; defaults to 'default'"`
+	} `positional-args:"true"`
+}
+
+var shortCreateKeyHelp = i18n.G("Creates a key pair for signing assertions")
+var longCreateKeyHelp = i18n.G(`
+The create-key command creates a 4096 bits RSA key pair in the GnuPG
+keyring of the user, usable to sign assertions with 'snap sign'.
+
+The key is protected by the passphrase that is asked for, leaving it
+empty creates an unprotected key.
+`)
+
+func init() {
+	addCommand("create-key", shortCreateKeyHelp, longCreateKeyHelp, func() flags.Commander {
+		return &cmdCreateKey{}
+	})
+}
+
+var readPassword = terminal.ReadPassword
+
+func (x *cmdCreateKey) Execute(args []string) error {
+	keyName := x.Positional.KeyName
+	if keyName == "" {
+		keyName = "default"
+	}
+
+	fmt.Fprint(Stdout, i18n.G("Passphrase: "))
+	passphrase, err := readPassword(0)
+	fmt.Fprint(Stdout, "\n")
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(Stdout, i18n.G("Confirm passphrase: "))
+	confirmPassphrase, err := readPassword(0)
+	fmt.Fprint(Stdout, "\n")
+	if err != nil {
+		return err
+	}
+	if string(passphrase) != string(confirmPassphrase) {
+		return fmt.Errorf(i18n.G("passphrases do not match"))
+	}
+
+	manager := asserts.NewGPGKeypairManager("")
+	return manager.Generate(string(passphrase), keyName)
+}
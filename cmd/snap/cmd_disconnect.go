@@ -45,6 +45,10 @@ $ snap disconnect <snap>:<slot>
 
 Disconnects any previously connected plugs from the provided slot.
 
+$ snap disconnect <snap>:<plug>
+
+Disconnects the provided plug from any previously connected slots.
+
 $ snap disconnect <snap>
 
 Disconnects all plugs from the provided snap.
@@ -58,6 +62,7 @@ func init() {
 
 func (x *cmdDisconnect) Execute(args []string) error {
 	// snap disconnect <snap>:<slot>
+	// snap disconnect <snap>:<plug> (told apart by snapd)
 	// snap disconnect <snap>
 	if x.Positionals.Use.Snap == "" && x.Positionals.Use.Name == "" {
 		// Swap Offer and Use around
@@ -44,6 +44,10 @@ $ snap disconnect <snap>:<slot>
 
 Disconnects any previously connected plugs from the provided slot.
 
+$ snap disconnect <snap>:<plug>
+
+Disconnects the provided plug from any previously connected slots.
+
 $ snap disconnect <snap>
 
 Disconnects all plugs from the provided snap.
@@ -0,0 +1,97 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdExportKey struct {
+	Account    string `long:"account" description:"Format public key material as a request for an account-key for this account-id"`
+	Positional struct {
+		KeyName string `positional-arg-name:"<key-name>" description:"name of key to export; defaults to 'default'"`
+	} `positional-args:"true"`
+}
+
+var shortExportKeyHelp = i18n.G("Exports the public part of a key pair")
+var longExportKeyHelp = i18n.G(`
+The export-key command exports the public part of the named key pair
+from the GnuPG keyring of the user, encoded as used in account-key
+assertions.
+
+With --account the public key is instead wrapped in an
+account-key-request assertion for the given account-id, signed by the
+key itself, which can be submitted to obtain the matching account-key
+assertion.
+`)
+
+func init() {
+	addCommand("export-key", shortExportKeyHelp, longExportKeyHelp, func() flags.Commander {
+		return &cmdExportKey{}
+	})
+}
+
+func (x *cmdExportKey) Execute(args []string) error {
+	keyName := x.Positional.KeyName
+	if keyName == "" {
+		keyName = "default"
+	}
+
+	manager := asserts.NewGPGKeypairManager("")
+	privKey, err := manager.GetByName(keyName)
+	if err != nil {
+		return err
+	}
+	pubKey := privKey.PublicKey()
+	encodedPubKey, err := asserts.EncodePublicKey(pubKey)
+	if err != nil {
+		return err
+	}
+
+	if x.Account == "" {
+		fmt.Fprintf(Stdout, "%s\n", encodedPubKey)
+		return nil
+	}
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: manager,
+	})
+	if err != nil {
+		return err
+	}
+	headers := map[string]interface{}{
+		"authority-id":           x.Account,
+		"account-id":             x.Account,
+		"public-key-id":          pubKey.ID(),
+		"public-key-fingerprint": pubKey.Fingerprint(),
+		"since":                  time.Now().UTC().Format(time.RFC3339),
+	}
+	accKeyRequest, err := db.Sign(asserts.AccountKeyRequestType, headers, encodedPubKey, pubKey.ID())
+	if err != nil {
+		return err
+	}
+	return asserts.NewEncoder(Stdout).Encode(accKeyRequest)
+}
@@ -0,0 +1,57 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapKeysSuite) TestExportKey(c *C) {
+	rest, err := snap.Parser().ParseArgs([]string{"export-key"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Matches, `(?s)openpgp [^\n]+\n.*`)
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapKeysSuite) TestExportKeyNotFound(c *C) {
+	_, err := snap.Parser().ParseArgs([]string{"export-key", "missing"})
+	c.Check(err, ErrorMatches, `cannot find key named "missing" in GPG keyring`)
+}
+
+func (s *SnapKeysSuite) TestExportKeyAccount(c *C) {
+	rest, err := snap.Parser().ParseArgs([]string{"export-key", "--account=dev1-id", "default"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	a, err := asserts.Decode(s.stdout.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(a.Type(), Equals, asserts.AccountKeyRequestType)
+	accKeyRequest := a.(*asserts.AccountKeyRequest)
+	c.Check(accKeyRequest.AuthorityID(), Equals, "dev1-id")
+	c.Check(accKeyRequest.AccountID(), Equals, "dev1-id")
+	c.Check(accKeyRequest.Since().After(time.Now().Add(-time.Hour)), Equals, true)
+	c.Check(accKeyRequest.Verify(), IsNil)
+}
@@ -0,0 +1,104 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type cmdSnapInfo struct {
+	Positional struct {
+		Snap string `positional-arg-name:"<snap>" required:"yes"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+var shortInfoHelp = i18n.G("Show detailed information about an installed snap")
+var longInfoHelp = i18n.G(`
+The info command shows detailed information about an installed snap,
+including the resource limits applied to the snap and to each of its
+services.`)
+
+func init() {
+	addCommand("info", shortInfoHelp, longInfoHelp, func() flags.Commander { return &cmdSnapInfo{} })
+}
+
+// formatResources renders resource limits as a comma separated list of
+// key=value pairs, or "-" when no limits are set.
+func formatResources(res *snap.Resources) string {
+	if res == nil {
+		return "-"
+	}
+	var limits []string
+	if res.MemoryLimit != "" {
+		limits = append(limits, "memory-limit="+res.MemoryLimit)
+	}
+	if res.CPUQuota != "" {
+		limits = append(limits, "cpu-quota="+res.CPUQuota)
+	}
+	if res.CPUWeight != 0 {
+		limits = append(limits, fmt.Sprintf("cpu-weight=%d", res.CPUWeight))
+	}
+	if res.TasksMax != 0 {
+		limits = append(limits, fmt.Sprintf("tasks-max=%d", res.TasksMax))
+	}
+	if len(limits) == 0 {
+		return "-"
+	}
+	return strings.Join(limits, ", ")
+}
+
+func (x *cmdSnapInfo) Execute([]string) error {
+	info, _, err := Client().Snap(x.Positional.Snap)
+	if err != nil {
+		return err
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintf(w, "name:\t%s\n", info.Name)
+	fmt.Fprintf(w, "summary:\t%s\n", info.Summary)
+	fmt.Fprintf(w, "developer:\t%s\n", info.Developer)
+	fmt.Fprintf(w, "version:\t%s\n", info.Version)
+	fmt.Fprintf(w, "revision:\t%s\n", info.Revision)
+	fmt.Fprintf(w, "resources:\t%s\n", formatResources(info.Resources))
+
+	var services []string
+	for _, app := range info.Apps {
+		if app.Daemon == "" {
+			continue
+		}
+		services = append(services, fmt.Sprintf("  %s:\t%s\n", app.Name, formatResources(app.Resources)))
+	}
+	if len(services) > 0 {
+		fmt.Fprintln(w, "services:")
+		for _, line := range services {
+			fmt.Fprint(w, line)
+		}
+	}
+
+	return nil
+}
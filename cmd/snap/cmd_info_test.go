@@ -0,0 +1,76 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestInfo(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"name": "foo", "summary": "some foo", "version": "4.2", "developer": "bar", "revision": 17,
+"resources": {"memory-limit": "512M", "tasks-max": 64},
+"apps": [{"name": "cli"}, {"name": "web", "daemon": "simple", "resources": {"cpu-quota": "50%", "cpu-weight": 200}}, {"name": "worker", "daemon": "forking"}]}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser().ParseArgs([]string{"info", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `name:       foo
+summary:    some foo
+developer:  bar
+version:    4.2
+revision:   17
+resources:  memory-limit=512M, tasks-max=64
+services:
+  web:     cpu-quota=50%, cpu-weight=200
+  worker:  -
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestInfoNoResources(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": {"name": "foo", "summary": "some foo", "version": "4.2", "developer": "bar", "revision": 17, "apps": [{"name": "cli"}]}}`)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"info", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `name:       foo
+summary:    some foo
+developer:  bar
+version:    4.2
+revision:   17
+resources:  -
+`)
+}
@@ -29,6 +29,8 @@ import (
 
 type cmdInterfaces struct {
 	Interface   string `short:"i" description:"constrain listing to specific interfaces"`
+	Check       bool   `long:"check" description:"check connections and security files for inconsistencies"`
+	Repair      bool   `long:"repair" description:"repair inconsistencies found by --check"`
 	Positionals struct {
 		Query SnapAndName `positional-arg-name:"<snap>:<slot or plug>" description:"snap or snap:name" skip-help:"true"`
 	} `positional-args:"true"`
@@ -51,6 +53,15 @@ Lists the slots offered and plugs used by the specified snap.
 $ snap interfaces -i=<interface> [<snap>]
 
 Filters the complete output so only plugs and/or slots matching the provided details are listed.
+
+$ snap interfaces --check
+
+Checks that the connections known to snapd and the security files generated
+for each snap agree with each other and lists any inconsistencies.
+
+$ snap interfaces --repair
+
+Repairs the inconsistencies found by --check.
 `)
 
 func init() {
@@ -60,6 +71,20 @@ func init() {
 }
 
 func (x *cmdInterfaces) Execute(args []string) error {
+	if x.Repair {
+		cli := Client()
+		id, err := cli.RepairInterfaces()
+		if err != nil {
+			return err
+		}
+		if _, err := wait(cli, id); err != nil {
+			return err
+		}
+		return x.check()
+	}
+	if x.Check {
+		return x.check()
+	}
 	ifaces, err := Client().Interfaces()
 	if err == nil {
 		if len(ifaces.Plugs) == 0 && len(ifaces.Slots) == 0 {
@@ -127,3 +152,28 @@ func (x *cmdInterfaces) Execute(args []string) error {
 	}
 	return err
 }
+
+func (x *cmdInterfaces) check() error {
+	drift, err := Client().CheckInterfaces()
+	if err != nil {
+		return err
+	}
+	if len(drift) == 0 {
+		fmt.Fprintln(Stdout, i18n.G("No inconsistencies found."))
+		return nil
+	}
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("Problem\tSnap\tBackend\tDetail"))
+	for _, d := range drift {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Kind, valueOrDash(d.Snap), valueOrDash(d.Backend), d.Detail)
+	}
+	w.Flush()
+	return fmt.Errorf(i18n.G("found %d inconsistencies, use --repair to repair them"), len(drift))
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -21,6 +21,7 @@
 package main_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 
@@ -51,6 +52,15 @@ $ snap interfaces -i=<interface> [<snap>]
 Filters the complete output so only plugs and/or slots matching the provided
 details are listed.
 
+$ snap interfaces --check
+
+Checks that the connections known to snapd and the security files generated
+for each snap agree with each other and lists any inconsistencies.
+
+$ snap interfaces --repair
+
+Repairs the inconsistencies found by --check.
+
 Application Options:
       --version                    print the version and exit
 
@@ -59,6 +69,9 @@ Help Options:
 
 [interfaces command options]
       -i=                          constrain listing to specific interfaces
+          --check                  check connections and security files for
+                                   inconsistencies
+          --repair                 repair inconsistencies found by --check
 
 [interfaces command arguments]
   <snap>:<slot or plug>:           snap or snap:name
@@ -526,3 +539,66 @@ func (s *SnapSuite) TestInterfacesNothingAtAll(c *C) {
 	c.Assert(s.Stdout(), Equals, "")
 	c.Assert(s.Stderr(), Equals, "")
 }
+
+func (s *SnapSuite) TestInterfacesCheck(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/interfaces/check")
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type": "sync",
+			"result": []client.InterfaceDrift{
+				{Kind: "missing-connection", Detail: "consumer:plug producer:slot"},
+				{Kind: "changed-file", Snap: "consumer", Backend: "seccomp", Detail: "snap.consumer.app"},
+			},
+		})
+	})
+	_, err := Parser().ParseArgs([]string{"interfaces", "--check"})
+	c.Assert(err, ErrorMatches, "found 2 inconsistencies, use --repair to repair them")
+	expectedStdout := "" +
+		"Problem             Snap      Backend  Detail\n" +
+		"missing-connection  -         -        consumer:plug producer:slot\n" +
+		"changed-file        consumer  seccomp  snap.consumer.app\n"
+	c.Assert(s.Stdout(), Equals, expectedStdout)
+	c.Assert(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestInterfacesCheckNoDrift(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/interfaces/check")
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": []client.InterfaceDrift{},
+		})
+	})
+	rest, err := Parser().ParseArgs([]string{"interfaces", "--check"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Assert(s.Stdout(), Equals, "No inconsistencies found.\n")
+	c.Assert(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestInterfacesRepair(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/interfaces/check":
+			if r.Method == "POST" {
+				c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{"action": "repair"})
+				fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "zzz"}`)
+				return
+			}
+			c.Check(r.Method, Equals, "GET")
+			fmt.Fprintln(w, `{"type":"sync", "result": []}`)
+		case "/v2/changes/zzz":
+			c.Check(r.Method, Equals, "GET")
+			fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	})
+	rest, err := Parser().ParseArgs([]string{"interfaces", "--repair"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Assert(s.Stdout(), Matches, "(?s).*No inconsistencies found.\n")
+	c.Assert(s.Stderr(), Equals, "")
+}
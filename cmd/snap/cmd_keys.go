@@ -0,0 +1,64 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdKeys struct{}
+
+var shortKeysHelp = i18n.G("Lists the key pairs usable for signing assertions")
+var longKeysHelp = i18n.G(`
+The keys command lists the key pairs in the GnuPG keyring of the user
+that can be used to sign assertions with 'snap sign', by name and key id.
+`)
+
+func init() {
+	addCommand("keys", shortKeysHelp, longKeysHelp, func() flags.Commander {
+		return &cmdKeys{}
+	})
+}
+
+func (x *cmdKeys) Execute(args []string) error {
+	manager := asserts.NewGPGKeypairManager("")
+	keys, err := manager.List()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No keys are available yet. Try 'snap create-key'."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Name\tKey ID"))
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", key.Name, key.ID)
+	}
+	return nil
+}
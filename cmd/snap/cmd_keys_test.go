@@ -0,0 +1,122 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+
+	. "gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/osutil"
+)
+
+type SnapKeysSuite struct {
+	BaseSnapSuite
+	// GnuPG home with a "default" key, shared by the tests
+	gnupgHome     string
+	prevGnupgHome string
+}
+
+var _ = Suite(&SnapKeysSuite{})
+
+func (s *SnapKeysSuite) SetUpSuite(c *C) {
+	if !osutil.FileExists("/usr/bin/gpg") {
+		c.Skip("gpg not installed")
+	}
+	s.gnupgHome = c.MkDir()
+	s.generateKey(c, s.gnupgHome, "default")
+}
+
+func (s *SnapKeysSuite) generateKey(c *C, gnupgHome, name string) {
+	gpg := exec.Command("gpg", "--homedir", gnupgHome, "-q", "--batch", "--gen-key")
+	gpg.Stdin = bytes.NewBufferString("Key-Type: RSA\nKey-Length: 4096\nName-Real: " + name + "\n%no-protection\n%commit\n")
+	out, err := gpg.CombinedOutput()
+	c.Assert(err, IsNil, Commentf("test key generation failed: %v (%q)", err, out))
+}
+
+func (s *SnapKeysSuite) SetUpTest(c *C) {
+	s.BaseSnapSuite.SetUpTest(c)
+	s.prevGnupgHome = os.Getenv("GNUPGHOME")
+	os.Setenv("GNUPGHOME", s.gnupgHome)
+}
+
+func (s *SnapKeysSuite) TearDownTest(c *C) {
+	os.Setenv("GNUPGHOME", s.prevGnupgHome)
+	s.BaseSnapSuite.TearDownTest(c)
+}
+
+func (s *SnapKeysSuite) TestKeys(c *C) {
+	rest, err := snap.Parser().ParseArgs([]string{"keys"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Matches, `Name +Key ID
+default +[0-9a-f]{16}
+`)
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapKeysSuite) TestKeysEmpty(c *C) {
+	os.Setenv("GNUPGHOME", c.MkDir())
+
+	rest, err := snap.Parser().ParseArgs([]string{"keys"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "No keys are available yet. Try 'snap create-key'.\n")
+}
+
+func (s *SnapKeysSuite) TestCreateKey(c *C) {
+	os.Setenv("GNUPGHOME", c.MkDir())
+	restore := snap.MockReadPassword(func(fd int) ([]byte, error) {
+		return []byte(""), nil
+	})
+	defer restore()
+
+	rest, err := snap.Parser().ParseArgs([]string{"create-key", "another"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "Passphrase: \nConfirm passphrase: \n")
+
+	s.stdout.Reset()
+	_, err = snap.Parser().ParseArgs([]string{"keys"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Matches, `Name +Key ID
+another +[0-9a-f]{16}
+`)
+
+	_, err = snap.Parser().ParseArgs([]string{"create-key", "another"})
+	c.Check(err, ErrorMatches, `key named "another" already exists in GPG keyring`)
+}
+
+func (s *SnapKeysSuite) TestCreateKeyPassphraseMismatch(c *C) {
+	passphrases := []string{"one", "two"}
+	restore := snap.MockReadPassword(func(fd int) ([]byte, error) {
+		passphrase := passphrases[0]
+		passphrases = passphrases[1:]
+		return []byte(passphrase), nil
+	})
+	defer restore()
+
+	_, err := snap.Parser().ParseArgs([]string{"create-key", "another"})
+	c.Check(err, ErrorMatches, `passphrases do not match`)
+}
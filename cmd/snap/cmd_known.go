@@ -30,6 +30,7 @@ import (
 )
 
 type cmdKnown struct {
+	Export       bool `long:"export" description:"Include the prerequisites of the assertions, to ack them together elsewhere"`
 	KnownOptions struct {
 		AssertTypeName string   `positional-arg-name:"<assertion type>" description:"assertion type name" required:"true"`
 		HeaderFilters  []string `positional-arg-name:"<header filters>" description:"header=value" required:"0"`
@@ -41,6 +42,10 @@ var longKnownHelp = i18n.G(`
 The known command shows known assertions of the provided type.
 If header=value pairs are provided after the assertion type, the assertions
 shown must also have the specified headers matching the provided values.
+
+With --export the assertions are preceded by all their prerequisites,
+producing a stream that can be saved to a file and acked in one go on
+another system, with no need to reach the store.
 `)
 
 func init() {
@@ -62,7 +67,13 @@ func (x *cmdKnown) Execute(args []string) error {
 		headers[parts[0]] = parts[1]
 	}
 
-	assertions, err := Client().Known(x.KnownOptions.AssertTypeName, headers)
+	var assertions []asserts.Assertion
+	var err error
+	if x.Export {
+		assertions, err = Client().KnownWithPrerequisites(x.KnownOptions.AssertTypeName, headers)
+	} else {
+		assertions, err = Client().Known(x.KnownOptions.AssertTypeName, headers)
+	}
 	if err != nil {
 		return err
 	}
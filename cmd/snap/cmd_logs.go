@@ -0,0 +1,75 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type cmdLogs struct {
+	N      string `short:"n" default:"10" description:"show only the given number of lines, or 'all'"`
+	Follow bool   `short:"f" description:"wait for new lines and print them as they come in"`
+
+	Positional struct {
+		ServiceNames []string `positional-arg-name:"<service>"`
+	} `positional-args:"yes"`
+}
+
+var shortLogsHelp = i18n.G("Retrieve logs of services")
+var longLogsHelp = i18n.G(`
+The logs command fetches the logs of the given services, given as <snap> for
+all the services of a snap or as <snap>.<app> for a single service. Without
+arguments the logs of all services are shown.
+`)
+
+func init() {
+	addCommand("logs", shortLogsHelp, longLogsHelp, func() flags.Commander { return &cmdLogs{} })
+}
+
+func (x *cmdLogs) Execute([]string) error {
+	n := -1
+	if x.N != "all" {
+		var err error
+		n, err = strconv.Atoi(x.N)
+		if err != nil || n < 0 {
+			return fmt.Errorf(i18n.G("invalid argument for flag -n: expected a non-negative integer or 'all', not %q"), x.N)
+		}
+	}
+
+	logs, err := Client().Logs(x.Positional.ServiceNames, client.LogOptions{
+		N:      n,
+		Follow: x.Follow,
+	})
+	if err != nil {
+		return err
+	}
+
+	for log := range logs {
+		fmt.Fprintln(Stdout, log)
+	}
+
+	return nil
+}
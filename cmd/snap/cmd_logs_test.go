@@ -0,0 +1,68 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestLogs(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/logs")
+			c.Check(r.URL.Query().Get("names"), Equals, "foo.web")
+			c.Check(r.URL.Query().Get("n"), Equals, "all")
+			c.Check(r.URL.Query().Get("follow"), Equals, "true")
+			w.Header().Set("Content-Type", "application/json-seq")
+			fmt.Fprint(w, "\x1e{\"timestamp\":\"1970-01-01T00:00:00.000042Z\",\"sid\":\"foo.web\",\"pid\":\"7\",\"message\":\"hello\"}\n")
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := Parser().ParseArgs([]string{"logs", "-n=all", "-f", "foo.web"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "1970-01-01T00:00:00.000042Z foo.web[7]: hello\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestLogsDefaultLines(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query().Get("n"), Equals, "10")
+		c.Check(r.URL.Query().Get("follow"), Equals, "")
+	})
+	_, err := Parser().ParseArgs([]string{"logs"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "")
+}
+
+func (s *SnapSuite) TestLogsBadN(c *C) {
+	_, err := Parser().ParseArgs([]string{"logs", "-n=10abc"})
+	c.Check(err, ErrorMatches, `invalid argument for flag -n: expected a non-negative integer or 'all', not "10abc"`)
+}
@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type svcPositionals struct {
+	Positional struct {
+		ServiceNames []string `positional-arg-name:"<service>"`
+	} `positional-args:"yes"`
+}
+
+type cmdServices struct {
+	svcPositionals
+}
+
+type cmdStart struct {
+	svcPositionals
+	Enable bool `long:"enable" description:"also enable the services so they start on boot"`
+}
+
+type cmdStop struct {
+	svcPositionals
+	Disable bool `long:"disable" description:"also disable the services so they no longer start on boot"`
+}
+
+type cmdRestart struct {
+	svcPositionals
+}
+
+var shortServicesHelp = i18n.G("List the services of installed snaps")
+var longServicesHelp = i18n.G(`
+The services command lists the services of installed snaps, whether they are
+enabled to start on boot and whether they are currently running.
+
+Services are given as <snap> for all the services of a snap, or as
+<snap>.<app> for a single service. Without arguments all services are listed.
+`)
+
+var shortStartHelp = i18n.G("Start services")
+var longStartHelp = i18n.G(`
+The start command starts the given services of installed snaps, given as
+<snap> or <snap>.<app>. With --enable the services are also enabled so they
+start on boot.
+`)
+
+var shortStopHelp = i18n.G("Stop services")
+var longStopHelp = i18n.G(`
+The stop command stops the given services of installed snaps, given as
+<snap> or <snap>.<app>. With --disable the services are also disabled so they
+no longer start on boot.
+`)
+
+var shortRestartHelp = i18n.G("Restart services")
+var longRestartHelp = i18n.G(`
+The restart command restarts the given services of installed snaps, given as
+<snap> or <snap>.<app>.
+`)
+
+func init() {
+	addCommand("services", shortServicesHelp, longServicesHelp, func() flags.Commander { return &cmdServices{} })
+	addCommand("start", shortStartHelp, longStartHelp, func() flags.Commander { return &cmdStart{} })
+	addCommand("stop", shortStopHelp, longStopHelp, func() flags.Commander { return &cmdStop{} })
+	addCommand("restart", shortRestartHelp, longRestartHelp, func() flags.Commander { return &cmdRestart{} })
+}
+
+func (x *cmdServices) Execute([]string) error {
+	services, err := Client().Apps(x.Positional.ServiceNames, client.AppOptions{Service: true})
+	if err != nil {
+		return err
+	}
+	if len(services) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("There are no services."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Service\tStartup\tCurrent"))
+
+	for _, svc := range services {
+		startup := i18n.G("disabled")
+		if svc.Enabled {
+			startup = i18n.G("enabled")
+		}
+		current := i18n.G("inactive")
+		if svc.Active {
+			current = i18n.G("active")
+		}
+		fmt.Fprintf(w, "%s.%s\t%s\t%s\n", svc.Snap, svc.Name, startup, current)
+	}
+
+	return nil
+}
+
+func (x *cmdStart) Execute([]string) error {
+	cli := Client()
+	id, err := cli.Start(x.Positional.ServiceNames, client.StartOptions{Enable: x.Enable})
+	if err != nil {
+		return err
+	}
+	_, err = wait(cli, id)
+	return err
+}
+
+func (x *cmdStop) Execute([]string) error {
+	cli := Client()
+	id, err := cli.Stop(x.Positional.ServiceNames, client.StopOptions{Disable: x.Disable})
+	if err != nil {
+		return err
+	}
+	_, err = wait(cli, id)
+	return err
+}
+
+func (x *cmdRestart) Execute([]string) error {
+	cli := Client()
+	id, err := cli.Restart(x.Positional.ServiceNames)
+	if err != nil {
+		return err
+	}
+	_, err = wait(cli, id)
+	return err
+}
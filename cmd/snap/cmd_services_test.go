@@ -0,0 +1,92 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestServices(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/apps")
+		c.Check(r.URL.Query().Get("names"), Equals, "foo")
+		c.Check(r.URL.Query().Get("select"), Equals, "service")
+		fmt.Fprintln(w, `{"type": "sync", "result": [
+{"snap": "foo", "name": "web", "daemon": "simple", "enabled": true, "active": true},
+{"snap": "foo", "name": "worker", "daemon": "forking"}]}`)
+	})
+	rest, err := Parser().ParseArgs([]string{"services", "foo"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, `Service     Startup   Current
+foo.web     enabled   active
+foo.worker  disabled  inactive
+`)
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestServicesNone(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := Parser().ParseArgs([]string{"services"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "There are no services.\n")
+}
+
+func (s *SnapSuite) TestServiceActions(c *C) {
+	for _, t := range []struct {
+		args     []string
+		expected map[string]interface{}
+	}{
+		{[]string{"start", "--enable", "foo"},
+			map[string]interface{}{"action": "start", "names": []interface{}{"foo"}, "enable": true}},
+		{[]string{"stop", "foo.web", "bar"},
+			map[string]interface{}{"action": "stop", "names": []interface{}{"foo.web", "bar"}}},
+		{[]string{"stop", "--disable", "foo.web"},
+			map[string]interface{}{"action": "stop", "names": []interface{}{"foo.web"}, "disable": true}},
+		{[]string{"restart", "foo"},
+			map[string]interface{}{"action": "restart", "names": []interface{}{"foo"}}},
+	} {
+		s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/apps":
+				c.Check(r.Method, Equals, "POST")
+				c.Check(DecodedRequestBody(c, r), DeepEquals, t.expected)
+				fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "zzz"}`)
+			case "/v2/changes/zzz":
+				c.Check(r.Method, Equals, "GET")
+				fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+			default:
+				c.Fatalf("unexpected path %q", r.URL.Path)
+			}
+		})
+		rest, err := Parser().ParseArgs(t.args)
+		c.Assert(err, IsNil)
+		c.Assert(rest, DeepEquals, []string{})
+	}
+}
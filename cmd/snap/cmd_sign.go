@@ -0,0 +1,159 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdSign struct {
+	KeyName string `short:"k" default:"default" description:"name of the key to use, otherwise use the default key"`
+}
+
+var shortSignHelp = i18n.G("Signs an assertion")
+var longSignHelp = i18n.G(`
+The sign command reads the headers of an assertion as a JSON or YAML
+mapping from standard input and writes the assertion signed with the
+named key pair from the GnuPG keyring of the user to standard output.
+
+The "type" header selects the assertion type and an optional "body"
+header provides the assertion body. Header values must be strings,
+integers, booleans or lists and mappings of those.
+`)
+
+func init() {
+	addCommand("sign", shortSignHelp, longSignHelp, func() flags.Commander {
+		return &cmdSign{}
+	})
+}
+
+func normalizeHeaderValue(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case int:
+		return strconv.Itoa(x), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case []interface{}:
+		l := make([]interface{}, len(x))
+		for i, elem := range x {
+			normElem, err := normalizeHeaderValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = normElem
+		}
+		return l, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, elem := range x {
+			name, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("header entry keys must be strings: %v", k)
+			}
+			normElem, err := normalizeHeaderValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = normElem
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("header values must be strings, integers, booleans or lists and mappings of those: %v", v)
+	}
+}
+
+func parseSignHeaders(input []byte) (map[string]interface{}, error) {
+	// JSON is accepted through YAML
+	var raw map[string]interface{}
+	err := yaml.Unmarshal(input, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the assertion headers: %v", err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("cannot sign an assertion without headers")
+	}
+	headers := make(map[string]interface{}, len(raw))
+	for name, v := range raw {
+		normValue, err := normalizeHeaderValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q header: %v", name, err)
+		}
+		headers[name] = normValue
+	}
+	return headers, nil
+}
+
+func (x *cmdSign) Execute(args []string) error {
+	input, err := ioutil.ReadAll(Stdin)
+	if err != nil {
+		return fmt.Errorf("cannot read the assertion headers: %v", err)
+	}
+	headers, err := parseSignHeaders(input)
+	if err != nil {
+		return err
+	}
+
+	typeName, ok := headers["type"].(string)
+	if !ok {
+		return fmt.Errorf(`the assertion headers must have a "type" string header`)
+	}
+	assertType := asserts.Type(typeName)
+	if assertType == nil {
+		return fmt.Errorf("invalid assertion type: %q", typeName)
+	}
+
+	var body []byte
+	if bodyValue, ok := headers["body"]; ok {
+		bodyStr, ok := bodyValue.(string)
+		if !ok {
+			return fmt.Errorf(`the "body" header must be a string`)
+		}
+		body = []byte(bodyStr)
+		delete(headers, "body")
+	}
+
+	manager := asserts.NewGPGKeypairManager("")
+	privKey, err := manager.GetByName(x.KeyName)
+	if err != nil {
+		return err
+	}
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: manager,
+	})
+	if err != nil {
+		return err
+	}
+	a, err := db.Sign(assertType, headers, body, privKey.PublicKey().ID())
+	if err != nil {
+		return err
+	}
+	return asserts.NewEncoder(Stdout).Encode(a)
+}
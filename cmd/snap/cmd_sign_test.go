@@ -0,0 +1,139 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapKeysSuite) TestSignJSON(c *C) {
+	s.stdin.WriteString(`{
+  "type": "snap-build",
+  "authority-id": "dev1-id",
+  "series": "16",
+  "snap-id": "snap-id-1",
+  "snap-digest": "sha512-...",
+  "grade": "devel",
+  "snap-size": 1025,
+  "timestamp": "2016-09-01T12:00:00Z"
+}`)
+
+	rest, err := snap.Parser().ParseArgs([]string{"sign"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	a, err := asserts.Decode(s.stdout.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(a.Type(), Equals, asserts.SnapBuildType)
+	snapBuild := a.(*asserts.SnapBuild)
+	c.Check(snapBuild.AuthorityID(), Equals, "dev1-id")
+	c.Check(snapBuild.SnapID(), Equals, "snap-id-1")
+	c.Check(snapBuild.SnapSize(), Equals, uint64(1025))
+}
+
+func (s *SnapKeysSuite) TestSignYAMLWithBodyAndNestedHeaders(c *C) {
+	s.stdin.WriteString(`type: snap-declaration
+authority-id: canonical
+revision: 2
+series: "16"
+snap-id: snap-id-1
+snap-name: foo
+publisher-id: dev1-id
+gates:
+  - snap-id-2
+  - snap-id-3
+timestamp: 2016-09-01T12:00:00Z
+body: |
+  some body
+`)
+
+	rest, err := snap.Parser().ParseArgs([]string{"sign", "-k", "default"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	a, err := asserts.Decode(s.stdout.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(a.Type(), Equals, asserts.SnapDeclarationType)
+	c.Check(a.Revision(), Equals, 2)
+	c.Check(a.Header("gates"), DeepEquals, []interface{}{"snap-id-2", "snap-id-3"})
+	c.Check(string(a.Body()), Equals, "some body\n")
+}
+
+func (s *SnapKeysSuite) TestSignVerifiableByKey(c *C) {
+	// sign an account-key-request for the key itself, this can be
+	// verified using only the public key it carries
+	_, err := snap.Parser().ParseArgs([]string{"export-key", "--account=dev1-id"})
+	c.Assert(err, IsNil)
+	a, err := asserts.Decode(s.stdout.Bytes())
+	c.Assert(err, IsNil)
+	exported := a.(*asserts.AccountKeyRequest)
+	s.stdout.Reset()
+
+	s.stdin.WriteString(`type: account-key-request
+authority-id: dev1-id
+account-id: dev1-id
+public-key-id: ` + exported.PublicKeyID() + `
+public-key-fingerprint: ` + exported.PublicKeyFingerprint() + `
+since: 2016-09-01T12:00:00Z
+body: |-
+  ` + strings.Replace(string(exported.Body()), "\n", "\n  ", -1) + `
+`)
+	_, err = snap.Parser().ParseArgs([]string{"sign"})
+	c.Assert(err, IsNil)
+
+	a, err = asserts.Decode(s.stdout.Bytes())
+	c.Assert(err, IsNil)
+	accKeyRequest := a.(*asserts.AccountKeyRequest)
+	c.Check(accKeyRequest.Body(), DeepEquals, exported.Body())
+	c.Check(accKeyRequest.Verify(), IsNil)
+}
+
+func (s *SnapKeysSuite) TestSignErrors(c *C) {
+	tests := []struct {
+		input, expectedErr string
+	}{
+		{``, `cannot sign an assertion without headers`},
+		{`[1, 2]`, `(?s)cannot parse the assertion headers: .*`},
+		{`{"authority-id": "dev1-id"}`, `the assertion headers must have a "type" string header`},
+		{`{"type": "foo"}`, `invalid assertion type: "foo"`},
+		{`{"type": "account", "body": [1]}`, `the "body" header must be a string`},
+		{`{"type": "account", "foo": 1.5}`, `invalid "foo" header: header values must be strings, integers, booleans or lists and mappings of those: 1.5`},
+		{"type: account\nfoo:\n  1: x\n", `invalid "foo" header: header entry keys must be strings: 1`},
+		{`{"type": "account", "authority-id": "canonical"}`, `"account-id" header is mandatory`},
+	}
+
+	for _, test := range tests {
+		s.stdin.Reset()
+		s.stdin.WriteString(test.input)
+		_, err := snap.Parser().ParseArgs([]string{"sign"})
+		c.Check(err, ErrorMatches, test.expectedErr, Commentf("input: %s", test.input))
+	}
+}
+
+func (s *SnapKeysSuite) TestSignKeyNotFound(c *C) {
+	s.stdin.WriteString(`{"type": "account", "authority-id": "canonical"}`)
+	_, err := snap.Parser().ParseArgs([]string{"sign", "-k", "missing"})
+	c.Check(err, ErrorMatches, `cannot find key named "missing" in GPG keyring`)
+}
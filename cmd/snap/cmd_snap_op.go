@@ -126,6 +126,10 @@ var (
 
 var longInstallHelp = i18n.G(`
 The install command installs the named snap in the system.
+
+A snap file is only installed if the assertions verifying it were
+acknowledged beforehand with 'snap ack', unless --dangerous (or --devmode)
+is given.
 `)
 
 var longRemoveHelp = i18n.G(`
@@ -137,6 +141,10 @@ will change before 16.04 is final.
 
 var longRefreshHelp = i18n.G(`
 The refresh command refreshes (updates) the named snap.
+
+While apps of the snap are running the refresh is postponed until they
+exit, unless --kill-apps is given. A refresh is postponed for at most 14
+days.
 `)
 
 var longTryHelp = i18n.G(`
@@ -203,6 +211,7 @@ type cmdInstall struct {
 	channelMixin
 
 	DevMode    bool `long:"devmode" description:"Install the snap with non-enforcing security"`
+	Dangerous  bool `long:"dangerous" description:"Install the given snap file even if there are no pre-acknowledged assertions for it"`
 	Positional struct {
 		Snap string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes" required:"yes"`
@@ -219,7 +228,7 @@ func (x *cmdInstall) Execute([]string) error {
 
 	cli := Client()
 	name := x.Positional.Snap
-	opts := &client.SnapOptions{Channel: x.Channel, DevMode: x.DevMode}
+	opts := &client.SnapOptions{Channel: x.Channel, DevMode: x.DevMode, Dangerous: x.Dangerous}
 	if strings.Contains(name, "/") || strings.HasSuffix(name, ".snap") || strings.Contains(name, ".snap.") {
 		installFromFile = true
 		changeID, err = cli.InstallPath(name, opts)
@@ -252,12 +261,13 @@ type cmdRefresh struct {
 	channelMixin
 
 	List       bool `long:"list" description:"show available snaps for refresh"`
+	KillApps   bool `long:"kill-apps" description:"kill running apps of the snap instead of waiting for them to exit"`
 	Positional struct {
 		Snap string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
 }
 
-func refreshAll() error {
+func refreshAll(killApps bool) error {
 	// FIXME: move this to snapd instead and have a new refresh-all endpoint
 	cli := Client()
 	updates, _, err := cli.Find(&client.FindOptions{Refresh: true})
@@ -272,7 +282,7 @@ func refreshAll() error {
 
 	names := make([]string, len(updates))
 	for i, update := range updates {
-		changeID, err := cli.Refresh(update.Name, &client.SnapOptions{Channel: update.Channel})
+		changeID, err := cli.Refresh(update.Name, &client.SnapOptions{Channel: update.Channel, KillApps: killApps})
 		if err != nil {
 			return err
 		}
@@ -285,9 +295,9 @@ func refreshAll() error {
 	return listSnaps(names)
 }
 
-func refreshOne(name, channel string) error {
+func refreshOne(name, channel string, killApps bool) error {
 	cli := Client()
-	changeID, err := cli.Refresh(name, &client.SnapOptions{Channel: channel})
+	changeID, err := cli.Refresh(name, &client.SnapOptions{Channel: channel, KillApps: killApps})
 	if err != nil {
 		return err
 	}
@@ -338,9 +348,9 @@ func (x *cmdRefresh) Execute([]string) error {
 		return listRefresh()
 	}
 	if x.Positional.Snap == "" {
-		return refreshAll()
+		return refreshAll(x.KillApps)
 	}
-	return refreshOne(x.Positional.Snap, x.Channel)
+	return refreshOne(x.Positional.Snap, x.Channel, x.KillApps)
 }
 
 type cmdTry struct {
@@ -66,3 +66,11 @@ func MockUserCurrent(f func() (*user.User, error)) (restore func()) {
 		userCurrent = userCurrentOrig
 	}
 }
+
+func MockReadPassword(f func(int) ([]byte, error)) (restore func()) {
+	readPasswordOrig := readPassword
+	readPassword = f
+	return func() {
+		readPassword = readPasswordOrig
+	}
+}
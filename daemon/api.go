@@ -30,6 +30,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -39,16 +40,19 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/denials"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/systemd"
 )
 
 var api = []*Command{
@@ -60,9 +64,14 @@ var api = []*Command{
 	findCmd,
 	snapsCmd,
 	snapCmd,
+	snapDenialsCmd,
 	//FIXME: renenable config for GA
 	//snapConfigCmd,
 	interfacesCmd,
+	interfacesCheckCmd,
+	connectionsCmd,
+	appsCmd,
+	logsCmd,
 	assertsCmd,
 	assertsFindManyCmd,
 	eventsCmd,
@@ -120,6 +129,11 @@ var (
 		GET:    getSnapInfo,
 		POST:   postSnap,
 	}
+	snapDenialsCmd = &Command{
+		Path: "/v2/snaps/{name}/denials",
+		GET:  getSnapDenials,
+	}
+
 	//FIXME: renenable config for GA
 	/*
 		snapConfigCmd = &Command{
@@ -136,6 +150,32 @@ var (
 		POST:   changeInterfaces,
 	}
 
+	interfacesCheckCmd = &Command{
+		Path:   "/v2/interfaces/check",
+		UserOK: true,
+		GET:    checkInterfaces,
+		POST:   repairInterfaces,
+	}
+
+	connectionsCmd = &Command{
+		Path:   "/v2/connections",
+		UserOK: true,
+		GET:    getConnections,
+	}
+
+	appsCmd = &Command{
+		Path:   "/v2/apps",
+		UserOK: true,
+		GET:    getAppsInfo,
+		POST:   postApps,
+	}
+
+	logsCmd = &Command{
+		Path:     "/v2/logs",
+		SudoerOK: true,
+		GET:      getLogs,
+	}
+
 	// TODO: allow to post assertions for UserOK? they are verified anyway
 	assertsCmd = &Command{
 		Path: "/v2/assertions",
@@ -324,6 +364,32 @@ func getSnapInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(result, nil)
 }
 
+var denialsForSnap = denials.ForSnap
+
+// getSnapDenials returns the confinement violations of a snap found in the
+// kernel log, with the interfaces that would allow them.
+func getSnapDenials(c *Command, r *http.Request, user *auth.UserState) Response {
+	name := muxVars(r)["name"]
+
+	st := c.d.overlord.State()
+	st.Lock()
+	var snapst snapstate.SnapState
+	err := snapstateGet(st, name, &snapst)
+	st.Unlock()
+	if err == state.ErrNoState {
+		return NotFound("cannot find snap %q", name)
+	}
+	if err != nil {
+		return InternalError("%v", err)
+	}
+
+	result, err := denialsForSnap(name)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	return SyncResponse(result, nil)
+}
+
 func webify(result map[string]interface{}, resource string) map[string]interface{} {
 	result["resource"] = resource
 
@@ -536,6 +602,9 @@ type snapInstruction struct {
 	Action  string `json:"action"`
 	Channel string `json:"channel"`
 	DevMode bool   `json:"devmode"`
+	// KillApps kills the running apps of a snap being refreshed instead
+	// of postponing the refresh until they exit
+	KillApps bool `json:"kill-apps"`
 	// dropping support temporarely until flag confusion is sorted,
 	// this isn't supported by client atm anyway
 	LeaveOld bool         `json:"temp-dropped-leave-old"`
@@ -621,6 +690,9 @@ func snapInstall(inst *snapInstruction, st *state.State) (string, []*state.TaskS
 
 func snapUpdate(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
 	flags := snapstate.Flags(0)
+	if inst.KillApps {
+		flags |= snapstate.KillApps
+	}
 
 	ts, err := snapstateUpdate(st, inst.snap, inst.Channel, inst.userID, flags)
 	if err != nil {
@@ -699,6 +771,17 @@ func postSnap(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 
 	msg, tsets, err := impl(&inst, state)
+	if perr, ok := err.(*snapstate.RefreshPostponedError); ok {
+		return &resp{
+			Type: ResponseTypeError,
+			Result: &errorResult{
+				Message: perr.Error(),
+				Kind:    errorKindRefreshPostponed,
+				Value:   map[string]interface{}{"apps": perr.Apps},
+			},
+			Status: http.StatusConflict,
+		}
+	}
 	if err != nil {
 		return InternalError("cannot %s %q: %v", inst.Action, inst.snap, err)
 	}
@@ -785,6 +868,9 @@ func sideloadSnap(c *Command, r *http.Request, user *auth.UserState) Response {
 	if release.ReleaseInfo.ForceDevMode() {
 		flags |= snapstate.DevMode
 	}
+	if len(form.Value["dangerous"]) > 0 && form.Value["dangerous"][0] == "true" {
+		flags |= snapstate.Dangerous
+	}
 
 	if len(form.Value["action"]) > 0 && form.Value["action"][0] == "try" {
 		if len(form.Value["snap-path"]) == 0 {
@@ -920,6 +1006,76 @@ func getInterfaces(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(repo.Interfaces(), nil)
 }
 
+// connectionJSON aids in marshaling a connection into JSON.
+type connectionJSON struct {
+	Plug      interfaces.PlugRef `json:"plug"`
+	Slot      interfaces.SlotRef `json:"slot"`
+	Interface string             `json:"interface"`
+	Manual    bool               `json:"manual,omitempty"`
+}
+
+// connectionsJSON aids in marshaling the connections of a snap into JSON.
+type connectionsJSON struct {
+	Established []connectionJSON   `json:"established"`
+	Plugs       []*interfaces.Plug `json:"plugs"`
+	Slots       []*interfaces.Slot `json:"slots"`
+}
+
+// getConnections returns the connections, plugs and slots of all snaps or,
+// with the "snap" parameter, of one snap.
+func getConnections(c *Command, r *http.Request, user *auth.UserState) Response {
+	snapName := r.URL.Query().Get("snap")
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if snapName != "" {
+		var snapst snapstate.SnapState
+		if err := snapstate.Get(st, snapName, &snapst); err == state.ErrNoState {
+			return NotFound("cannot find snap %q", snapName)
+		} else if err != nil {
+			return InternalError("%v", err)
+		}
+	}
+	connStates, err := ifacestate.ConnectionStates(st)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+
+	repo := c.d.overlord.InterfaceManager().Repository()
+	result := connectionsJSON{
+		Established: []connectionJSON{},
+		Plugs:       []*interfaces.Plug{},
+		Slots:       []*interfaces.Slot{},
+	}
+	for _, conn := range repo.Connections() {
+		if snapName != "" && conn.PlugRef.Snap != snapName && conn.SlotRef.Snap != snapName {
+			continue
+		}
+		plug := repo.Plug(conn.PlugRef.Snap, conn.PlugRef.Name)
+		connState, ok := connStates[conn.ID()]
+		result.Established = append(result.Established, connectionJSON{
+			Plug:      conn.PlugRef,
+			Slot:      conn.SlotRef,
+			Interface: plug.Interface,
+			Manual:    ok && !connState.Auto,
+		})
+	}
+	ifaces := repo.Interfaces()
+	for _, plug := range ifaces.Plugs {
+		if snapName == "" || plug.Snap.Name() == snapName {
+			result.Plugs = append(result.Plugs, plug)
+		}
+	}
+	for _, slot := range ifaces.Slots {
+		if snapName == "" || slot.Snap.Name() == snapName {
+			result.Slots = append(result.Slots, slot)
+		}
+	}
+	return SyncResponse(result, nil)
+}
+
 // plugJSON aids in marshaling Plug into JSON.
 type plugJSON struct {
 	Snap        string                 `json:"snap"`
@@ -988,15 +1144,35 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 		summary = fmt.Sprintf("Connect %s:%s to %s:%s", a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
 		taskset, err = ifacestate.Connect(state, a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
 	case "disconnect":
-		summary = fmt.Sprintf("Disconnect %s:%s from %s:%s", a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
+		// A lone <snap>:<name> is sent as a slot since the client cannot
+		// tell plugs and slots apart, resolve it to a plug when needed.
+		if a.Plugs[0].Snap == "" && a.Plugs[0].Name == "" && a.Slots[0].Name != "" {
+			repo := c.d.overlord.InterfaceManager().Repository()
+			snapName, name := a.Slots[0].Snap, a.Slots[0].Name
+			if repo.Slot(snapName, name) == nil && repo.Plug(snapName, name) != nil {
+				a.Plugs[0] = plugJSON{Snap: snapName, Name: name}
+				a.Slots[0] = slotJSON{}
+			}
+		}
 		taskset, err = ifacestate.Disconnect(state, a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
+		if err == nil {
+			// Either side may be empty, the task knows how to describe it.
+			summary = taskset.Tasks()[0].Summary()
+		}
 	}
 	if err != nil {
 		return BadRequest("%v", err)
 	}
 
+	var snapNames []string
+	for _, snapName := range []string{a.Plugs[0].Snap, a.Slots[0].Snap} {
+		if snapName != "" {
+			snapNames = append(snapNames, snapName)
+		}
+	}
+
 	change := state.NewChange(a.Action+"-snap", summary)
-	change.Set("snap-names", []string{a.Plugs[0].Snap, a.Slots[0].Snap})
+	change.Set("snap-names", snapNames)
 	change.AddAll(taskset)
 
 	state.EnsureBefore(0)
@@ -1004,20 +1180,276 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 	return AsyncResponse(nil, &Meta{Change: change.ID()})
 }
 
-func doAssert(c *Command, r *http.Request, user *auth.UserState) Response {
-	b, err := ioutil.ReadAll(r.Body)
+// appInfoJSON describes an app and, for services, their current state.
+type appInfoJSON struct {
+	Snap    string `json:"snap"`
+	Name    string `json:"name"`
+	Daemon  string `json:"daemon,omitempty"`
+	Enabled bool   `json:"enabled,omitempty"`
+	Active  bool   `json:"active,omitempty"`
+}
+
+// splitNames splits a comma separated list of names, as found in a query.
+func splitNames(names string) []string {
+	if names == "" {
+		return nil
+	}
+	return strings.Split(names, ",")
+}
+
+// getAppsInfo lists the apps of all active snaps or, with the "names"
+// parameter, of the given snaps and <snap>.<app> pairs. With
+// "select=service" only services are listed.
+func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
+	query := r.URL.Query()
+	names := splitNames(query.Get("names"))
+	servicesOnly := false
+	switch query.Get("select") {
+	case "":
+	case "service":
+		servicesOnly = true
+	default:
+		return BadRequest("invalid select parameter: %q", query.Get("select"))
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	apps, err := servicestate.AppInfos(st, names, servicesOnly)
+	st.Unlock()
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, &progress.NullProgress{})
+	result := make([]appInfoJSON, 0, len(apps))
+	for _, app := range apps {
+		info := appInfoJSON{
+			Snap:   app.Snap.Name(),
+			Name:   app.Name,
+			Daemon: app.Daemon,
+		}
+		if app.Daemon != "" {
+			status, err := sysd.ServiceStatus(filepath.Base(app.ServiceFile()))
+			if err != nil {
+				return InternalError("cannot get status of service %q: %v", app.Name, err)
+			}
+			info.Enabled = status.UnitFileState == "enabled"
+			info.Active = status.ActiveState == "active"
+		}
+		result = append(result, info)
+	}
+
+	return SyncResponse(result, nil)
+}
+
+// appInstruction is the body of a request to act on services.
+type appInstruction struct {
+	Action  string   `json:"action"`
+	Names   []string `json:"names"`
+	Enable  bool     `json:"enable"`
+	Disable bool     `json:"disable"`
+}
+
+// postApps starts, stops, restarts, enables or disables the services
+// selected by names, or all services when no names are given.
+func postApps(c *Command, r *http.Request, user *auth.UserState) Response {
+	var inst appInstruction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&inst); err != nil {
+		return BadRequest("cannot decode request body into service operation: %v", err)
+	}
+	if inst.Action == "" {
+		return BadRequest("service action not specified")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	apps, err := servicestate.AppInfos(st, inst.Names, true)
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+	taskset, err := servicestate.Control(st, apps, &servicestate.Instruction{
+		Action:  inst.Action,
+		Enable:  inst.Enable,
+		Disable: inst.Disable,
+	})
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+
+	var summary string
+	if len(inst.Names) == 0 {
+		summary = fmt.Sprintf(i18n.G("Running %q on all services"), inst.Action)
+	} else {
+		summary = fmt.Sprintf(i18n.G("Running %q on services of %s"), inst.Action, strings.Join(inst.Names, ", "))
+	}
+	change := st.NewChange("service-control", summary)
+	change.AddAll(taskset)
+
+	st.EnsureBefore(0)
+
+	return AsyncResponse(nil, &Meta{Change: change.ID()})
+}
+
+func systemdLogReaderImpl(serviceNames []string, n int, follow bool) (io.ReadCloser, error) {
+	return systemd.New(dirs.GlobalRootDir, &progress.NullProgress{}).LogReader(serviceNames, n, follow)
+}
+
+var systemdLogReader = systemdLogReaderImpl
+
+// getLogs streams the journal entries of the services selected by the
+// "names" parameter, or of all services. "n" is the number of past entries
+// to start with, or "all", and "follow" keeps sending new entries.
+func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
+	query := r.URL.Query()
+	n := 10
+	if s := query.Get("n"); s != "" {
+		if s == "all" {
+			n = -1
+		} else {
+			m, err := strconv.Atoi(s)
+			if err != nil || m < 0 {
+				return BadRequest("invalid n parameter: %q", s)
+			}
+			n = m
+		}
+	}
+	follow := false
+	if s := query.Get("follow"); s != "" {
+		f, err := strconv.ParseBool(s)
+		if err != nil {
+			return BadRequest("invalid follow parameter: %q", s)
+		}
+		follow = f
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	apps, err := servicestate.AppInfos(st, splitNames(query.Get("names")), true)
+	st.Unlock()
 	if err != nil {
-		return BadRequest("reading assert request body gave %v", err)
+		return BadRequest("%v", err)
+	}
+	if len(apps) == 0 {
+		return NotFound("no services found")
+	}
+
+	serviceNames := make([]string, len(apps))
+	for i, app := range apps {
+		serviceNames[i] = filepath.Base(app.ServiceFile())
 	}
-	a, err := asserts.Decode(b)
+
+	reader, err := systemdLogReader(serviceNames, n, follow)
 	if err != nil {
-		return BadRequest("cannot decode request body into an assertion: %v", err)
+		return InternalError("cannot read service logs: %v", err)
 	}
+
+	return LogResponse(reader)
+}
+
+// checkInterfaces reports the drift between the interface connections
+// recorded in the state, the interface repository and the security files.
+func checkInterfaces(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	drift, err := c.d.overlord.InterfaceManager().Check()
+	if err != nil {
+		return InternalError("cannot check interfaces: %v", err)
+	}
+	if drift == nil {
+		drift = []*ifacestate.Drift{}
+	}
+	return SyncResponse(drift, nil)
+}
+
+// repairInterfaces repairs the drift reported by checkInterfaces.
+func repairInterfaces(c *Command, r *http.Request, user *auth.UserState) Response {
+	var a struct {
+		Action string `json:"action"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&a); err != nil {
+		return BadRequest("cannot decode request body into an interface check action: %v", err)
+	}
+	if a.Action != "repair" {
+		return BadRequest("unsupported interface check action: %q", a.Action)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	taskset, err := ifacestate.Repair(st)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	change := st.NewChange("repair-interfaces", i18n.G("Repair interface connections and security files"))
+	change.AddAll(taskset)
+
+	st.EnsureBefore(0)
+
+	return AsyncResponse(nil, &Meta{Change: change.ID()})
+}
+
+func doAssert(c *Command, r *http.Request, user *auth.UserState) Response {
+	// the body is a stream of assertions, which can include the
+	// prerequisites of the ones of interest
+	batch := make(map[string]asserts.Assertion)
+	var assertions []asserts.Assertion
+	dec := asserts.NewDecoder(r.Body)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return BadRequest("cannot decode request body into an assertion: %v", err)
+		}
+		batch[a.Ref().Unique()] = a
+		assertions = append(assertions, a)
+	}
+	if len(assertions) == 0 {
+		return BadRequest("cannot decode request body into an assertion: no assertion in request body")
+	}
+
+	var auther store.Authenticator
+	if user != nil {
+		auther = user.Authenticator()
+	}
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		if a := batch[ref.Unique()]; a != nil {
+			return a, nil
+		}
+		return getStore(c).Assertion(ref.Type, ref.PrimaryKey, auther)
+	}
+
 	// TODO/XXX: turn this into a Change/Task combination
-	amgr := c.d.overlord.AssertManager()
-	if err := amgr.DB().Add(a); err != nil {
-		// TODO: have a specific error to be able to return  409 for not newer revision?
-		return BadRequest("assert failed: %v", err)
+	db := c.d.overlord.AssertManager().DB()
+	save := func(a asserts.Assertion) error {
+		err := db.Add(a)
+		if revErr, ok := err.(*asserts.RevisionError); ok && revErr.Current == revErr.Used {
+			// already known, as when importing the same bundle again
+			return nil
+		}
+		return err
+	}
+	f := asserts.NewFetcher(db, retrieve, save)
+	var failed []string
+	for _, a := range assertions {
+		if err := f.Save(a); err != nil {
+			// TODO: have a specific error to be able to return  409 for not newer revision?
+			if len(assertions) == 1 {
+				return BadRequest("assert failed: %v", err)
+			}
+			failed = append(failed, fmt.Sprintf("- %s: %v", a.Ref(), err))
+		}
+	}
+	if len(failed) > 0 {
+		return BadRequest("assert failed for %d out of %d assertions:\n%s", len(failed), len(assertions), strings.Join(failed, "\n"))
 	}
 	// TODO: what more info do we want to return on success?
 	return &resp{
@@ -1034,16 +1466,28 @@ func assertsFindMany(c *Command, r *http.Request, user *auth.UserState) Response
 	}
 	headers := map[string]string{}
 	q := r.URL.Query()
+	export := false
 	for k := range q {
+		if k == "export" {
+			// not a header filter but asks for the prerequisites too
+			export = q.Get(k) == "true"
+			continue
+		}
 		headers[k] = q.Get(k)
 	}
-	amgr := c.d.overlord.AssertManager()
-	assertions, err := amgr.DB().FindMany(assertType, headers)
+	db := c.d.overlord.AssertManager().DB()
+	assertions, err := db.FindMany(assertType, headers)
 	if err == asserts.ErrNotFound {
 		return AssertResponse(nil, true)
 	} else if err != nil {
 		return InternalError("searching assertions failed: %v", err)
 	}
+	if export {
+		assertions, err = asserts.WithPrerequisites(db, assertions)
+		if err != nil {
+			return InternalError("cannot export assertions: %v", err)
+		}
+	}
 	return AssertResponse(assertions, true)
 }
 
@@ -33,13 +33,16 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/denials"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -49,6 +52,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -62,7 +66,9 @@ type apiSuite struct {
 	d                 *Daemon
 	auther            store.Authenticator
 	restoreBackends   func()
+	restoreUEvents    func()
 	refreshCandidates []*store.RefreshCandidate
+	storeAsserts      []asserts.Assertion
 }
 
 var _ = check.Suite(&apiSuite{})
@@ -97,6 +103,17 @@ func (s *apiSuite) Download(*snap.Info, progress.Meter, store.Authenticator) (st
 	panic("Download not expected to be called")
 }
 
+func (s *apiSuite) Assertion(assertType *asserts.AssertionType, primaryKey []string, auther store.Authenticator) (asserts.Assertion, error) {
+	s.auther = auther
+	ref := &asserts.Ref{Type: assertType, PrimaryKey: primaryKey}
+	for _, a := range s.storeAsserts {
+		if a.Ref().Unique() == ref.Unique() {
+			return a, nil
+		}
+	}
+	return nil, store.ErrAssertionNotFound
+}
+
 func (s *apiSuite) muxVars(*http.Request) map[string]string {
 	return s.vars
 }
@@ -124,18 +141,23 @@ func (s *apiSuite) SetUpTest(c *check.C) {
 	s.auther = nil
 	s.d = nil
 	s.refreshCandidates = nil
+	s.storeAsserts = nil
 	// Disable real security backends for all API tests
 	s.restoreBackends = ifacestate.MockSecurityBackends(nil)
+	s.restoreUEvents = mockNoUEvents()
 }
 
 func (s *apiSuite) TearDownTest(c *check.C) {
 	s.d = nil
 	s.restoreBackends()
+	s.restoreUEvents()
 	snapstateInstall = snapstate.Install
 	snapstateGet = snapstate.Get
 	snapstateInstallPath = snapstate.InstallPath
 	readSnapInfo = readSnapInfoImpl
+	denialsForSnap = denials.ForSnap
 	ensureStateSoon = ensureStateSoonImpl
+	systemdLogReader = systemdLogReaderImpl
 	dirs.SetRootDir("")
 }
 
@@ -308,6 +330,71 @@ func (s *apiSuite) TestSnapInfoIgnoresRemoteErrors(c *check.C) {
 	c.Check(rsp.Result, check.NotNil)
 }
 
+func (s *apiSuite) TestSnapDenials(c *check.C) {
+	d := s.daemon(c)
+	s.vars = map[string]string{"name": "foo"}
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	denialsForSnap = func(snapName string) ([]*denials.Denial, error) {
+		c.Check(snapName, check.Equals, "foo")
+		return []*denials.Denial{{
+			Snap: "foo", Kind: "apparmor", SecurityTag: "snap.foo.bar", Operation: "open",
+			Path: "/var/log/syslog", Mask: "r", Count: 2, Interfaces: []string{"log-observe"},
+		}}, nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/denials", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	snapDenialsCmd.GET(snapDenialsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	c.Check(body["result"], check.DeepEquals, []interface{}{
+		map[string]interface{}{
+			"snap":         "foo",
+			"kind":         "apparmor",
+			"security-tag": "snap.foo.bar",
+			"operation":    "open",
+			"path":         "/var/log/syslog",
+			"mask":         "r",
+			"count":        2.0,
+			"interfaces":   []interface{}{"log-observe"},
+		},
+	})
+}
+
+func (s *apiSuite) TestSnapDenialsNotFound(c *check.C) {
+	s.daemon(c)
+	s.vars = map[string]string{"name": "foo"}
+	denialsForSnap = func(snapName string) ([]*denials.Denial, error) {
+		c.Fatalf("unexpected call")
+		return nil, nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/denials", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getSnapDenials(snapDenialsCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, http.StatusNotFound)
+}
+
+func (s *apiSuite) TestSnapDenialsError(c *check.C) {
+	d := s.daemon(c)
+	s.vars = map[string]string{"name": "foo"}
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+	denialsForSnap = func(snapName string) ([]*denials.Denial, error) {
+		return nil, errors.New("cannot read kernel log: boom")
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/denials", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getSnapDenials(snapDenialsCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, http.StatusInternalServerError)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot read kernel log: boom")
+}
+
 func (s *apiSuite) TestListIncludesAll(c *check.C) {
 	// Very basic check to help stop us from not adding all the
 	// commands to the command list.
@@ -351,6 +438,8 @@ func (s *apiSuite) TestListIncludesAll(c *check.C) {
 		"snapstateGet",
 		"readSnapInfo",
 		"ensureStateSoon",
+		"denialsForSnap",
+		"systemdLogReader",
 	}
 	c.Check(found, check.Equals, len(api)+len(exceptions),
 		check.Commentf(`At a glance it looks like you've not added all the Commands defined in api to the api list. If that is not the case, please add the exception to the "exceptions" list in this test.`))
@@ -1075,6 +1164,32 @@ func (s *apiSuite) TestPostSnap(c *check.C) {
 	c.Check(soon, check.Equals, 1)
 }
 
+func (s *apiSuite) TestPostSnapRefreshPostponed(c *check.C) {
+	s.daemon(c)
+	s.vars = map[string]string{"name": "foo"}
+
+	snapInstructionDispTable["refresh"] = func(*snapInstruction, *state.State) (string, []*state.TaskSet, error) {
+		return "", nil, &snapstate.RefreshPostponedError{Snap: "foo", Apps: []string{"kiosk"}}
+	}
+	defer func() {
+		snapInstructionDispTable["refresh"] = snapUpdate
+	}()
+
+	buf := bytes.NewBufferString(`{"action": "refresh"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, http.StatusConflict)
+	c.Check(rsp.Result, check.DeepEquals, &errorResult{
+		Message: `refresh of snap "foo" postponed until its apps exit: kiosk`,
+		Kind:    errorKindRefreshPostponed,
+		Value:   map[string]interface{}{"apps": []string{"kiosk"}},
+	})
+}
+
 func (s *apiSuite) TestPostSnapSetsUser(c *check.C) {
 	d := s.daemon(c)
 	ensureStateSoon = func(st *state.State) {}
@@ -1182,6 +1297,24 @@ func (s *apiSuite) TestSideloadSnapDevMode(c *check.C) {
 	c.Check(chgSummary, check.Equals, `Install "local" snap from file "x"`)
 }
 
+func (s *apiSuite) TestSideloadSnapDangerous(c *check.C) {
+	body := "" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"x\"\r\n" +
+		"\r\n" +
+		"xyzzy\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"dangerous\"\r\n" +
+		"\r\n" +
+		"true\r\n" +
+		"----hello--\r\n"
+	head := map[string]string{"Content-Type": "multipart/thing; boundary=--hello--"}
+	restore := release.MockReleaseInfo(&release.OS{ID: "ubuntu"})
+	defer restore()
+	chgSummary := s.sideloadCheck(c, body, head, snapstate.Dangerous, false)
+	c.Check(chgSummary, check.Equals, `Install "local" snap from file "x"`)
+}
+
 func (s *apiSuite) TestSideloadSnapNotValidFormFile(c *check.C) {
 	newTestDaemon(c)
 
@@ -1553,6 +1686,35 @@ func (s *apiSuite) TestRefresh(c *check.C) {
 	c.Check(summary, check.Equals, `Refresh "some-snap" snap`)
 }
 
+func (s *apiSuite) TestRefreshKillApps(c *check.C) {
+	var calledFlags snapstate.Flags
+
+	snapstateGet = func(s *state.State, name string, snapst *snapstate.SnapState) error {
+		return nil
+	}
+	snapstateUpdate = func(s *state.State, name, channel string, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		calledFlags = flags
+
+		t := s.NewTask("fake-refresh-snap", "Doing a fake install")
+		return state.NewTaskSet(t), nil
+	}
+
+	d := s.daemon(c)
+	inst := &snapInstruction{
+		Action:   "refresh",
+		KillApps: true,
+		snap:     "some-snap",
+	}
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	_, _, err := inst.dispatch()(inst, st)
+	c.Check(err, check.IsNil)
+
+	c.Check(calledFlags, check.Equals, snapstate.Flags(snapstate.KillApps))
+}
+
 func (s *apiSuite) TestInstallMissingUbuntuCore(c *check.C) {
 	installQueue := []*state.Task{}
 
@@ -1794,6 +1956,443 @@ func (s *apiSuite) TestInterfaces(c *check.C) {
 	})
 }
 
+// Tests for GET /v2/connections
+
+func (s *apiSuite) TestConnections(c *check.C) {
+	d := s.daemon(c)
+
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	repo := d.overlord.InterfaceManager().Repository()
+	repo.Connect("consumer", "plug", "producer", "slot")
+	st := d.overlord.State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test"},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/connections?snap=consumer", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	connectionsCmd.GET(connectionsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	c.Check(body["result"], check.DeepEquals, map[string]interface{}{
+		"established": []interface{}{
+			map[string]interface{}{
+				"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+				"slot":      map[string]interface{}{"snap": "producer", "slot": "slot"},
+				"interface": "test",
+				"manual":    true,
+			},
+		},
+		"plugs": []interface{}{
+			map[string]interface{}{
+				"snap":      "consumer",
+				"plug":      "plug",
+				"interface": "test",
+				"attrs":     map[string]interface{}{"key": "value"},
+				"apps":      []interface{}{"app"},
+				"label":     "label",
+				"connections": []interface{}{
+					map[string]interface{}{"snap": "producer", "slot": "slot"},
+				},
+			},
+		},
+		"slots": []interface{}{},
+	})
+}
+
+func (s *apiSuite) TestConnectionsAuto(c *check.C) {
+	d := s.daemon(c)
+
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	repo := d.overlord.InterfaceManager().Repository()
+	repo.Connect("consumer", "plug", "producer", "slot")
+	st := d.overlord.State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/connections?snap=producer", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	connectionsCmd.GET(connectionsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	result := body["result"].(map[string]interface{})
+	c.Check(result["established"], check.DeepEquals, []interface{}{
+		map[string]interface{}{
+			"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+			"slot":      map[string]interface{}{"snap": "producer", "slot": "slot"},
+			"interface": "test",
+		},
+	})
+	c.Check(result["plugs"], check.HasLen, 0)
+	c.Check(result["slots"], check.HasLen, 1)
+}
+
+func (s *apiSuite) TestConnectionsUnknownSnap(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/connections?snap=unknown", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	connectionsCmd.GET(connectionsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 404)
+}
+
+// Test for plug-side bulk disconnect through POST /v2/interfaces, the plug is
+// sent as a slot like "snap disconnect <snap>:<plug>" does.
+
+func (s *apiSuite) TestDisconnectEverythingFromPlug(c *check.C) {
+	d := s.daemon(c)
+
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	repo := d.overlord.InterfaceManager().Repository()
+	repo.Connect("consumer", "plug", "producer", "slot")
+
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	action := &interfaceAction{
+		Action: "disconnect",
+		Plugs:  []plugJSON{{}},
+		Slots:  []slotJSON{{Snap: "consumer", Name: "plug"}},
+	}
+	text, err := json.Marshal(action)
+	c.Assert(err, check.IsNil)
+	buf := bytes.NewBuffer(text)
+	req, err := http.NewRequest("POST", "/v2/interfaces", buf)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	interfacesCmd.POST(interfacesCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 202)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	id := body["change"].(string)
+
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.Change(id)
+	st.Unlock()
+	c.Assert(chg, check.NotNil)
+
+	<-chg.Ready()
+
+	st.Lock()
+	err = chg.Err()
+	summary := chg.Summary()
+	var snapNames []string
+	chg.Get("snap-names", &snapNames)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(summary, check.Equals, "Disconnect consumer:plug from all slots")
+	c.Check(snapNames, check.DeepEquals, []string{"consumer"})
+
+	c.Check(repo.Plug("consumer", "plug").Connections, check.HasLen, 0)
+	c.Check(repo.Slot("producer", "slot").Connections, check.HasLen, 0)
+}
+
+// Tests for GET and POST /v2/interfaces/check
+
+func (s *apiSuite) TestCheckInterfaces(c *check.C) {
+	d := s.daemon(c)
+
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	// The connection is not recorded in the state.
+	repo := d.overlord.InterfaceManager().Repository()
+	repo.Connect("consumer", "plug", "producer", "slot")
+
+	req, err := http.NewRequest("GET", "/v2/interfaces/check", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	interfacesCheckCmd.GET(interfacesCheckCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	c.Check(body["result"], check.DeepEquals, []interface{}{
+		map[string]interface{}{
+			"kind":   "unexpected-connection",
+			"detail": "consumer:plug producer:slot",
+		},
+	})
+}
+
+func (s *apiSuite) TestCheckInterfacesNoDrift(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/interfaces/check", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	interfacesCheckCmd.GET(interfacesCheckCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	c.Check(body["result"], check.DeepEquals, []interface{}{})
+}
+
+func (s *apiSuite) TestRepairInterfaces(c *check.C) {
+	d := s.daemon(c)
+
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	repo := d.overlord.InterfaceManager().Repository()
+	repo.Connect("consumer", "plug", "producer", "slot")
+
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	buf := bytes.NewBufferString(`{"action": "repair"}`)
+	req, err := http.NewRequest("POST", "/v2/interfaces/check", buf)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	interfacesCheckCmd.POST(interfacesCheckCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 202)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	id := body["change"].(string)
+
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.Change(id)
+	st.Unlock()
+	c.Assert(chg, check.NotNil)
+
+	<-chg.Ready()
+
+	st.Lock()
+	err = chg.Err()
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+
+	// The repository follows the state again.
+	c.Check(repo.Plug("consumer", "plug").Connections, check.HasLen, 0)
+}
+
+func (s *apiSuite) TestRepairInterfacesBadAction(c *check.C) {
+	s.daemon(c)
+
+	buf := bytes.NewBufferString(`{"action": "frobnicate"}`)
+	req, err := http.NewRequest("POST", "/v2/interfaces/check", buf)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	interfacesCheckCmd.POST(interfacesCheckCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 400)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	c.Check(body["result"], check.DeepEquals, map[string]interface{}{
+		"message": `unsupported interface check action: "frobnicate"`,
+	})
+}
+
+const servicesYaml = `apps:
+ cli:
+  command: bin/cli
+ web:
+  command: bin/web
+  daemon: simple
+`
+
+func (s *apiSuite) TestAppsInfo(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, servicesYaml)
+
+	oldSystemctl := systemd.SystemctlCmd
+	defer func() { systemd.SystemctlCmd = oldSystemctl }()
+	systemd.SystemctlCmd = func(args ...string) ([]byte, error) {
+		c.Check(args, check.DeepEquals, []string{"show", "--property=Id,LoadState,ActiveState,SubState,UnitFileState", "snap.foo.web.service"})
+		return []byte("Id=snap.foo.web.service\nLoadState=loaded\nActiveState=active\nSubState=running\nUnitFileState=enabled\n"), nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/apps", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getAppsInfo(appsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []appInfoJSON{
+		{Snap: "foo", Name: "cli"},
+		{Snap: "foo", Name: "web", Daemon: "simple", Enabled: true, Active: true},
+	})
+
+	req, err = http.NewRequest("GET", "/v2/apps?select=service&names=foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp = getAppsInfo(appsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []appInfoJSON{
+		{Snap: "foo", Name: "web", Daemon: "simple", Enabled: true, Active: true},
+	})
+}
+
+func (s *apiSuite) TestAppsInfoErrors(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, servicesYaml)
+
+	for _, t := range []struct {
+		query string
+		err   string
+	}{
+		{"?select=all", `invalid select parameter: "all"`},
+		{"?names=baz", `cannot find snap "baz"`},
+		{"?names=foo.cli&select=service", `app "foo.cli" is not a service`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/apps"+t.query, nil)
+		c.Assert(err, check.IsNil)
+		rsp := getAppsInfo(appsCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, http.StatusBadRequest)
+		c.Check(rsp.Result.(*errorResult).Message, check.Equals, t.err)
+	}
+}
+
+func (s *apiSuite) TestPostApps(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, servicesYaml)
+
+	oldSystemctl := systemd.SystemctlCmd
+	defer func() { systemd.SystemctlCmd = oldSystemctl }()
+	var sysdLog [][]string
+	systemd.SystemctlCmd = func(args ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, args)
+		return nil, nil
+	}
+
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	buf := bytes.NewBufferString(`{"action": "start", "names": ["foo"], "enable": true}`)
+	req, err := http.NewRequest("POST", "/v2/apps", buf)
+	c.Assert(err, check.IsNil)
+	rsp := postApps(appsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.Change(rsp.Change)
+	st.Unlock()
+	c.Assert(chg, check.NotNil)
+
+	<-chg.Ready()
+
+	st.Lock()
+	err = chg.Err()
+	summary := chg.Summary()
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(summary, check.Equals, `Running "start" on services of foo`)
+
+	c.Check(sysdLog, check.DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "enable", "snap.foo.web.service"},
+		{"start", "snap.foo.web.service"},
+	})
+}
+
+func (s *apiSuite) TestPostAppsErrors(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, servicesYaml)
+
+	for _, t := range []struct {
+		body string
+		err  string
+	}{
+		{`{}`, `service action not specified`},
+		{`{"action": "frobnicate"}`, `unknown service action "frobnicate"`},
+		{`{"action": "start", "names": ["foo.cli"]}`, `app "foo.cli" is not a service`},
+		{`{"action": "stop", "enable": true}`, `cannot enable services while stopping them`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/apps", bytes.NewBufferString(t.body))
+		c.Assert(err, check.IsNil)
+		rsp := postApps(appsCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, http.StatusBadRequest)
+		c.Check(rsp.Result.(*errorResult).Message, check.Equals, t.err)
+	}
+}
+
+func (s *apiSuite) TestLogs(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, servicesYaml)
+
+	var args []interface{}
+	systemdLogReader = func(serviceNames []string, n int, follow bool) (io.ReadCloser, error) {
+		args = []interface{}{serviceNames, n, follow}
+		return ioutil.NopCloser(strings.NewReader(`
+{"__REALTIME_TIMESTAMP": "42", "SYSLOG_IDENTIFIER": "foo.web", "_PID": "7", "MESSAGE": "hello"}
+{"__REALTIME_TIMESTAMP": "44", "SYSLOG_IDENTIFIER": "foo.web", "_PID": "7", "MESSAGE": "bye"}
+`)), nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/logs?names=foo.web&n=all&follow=true", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	logsCmd.GET(logsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(rec.HeaderMap.Get("Content-Type"), check.Equals, "application/json-seq")
+	c.Check(rec.Body.String(), check.Equals, ""+
+		"\x1e{\"timestamp\":\"1970-01-01T00:00:00.000042Z\",\"sid\":\"foo.web\",\"pid\":\"7\",\"message\":\"hello\"}\n"+
+		"\x1e{\"timestamp\":\"1970-01-01T00:00:00.000044Z\",\"sid\":\"foo.web\",\"pid\":\"7\",\"message\":\"bye\"}\n")
+	c.Check(args, check.DeepEquals, []interface{}{[]string{"snap.foo.web.service"}, -1, true})
+
+	// defaults
+	req, err = http.NewRequest("GET", "/v2/logs", nil)
+	c.Assert(err, check.IsNil)
+	rec = httptest.NewRecorder()
+	logsCmd.GET(logsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(args, check.DeepEquals, []interface{}{[]string{"snap.foo.web.service"}, 10, false})
+}
+
+func (s *apiSuite) TestLogsErrors(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, servicesYaml)
+	s.mkInstalledInState(c, d, "baz", "bar", "v1", snap.R(1), true, "")
+
+	systemdLogReader = func([]string, int, bool) (io.ReadCloser, error) {
+		return nil, errors.New("boom")
+	}
+
+	for _, t := range []struct {
+		query  string
+		status int
+		err    string
+	}{
+		{"?n=-3", http.StatusBadRequest, `invalid n parameter: "-3"`},
+		{"?n=many", http.StatusBadRequest, `invalid n parameter: "many"`},
+		{"?follow=sure", http.StatusBadRequest, `invalid follow parameter: "sure"`},
+		{"?names=foo.cli", http.StatusBadRequest, `app "foo.cli" is not a service`},
+		{"?names=baz", http.StatusNotFound, `no services found`},
+		{"?names=foo", http.StatusInternalServerError, `cannot read service logs: boom`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/logs"+t.query, nil)
+		c.Assert(err, check.IsNil)
+		rsp := getLogs(logsCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, t.status)
+		c.Check(rsp.Result.(*errorResult).Message, check.Equals, t.err)
+	}
+}
+
 // Test for POST /v2/interfaces
 
 func (s *apiSuite) TestConnectPlugSuccess(c *check.C) {
@@ -2351,6 +2950,168 @@ func (s *apiSuite) TestAssertError(c *check.C) {
 	c.Check(rec.Body.String(), testutil.Contains, "assert failed")
 }
 
+var (
+	rootPrivKey  = assertstest.GenerateKey(752)
+	storePrivKey = assertstest.GenerateKey(752)
+)
+
+func (s *apiSuite) mockStoreSigning(c *check.C) *assertstest.StoreStack {
+	storeSigning := assertstest.NewStoreStack("canonical", rootPrivKey, storePrivKey)
+	err := os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(dirs.SnapTrustedAccountKey, asserts.Encode(storeSigning.TrustedKey), 0640)
+	c.Assert(err, check.IsNil)
+	return storeSigning
+}
+
+func mockSnapDeclaration(c *check.C, storeSigning *assertstest.StoreStack) asserts.Assertion {
+	snapDecl, err := storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "foo-id",
+		"snap-name":    "foo",
+		"publisher-id": "developer1",
+		"gates":        "",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, check.IsNil)
+	return snapDecl
+}
+
+func (s *apiSuite) TestAssertStreamWithPrerequisites(c *check.C) {
+	storeSigning := s.mockStoreSigning(c)
+	d := s.daemon(c)
+	snapDecl := mockSnapDeclaration(c, storeSigning)
+	devAcct := assertstest.NewAccount(storeSigning.SigningDB, "developer1", nil)
+
+	// the assertions don't need to come in dependency order
+	buf := new(bytes.Buffer)
+	enc := asserts.NewEncoder(buf)
+	for _, a := range []asserts.Assertion{snapDecl, devAcct, storeSigning.StoreAccountKey} {
+		c.Assert(enc.Encode(a), check.IsNil)
+	}
+	req, err := http.NewRequest("POST", "/v2/assertions", buf)
+	c.Assert(err, check.IsNil)
+	rsp := doAssert(assertsCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Status, check.Equals, http.StatusOK)
+
+	db := d.overlord.AssertManager().DB()
+	for _, a := range []asserts.Assertion{snapDecl, devAcct, storeSigning.StoreAccountKey} {
+		_, err := a.Ref().Resolve(db.Find)
+		c.Check(err, check.IsNil)
+	}
+}
+
+func (s *apiSuite) TestAssertFetchesPrerequisitesFromStore(c *check.C) {
+	storeSigning := s.mockStoreSigning(c)
+	d := s.daemon(c)
+	snapDecl := mockSnapDeclaration(c, storeSigning)
+	devAcct := assertstest.NewAccount(storeSigning.SigningDB, "developer1", nil)
+	s.storeAsserts = []asserts.Assertion{storeSigning.StoreAccountKey, devAcct}
+
+	req, err := http.NewRequest("POST", "/v2/assertions", bytes.NewReader(asserts.Encode(snapDecl)))
+	c.Assert(err, check.IsNil)
+	user := &auth.UserState{ID: 1, Username: "username", Macaroon: "macaroon"}
+	rsp := doAssert(assertsCmd, req, user).(*resp)
+	c.Check(rsp.Status, check.Equals, http.StatusOK)
+	c.Check(s.auther, check.DeepEquals, user.Authenticator())
+
+	db := d.overlord.AssertManager().DB()
+	for _, a := range []asserts.Assertion{snapDecl, devAcct, storeSigning.StoreAccountKey} {
+		_, err := a.Ref().Resolve(db.Find)
+		c.Check(err, check.IsNil)
+	}
+}
+
+func (s *apiSuite) TestAssertMissingPrerequisite(c *check.C) {
+	storeSigning := s.mockStoreSigning(c)
+	s.daemon(c)
+	snapDecl := mockSnapDeclaration(c, storeSigning)
+	s.storeAsserts = []asserts.Assertion{storeSigning.StoreAccountKey}
+
+	req, err := http.NewRequest("POST", "/v2/assertions", bytes.NewReader(asserts.Encode(snapDecl)))
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	assertsCmd.POST(assertsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 400)
+	c.Check(rec.Body.String(), testutil.Contains, `assert failed: cannot fetch account [developer1]: assertion not found`)
+}
+
+func (s *apiSuite) TestAssertBundleTwice(c *check.C) {
+	storeSigning := s.mockStoreSigning(c)
+	s.daemon(c)
+	snapDecl := mockSnapDeclaration(c, storeSigning)
+	devAcct := assertstest.NewAccount(storeSigning.SigningDB, "developer1", nil)
+
+	buf := new(bytes.Buffer)
+	enc := asserts.NewEncoder(buf)
+	for _, a := range []asserts.Assertion{storeSigning.StoreAccountKey, devAcct, snapDecl} {
+		c.Assert(enc.Encode(a), check.IsNil)
+	}
+	bundle := buf.Bytes()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", "/v2/assertions", bytes.NewReader(bundle))
+		c.Assert(err, check.IsNil)
+		rsp := doAssert(assertsCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, http.StatusOK, check.Commentf("#%d: %v", i, rsp.Result))
+	}
+}
+
+func (s *apiSuite) TestAssertBundlePartialFailure(c *check.C) {
+	storeSigning := s.mockStoreSigning(c)
+	d := s.daemon(c)
+	snapDecl := mockSnapDeclaration(c, storeSigning)
+	otherAcct := assertstest.NewAccount(storeSigning.SigningDB, "other", nil)
+
+	buf := new(bytes.Buffer)
+	enc := asserts.NewEncoder(buf)
+	for _, a := range []asserts.Assertion{storeSigning.StoreAccountKey, snapDecl, otherAcct} {
+		c.Assert(enc.Encode(a), check.IsNil)
+	}
+	req, err := http.NewRequest("POST", "/v2/assertions", buf)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	assertsCmd.POST(assertsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 400)
+	c.Check(rec.Body.String(), testutil.Contains, `assert failed for 1 out of 3 assertions:\n- snap-declaration [16 foo-id]: cannot fetch account [developer1]: assertion not found`)
+
+	// the other assertions were added
+	db := d.overlord.AssertManager().DB()
+	for _, a := range []asserts.Assertion{storeSigning.StoreAccountKey, otherAcct} {
+		_, err := a.Ref().Resolve(db.Find)
+		c.Check(err, check.IsNil)
+	}
+}
+
+func (s *apiSuite) TestAssertsFindManyExport(c *check.C) {
+	storeSigning := s.mockStoreSigning(c)
+	d := s.daemon(c)
+	snapDecl := mockSnapDeclaration(c, storeSigning)
+	devAcct := assertstest.NewAccount(storeSigning.SigningDB, "developer1", nil)
+	db := d.overlord.AssertManager().DB()
+	for _, a := range []asserts.Assertion{storeSigning.StoreAccountKey, devAcct, snapDecl} {
+		c.Assert(db.Add(a), check.IsNil)
+	}
+
+	req, err := http.NewRequest("GET", "/v2/assertions/snap-declaration?snap-id=foo-id&export=true", nil)
+	c.Assert(err, check.IsNil)
+	s.vars = map[string]string{"assertType": "snap-declaration"}
+	rec := httptest.NewRecorder()
+	assertsFindManyCmd.GET(assertsFindManyCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, http.StatusOK, check.Commentf("body %q", rec.Body))
+	c.Check(rec.HeaderMap.Get("X-Ubuntu-Assertions-Count"), check.Equals, "3")
+
+	dec := asserts.NewDecoder(rec.Body)
+	for _, expected := range []asserts.Assertion{storeSigning.StoreAccountKey, devAcct, snapDecl} {
+		a, err := dec.Decode()
+		c.Assert(err, check.IsNil)
+		c.Check(a.Ref().Unique(), check.Equals, expected.Ref().Unique())
+	}
+	_, err = dec.Decode()
+	c.Check(err, check.Equals, io.EOF)
+}
+
 func (s *apiSuite) TestAssertsFindManyAll(c *check.C) {
 	// Setup
 	os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755)
@@ -268,10 +268,25 @@ func New() (*Daemon, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Daemon{
+	d := &Daemon{
 		overlord: ovld,
 		hub:      notifications.NewHub(),
 		// TODO: Decide when this should be disabled by default.
 		enableInternalInterfaceActions: true,
-	}, nil
+	}
+	ovld.SnapManager().NotifyRefreshInhibited(d.publishRefreshInhibited)
+	return d, nil
+}
+
+// publishRefreshInhibited tells the subscribers to events that the refresh
+// of a snap is postponed until its running apps exit.
+func (d *Daemon) publishRefreshInhibited(snapName string, apps []string) {
+	d.hub.Publish(&notifications.Notification{
+		Timestamp: time.Now().Unix(),
+		Type:      "refresh-inhibited",
+		Resource:  "/v2/snaps/" + snapName,
+		Metadata: map[string]interface{}{
+			"apps": apps,
+		},
+	})
 }
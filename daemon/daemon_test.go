@@ -32,13 +32,31 @@ import (
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/notifications"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/ifacestate"
 )
 
 // Hook up check.v1 into the "go test" runner
 func Test(t *testing.T) { check.TestingT(t) }
 
-type daemonSuite struct{}
+// noUEvents is a source of device events that never reports any, used so
+// that the tests do not follow the devices of the host.
+type noUEvents chan *udev.UEvent
+
+func (s noUEvents) Events() <-chan *udev.UEvent { return s }
+func (s noUEvents) Close() error                { close(s); return nil }
+
+func mockNoUEvents() (restore func()) {
+	return ifacestate.MockUEventSource(func() (ifacestate.UEventSource, error) {
+		return make(noUEvents), nil
+	})
+}
+
+type daemonSuite struct {
+	restoreUEvents func()
+}
 
 var _ = check.Suite(&daemonSuite{})
 
@@ -46,10 +64,12 @@ func (s *daemonSuite) SetUpTest(c *check.C) {
 	dirs.SetRootDir(c.MkDir())
 	err := os.MkdirAll(filepath.Dir(dirs.SnapStateFile), 0755)
 	c.Assert(err, check.IsNil)
+	s.restoreUEvents = mockNoUEvents()
 }
 
 func (s *daemonSuite) TearDownTest(c *check.C) {
 	dirs.SetRootDir("")
+	s.restoreUEvents()
 }
 
 // build a new daemon, with only a little of Init(), suitable for the tests
@@ -309,3 +329,30 @@ func (s *daemonSuite) TestRestartWiring(c *check.C) {
 		c.Fatal("RequestRestart -> overlord -> Kill chain didn't work")
 	}
 }
+
+type fakeWebsocket struct {
+	messages []string
+}
+
+func (ws *fakeWebsocket) WriteMessage(messageType int, data []byte) error {
+	ws.messages = append(ws.messages, string(data))
+	return nil
+}
+
+func (ws *fakeWebsocket) Close() error {
+	return nil
+}
+
+func (s *daemonSuite) TestPublishRefreshInhibited(c *check.C) {
+	d := newTestDaemon(c)
+
+	ws := &fakeWebsocket{}
+	req, err := http.NewRequest("GET", "/v2/events?types=refresh-inhibited", nil)
+	c.Assert(err, check.IsNil)
+	d.hub.Subscribe(notifications.NewSubscriber(ws, req))
+
+	d.publishRefreshInhibited("foo", []string{"kiosk"})
+
+	c.Assert(ws.messages, check.HasLen, 1)
+	c.Check(ws.messages[0], check.Matches, `\{"timestamp":[0-9]+,"type":"refresh-inhibited","resource":"/v2/snaps/foo","metadata":\{"apps":\["kiosk"\]\}\}`)
+}
@@ -22,6 +22,7 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime"
 	"net/http"
 	"path/filepath"
@@ -32,6 +33,7 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/notifications"
+	"github.com/snapcore/snapd/systemd"
 )
 
 // ResponseType is the response type
@@ -124,6 +126,7 @@ const (
 	errorKindTwoFactorRequired = errorKind("two-factor-required")
 	errorKindTwoFactorFailed   = errorKind("two-factor-failed")
 	errorKindLoginRequired     = errorKind("login-required")
+	errorKindRefreshPostponed  = errorKind("refresh-postponed")
 )
 
 type errorValue interface{}
@@ -240,6 +243,74 @@ func (e eventResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	e.h.Subscribe(s)
 }
 
+// logJSON is a single service log entry as sent by LogResponse.
+type logJSON struct {
+	Timestamp string `json:"timestamp"`
+	SID       string `json:"sid"`
+	PID       string `json:"pid"`
+	Message   string `json:"message"`
+}
+
+type logResponse struct {
+	reader io.ReadCloser
+}
+
+// LogResponse builds a response whose ServeHTTP method streams the journal
+// entries read from reader as a JSON text sequence (RFC 7464), until the
+// reader runs dry or the client goes away. The reader is closed when done.
+func LogResponse(reader io.ReadCloser) Response {
+	return &logResponse{reader: reader}
+}
+
+func (lr *logResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer lr.reader.Close()
+
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		done := make(chan struct{})
+		defer close(done)
+		gone := notifier.CloseNotify()
+		go func() {
+			select {
+			case <-gone:
+				// unblocks the decoder below
+				lr.reader.Close()
+			case <-done:
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json-seq")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	dec := json.NewDecoder(lr.reader)
+	enc := json.NewEncoder(w)
+	for {
+		var log systemd.Log
+		if err := dec.Decode(&log); err != nil {
+			if err != io.EOF {
+				logger.Debugf("cannot decode service log: %v", err)
+			}
+			return
+		}
+		if _, err := w.Write([]byte{0x1E}); err != nil {
+			return
+		}
+		if err := enc.Encode(logJSON{
+			Timestamp: log.Timestamp(),
+			SID:       log.SID(),
+			PID:       log.PID(),
+			Message:   log.Message(),
+		}); err != nil {
+			logger.Noticef("cannot write service log into response: %v", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
 // errorResponder is a callable that produces an error Response.
 // e.g., InternalError("something broke: %v", err), etc.
 type errorResponder func(string, ...interface{}) Response
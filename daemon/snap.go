@@ -120,7 +120,9 @@ func effectiveConfinement(snapst *snapstate.SnapState) snap.ConfinementType {
 
 // appJSON contains the json for snap.AppInfo
 type appJSON struct {
-	Name string `json:"name"`
+	Name      string          `json:"name"`
+	Daemon    string          `json:"daemon,omitempty"`
+	Resources *snap.Resources `json:"resources,omitempty"`
 }
 
 func mapLocal(localSnap *snap.Info, snapst *snapstate.SnapState) map[string]interface{} {
@@ -132,11 +134,13 @@ func mapLocal(localSnap *snap.Info, snapst *snapstate.SnapState) map[string]inte
 	apps := make([]appJSON, 0, len(localSnap.Apps))
 	for _, app := range localSnap.Apps {
 		apps = append(apps, appJSON{
-			Name: app.Name,
+			Name:      app.Name,
+			Daemon:    app.Daemon,
+			Resources: app.Resources,
 		})
 	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"description":    localSnap.Description(),
 		"developer":      localSnap.Developer,
 		"icon":           snapIcon(localSnap),
@@ -156,6 +160,11 @@ func mapLocal(localSnap *snap.Info, snapst *snapstate.SnapState) map[string]inte
 		"private":        localSnap.Private,
 		"apps":           apps,
 	}
+	if localSnap.Resources != nil {
+		result["resources"] = localSnap.Resources
+	}
+
+	return result
 }
 
 func mapRemote(remoteSnap *snap.Info) map[string]interface{} {
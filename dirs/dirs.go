@@ -34,6 +34,7 @@ var (
 	SnapDataHomeGlob          string
 	SnapAppArmorDir           string
 	AppArmorCacheDir          string
+	SnapAppArmorCacheDir      string
 	SnapAppArmorAdditionalDir string
 	SnapSeccompDir            string
 	SnapMountPolicyDir        string
@@ -53,6 +54,9 @@ var (
 	SnapBinariesDir     string
 	SnapServicesDir     string
 	SnapDesktopFilesDir string
+	SnapDesktopIconsDir string
+	SnapMimeDir         string
+	SnapAutostartDir    string
 	SnapBusPolicyDir    string
 
 	CloudMetaDataFile string
@@ -85,12 +89,16 @@ func SetRootDir(rootdir string) {
 	SnapDataHomeGlob = filepath.Join(rootdir, "/home/*/snap/")
 	SnapAppArmorDir = filepath.Join(rootdir, snappyDir, "apparmor", "profiles")
 	AppArmorCacheDir = filepath.Join(rootdir, "/var/cache/apparmor")
+	SnapAppArmorCacheDir = filepath.Join(rootdir, snappyDir, "apparmor", "cache")
 	SnapAppArmorAdditionalDir = filepath.Join(rootdir, snappyDir, "apparmor", "additional")
 	SnapSeccompDir = filepath.Join(rootdir, snappyDir, "seccomp", "profiles")
 	SnapMountPolicyDir = filepath.Join(rootdir, snappyDir, "mount")
 	SnapMetaDir = filepath.Join(rootdir, snappyDir, "meta")
 	SnapBlobDir = filepath.Join(rootdir, snappyDir, "snaps")
 	SnapDesktopFilesDir = filepath.Join(rootdir, snappyDir, "desktop", "applications")
+	SnapDesktopIconsDir = filepath.Join(rootdir, snappyDir, "desktop", "icons")
+	SnapMimeDir = filepath.Join(rootdir, snappyDir, "desktop", "mime")
+	SnapAutostartDir = filepath.Join(rootdir, snappyDir, "desktop", "xdg", "autostart")
 	// keep in sync with the debian/ubuntu-snappy.snapd.socket file:
 	SnapdSocket = filepath.Join(rootdir, "/run/snapd.socket")
 
@@ -55,7 +55,8 @@ func LoadProfile(fname string) error {
 // UnloadProfile removes the named profile from the running kernel.
 //
 // The operation is done with: apparmor_parser --remove $name
-// The binary cache file is removed from /var/cache/apparmor
+// The binary cache file is removed from /var/cache/apparmor, along with the
+// compiled profiles cached by LoadProfiles.
 func UnloadProfile(name string) error {
 	output, err := exec.Command("apparmor_parser", "--remove", name).CombinedOutput()
 	if err != nil {
@@ -66,6 +67,9 @@ func UnloadProfile(name string) error {
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("cannot remove apparmor profile cache: %s", err)
 	}
+	if err := pruneCache(name, ""); err != nil {
+		return fmt.Errorf("cannot remove apparmor profile cache: %s", err)
+	}
 	return nil
 }
 
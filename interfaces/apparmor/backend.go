@@ -32,9 +32,13 @@
 // names.
 //
 // The actual profiles are stored in /var/lib/snappy/apparmor/profiles.
+// Snappy compiles them itself, in parallel, and keeps the compiled profiles in
+// /var/lib/snappy/apparmor/cache so that they are only compiled again when
+// their content, the parser or the kernel change.
 //
 // NOTE: A systemd job (apparmor.service) loads all snappy-specific apparmor
-// profiles into the kernel during the boot process.
+// profiles into the kernel during the boot process, from the copies of the
+// compiled profiles that snappy keeps in /var/cache/apparmor.
 package apparmor
 
 import (
@@ -84,8 +88,9 @@ func (b *Backend) Setup(snapInfo *snap.Info, devMode bool, repo *interfaces.Repo
 	}
 	_, removed, errEnsure := osutil.EnsureDirState(dir, glob, content)
 	// NOTE: load all profiles instead of just the changed profiles.  We're
-	// relying on apparmor cache to make this efficient. This gives us
-	// certainty that each call to Setup ends up with working profiles.
+	// relying on the cache of compiled profiles to make this efficient. This
+	// gives us certainty that each call to Setup ends up with working
+	// profiles.
 	all := make([]string, 0, len(content))
 	for name := range content {
 		all = append(all, name)
@@ -102,6 +107,25 @@ func (b *Backend) Setup(snapInfo *snap.Info, devMode bool, repo *interfaces.Repo
 	return errUnload
 }
 
+// Verify checks that the apparmor profiles of a given snap are up to date.
+func (b *Backend) Verify(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (changed, extra []string, err error) {
+	snapName := snapInfo.Name()
+	snippets, err := repo.SecuritySnippetsForSnap(snapName, interfaces.SecurityAppArmor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot obtain security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, devMode, snippets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapName, err)
+	}
+	glob := interfaces.SecurityTagGlob(snapName)
+	changed, extra, err = osutil.CheckDirState(dirs.SnapAppArmorDir, glob, content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot check security files for snap %q: %s", snapName, err)
+	}
+	return changed, extra, nil
+}
+
 // Remove removes and unloads apparmor profiles of a given snap.
 func (b *Backend) Remove(snapName string) error {
 	glob := interfaces.SecurityTagGlob(snapName)
@@ -167,14 +191,11 @@ func addContent(securityTag string, snapInfo *snap.Info, devMode bool, snippets
 }
 
 func reloadProfiles(profiles []string) error {
-	for _, profile := range profiles {
-		fname := filepath.Join(dirs.SnapAppArmorDir, profile)
-		err := LoadProfile(fname)
-		if err != nil {
-			return fmt.Errorf("cannot load apparmor profile %q: %s", profile, err)
-		}
+	fnames := make([]string, len(profiles))
+	for i, profile := range profiles {
+		fnames[i] = filepath.Join(dirs.SnapAppArmorDir, profile)
 	}
-	return nil
+	return LoadProfiles(fnames)
 }
 
 func unloadProfiles(profiles []string) error {
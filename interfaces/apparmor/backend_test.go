@@ -20,7 +20,6 @@
 package apparmor_test
 
 import (
-	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -38,17 +37,20 @@ import (
 type backendSuite struct {
 	backendtest.BackendSuite
 
-	parserCmd *testutil.MockCmd
+	parserCmd       *testutil.MockCmd
+	restoreFeatures func()
+	restoreWorkers  func()
 }
 
 var _ = Suite(&backendSuite{})
 
 // fakeAppAprmorParser contains shell program that creates fake binary cache entries
-// in accordance with what real apparmor_parser would do.
+// and compiled profiles in accordance with what real apparmor_parser would do.
 const fakeAppArmorParser = `
 cache_dir=""
 profile=""
 write=""
+ofile=""
 while [ -n "$1" ]; do
 	case "$1" in
 		--cache-loc=*)
@@ -57,7 +59,10 @@ while [ -n "$1" ]; do
 		--write-cache)
 			write=yes
 			;;
-		--replace|--remove)
+		--ofile=*)
+			ofile="$(echo "$1" | cut -d = -f 2)" || exit 1
+			;;
+		--replace|--remove|--binary|--skip-kernel-load|--skip-cache)
 			# Ignore
 			;;
 		-O)
@@ -73,6 +78,9 @@ done
 if [ "$write" = yes ]; then
 	echo fake > "$cache_dir/$profile"
 fi
+if [ -n "$ofile" ]; then
+	echo fake > "$ofile"
+fi
 `
 
 func (s *backendSuite) SetUpTest(c *C) {
@@ -87,14 +95,44 @@ func (s *backendSuite) SetUpTest(c *C) {
 	c.Assert(err, IsNil)
 	// Mock away any real apparmor interaction
 	s.parserCmd = testutil.MockCommand(c, "apparmor_parser", fakeAppArmorParser)
+	features := filepath.Join(s.RootDir, "features")
+	c.Assert(os.MkdirAll(features, 0755), IsNil)
+	s.restoreFeatures = apparmor.MockFeaturesPath(features)
+	// Compile one profile at a time to keep the calls in a stable order.
+	s.restoreWorkers = apparmor.MockCompileWorkers(1)
 }
 
 func (s *backendSuite) TearDownTest(c *C) {
+	s.restoreWorkers()
+	s.restoreFeatures()
 	s.parserCmd.Restore()
 
 	s.BackendSuite.TearDownTest(c)
 }
 
+// compiledProfile returns the compiled version of a profile in the cache.
+func (s *backendSuite) compiledProfile(c *C, name string) string {
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapAppArmorCacheDir, name+"@*"))
+	c.Assert(err, IsNil)
+	c.Assert(matches, HasLen, 1)
+	return matches[0]
+}
+
+// compileCall returns the call of apparmor_parser compiling a profile.
+func (s *backendSuite) compileCall(c *C, name string) []string {
+	return []string{"apparmor_parser", "--skip-kernel-load", "--skip-cache", "-O", "no-expr-simplify",
+		"--ofile=" + s.compiledProfile(c, name) + ".tmp", filepath.Join(dirs.SnapAppArmorDir, name)}
+}
+
+// loadCall returns the call of apparmor_parser loading compiled profiles.
+func (s *backendSuite) loadCall(c *C, names ...string) []string {
+	call := []string{"apparmor_parser", "--replace", "--binary"}
+	for _, name := range names {
+		call = append(call, s.compiledProfile(c, name))
+	}
+	return call
+}
+
 // Tests for Setup() and Remove()
 
 func (s *backendSuite) TestName(c *C) {
@@ -110,7 +148,8 @@ func (s *backendSuite) TestInstallingSnapWritesAndLoadsProfiles(c *C) {
 	c.Check(err, IsNil)
 	// apparmor_parser was used to load that file
 	c.Check(s.parserCmd.Calls(), DeepEquals, [][]string{
-		{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), profile},
+		s.compileCall(c, "snap.samba.smbd"),
+		s.loadCall(c, "snap.samba.smbd"),
 	})
 }
 
@@ -124,7 +163,8 @@ func (s *backendSuite) TestInstallingSnapWithHookWritesAndLoadsProfiles(c *C) {
 	c.Check(err, IsNil)
 	// apparmor_parser was used to load that file
 	c.Check(s.parserCmd.Calls(), DeepEquals, [][]string{
-		{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), profile},
+		s.compileCall(c, "snap.foo.hook.test-hook"),
+		s.loadCall(c, "snap.foo.hook.test-hook"),
 	})
 }
 
@@ -134,9 +174,9 @@ func (s *backendSuite) TestProfilesAreAlwaysLoaded(c *C) {
 		s.parserCmd.ForgetCalls()
 		err := s.Backend.Setup(snapInfo, devMode, s.Repo)
 		c.Assert(err, IsNil)
-		profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
 		c.Check(s.parserCmd.Calls(), DeepEquals, [][]string{
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), profile},
+			// The compiled profile is in the cache already.
+			s.loadCall(c, "snap.samba.smbd"),
 		})
 		s.RemoveSnap(c, snapInfo)
 	}
@@ -187,11 +227,11 @@ func (s *backendSuite) TestUpdatingSnapMakesNeccesaryChanges(c *C) {
 		snapInfo := s.InstallSnap(c, devMode, backendtest.SambaYamlV1, 1)
 		s.parserCmd.ForgetCalls()
 		snapInfo = s.UpdateSnap(c, snapInfo, devMode, backendtest.SambaYamlV1, 2)
-		profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
 		// apparmor_parser was used to reload the profile because snap revision
 		// is inside the generated policy.
 		c.Check(s.parserCmd.Calls(), DeepEquals, [][]string{
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), profile},
+			s.compileCall(c, "snap.samba.smbd"),
+			s.loadCall(c, "snap.samba.smbd"),
 		})
 		s.RemoveSnap(c, snapInfo)
 	}
@@ -203,15 +243,14 @@ func (s *backendSuite) TestUpdatingSnapToOneWithMoreApps(c *C) {
 		s.parserCmd.ForgetCalls()
 		// NOTE: the revision is kept the same to just test on the new application being added
 		snapInfo = s.UpdateSnap(c, snapInfo, devMode, backendtest.SambaYamlV1WithNmbd, 1)
-		smbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
 		nmbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.nmbd")
 		// file called "snap.sambda.nmbd" was created
 		_, err := os.Stat(nmbdProfile)
 		c.Check(err, IsNil)
 		// apparmor_parser was used to load the both profiles
 		c.Check(s.parserCmd.Calls(), DeepEquals, [][]string{
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), nmbdProfile},
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), smbdProfile},
+			s.compileCall(c, "snap.samba.nmbd"),
+			s.loadCall(c, "snap.samba.nmbd", "snap.samba.smbd"),
 		})
 		s.RemoveSnap(c, snapInfo)
 	}
@@ -223,8 +262,6 @@ func (s *backendSuite) TestUpdatingSnapToOneWithMoreHooks(c *C) {
 		s.parserCmd.ForgetCalls()
 		// NOTE: the revision is kept the same to just test on the new application being added
 		snapInfo = s.UpdateSnap(c, snapInfo, devMode, backendtest.SambaYamlWithHook, 1)
-		smbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
-		nmbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.nmbd")
 		hookProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.hook.test-hook")
 
 		// Verify that profile "snap.samba.hook.test-hook" was created
@@ -232,9 +269,8 @@ func (s *backendSuite) TestUpdatingSnapToOneWithMoreHooks(c *C) {
 		c.Check(err, IsNil)
 		// apparmor_parser was used to load the both profiles
 		c.Check(s.parserCmd.Calls(), DeepEquals, [][]string{
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), hookProfile},
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), nmbdProfile},
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), smbdProfile},
+			s.compileCall(c, "snap.samba.hook.test-hook"),
+			s.loadCall(c, "snap.samba.hook.test-hook", "snap.samba.nmbd", "snap.samba.smbd"),
 		})
 		s.RemoveSnap(c, snapInfo)
 	}
@@ -246,14 +282,13 @@ func (s *backendSuite) TestUpdatingSnapToOneWithFewerApps(c *C) {
 		s.parserCmd.ForgetCalls()
 		// NOTE: the revision is kept the same to just test on the application being removed
 		snapInfo = s.UpdateSnap(c, snapInfo, devMode, backendtest.SambaYamlV1, 1)
-		smbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
 		nmbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.nmbd")
 		// file called "snap.sambda.nmbd" was removed
 		_, err := os.Stat(nmbdProfile)
 		c.Check(os.IsNotExist(err), Equals, true)
 		// apparmor_parser was used to remove the unused profile
 		c.Check(s.parserCmd.Calls(), DeepEquals, [][]string{
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), smbdProfile},
+			s.loadCall(c, "snap.samba.smbd"),
 			{"apparmor_parser", "--remove", "snap.samba.nmbd"},
 		})
 		s.RemoveSnap(c, snapInfo)
@@ -266,8 +301,6 @@ func (s *backendSuite) TestUpdatingSnapToOneWithFewerHooks(c *C) {
 		s.parserCmd.ForgetCalls()
 		// NOTE: the revision is kept the same to just test on the application being removed
 		snapInfo = s.UpdateSnap(c, snapInfo, devMode, backendtest.SambaYamlV1WithNmbd, 1)
-		smbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
-		nmbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.nmbd")
 		hookProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.hook.test-hook")
 
 		// Verify profile "snap.samba.hook.test-hook" was removed
@@ -275,8 +308,7 @@ func (s *backendSuite) TestUpdatingSnapToOneWithFewerHooks(c *C) {
 		c.Check(os.IsNotExist(err), Equals, true)
 		// apparmor_parser was used to remove the unused profile
 		c.Check(s.parserCmd.Calls(), DeepEquals, [][]string{
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), nmbdProfile},
-			{"apparmor_parser", "--replace", "--write-cache", "-O", "no-expr-simplify", fmt.Sprintf("--cache-loc=%s/var/cache/apparmor", s.RootDir), smbdProfile},
+			s.loadCall(c, "snap.samba.nmbd", "snap.samba.smbd"),
 			{"apparmor_parser", "--remove", "snap.samba.hook.test-hook"},
 		})
 		s.RemoveSnap(c, snapInfo)
@@ -368,3 +400,22 @@ func (s *backendSuite) TestCombineSnippets(c *C) {
 		s.RemoveSnap(c, snapInfo)
 	}
 }
+
+func (s *backendSuite) TestVerifyReportsDrift(c *C) {
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	verifier := s.Backend.(interfaces.SecurityBackendVerifier)
+	changed, extra, err := verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, HasLen, 0)
+	c.Check(extra, HasLen, 0)
+	// Tamper with the generated file and add an unexpected one.
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd"), []byte("tampered"), 0644)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapAppArmorDir, "snap.samba.nmbd"), nil, 0644)
+	c.Assert(err, IsNil)
+	changed, extra, err = verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, DeepEquals, []string{"snap.samba.smbd"})
+	c.Check(extra, DeepEquals, []string{"snap.samba.nmbd"})
+	s.RemoveSnap(c, snapInfo)
+}
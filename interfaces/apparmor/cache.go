@@ -0,0 +1,251 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package apparmor
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// Compiled profiles are cached in dirs.SnapAppArmorCacheDir, in files named
+// name@hash. The hash covers the content of the profile, the apparmor_parser
+// binary, the tunables and abstractions the profiles include and the apparmor
+// features of the running kernel, so a profile is only compiled again when any
+// of those change.
+//
+// The compiled profiles are also copied to the system cache of apparmor in
+// dirs.AppArmorCacheDir, where apparmor.service finds them when it loads the
+// profiles of snaps at boot instead of compiling them again.
+
+// compileWorkers is the number of profiles compiled at the same time.
+var compileWorkers = runtime.NumCPU()
+
+// realFeaturesPath contains the apparmor features of the running kernel.
+const realFeaturesPath = "/sys/kernel/security/apparmor/features"
+
+var featuresPath = realFeaturesPath
+
+// includeDirs contain the files the profiles include, relative to the root
+// directory.
+var includeDirs = []string{"/etc/apparmor.d/tunables", "/etc/apparmor.d/abstractions"}
+
+// hashTree adds the names and content of the files in the given directory to
+// the hash. A missing directory adds nothing.
+func hashTree(h hash.Hash, root string) error {
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00", strings.TrimPrefix(path, root))
+		if info.Mode().IsRegular() {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			h.Write(data)
+			h.Write([]byte{0})
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// compilerKey returns a hash of the apparmor_parser binary, of the files
+// included by the profiles and of the apparmor features of the running
+// kernel.
+func compilerKey() ([]byte, error) {
+	h := sha256.New()
+	parser, err := exec.LookPath("apparmor_parser")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(parser)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	for _, dir := range includeDirs {
+		fmt.Fprintf(h, "%s\x00", dir)
+		if err := hashTree(h, filepath.Join(dirs.GlobalRootDir, dir)); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Fprintf(h, "features\x00")
+	// Kernels without apparmor features have no such directory.
+	if err := hashTree(h, featuresPath); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// cacheEntry returns the name of the file caching the compiled profile.
+func cacheEntry(name string, key, content []byte) string {
+	h := sha256.New()
+	h.Write(key)
+	h.Write(content)
+	return filepath.Join(dirs.SnapAppArmorCacheDir, fmt.Sprintf("%s@%x", name, h.Sum(nil)))
+}
+
+// pruneCache removes the cached profiles of the given name except for the
+// one to keep, if any.
+func pruneCache(name, keep string) error {
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapAppArmorCacheDir, name+"@*"))
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if match == keep {
+			continue
+		}
+		if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateSystemCache copies the compiled profile of the given name to the
+// system cache of apparmor.
+func updateSystemCache(name, binary string) error {
+	data, err := ioutil.ReadFile(binary)
+	if err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(filepath.Join(dirs.AppArmorCacheDir, name), data, 0644, 0)
+}
+
+// compileProfile compiles the profile in the given file without loading it
+// into the kernel.
+func compileProfile(fname, binary string) error {
+	tmp := binary + ".tmp"
+	// Use no-expr-simplify since expr-simplify is actually slower on armhf (LP: #1383858)
+	output, err := exec.Command(
+		"apparmor_parser", "--skip-kernel-load", "--skip-cache", "-O",
+		"no-expr-simplify", fmt.Sprintf("--ofile=%s", tmp), fname).CombinedOutput()
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("cannot compile apparmor profile: %s\napparmor_parser output:\n%s", err, string(output))
+	}
+	return os.Rename(tmp, binary)
+}
+
+// compileProfiles compiles the profiles in the given files, at most
+// compileWorkers at a time.
+func compileProfiles(fnames, binaries []string) error {
+	workers := compileWorkers
+	if workers > len(fnames) {
+		workers = len(fnames)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	errs := make([]error, len(fnames))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = compileProfile(fnames[i], binaries[i])
+			}
+		}()
+	}
+	for i := range fnames {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("cannot load apparmor profile %q: %s", filepath.Base(fnames[i]), err)
+		}
+	}
+	return nil
+}
+
+// LoadProfiles loads the apparmor profiles from the given files.
+//
+// Profiles not found in the cache of compiled profiles are compiled in
+// parallel first. The compiled profiles are copied to the system cache of
+// apparmor, and then all loaded into the kernel at once, replacing the
+// profiles with the same names.
+func LoadProfiles(fnames []string) error {
+	if len(fnames) == 0 {
+		return nil
+	}
+	key, err := compilerKey()
+	if err != nil {
+		return fmt.Errorf("cannot determine apparmor features: %s", err)
+	}
+	for _, dir := range []string{dirs.SnapAppArmorCacheDir, dirs.AppArmorCacheDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("cannot create directory for compiled apparmor profiles: %s", err)
+		}
+	}
+	binaries := make([]string, len(fnames))
+	var missingFnames, missingBinaries []string
+	for i, fname := range fnames {
+		content, err := ioutil.ReadFile(fname)
+		if err != nil {
+			return fmt.Errorf("cannot load apparmor profile %q: %s", filepath.Base(fname), err)
+		}
+		binaries[i] = cacheEntry(filepath.Base(fname), key, content)
+		if !osutil.FileExists(binaries[i]) {
+			missingFnames = append(missingFnames, fname)
+			missingBinaries = append(missingBinaries, binaries[i])
+		}
+	}
+	if err := compileProfiles(missingFnames, missingBinaries); err != nil {
+		return err
+	}
+	for i, fname := range fnames {
+		if err := pruneCache(filepath.Base(fname), binaries[i]); err != nil {
+			return fmt.Errorf("cannot prune compiled apparmor profiles: %s", err)
+		}
+		if err := updateSystemCache(filepath.Base(fname), binaries[i]); err != nil {
+			return fmt.Errorf("cannot update apparmor profile cache: %s", err)
+		}
+	}
+	args := append([]string{"--replace", "--binary"}, binaries...)
+	output, err := exec.Command("apparmor_parser", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot load apparmor profiles: %s\napparmor_parser output:\n%s", err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package apparmor_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type cacheSuite struct {
+	testutil.BaseTest
+	features  string
+	parserCmd *testutil.MockCmd
+}
+
+var _ = Suite(&cacheSuite{})
+
+// fakeCompiler writes the content of the profile being compiled to the
+// requested output file.
+const fakeCompiler = `
+ofile=""
+while [ -n "$1" ]; do
+	case "$1" in
+		--ofile=*)
+			ofile="$(echo "$1" | cut -d = -f 2)"
+			;;
+		--fail)
+			exit 1
+			;;
+		*)
+			profile="$1"
+			;;
+	esac
+	shift
+done
+if [ -n "$ofile" ]; then
+	if grep -q broken "$profile"; then
+		echo "syntax error" >&2
+		exit 1
+	fi
+	cp "$profile" "$ofile"
+fi
+`
+
+func (s *cacheSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+	c.Assert(os.MkdirAll(dirs.SnapAppArmorDir, 0755), IsNil)
+	s.features = filepath.Join(dirs.GlobalRootDir, "features")
+	c.Assert(os.MkdirAll(filepath.Join(s.features, "policy"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(s.features, "policy", "versions"), []byte("v7"), 0644), IsNil)
+	s.AddCleanup(apparmor.MockFeaturesPath(s.features))
+	s.parserCmd = testutil.MockCommand(c, "apparmor_parser", fakeCompiler)
+	s.AddCleanup(s.parserCmd.Restore)
+}
+
+func (s *cacheSuite) TearDownTest(c *C) {
+	s.BaseTest.TearDownTest(c)
+}
+
+func (s *cacheSuite) writeProfiles(c *C, n int, content string) []string {
+	var fnames []string
+	for i := 0; i < n; i++ {
+		fname := filepath.Join(dirs.SnapAppArmorDir, fmt.Sprintf("snap.foo.app%d", i))
+		err := ioutil.WriteFile(fname, []byte(fmt.Sprintf("%s %d", content, i)), 0644)
+		c.Assert(err, IsNil)
+		fnames = append(fnames, fname)
+	}
+	return fnames
+}
+
+func (s *cacheSuite) cached(c *C) []string {
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapAppArmorCacheDir, "*"))
+	c.Assert(err, IsNil)
+	return matches
+}
+
+// compileCalls counts the calls of apparmor_parser compiling profiles.
+func (s *cacheSuite) compileCalls() int {
+	n := 0
+	for _, call := range s.parserCmd.Calls() {
+		if len(call) > 1 && call[1] == "--skip-kernel-load" {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *cacheSuite) TestLoadProfilesCompilesInParallelAndLoadsAtOnce(c *C) {
+	defer apparmor.MockCompileWorkers(4)()
+	fnames := s.writeProfiles(c, 10, "profile")
+
+	err := apparmor.LoadProfiles(fnames)
+	c.Assert(err, IsNil)
+
+	cached := s.cached(c)
+	c.Assert(cached, HasLen, 10)
+	for i, binary := range cached {
+		c.Check(filepath.Base(binary), Matches, fmt.Sprintf(`snap\.foo\.app%d@[0-9a-f]{64}`, i))
+		data, err := ioutil.ReadFile(binary)
+		c.Assert(err, IsNil)
+		c.Check(string(data), Equals, fmt.Sprintf("profile %d", i))
+	}
+	c.Check(s.compileCalls(), Equals, 10)
+	calls := s.parserCmd.Calls()
+	c.Check(calls[len(calls)-1], DeepEquals, append([]string{"apparmor_parser", "--replace", "--binary"}, cached...))
+}
+
+func (s *cacheSuite) TestLoadProfilesUsesCache(c *C) {
+	fnames := s.writeProfiles(c, 2, "profile")
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	c.Check(s.compileCalls(), Equals, 2)
+
+	// Loading the same profiles again does not compile anything.
+	s.parserCmd.ForgetCalls()
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	c.Check(s.compileCalls(), Equals, 0)
+	c.Check(s.parserCmd.Calls(), HasLen, 1)
+
+	// Changing a profile compiles only that one and drops its old version.
+	s.parserCmd.ForgetCalls()
+	c.Assert(ioutil.WriteFile(fnames[1], []byte("changed"), 0644), IsNil)
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	c.Check(s.compileCalls(), Equals, 1)
+	c.Check(s.cached(c), HasLen, 2)
+}
+
+func (s *cacheSuite) TestLoadProfilesUpdatesSystemCache(c *C) {
+	fnames := s.writeProfiles(c, 2, "profile")
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+
+	// The compiled profiles are copied where apparmor.service finds them at boot.
+	for i := range fnames {
+		data, err := ioutil.ReadFile(filepath.Join(dirs.AppArmorCacheDir, fmt.Sprintf("snap.foo.app%d", i)))
+		c.Assert(err, IsNil)
+		c.Check(string(data), Equals, fmt.Sprintf("profile %d", i))
+	}
+
+	// The copies follow the changes of the profiles, compiled or not.
+	c.Assert(ioutil.WriteFile(fnames[1], []byte("changed"), 0644), IsNil)
+	c.Assert(os.Remove(filepath.Join(dirs.AppArmorCacheDir, "snap.foo.app0")), IsNil)
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	data, err := ioutil.ReadFile(filepath.Join(dirs.AppArmorCacheDir, "snap.foo.app0"))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, "profile 0")
+	data, err = ioutil.ReadFile(filepath.Join(dirs.AppArmorCacheDir, "snap.foo.app1"))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, "changed")
+}
+
+func (s *cacheSuite) TestLoadProfilesRecompilesOnNewKernelFeatures(c *C) {
+	fnames := s.writeProfiles(c, 2, "profile")
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	before := s.cached(c)
+
+	s.parserCmd.ForgetCalls()
+	err := ioutil.WriteFile(filepath.Join(s.features, "policy", "versions"), []byte("v8"), 0644)
+	c.Assert(err, IsNil)
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	c.Check(s.compileCalls(), Equals, 2)
+	after := s.cached(c)
+	c.Check(after, HasLen, 2)
+	c.Check(after, Not(DeepEquals), before)
+}
+
+func (s *cacheSuite) TestLoadProfilesRecompilesOnNewAbstractions(c *C) {
+	abstractions := filepath.Join(dirs.GlobalRootDir, "/etc/apparmor.d/abstractions")
+	c.Assert(os.MkdirAll(abstractions, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(abstractions, "base"), []byte("v1"), 0644), IsNil)
+	fnames := s.writeProfiles(c, 2, "profile")
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	before := s.cached(c)
+
+	s.parserCmd.ForgetCalls()
+	c.Assert(ioutil.WriteFile(filepath.Join(abstractions, "base"), []byte("v2"), 0644), IsNil)
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	c.Check(s.compileCalls(), Equals, 2)
+	after := s.cached(c)
+	c.Check(after, HasLen, 2)
+	c.Check(after, Not(DeepEquals), before)
+
+	// New tunables have the same effect.
+	s.parserCmd.ForgetCalls()
+	tunables := filepath.Join(dirs.GlobalRootDir, "/etc/apparmor.d/tunables")
+	c.Assert(os.MkdirAll(tunables, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(tunables, "global"), []byte("v1"), 0644), IsNil)
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	c.Check(s.compileCalls(), Equals, 2)
+}
+
+func (s *cacheSuite) TestLoadProfilesRecompilesOnNewParser(c *C) {
+	fnames := s.writeProfiles(c, 2, "profile")
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+
+	newParser := testutil.MockCommand(c, "apparmor_parser", fakeCompiler+"\n# new version\n")
+	defer newParser.Restore()
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	compiled := 0
+	for _, call := range newParser.Calls() {
+		if call[1] == "--skip-kernel-load" {
+			compiled++
+		}
+	}
+	c.Check(compiled, Equals, 2)
+}
+
+func (s *cacheSuite) TestLoadProfilesWithoutKernelFeatures(c *C) {
+	c.Assert(os.RemoveAll(s.features), IsNil)
+	fnames := s.writeProfiles(c, 1, "profile")
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+	c.Check(s.cached(c), HasLen, 1)
+}
+
+func (s *cacheSuite) TestLoadProfilesCompileError(c *C) {
+	fnames := s.writeProfiles(c, 2, "profile")
+	c.Assert(ioutil.WriteFile(fnames[0], []byte("broken"), 0644), IsNil)
+
+	err := apparmor.LoadProfiles(fnames)
+	c.Assert(err, ErrorMatches, `cannot load apparmor profile "snap.foo.app0": cannot compile apparmor profile: exit status 1
+apparmor_parser output:
+syntax error
+`)
+	c.Check(s.compileCalls(), Equals, 2)
+	// Nothing was loaded and only complete results are left behind.
+	c.Check(s.parserCmd.Calls(), HasLen, 2)
+	cached := s.cached(c)
+	c.Assert(cached, HasLen, 1)
+	c.Check(filepath.Base(cached[0]), Matches, `snap\.foo\.app1@[0-9a-f]{64}`)
+}
+
+func (s *cacheSuite) TestLoadProfilesLoadError(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", `
+if [ "$1" = --replace ]; then
+	echo "cannot load" >&2
+	exit 1
+fi
+`+fakeCompiler)
+	defer cmd.Restore()
+	fnames := s.writeProfiles(c, 1, "profile")
+	err := apparmor.LoadProfiles(fnames)
+	c.Assert(err, ErrorMatches, `cannot load apparmor profiles: exit status 1
+apparmor_parser output:
+cannot load
+`)
+}
+
+func (s *cacheSuite) TestUnloadProfileRemovesCompiledProfiles(c *C) {
+	fnames := s.writeProfiles(c, 2, "profile")
+	c.Assert(apparmor.LoadProfiles(fnames), IsNil)
+
+	c.Assert(apparmor.UnloadProfile("snap.foo.app0"), IsNil)
+	cached := s.cached(c)
+	c.Assert(cached, HasLen, 1)
+	c.Check(filepath.Base(cached[0]), Matches, `snap\.foo\.app1@.*`)
+}
@@ -40,3 +40,16 @@ func MockTemplate(fakeTemplate []byte) (restore func()) {
 	defaultTemplate = fakeTemplate
 	return func() { defaultTemplate = orig }
 }
+
+// MockFeaturesPath mocks the directory with the apparmor features of the kernel.
+func MockFeaturesPath(path string) (restore func()) {
+	featuresPath = path
+	return func() { featuresPath = realFeaturesPath }
+}
+
+// MockCompileWorkers replaces the number of profiles compiled at the same time.
+func MockCompileWorkers(n int) (restore func()) {
+	orig := compileWorkers
+	compileWorkers = n
+	return func() { compileWorkers = orig }
+}
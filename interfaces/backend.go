@@ -43,3 +43,13 @@ type SecurityBackend interface {
 	// This method should be called during the process of removing a snap.
 	Remove(snapName string) error
 }
+
+// SecurityBackendVerifier is implemented by security backends that can check
+// their security artefacts without changing them.
+type SecurityBackendVerifier interface {
+	// Verify compares the security artefacts of a given snap with the ones
+	// that Setup would create. It returns the names of artefacts that are
+	// missing or have unexpected content and the names of artefacts that
+	// should not exist at all.
+	Verify(snapInfo *snap.Info, devMode bool, repo *Repository) (changed, extra []string, err error)
+}
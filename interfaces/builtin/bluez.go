@@ -194,7 +194,7 @@ func (iface *BluezInterface) Name() string {
 
 func (iface *BluezInterface) PermanentPlugSnippet(plug *interfaces.Plug, securitySystem interfaces.SecuritySystem) ([]byte, error) {
 	switch securitySystem {
-	case interfaces.SecurityDBus, interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityUDev, interfaces.SecurityMount:
+	case interfaces.SecurityDBus, interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
 		return nil, nil
 	default:
 		return nil, interfaces.ErrUnknownSecurity
@@ -210,7 +210,7 @@ func (iface *BluezInterface) ConnectedPlugSnippet(plug *interfaces.Plug, slot *i
 		return snippet, nil
 	case interfaces.SecuritySecComp:
 		return bluezConnectedPlugSecComp, nil
-	case interfaces.SecurityUDev, interfaces.SecurityDBus:
+	case interfaces.SecurityUDev, interfaces.SecurityDBus, interfaces.SecuritySystemd:
 		return nil, nil
 	default:
 		return nil, interfaces.ErrUnknownSecurity
@@ -225,7 +225,7 @@ func (iface *BluezInterface) PermanentSlotSnippet(slot *interfaces.Slot, securit
 		return bluezPermanentSlotSecComp, nil
 	case interfaces.SecurityDBus:
 		return bluezPermanentSlotDBus, nil
-	case interfaces.SecurityUDev:
+	case interfaces.SecurityUDev, interfaces.SecuritySystemd:
 		return nil, nil
 	default:
 		return nil, interfaces.ErrUnknownSecurity
@@ -234,7 +234,7 @@ func (iface *BluezInterface) PermanentSlotSnippet(slot *interfaces.Slot, securit
 
 func (iface *BluezInterface) ConnectedSlotSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
 	switch securitySystem {
-	case interfaces.SecurityDBus, interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityUDev, interfaces.SecurityMount:
+	case interfaces.SecurityDBus, interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
 		return nil, nil
 	default:
 		return nil, interfaces.ErrUnknownSecurity
@@ -0,0 +1,145 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/systemd"
+)
+
+// GpioInterface is the type of the gpio interfaces.
+//
+// Slots of gpio interfaces are declared by the gadget snap of the device, one
+// for each GPIO pin, with the number of the pin in the "number" attribute.
+type GpioInterface struct{}
+
+// String returns the same value as Name().
+func (iface *GpioInterface) String() string {
+	return iface.Name()
+}
+
+// Name returns the name of the gpio interface.
+func (iface *GpioInterface) Name() string {
+	return "gpio"
+}
+
+// SanitizeSlot checks validity of the defined slot.
+// Valid "gpio" slots must contain the attribute "number".
+func (iface *GpioInterface) SanitizeSlot(slot *interfaces.Slot) error {
+	if err := sanitizeGadgetSlot(iface, slot); err != nil {
+		return err
+	}
+	number, ok := slot.Attrs["number"].(int)
+	if !ok {
+		return fmt.Errorf("gpio slot must have a number attribute")
+	}
+	if number < 0 {
+		return fmt.Errorf("gpio slot number attribute must be a valid pin number")
+	}
+	return nil
+}
+
+// SanitizePlug checks and possibly modifies a plug.
+func (iface *GpioInterface) SanitizePlug(plug *interfaces.Plug) error {
+	if iface.Name() != plug.Interface {
+		panic(fmt.Sprintf("plug is not of interface %q", iface))
+	}
+	// NOTE: currently we don't check anything on the plug side.
+	return nil
+}
+
+// PermanentSlotSnippet returns security snippet permanently granted to gpio slots.
+func (iface *GpioInterface) PermanentSlotSnippet(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// ConnectedSlotSnippet returns security snippet specific to a given connection between the gpio slot and some plug.
+// Applications associated with the slot don't gain any extra permissions.
+func (iface *GpioInterface) ConnectedSlotSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// PermanentPlugSnippet returns the configuration snippet required to use a gpio interface.
+// Applications associated with the plug don't gain any extra permissions.
+func (iface *GpioInterface) PermanentPlugSnippet(plug *interfaces.Plug, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// ConnectedPlugSnippet returns security snippet specific to a given connection between the gpio plug and some slot.
+// The pin is exported to user space when the snap of the plug is set up and
+// applications associated with the plug gain permission to use it.
+func (iface *GpioInterface) ConnectedPlugSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor:
+		number := iface.number(slot)
+		// The files in /sys/class/gpio/gpioN are symbolic links to the
+		// files of the GPIO controller the pin belongs to. The pin may not
+		// be exported yet so the links cannot be dereferenced here.
+		return []byte(fmt.Sprintf("/sys/devices/**/gpio/gpio%d/{value,direction,edge,active_low} rwk,\n", number)), nil
+	case interfaces.SecuritySystemd:
+		number := iface.number(slot)
+		// Pins are not unexported when the service stops as they may be
+		// used by other snaps.
+		snippet := &systemd.Snippet{Services: map[string]systemd.Service{
+			fmt.Sprintf("gpio-%d", number): {
+				Type:            "oneshot",
+				RemainAfterExit: true,
+				ExecStart:       fmt.Sprintf("/bin/sh -c 'test -e /sys/class/gpio/gpio%d || echo %d > /sys/class/gpio/export'", number, number),
+			},
+		}}
+		return snippet.Bytes(), nil
+	case interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+func (iface *GpioInterface) number(slot *interfaces.Slot) int {
+	if number, ok := slot.Attrs["number"].(int); ok {
+		return number
+	}
+	panic("slot is not sanitized")
+}
+
+// AutoConnect returns true if plugs and slots should be implicitly
+// auto-connected when an unambiguous connection candidate is available.
+//
+// This interface does not auto-connect.
+func (iface *GpioInterface) AutoConnect() bool {
+	return false
+}
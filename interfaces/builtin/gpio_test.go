@@ -0,0 +1,185 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/systemd"
+	"github.com/snapcore/snapd/snap"
+)
+
+type GpioInterfaceSuite struct {
+	iface            interfaces.Interface
+	gadgetSlot       *interfaces.Slot
+	missingNumber    *interfaces.Slot
+	badNumber        *interfaces.Slot
+	negativeNumber   *interfaces.Slot
+	badInterfaceSlot *interfaces.Slot
+	appSlot          *interfaces.Slot
+	plug             *interfaces.Plug
+	badInterfacePlug *interfaces.Plug
+}
+
+var _ = Suite(&GpioInterfaceSuite{
+	iface: &builtin.GpioInterface{},
+})
+
+func (s *GpioInterfaceSuite) SetUpTest(c *C) {
+	gadgetInfo, err := snap.InfoFromSnapYaml([]byte(`
+name: my-device
+type: gadget
+slots:
+    my-pin:
+        interface: gpio
+        number: 100
+    missing-number: gpio
+    bad-number:
+        interface: gpio
+        number: forty-two
+    negative-number:
+        interface: gpio
+        number: -1
+    bad-interface: other-interface
+`))
+	c.Assert(err, IsNil)
+	s.gadgetSlot = &interfaces.Slot{SlotInfo: gadgetInfo.Slots["my-pin"]}
+	s.missingNumber = &interfaces.Slot{SlotInfo: gadgetInfo.Slots["missing-number"]}
+	s.badNumber = &interfaces.Slot{SlotInfo: gadgetInfo.Slots["bad-number"]}
+	s.negativeNumber = &interfaces.Slot{SlotInfo: gadgetInfo.Slots["negative-number"]}
+	s.badInterfaceSlot = &interfaces.Slot{SlotInfo: gadgetInfo.Slots["bad-interface"]}
+
+	appInfo, err := snap.InfoFromSnapYaml([]byte(`
+name: my-app
+slots:
+    my-pin:
+        interface: gpio
+        number: 100
+plugs:
+    pin: gpio
+    bad-interface: other-interface
+apps:
+    app:
+        command: foo
+`))
+	c.Assert(err, IsNil)
+	s.appSlot = &interfaces.Slot{SlotInfo: appInfo.Slots["my-pin"]}
+	s.plug = &interfaces.Plug{PlugInfo: appInfo.Plugs["pin"]}
+	s.badInterfacePlug = &interfaces.Plug{PlugInfo: appInfo.Plugs["bad-interface"]}
+}
+
+func (s *GpioInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "gpio")
+}
+
+func (s *GpioInterfaceSuite) TestSanitizeSlot(c *C) {
+	err := s.iface.SanitizeSlot(s.gadgetSlot)
+	c.Assert(err, IsNil)
+	err = s.iface.SanitizeSlot(s.missingNumber)
+	c.Assert(err, ErrorMatches, "gpio slot must have a number attribute")
+	err = s.iface.SanitizeSlot(s.badNumber)
+	c.Assert(err, ErrorMatches, "gpio slot must have a number attribute")
+	err = s.iface.SanitizeSlot(s.negativeNumber)
+	c.Assert(err, ErrorMatches, "gpio slot number attribute must be a valid pin number")
+	// Only the gadget and OS snaps can have gpio slots.
+	err = s.iface.SanitizeSlot(s.appSlot)
+	c.Assert(err, ErrorMatches, "gpio slots are reserved for the gadget and operating system snaps")
+	c.Assert(func() { s.iface.SanitizeSlot(s.badInterfaceSlot) }, PanicMatches,
+		`slot is not of interface "gpio"`)
+}
+
+func (s *GpioInterfaceSuite) TestSanitizePlug(c *C) {
+	err := s.iface.SanitizePlug(s.plug)
+	c.Assert(err, IsNil)
+	c.Assert(func() { s.iface.SanitizePlug(s.badInterfacePlug) }, PanicMatches,
+		`plug is not of interface "gpio"`)
+}
+
+func (s *GpioInterfaceSuite) TestConnectedPlugSnippetAppArmor(c *C) {
+	snippet, err := s.iface.ConnectedPlugSnippet(s.plug, s.gadgetSlot, interfaces.SecurityAppArmor)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, "/sys/devices/**/gpio/gpio100/{value,direction,edge,active_low} rwk,\n")
+}
+
+func (s *GpioInterfaceSuite) TestConnectedPlugSnippetExportsPin(c *C) {
+	data, err := s.iface.ConnectedPlugSnippet(s.plug, s.gadgetSlot, interfaces.SecuritySystemd)
+	c.Assert(err, IsNil)
+	var snippet systemd.Snippet
+	c.Assert(json.Unmarshal(data, &snippet), IsNil)
+	c.Check(snippet.Services, DeepEquals, map[string]systemd.Service{
+		"gpio-100": {
+			Type:            "oneshot",
+			RemainAfterExit: true,
+			ExecStart:       "/bin/sh -c 'test -e /sys/class/gpio/gpio100 || echo 100 > /sys/class/gpio/export'",
+		},
+	})
+}
+
+func (s *GpioInterfaceSuite) TestConnectedPlugSnippetPanicsOnUnsanitizedSlots(c *C) {
+	c.Assert(func() {
+		s.iface.ConnectedPlugSnippet(s.plug, s.missingNumber, interfaces.SecurityAppArmor)
+	}, PanicMatches, "slot is not sanitized")
+}
+
+func (s *GpioInterfaceSuite) TestUnusedSecuritySystems(c *C) {
+	systems := [...]interfaces.SecuritySystem{interfaces.SecurityAppArmor,
+		interfaces.SecuritySecComp, interfaces.SecurityDBus,
+		interfaces.SecurityUDev, interfaces.SecurityMount,
+		interfaces.SecuritySystemd}
+	for _, system := range systems {
+		snippet, err := s.iface.PermanentPlugSnippet(s.plug, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+		snippet, err = s.iface.PermanentSlotSnippet(s.gadgetSlot, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+		snippet, err = s.iface.ConnectedSlotSnippet(s.plug, s.gadgetSlot, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+	}
+	for _, system := range systems[1:5] {
+		snippet, err := s.iface.ConnectedPlugSnippet(s.plug, s.gadgetSlot, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+	}
+}
+
+func (s *GpioInterfaceSuite) TestUnexpectedSecuritySystems(c *C) {
+	snippet, err := s.iface.PermanentPlugSnippet(s.plug, "foo")
+	c.Assert(err, Equals, interfaces.ErrUnknownSecurity)
+	c.Assert(snippet, IsNil)
+	snippet, err = s.iface.ConnectedPlugSnippet(s.plug, s.gadgetSlot, "foo")
+	c.Assert(err, Equals, interfaces.ErrUnknownSecurity)
+	c.Assert(snippet, IsNil)
+	snippet, err = s.iface.PermanentSlotSnippet(s.gadgetSlot, "foo")
+	c.Assert(err, Equals, interfaces.ErrUnknownSecurity)
+	c.Assert(snippet, IsNil)
+	snippet, err = s.iface.ConnectedSlotSnippet(s.plug, s.gadgetSlot, "foo")
+	c.Assert(err, Equals, interfaces.ErrUnknownSecurity)
+	c.Assert(snippet, IsNil)
+}
+
+func (s *GpioInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(), Equals, false)
+}
@@ -0,0 +1,61 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"github.com/snapcore/snapd/interfaces"
+)
+
+const hardwareObserveConnectedPlugAppArmor = `
+# Description: Can query hardware information from the system, as done by
+# lscpu, lspci, lsusb and sensors. This is restricted because it gives read
+# access to potentially sensitive details of the system and its peripherals.
+# Usage: reserved
+
+# Devices, buses and firmware
+/sys/{block,bus,class,devices,firmware}/{,**} r,
+
+# Interrupts, processors and PCI devices
+@{PROC}/interrupts r,
+@{PROC}/cpuinfo r,
+@{PROC}/bus/pci/{,**} r,
+
+# udev database, used by lsusb
+/run/udev/data/** r,
+/etc/udev/udev.conf r,
+
+# Hardware identification databases
+/usr/share/hwdata/** r,
+/usr/share/misc/{pci,usb}.ids r,
+/var/lib/usbutils/usb.ids r,
+
+# libsensors
+/etc/sensors3.conf r,
+/etc/sensors.d/{,*} r,
+`
+
+// NewHardwareObserveInterface returns a new "hardware-observe" interface.
+func NewHardwareObserveInterface() interfaces.Interface {
+	return &commonInterface{
+		name:                  "hardware-observe",
+		connectedPlugAppArmor: hardwareObserveConnectedPlugAppArmor,
+		reservedForOS:         true,
+	}
+}
@@ -0,0 +1,140 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+)
+
+// I2CInterface is the type of the i2c interfaces.
+//
+// Slots of i2c interfaces are declared by the gadget snap of the device, one
+// for each bus, with the device node of the bus in the "path" attribute.
+type I2CInterface struct{}
+
+// String returns the same value as Name().
+func (iface *I2CInterface) String() string {
+	return iface.Name()
+}
+
+// Name returns the name of the i2c interface.
+func (iface *I2CInterface) Name() string {
+	return "i2c"
+}
+
+// Pattern to match allowed i2c device nodes, path attributes will be compared
+// to this for validity
+var i2cAllowedPathPattern = regexp.MustCompile("^/dev/i2c-[0-9]{1,3}$")
+
+// SanitizeSlot checks validity of the defined slot.
+// Valid "i2c" slots must contain the attribute "path".
+func (iface *I2CInterface) SanitizeSlot(slot *interfaces.Slot) error {
+	if err := sanitizeGadgetSlot(iface, slot); err != nil {
+		return err
+	}
+	path, ok := slot.Attrs["path"].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("i2c slot must have a path attribute")
+	}
+	// Clean the path before checking it matches the pattern
+	path = filepath.Clean(path)
+	if !i2cAllowedPathPattern.MatchString(path) {
+		return fmt.Errorf("i2c path attribute must be a valid device node")
+	}
+	return nil
+}
+
+// SanitizePlug checks and possibly modifies a plug.
+func (iface *I2CInterface) SanitizePlug(plug *interfaces.Plug) error {
+	if iface.Name() != plug.Interface {
+		panic(fmt.Sprintf("plug is not of interface %q", iface))
+	}
+	// NOTE: currently we don't check anything on the plug side.
+	return nil
+}
+
+// PermanentSlotSnippet returns security snippet permanently granted to i2c slots.
+func (iface *I2CInterface) PermanentSlotSnippet(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// ConnectedSlotSnippet returns security snippet specific to a given connection between the i2c slot and some plug.
+// Applications associated with the slot don't gain any extra permissions.
+func (iface *I2CInterface) ConnectedSlotSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// PermanentPlugSnippet returns the configuration snippet required to use an i2c interface.
+// Applications associated with the plug don't gain any extra permissions.
+func (iface *I2CInterface) PermanentPlugSnippet(plug *interfaces.Plug, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// ConnectedPlugSnippet returns security snippet specific to a given connection between the i2c plug and some slot.
+// Applications associated with the plug gain permission to read and write the device node of the bus.
+func (iface *I2CInterface) ConnectedPlugSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor:
+		// Also allow reading the description of the bus and its devices.
+		path := iface.path(slot)
+		return []byte(fmt.Sprintf("%s rw,\n/sys/devices/**/%s/** r,\n", path, filepath.Base(path))), nil
+	case interfaces.SecurityUDev:
+		return udevPlugDeviceSnippet(plug, strings.TrimPrefix(iface.path(slot), "/dev/")), nil
+	case interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+func (iface *I2CInterface) path(slot *interfaces.Slot) string {
+	if path, ok := slot.Attrs["path"].(string); ok {
+		return filepath.Clean(path)
+	}
+	panic("slot is not sanitized")
+}
+
+// AutoConnect returns true if plugs and slots should be implicitly
+// auto-connected when an unambiguous connection candidate is available.
+//
+// This interface does not auto-connect.
+func (iface *I2CInterface) AutoConnect() bool {
+	return false
+}
@@ -0,0 +1,194 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+)
+
+type I2CInterfaceSuite struct {
+	iface            interfaces.Interface
+	gadgetSlot       *interfaces.Slot
+	osSlot           *interfaces.Slot
+	missingPathSlot  *interfaces.Slot
+	badPathSlots     []*interfaces.Slot
+	badInterfaceSlot *interfaces.Slot
+	appSlot          *interfaces.Slot
+	plug             *interfaces.Plug
+	badInterfacePlug *interfaces.Plug
+}
+
+var _ = Suite(&I2CInterfaceSuite{
+	iface: &builtin.I2CInterface{},
+})
+
+func (s *I2CInterfaceSuite) SetUpTest(c *C) {
+	gadgetInfo, err := snap.InfoFromSnapYaml([]byte(`
+name: my-device
+type: gadget
+slots:
+    my-bus:
+        interface: i2c
+        path: /dev/i2c-1
+    missing-path: i2c
+    bad-path-1:
+        interface: i2c
+        path: /dev/i2c
+    bad-path-2:
+        interface: i2c
+        path: /dev/ttyS0
+    bad-path-3:
+        interface: i2c
+        path: /dev/i2c-1234
+    bad-interface: other-interface
+`))
+	c.Assert(err, IsNil)
+	s.gadgetSlot = &interfaces.Slot{SlotInfo: gadgetInfo.Slots["my-bus"]}
+	s.missingPathSlot = &interfaces.Slot{SlotInfo: gadgetInfo.Slots["missing-path"]}
+	s.badPathSlots = []*interfaces.Slot{
+		{SlotInfo: gadgetInfo.Slots["bad-path-1"]},
+		{SlotInfo: gadgetInfo.Slots["bad-path-2"]},
+		{SlotInfo: gadgetInfo.Slots["bad-path-3"]},
+	}
+	s.badInterfaceSlot = &interfaces.Slot{SlotInfo: gadgetInfo.Slots["bad-interface"]}
+
+	osInfo, err := snap.InfoFromSnapYaml([]byte(`
+name: ubuntu-core
+type: os
+slots:
+    my-bus:
+        interface: i2c
+        path: /dev/i2c-0
+`))
+	c.Assert(err, IsNil)
+	s.osSlot = &interfaces.Slot{SlotInfo: osInfo.Slots["my-bus"]}
+
+	appInfo, err := snap.InfoFromSnapYaml([]byte(`
+name: my-app
+slots:
+    my-bus:
+        interface: i2c
+        path: /dev/i2c-1
+plugs:
+    bus: i2c
+    bad-interface: other-interface
+apps:
+    app1:
+        command: foo
+    app2:
+        command: bar
+`))
+	c.Assert(err, IsNil)
+	s.appSlot = &interfaces.Slot{SlotInfo: appInfo.Slots["my-bus"]}
+	s.plug = &interfaces.Plug{PlugInfo: appInfo.Plugs["bus"]}
+	s.badInterfacePlug = &interfaces.Plug{PlugInfo: appInfo.Plugs["bad-interface"]}
+}
+
+func (s *I2CInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "i2c")
+}
+
+func (s *I2CInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(s.iface.SanitizeSlot(s.gadgetSlot), IsNil)
+	c.Assert(s.iface.SanitizeSlot(s.osSlot), IsNil)
+	err := s.iface.SanitizeSlot(s.missingPathSlot)
+	c.Assert(err, ErrorMatches, "i2c slot must have a path attribute")
+	for _, slot := range s.badPathSlots {
+		err := s.iface.SanitizeSlot(slot)
+		c.Assert(err, ErrorMatches, "i2c path attribute must be a valid device node")
+	}
+	err = s.iface.SanitizeSlot(s.appSlot)
+	c.Assert(err, ErrorMatches, "i2c slots are reserved for the gadget and operating system snaps")
+	c.Assert(func() { s.iface.SanitizeSlot(s.badInterfaceSlot) }, PanicMatches,
+		`slot is not of interface "i2c"`)
+}
+
+func (s *I2CInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(s.iface.SanitizePlug(s.plug), IsNil)
+	c.Assert(func() { s.iface.SanitizePlug(s.badInterfacePlug) }, PanicMatches,
+		`plug is not of interface "i2c"`)
+}
+
+func (s *I2CInterfaceSuite) TestConnectedPlugSnippetAppArmor(c *C) {
+	snippet, err := s.iface.ConnectedPlugSnippet(s.plug, s.gadgetSlot, interfaces.SecurityAppArmor)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, "/dev/i2c-1 rw,\n/sys/devices/**/i2c-1/** r,\n")
+}
+
+func (s *I2CInterfaceSuite) TestConnectedPlugSnippetUDev(c *C) {
+	snippet, err := s.iface.ConnectedPlugSnippet(s.plug, s.gadgetSlot, interfaces.SecurityUDev)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, `KERNEL=="i2c-1", TAG+="snap_my-app_app1"
+KERNEL=="i2c-1", TAG+="snap_my-app_app2"
+`)
+}
+
+func (s *I2CInterfaceSuite) TestConnectedPlugSnippetPanicsOnUnsanitizedSlots(c *C) {
+	c.Assert(func() {
+		s.iface.ConnectedPlugSnippet(s.plug, s.missingPathSlot, interfaces.SecurityAppArmor)
+	}, PanicMatches, "slot is not sanitized")
+}
+
+func (s *I2CInterfaceSuite) TestUnusedSecuritySystems(c *C) {
+	systems := [...]interfaces.SecuritySystem{interfaces.SecurityAppArmor,
+		interfaces.SecuritySecComp, interfaces.SecurityDBus,
+		interfaces.SecurityUDev, interfaces.SecurityMount,
+		interfaces.SecuritySystemd}
+	for _, system := range systems {
+		snippet, err := s.iface.PermanentPlugSnippet(s.plug, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+		snippet, err = s.iface.PermanentSlotSnippet(s.gadgetSlot, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+		snippet, err = s.iface.ConnectedSlotSnippet(s.plug, s.gadgetSlot, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+	}
+	for _, system := range []interfaces.SecuritySystem{interfaces.SecuritySecComp,
+		interfaces.SecurityDBus, interfaces.SecurityMount, interfaces.SecuritySystemd} {
+		snippet, err := s.iface.ConnectedPlugSnippet(s.plug, s.gadgetSlot, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+	}
+}
+
+func (s *I2CInterfaceSuite) TestUnexpectedSecuritySystems(c *C) {
+	snippet, err := s.iface.PermanentPlugSnippet(s.plug, "foo")
+	c.Assert(err, Equals, interfaces.ErrUnknownSecurity)
+	c.Assert(snippet, IsNil)
+	snippet, err = s.iface.ConnectedPlugSnippet(s.plug, s.gadgetSlot, "foo")
+	c.Assert(err, Equals, interfaces.ErrUnknownSecurity)
+	c.Assert(snippet, IsNil)
+	snippet, err = s.iface.PermanentSlotSnippet(s.gadgetSlot, "foo")
+	c.Assert(err, Equals, interfaces.ErrUnknownSecurity)
+	c.Assert(snippet, IsNil)
+	snippet, err = s.iface.ConnectedSlotSnippet(s.plug, s.gadgetSlot, "foo")
+	c.Assert(err, Equals, interfaces.ErrUnknownSecurity)
+	c.Assert(snippet, IsNil)
+}
+
+func (s *I2CInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(), Equals, false)
+}
@@ -161,7 +161,7 @@ func (iface *MprisInterface) Name() string {
 
 func (iface *MprisInterface) PermanentPlugSnippet(plug *interfaces.Plug, securitySystem interfaces.SecuritySystem) ([]byte, error) {
 	switch securitySystem {
-	case interfaces.SecurityDBus, interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityUDev, interfaces.SecurityMount:
+	case interfaces.SecurityDBus, interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
 		return nil, nil
 	default:
 		return nil, interfaces.ErrUnknownSecurity
@@ -175,7 +175,7 @@ func (iface *MprisInterface) ConnectedPlugSnippet(plug *interfaces.Plug, slot *i
 		new := slotAppLabelExpr(slot)
 		snippet := bytes.Replace(mprisConnectedPlugAppArmor, old, new, -1)
 		return snippet, nil
-	case interfaces.SecurityDBus:
+	case interfaces.SecurityDBus, interfaces.SecuritySystemd:
 		return nil, nil
 	case interfaces.SecuritySecComp:
 		return mprisConnectedPlugSecComp, nil
@@ -196,7 +196,7 @@ func (iface *MprisInterface) PermanentSlotSnippet(slot *interfaces.Slot, securit
 			snippet = append(snippet, mprisConnectedSlotAppArmorClassic...)
 		}
 		return snippet, nil
-	case interfaces.SecurityDBus:
+	case interfaces.SecurityDBus, interfaces.SecuritySystemd:
 		return nil, nil
 	case interfaces.SecuritySecComp:
 		return mprisPermanentSlotSecComp, nil
@@ -214,7 +214,7 @@ func (iface *MprisInterface) ConnectedSlotSnippet(plug *interfaces.Plug, slot *i
 		new := plugAppLabelExpr(plug)
 		snippet := bytes.Replace(mprisConnectedSlotAppArmor, old, new, -1)
 		return snippet, nil
-	case interfaces.SecurityDBus, interfaces.SecuritySecComp, interfaces.SecurityUDev, interfaces.SecurityMount:
+	case interfaces.SecurityDBus, interfaces.SecuritySecComp, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
 		return nil, nil
 	default:
 		return nil, interfaces.ErrUnknownSecurity
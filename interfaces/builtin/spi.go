@@ -0,0 +1,138 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+)
+
+// SPIInterface is the type of the spi interfaces.
+//
+// Slots of spi interfaces are declared by the gadget snap of the device, one
+// for each chip select of a bus, with its device node in the "path" attribute.
+type SPIInterface struct{}
+
+// String returns the same value as Name().
+func (iface *SPIInterface) String() string {
+	return iface.Name()
+}
+
+// Name returns the name of the spi interface.
+func (iface *SPIInterface) Name() string {
+	return "spi"
+}
+
+// Pattern to match allowed spi device nodes, path attributes will be compared
+// to this for validity
+var spiAllowedPathPattern = regexp.MustCompile(`^/dev/spidev[0-9]{1,3}\.[0-9]{1,3}$`)
+
+// SanitizeSlot checks validity of the defined slot.
+// Valid "spi" slots must contain the attribute "path".
+func (iface *SPIInterface) SanitizeSlot(slot *interfaces.Slot) error {
+	if err := sanitizeGadgetSlot(iface, slot); err != nil {
+		return err
+	}
+	path, ok := slot.Attrs["path"].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("spi slot must have a path attribute")
+	}
+	// Clean the path before checking it matches the pattern
+	path = filepath.Clean(path)
+	if !spiAllowedPathPattern.MatchString(path) {
+		return fmt.Errorf("spi path attribute must be a valid device node")
+	}
+	return nil
+}
+
+// SanitizePlug checks and possibly modifies a plug.
+func (iface *SPIInterface) SanitizePlug(plug *interfaces.Plug) error {
+	if iface.Name() != plug.Interface {
+		panic(fmt.Sprintf("plug is not of interface %q", iface))
+	}
+	// NOTE: currently we don't check anything on the plug side.
+	return nil
+}
+
+// PermanentSlotSnippet returns security snippet permanently granted to spi slots.
+func (iface *SPIInterface) PermanentSlotSnippet(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// ConnectedSlotSnippet returns security snippet specific to a given connection between the spi slot and some plug.
+// Applications associated with the slot don't gain any extra permissions.
+func (iface *SPIInterface) ConnectedSlotSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// PermanentPlugSnippet returns the configuration snippet required to use an spi interface.
+// Applications associated with the plug don't gain any extra permissions.
+func (iface *SPIInterface) PermanentPlugSnippet(plug *interfaces.Plug, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// ConnectedPlugSnippet returns security snippet specific to a given connection between the spi plug and some slot.
+// Applications associated with the plug gain permission to read and write the device node.
+func (iface *SPIInterface) ConnectedPlugSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor:
+		return []byte(fmt.Sprintf("%s rw,\n", iface.path(slot))), nil
+	case interfaces.SecurityUDev:
+		return udevPlugDeviceSnippet(plug, strings.TrimPrefix(iface.path(slot), "/dev/")), nil
+	case interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityMount, interfaces.SecuritySystemd:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+func (iface *SPIInterface) path(slot *interfaces.Slot) string {
+	if path, ok := slot.Attrs["path"].(string); ok {
+		return filepath.Clean(path)
+	}
+	panic("slot is not sanitized")
+}
+
+// AutoConnect returns true if plugs and slots should be implicitly
+// auto-connected when an unambiguous connection candidate is available.
+//
+// This interface does not auto-connect.
+func (iface *SPIInterface) AutoConnect() bool {
+	return false
+}
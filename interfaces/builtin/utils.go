@@ -69,3 +69,31 @@ func slotAppLabelExpr(slot *interfaces.Slot) []byte {
 func plugAppLabelExpr(plug *interfaces.Plug) []byte {
 	return appLabelExpr(plug.Apps, plug.Snap)
 }
+
+// udevPlugDeviceSnippet returns udev rules tagging the device with the given
+// kernel name for each of the apps bound to a given plug, so that those apps
+// are allowed to access it.
+func udevPlugDeviceSnippet(plug *interfaces.Plug, kernelName string) []byte {
+	appNames := make([]string, 0, len(plug.Apps))
+	for appName := range plug.Apps {
+		appNames = append(appNames, appName)
+	}
+	sort.Strings(appNames)
+	var buf bytes.Buffer
+	for _, appName := range appNames {
+		fmt.Fprintf(&buf, "KERNEL==\"%s\", TAG+=\"snap_%s_%s\"\n", kernelName, plug.Snap.Name(), appName)
+	}
+	return buf.Bytes()
+}
+
+// sanitizeGadgetSlot checks that a slot of a hardware interface is provided
+// by the gadget snap or by the OS snap.
+func sanitizeGadgetSlot(iface interfaces.Interface, slot *interfaces.Slot) error {
+	if iface.Name() != slot.Interface {
+		panic(fmt.Sprintf("slot is not of interface %q", iface))
+	}
+	if slot.Snap.Type != snap.TypeGadget && slot.Snap.Type != snap.TypeOS {
+		return fmt.Errorf("%s slots are reserved for the gadget and operating system snaps", iface.Name())
+	}
+	return nil
+}
@@ -51,6 +51,17 @@ type SlotRef struct {
 	Name string `json:"slot"`
 }
 
+// ConnRef is a reference to a connection between a plug and a slot.
+type ConnRef struct {
+	PlugRef PlugRef `json:"plug"`
+	SlotRef SlotRef `json:"slot"`
+}
+
+// ID returns a string identifying a given connection.
+func (conn *ConnRef) ID() string {
+	return fmt.Sprintf("%s:%s %s:%s", conn.PlugRef.Snap, conn.PlugRef.Name, conn.SlotRef.Snap, conn.SlotRef.Name)
+}
+
 // Interfaces holds information about a list of plugs and slots, and their connections.
 type Interfaces struct {
 	Plugs []*Plug `json:"plugs"`
@@ -154,6 +165,8 @@ const (
 	SecurityUDev SecuritySystem = "udev"
 	// SecurityMount identifies the mount security system.
 	SecurityMount SecuritySystem = "mount"
+	// SecuritySystemd identifies the systemd services security system.
+	SecuritySystemd SecuritySystem = "systemd"
 )
 
 var (
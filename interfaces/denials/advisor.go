@@ -0,0 +1,263 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Suggest returns the names of the interfaces whose plug side security
+// snippets would allow the access described by the denial.
+//
+// Only file, capability and network accesses are considered for AppArmor.
+func Suggest(d *Denial, ifaces []interfaces.Interface) []string {
+	var securitySystem interfaces.SecuritySystem
+	switch d.Kind {
+	case KindAppArmor:
+		securitySystem = interfaces.SecurityAppArmor
+	case KindSecComp:
+		securitySystem = interfaces.SecuritySecComp
+	default:
+		return nil
+	}
+	var names []string
+	for _, iface := range ifaces {
+		for _, snippet := range plugSnippets(iface, securitySystem) {
+			var allowed bool
+			if d.Kind == KindAppArmor {
+				allowed = appArmorAllows(snippet, d)
+			} else {
+				allowed = secCompAllows(snippet, d.Syscall)
+			}
+			if allowed {
+				names = append(names, iface.Name())
+				break
+			}
+		}
+	}
+	return names
+}
+
+// plugSnippets returns the snippets an interface gives to a snap plugging it
+// into the OS snap. Interfaces that need attributes do not give any.
+func plugSnippets(iface interfaces.Interface, securitySystem interfaces.SecuritySystem) [][]byte {
+	plug := &interfaces.Plug{PlugInfo: &snap.PlugInfo{
+		Snap:      &snap.Info{SuggestedName: "snap"},
+		Name:      iface.Name(),
+		Interface: iface.Name(),
+	}}
+	slot := &interfaces.Slot{SlotInfo: &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "ubuntu-core", Type: snap.TypeOS},
+		Name:      iface.Name(),
+		Interface: iface.Name(),
+	}}
+	if iface.SanitizePlug(plug) != nil || iface.SanitizeSlot(slot) != nil {
+		return nil
+	}
+	var snippets [][]byte
+	if snippet, err := iface.PermanentPlugSnippet(plug, securitySystem); err == nil && snippet != nil {
+		snippets = append(snippets, snippet)
+	}
+	if snippet, err := iface.ConnectedPlugSnippet(plug, slot, securitySystem); err == nil && snippet != nil {
+		snippets = append(snippets, snippet)
+	}
+	return snippets
+}
+
+// snippetLines returns the lines of a snippet without comments and
+// surrounding white space, skipping empty ones.
+func snippetLines(snippet []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(snippet))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexRune(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func secCompAllows(snippet []byte, syscall string) bool {
+	for _, line := range snippetLines(snippet) {
+		if strings.Fields(line)[0] == syscall {
+			return true
+		}
+	}
+	return false
+}
+
+// appArmorVariables are the values of the AppArmor variables used by the
+// snippets, as regular expressions.
+var appArmorVariables = map[string]string{
+	"PROC":     "/proc",
+	"pid":      "[0-9]+",
+	"HOME":     "/(home/[^/]+|root)",
+	"HOMEDIRS": "/home",
+}
+
+// globRegexp converts an AppArmor path glob to an anchored regular expression.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var buf bytes.Buffer
+	buf.WriteRune('^')
+	depth := 0
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "@{"):
+			end := strings.IndexRune(glob[i:], '}')
+			if end == -1 {
+				buf.WriteString(regexp.QuoteMeta(glob[i:]))
+				i = len(glob)
+				continue
+			}
+			value, ok := appArmorVariables[glob[i+2:i+end]]
+			if !ok {
+				value = "[^/]+"
+			}
+			buf.WriteString("(" + value + ")")
+			i += end
+		case strings.HasPrefix(glob[i:], "**"):
+			buf.WriteString(".*")
+			i++
+		case ch == '*':
+			buf.WriteString("[^/]*")
+		case ch == '?':
+			buf.WriteString("[^/]")
+		case ch == '{':
+			buf.WriteRune('(')
+			depth++
+		case ch == '}' && depth > 0:
+			buf.WriteRune(')')
+			depth--
+		case ch == ',' && depth > 0:
+			buf.WriteRune('|')
+		case ch == '[':
+			end := strings.IndexRune(glob[i:], ']')
+			if end == -1 {
+				buf.WriteString(regexp.QuoteMeta(glob[i:]))
+				i = len(glob)
+				continue
+			}
+			buf.WriteString(glob[i : i+end+1])
+			i += end
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	buf.WriteRune('$')
+	return regexp.Compile(buf.String())
+}
+
+// permsAllow checks if AppArmor file permissions, like "rw" or "ixr", grant
+// the mask of a denial, like "r" or "wc".
+func permsAllow(perms, mask string) bool {
+	for _, ch := range mask {
+		switch ch {
+		case 'r', 'm', 'k', 'l':
+			if !strings.ContainsRune(perms, ch) {
+				return false
+			}
+		case 'w', 'c', 'd':
+			if !strings.ContainsRune(perms, 'w') {
+				return false
+			}
+		case 'a':
+			if !strings.ContainsAny(perms, "aw") {
+				return false
+			}
+		case 'x':
+			if !strings.ContainsRune(perms, 'x') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// appArmorAllows checks if a rule of an AppArmor snippet allows the access
+// described by the denial.
+func appArmorAllows(snippet []byte, d *Denial) bool {
+	for _, line := range snippetLines(snippet) {
+		fields := strings.Fields(strings.TrimSuffix(line, ","))
+		// Qualifiers that do not matter here.
+		for len(fields) > 0 && (fields[0] == "owner" || fields[0] == "audit" || fields[0] == "allow") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 || fields[0] == "deny" {
+			continue
+		}
+		switch {
+		case d.Capability != "":
+			if fields[0] == "capability" && (len(fields) == 1 || contains(fields[1:], d.Capability)) {
+				return true
+			}
+		case d.Network != "":
+			if fields[0] == "network" && networkAllows(fields[1:], strings.Fields(d.Network)) {
+				return true
+			}
+		case d.Path != "":
+			if len(fields) < 2 || !(strings.HasPrefix(fields[0], "/") || strings.HasPrefix(fields[0], "@{")) {
+				continue
+			}
+			re, err := globRegexp(fields[0])
+			if err != nil {
+				continue
+			}
+			if re.MatchString(d.Path) && permsAllow(fields[1], d.Mask) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// networkAllows checks if the arguments of an AppArmor network rule, like
+// "netlink raw", allow the family and socket type of a denial.
+func networkAllows(rule, network []string) bool {
+	for i, value := range network {
+		if i >= len(rule) {
+			return true
+		}
+		if rule[i] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package denials collects the confinement violations of snaps from the
+// kernel audit messages and suggests the interfaces that would allow them.
+//
+// Snaps in developer mode run with AppArmor in complain mode, violations are
+// then logged as ALLOWED instead of DENIED but are collected all the same.
+package denials
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// Kinds of denials.
+const (
+	KindAppArmor = "apparmor"
+	KindSecComp  = "seccomp"
+)
+
+// Denial describes an access of a snap that its confinement did not allow,
+// aggregated over all the times it happened.
+type Denial struct {
+	Snap string `json:"snap"`
+	Kind string `json:"kind"`
+	// SecurityTag is the security tag of the app or hook, only known for
+	// AppArmor denials.
+	SecurityTag string `json:"security-tag,omitempty"`
+	// Allowed is set when the access was only logged, for snaps in
+	// developer mode.
+	Allowed    bool   `json:"allowed,omitempty"`
+	Operation  string `json:"operation,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Mask       string `json:"mask,omitempty"`
+	Capability string `json:"capability,omitempty"`
+	Network    string `json:"network,omitempty"`
+	Syscall    string `json:"syscall,omitempty"`
+	Count      int    `json:"count"`
+	// Interfaces lists the interfaces that would allow the access.
+	Interfaces []string `json:"interfaces,omitempty"`
+}
+
+func (d *Denial) key() string {
+	return strings.Join([]string{d.Snap, d.Kind, d.SecurityTag, strconv.FormatBool(d.Allowed),
+		d.Operation, d.Path, d.Mask, d.Capability, d.Network, d.Syscall}, "\x00")
+}
+
+var fieldRegexp = regexp.MustCompile(`([a-z_]+)=("[^"]*"|\S+)`)
+
+// parseFields returns the key=value pairs of an audit record. Values with
+// special characters are hex encoded by the kernel, those are decoded.
+func parseFields(line string) map[string]string {
+	fields := make(map[string]string)
+	for _, match := range fieldRegexp.FindAllStringSubmatch(line, -1) {
+		key, value := match[1], match[2]
+		if strings.HasPrefix(value, `"`) {
+			value = strings.Trim(value, `"`)
+		} else if key == "name" || key == "profile" || key == "exe" || key == "comm" {
+			if decoded, err := hex.DecodeString(value); err == nil {
+				value = string(decoded)
+			}
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// SnapNameFromSecurityTag returns the name of the snap that a security tag,
+// as made by snap.AppSecurityTag or snap.HookSecurityTag, belongs to.
+func SnapNameFromSecurityTag(tag string) (string, error) {
+	parts := strings.Split(tag, ".")
+	if len(parts) < 3 || parts[0] != "snap" || parts[1] == "" {
+		return "", fmt.Errorf("invalid security tag %q", tag)
+	}
+	return parts[1], nil
+}
+
+// snapNameFromExe returns the name of the snap the executable is part of.
+func snapNameFromExe(exe string) (string, error) {
+	rel, err := filepath.Rel(dirs.SnapSnapsDir, exe)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("executable %q is not part of a snap", exe)
+	}
+	return strings.SplitN(rel, "/", 2)[0], nil
+}
+
+// parseAppArmor returns the denial described by the fields of an AppArmor
+// audit record.
+func parseAppArmor(fields map[string]string) (*Denial, error) {
+	// Child profiles are named profile//child.
+	tag := strings.SplitN(fields["profile"], "//", 2)[0]
+	snapName, err := SnapNameFromSecurityTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	d := &Denial{
+		Snap:        snapName,
+		Kind:        KindAppArmor,
+		SecurityTag: tag,
+		Allowed:     fields["apparmor"] == "ALLOWED",
+		Operation:   fields["operation"],
+		Path:        fields["name"],
+		Mask:        fields["requested_mask"],
+		Capability:  fields["capname"],
+	}
+	if family := fields["family"]; family != "" {
+		d.Network = strings.TrimSpace(family + " " + fields["sock_type"])
+	}
+	return d, nil
+}
+
+// parseSecComp returns the denial described by the fields of a seccomp audit
+// record.
+func parseSecComp(fields map[string]string) (*Denial, error) {
+	snapName, err := snapNameFromExe(fields["exe"])
+	if err != nil {
+		return nil, err
+	}
+	auditArch, err := strconv.ParseUint(fields["arch"], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid architecture %q", fields["arch"])
+	}
+	nr, err := strconv.ParseUint(fields["syscall"], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid system call %q", fields["syscall"])
+	}
+	syscall := seccomp.SyscallName(uint32(auditArch), uint32(nr))
+	if syscall == "" {
+		syscall = fields["syscall"]
+	}
+	return &Denial{
+		Snap:    snapName,
+		Kind:    KindSecComp,
+		Allowed: fields["code"] != "" && fields["code"] != "0x0",
+		Syscall: syscall,
+	}, nil
+}
+
+// Parse returns the denials of snaps found in the given kernel log, aggregated
+// by kind and access, in the order they first appear. Lines that are not
+// audit records of snaps are ignored.
+func Parse(log []byte) []*Denial {
+	var result []*Denial
+	seen := make(map[string]*Denial)
+	scanner := bufio.NewScanner(bytes.NewReader(log))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := parseFields(line)
+		var d *Denial
+		var err error
+		switch {
+		case fields["apparmor"] == "DENIED" || fields["apparmor"] == "ALLOWED":
+			d, err = parseAppArmor(fields)
+		case fields["type"] == "1326" || strings.HasPrefix(line, "SECCOMP "):
+			d, err = parseSecComp(fields)
+		default:
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if prev := seen[d.key()]; prev != nil {
+			prev.Count++
+			continue
+		}
+		d.Count = 1
+		seen[d.key()] = d
+		result = append(result, d)
+	}
+	return result
+}
+
+// kernelLog returns the kernel and audit messages of the current boot.
+func kernelLog() ([]byte, error) {
+	cmd := []string{"journalctl", "-b", "-o", "cat", "--no-pager", "_TRANSPORT=kernel", "_TRANSPORT=audit"}
+	// journalctl can be messy with its stderr
+	output, err := exec.Command(cmd[0], cmd[1:]...).Output()
+	if err != nil {
+		exitCode, _ := osutil.ExitCode(err)
+		return nil, fmt.Errorf("journalctl failed with exit status %d", exitCode)
+	}
+	return output, nil
+}
+
+// KernelLogCmd is called by ForSnap to obtain the kernel log; exported for
+// testing.
+var KernelLogCmd = kernelLog
+
+// ForSnap returns the denials of the given snap since boot, along with the
+// builtin interfaces that would allow each of them.
+func ForSnap(snapName string) ([]*Denial, error) {
+	log, err := KernelLogCmd()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read kernel log: %s", err)
+	}
+	ifaces := builtin.Interfaces()
+	result := []*Denial{}
+	for _, d := range Parse(log) {
+		if d.Snap != snapName {
+			continue
+		}
+		d.Interfaces = Suggest(d, ifaces)
+		result = append(result, d)
+	}
+	return result, nil
+}
@@ -0,0 +1,186 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials_test
+
+import (
+	"errors"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/denials"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type denialsSuite struct {
+	restore func() ([]byte, error)
+}
+
+var _ = Suite(&denialsSuite{})
+
+func (s *denialsSuite) SetUpTest(c *C) {
+	s.restore = denials.KernelLogCmd
+}
+
+func (s *denialsSuite) TearDownTest(c *C) {
+	denials.KernelLogCmd = s.restore
+}
+
+var sampleLog = `[  12.345] audit: type=1400 audit(1465896000.123:42): apparmor="DENIED" operation="open" profile="snap.foo.bar" name="/var/log/syslog" pid=1234 comm="bar" requested_mask="r" denied_mask="r" fsuid=0 ouid=0
+[  12.346] audit: type=1400 audit(1465896000.124:43): apparmor="DENIED" operation="open" profile="snap.foo.bar" name="/var/log/syslog" pid=1234 comm="bar" requested_mask="r" denied_mask="r" fsuid=0 ouid=0
+[  12.347] audit: type=1400 audit(1465896000.125:44): apparmor="ALLOWED" operation="capable" profile="snap.foo.hook.configure" pid=1235 comm="configure" capability=12 capname="net_admin"
+[  12.348] audit: type=1400 audit(1465896000.126:45): apparmor="DENIED" operation="create" profile="snap.foo.bar//null-1" pid=1234 comm="bar" family="netlink" sock_type="raw" protocol=0
+[  12.349] audit: type=1326 audit(1465896000.127:46): auid=4294967295 uid=0 gid=0 ses=4294967295 pid=1234 comm="bar" exe="/snap/foo/x1/usr/bin/bar" sig=31 arch=c000003e syscall=41 compat=0 ip=0x7f1234 code=0x0
+[  12.350] audit: type=1400 audit(1465896000.128:47): apparmor="DENIED" operation="open" profile="/usr/sbin/cupsd" name="/etc/shadow" pid=99 comm="cupsd" requested_mask="r" denied_mask="r" fsuid=0 ouid=0
+[  12.351] audit: type=1400 audit(1465896000.129:48): apparmor="DENIED" operation="open" profile="snap.other.app" name=2F746D702F6120622F63 pid=100 comm="app" requested_mask="wc" denied_mask="wc" fsuid=0 ouid=0
+[  12.352] audit: type=1326 audit(1465896000.130:49): auid=4294967295 uid=0 gid=0 ses=4294967295 pid=99 comm="sh" exe="/bin/sh" sig=31 arch=c000003e syscall=41 compat=0 ip=0x7f1234 code=0x0
+[  12.353] usb 1-1: new high-speed USB device number 2 using xhci_hcd
+`
+
+func (s *denialsSuite) TestParse(c *C) {
+	c.Check(denials.Parse([]byte(sampleLog)), DeepEquals, []*denials.Denial{{
+		Snap: "foo", Kind: "apparmor", SecurityTag: "snap.foo.bar",
+		Operation: "open", Path: "/var/log/syslog", Mask: "r", Count: 2,
+	}, {
+		Snap: "foo", Kind: "apparmor", SecurityTag: "snap.foo.hook.configure", Allowed: true,
+		Operation: "capable", Capability: "net_admin", Count: 1,
+	}, {
+		Snap: "foo", Kind: "apparmor", SecurityTag: "snap.foo.bar",
+		Operation: "create", Network: "netlink raw", Count: 1,
+	}, {
+		Snap: "foo", Kind: "seccomp", Syscall: "socket", Count: 1,
+	}, {
+		Snap: "other", Kind: "apparmor", SecurityTag: "snap.other.app",
+		Operation: "open", Path: "/tmp/a b/c", Mask: "wc", Count: 1,
+	}})
+}
+
+func (s *denialsSuite) TestParseAuditTransport(c *C) {
+	log := `AVC apparmor="DENIED" operation="open" profile="snap.foo.bar" name="/etc/shadow" pid=1 comm="bar" requested_mask="r" denied_mask="r" fsuid=0 ouid=0
+SECCOMP auid=0 uid=0 gid=0 ses=1 pid=1 comm="bar" exe="/snap/foo/x1/bar" sig=31 arch=c000003e syscall=100000 compat=0 ip=0x1 code=0x7ffc0000
+`
+	c.Check(denials.Parse([]byte(log)), DeepEquals, []*denials.Denial{{
+		Snap: "foo", Kind: "apparmor", SecurityTag: "snap.foo.bar",
+		Operation: "open", Path: "/etc/shadow", Mask: "r", Count: 1,
+	}, {
+		Snap: "foo", Kind: "seccomp", Allowed: true, Syscall: "100000", Count: 1,
+	}})
+}
+
+func (s *denialsSuite) TestSnapNameFromSecurityTag(c *C) {
+	name, err := denials.SnapNameFromSecurityTag("snap.foo.bar")
+	c.Assert(err, IsNil)
+	c.Check(name, Equals, "foo")
+	name, err = denials.SnapNameFromSecurityTag("snap.foo.hook.configure")
+	c.Assert(err, IsNil)
+	c.Check(name, Equals, "foo")
+	for _, tag := range []string{"", "snap", "snap.foo", "snap..bar", "/usr/bin/foo"} {
+		_, err = denials.SnapNameFromSecurityTag(tag)
+		c.Check(err, ErrorMatches, "invalid security tag .*")
+	}
+}
+
+func (s *denialsSuite) TestSuggest(c *C) {
+	ifaces := builtin.Interfaces()
+	for _, t := range []struct {
+		denial   denials.Denial
+		expected string
+	}{
+		{denials.Denial{Kind: "apparmor", Path: "/var/log/syslog", Mask: "r"}, "log-observe"},
+		{denials.Denial{Kind: "apparmor", Capability: "net_admin"}, "network-control"},
+		{denials.Denial{Kind: "apparmor", Network: "netlink dgram"}, "network-control"},
+		{denials.Denial{Kind: "seccomp", Syscall: "bind"}, "network-bind"},
+	} {
+		c.Check(denials.Suggest(&t.denial, ifaces), testutil.Contains, t.expected, Commentf("%#v", t.denial))
+	}
+	// Writing is not granted by read-only rules.
+	d := &denials.Denial{Kind: "apparmor", Path: "/var/log/syslog", Mask: "w"}
+	c.Check(denials.Suggest(d, ifaces), Not(testutil.Contains), "log-observe")
+	// Nothing grants everything.
+	d = &denials.Denial{Kind: "apparmor", Path: "/no/such/path", Mask: "r"}
+	c.Check(denials.Suggest(d, ifaces), HasLen, 0)
+}
+
+func (s *denialsSuite) TestSuggestGlobs(c *C) {
+	iface := &interfaces.TestInterface{
+		InterfaceName: "test",
+		PlugSnippetCallback: func(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+			return []byte(`
+# comment
+owner @{PROC}/@{pid}/{stat,status} r,
+/dev/tty[A-Z]* rw,
+/sys/devices/**/uevent r,
+deny /etc/** r,
+`), nil
+		},
+	}
+	ifaces := []interfaces.Interface{iface}
+	for _, t := range []struct {
+		path, mask string
+		allowed    bool
+	}{
+		{"/proc/123/stat", "r", true},
+		{"/proc/123/status", "r", true},
+		{"/proc/123/stat", "w", false},
+		{"/proc/self/stat", "r", false},
+		{"/dev/ttyS0", "wc", true},
+		{"/dev/tty0", "r", false},
+		{"/sys/devices/pci0000:00/0000:00:14.0/uevent", "r", true},
+		{"/etc/passwd", "r", false},
+	} {
+		d := &denials.Denial{Kind: "apparmor", Path: t.path, Mask: t.mask}
+		var expected []string
+		if t.allowed {
+			expected = []string{"test"}
+		}
+		c.Check(denials.Suggest(d, ifaces), DeepEquals, expected, Commentf("%s %s", t.path, t.mask))
+	}
+}
+
+func (s *denialsSuite) TestForSnap(c *C) {
+	denials.KernelLogCmd = func() ([]byte, error) {
+		return []byte(sampleLog), nil
+	}
+	result, err := denials.ForSnap("other")
+	c.Assert(err, IsNil)
+	c.Assert(result, HasLen, 1)
+	c.Check(result[0].Path, Equals, "/tmp/a b/c")
+
+	result, err = denials.ForSnap("foo")
+	c.Assert(err, IsNil)
+	c.Assert(result, HasLen, 4)
+	c.Check(result[0].Interfaces, testutil.Contains, "log-observe")
+
+	result, err = denials.ForSnap("none")
+	c.Assert(err, IsNil)
+	c.Check(result, HasLen, 0)
+}
+
+func (s *denialsSuite) TestForSnapError(c *C) {
+	denials.KernelLogCmd = func() ([]byte, error) {
+		return nil, errors.New("boom")
+	}
+	_, err := denials.ForSnap("foo")
+	c.Check(err, ErrorMatches, "cannot read kernel log: boom")
+}
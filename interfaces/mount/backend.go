@@ -73,6 +73,25 @@ func (b *Backend) Setup(snapInfo *snap.Info, devMode bool, repo *interfaces.Repo
 	return nil
 }
 
+// Verify checks that the mount profile files of a given snap are up to date.
+func (b *Backend) Verify(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (changed, extra []string, err error) {
+	snapName := snapInfo.Name()
+	snippets, err := repo.SecuritySnippetsForSnap(snapName, interfaces.SecurityMount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot obtain mount security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, snippets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot obtain expected mount configuration files for snap %q: %s", snapName, err)
+	}
+	glob := fmt.Sprintf("%s.fstab", interfaces.SecurityTagGlob(snapName))
+	changed, extra, err = osutil.CheckDirState(dirs.SnapMountPolicyDir, glob, content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot check mount profile files for snap %q: %s", snapName, err)
+	}
+	return changed, extra, nil
+}
+
 // Remove removes mount configuration files of a given snap.
 //
 // This method should be called after removing a snap.
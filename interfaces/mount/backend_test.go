@@ -138,3 +138,26 @@ func (s *backendSuite) TestSetupSetsupWithoutDir(c *C) {
 	fn := filepath.Join(dirs.SnapMountPolicyDir, "snap.snap-name.app1.fstab")
 	c.Assert(osutil.FileExists(fn), Equals, true)
 }
+
+func (s *backendSuite) TestVerifyReportsDrift(c *C) {
+	// NOTE: Hand out a permanent snippet so that a file is generated.
+	s.Iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("dummy"), nil
+	}
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	verifier := s.Backend.(interfaces.SecurityBackendVerifier)
+	changed, extra, err := verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, HasLen, 0)
+	c.Check(extra, HasLen, 0)
+	// Tamper with the generated file and add an unexpected one.
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapMountPolicyDir, "snap.samba.smbd.fstab"), []byte("tampered"), 0644)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapMountPolicyDir, "snap.samba.nmbd.fstab"), nil, 0644)
+	c.Assert(err, IsNil)
+	changed, extra, err = verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, DeepEquals, []string{"snap.samba.smbd.fstab"})
+	c.Check(extra, DeepEquals, []string{"snap.samba.nmbd.fstab"})
+	s.RemoveSnap(c, snapInfo)
+}
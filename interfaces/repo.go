@@ -307,7 +307,7 @@ func (r *Repository) Connect(plugSnapName, plugName, slotSnapName, slotName stri
 
 // Disconnect disconnects the named plug from the slot of the given snap.
 //
-// Disconnect has three modes of operation that depend on the passed arguments:
+// Disconnect has four modes of operation that depend on the passed arguments:
 //
 // - If all the arguments are specified then Disconnect() finds a specific slot
 //   and a specific plug and disconnects that plug from that slot. It is
@@ -322,6 +322,10 @@ func (r *Repository) Connect(plugSnapName, plugName, slotSnapName, slotName stri
 //   from all the slots found therein. It is not an error if there are no
 //   such plugs but it is still an error if the snap does not exist or has no
 //   slots at all.
+// - If slotSnapName and slotName are empty then Disconnect() finds the specified
+//   plug and disconnects it from all the slots it is connected to. It is not an
+//   error if there are no such slots but it is still an error if the plug does
+//   not exist.
 func (r *Repository) Disconnect(plugSnapName, plugName, slotSnapName, slotName string) error {
 	r.m.Lock()
 	defer r.m.Unlock()
@@ -333,6 +337,9 @@ func (r *Repository) Disconnect(plugSnapName, plugName, slotSnapName, slotName s
 	case plugSnapName == "" && plugName == "":
 		// Disconnect everything from slotSnapName:slotName
 		return r.disconnectEverythingFromSlot(slotSnapName, slotName)
+	case slotSnapName == "" && slotName == "":
+		// Disconnect everything from plugSnapName:plugName
+		return r.disconnectEverythingFromPlug(plugSnapName, plugName)
 	default:
 		return r.disconnectPlugFromSlot(plugSnapName, plugName, slotSnapName, slotName)
 	}
@@ -365,6 +372,19 @@ func (r *Repository) disconnectEverythingFromSlot(slotSnapName, slotName string)
 	return nil
 }
 
+// disconnectEverythingFromPlug finds a specific plug and disconnects it from all the slots it is connected to.
+func (r *Repository) disconnectEverythingFromPlug(plugSnapName, plugName string) error {
+	// Ensure that such plug exists
+	plug := r.plugs[plugSnapName][plugName]
+	if plug == nil {
+		return fmt.Errorf("cannot disconnect plug %q from snap %q, no such plug", plugName, plugSnapName)
+	}
+	for slot := range r.plugSlots[plug] {
+		r.disconnect(plug, slot)
+	}
+	return nil
+}
+
 // disconnectPlugFromSlot finds a specific slot and plug and disconnects it.
 func (r *Repository) disconnectPlugFromSlot(plugSnapName, plugName, slotSnapName, slotName string) error {
 	// Ensure that such plug exists
@@ -450,6 +470,130 @@ func (r *Repository) Interfaces() *Interfaces {
 	return ifaces
 }
 
+// Connections returns references to all the connections in the repository.
+func (r *Repository) Connections() []ConnRef {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	var conns []ConnRef
+	for plug, slots := range r.plugSlots {
+		for slot := range slots {
+			conns = append(conns, ConnRef{
+				PlugRef: PlugRef{Snap: plug.Snap.Name(), Name: plug.Name},
+				SlotRef: SlotRef{Snap: slot.Snap.Name(), Name: slot.Name},
+			})
+		}
+	}
+	sort.Sort(byConnRef(conns))
+	return conns
+}
+
+// CheckConsistency checks that the connections recorded in the repository
+// agree with each other. Connections are tracked both from the side of the
+// plug and from the side of the slot, as well as in the lists of connections
+// of each plug and slot. The returned list describes each disagreement.
+func (r *Repository) CheckConsistency() []string {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	var problems []string
+	for plug, slots := range r.plugSlots {
+		if r.plugs[plug.Snap.Name()][plug.Name] != plug {
+			problems = append(problems, fmt.Sprintf("connected plug %s:%s is not in the repository", plug.Snap.Name(), plug.Name))
+		}
+		for slot := range slots {
+			if !r.slotPlugs[slot][plug] {
+				problems = append(problems, fmt.Sprintf("connection %s:%s %s:%s is not tracked by the slot",
+					plug.Snap.Name(), plug.Name, slot.Snap.Name(), slot.Name))
+			}
+		}
+		if refs := plugConnections(slots); !sameSlotRefs(refs, plug.Connections) {
+			problems = append(problems, fmt.Sprintf("connections of plug %s:%s are %v, expected %v",
+				plug.Snap.Name(), plug.Name, plug.Connections, refs))
+		}
+	}
+	for slot, plugs := range r.slotPlugs {
+		if r.slots[slot.Snap.Name()][slot.Name] != slot {
+			problems = append(problems, fmt.Sprintf("connected slot %s:%s is not in the repository", slot.Snap.Name(), slot.Name))
+		}
+		for plug := range plugs {
+			if !r.plugSlots[plug][slot] {
+				problems = append(problems, fmt.Sprintf("connection %s:%s %s:%s is not tracked by the plug",
+					plug.Snap.Name(), plug.Name, slot.Snap.Name(), slot.Name))
+			}
+		}
+		if refs := slotConnections(plugs); !samePlugRefs(refs, slot.Connections) {
+			problems = append(problems, fmt.Sprintf("connections of slot %s:%s are %v, expected %v",
+				slot.Snap.Name(), slot.Name, slot.Connections, refs))
+		}
+	}
+	// Plugs and slots that are not connected must not claim any connections.
+	for _, plugs := range r.plugs {
+		for _, plug := range plugs {
+			if len(r.plugSlots[plug]) == 0 && len(plug.Connections) != 0 {
+				problems = append(problems, fmt.Sprintf("connections of plug %s:%s are %v, expected none",
+					plug.Snap.Name(), plug.Name, plug.Connections))
+			}
+		}
+	}
+	for _, slots := range r.slots {
+		for _, slot := range slots {
+			if len(r.slotPlugs[slot]) == 0 && len(slot.Connections) != 0 {
+				problems = append(problems, fmt.Sprintf("connections of slot %s:%s are %v, expected none",
+					slot.Snap.Name(), slot.Name, slot.Connections))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func plugConnections(slots map[*Slot]bool) []SlotRef {
+	refs := make([]SlotRef, 0, len(slots))
+	for slot := range slots {
+		refs = append(refs, SlotRef{Snap: slot.Snap.Name(), Name: slot.Name})
+	}
+	sort.Sort(bySlotRef(refs))
+	return refs
+}
+
+func slotConnections(plugs map[*Plug]bool) []PlugRef {
+	refs := make([]PlugRef, 0, len(plugs))
+	for plug := range plugs {
+		refs = append(refs, PlugRef{Snap: plug.Snap.Name(), Name: plug.Name})
+	}
+	sort.Sort(byPlugRef(refs))
+	return refs
+}
+
+func sameSlotRefs(sorted, refs []SlotRef) bool {
+	refs = append([]SlotRef(nil), refs...)
+	sort.Sort(bySlotRef(refs))
+	if len(sorted) != len(refs) {
+		return false
+	}
+	for i := range sorted {
+		if sorted[i] != refs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func samePlugRefs(sorted, refs []PlugRef) bool {
+	refs = append([]PlugRef(nil), refs...)
+	sort.Sort(byPlugRef(refs))
+	if len(sorted) != len(refs) {
+		return false
+	}
+	for i := range sorted {
+		if sorted[i] != refs[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // SecuritySnippetsForSnap collects all of the snippets of a given security
 // system that affect a given snap. The return value is indexed by app/hook
 // security tag within that snap.
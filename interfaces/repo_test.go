@@ -622,6 +622,14 @@ func (s *RepositorySuite) TestDisconnectFromSnapFailsWhenSlotDoesNotExist(c *C)
 	c.Assert(err, ErrorMatches, `cannot disconnect plug from snap "producer", no such snap`)
 }
 
+func (s *RepositorySuite) TestDisconnectFromPlugFailsWhenPlugDoesNotExist(c *C) {
+	err := s.testRepo.AddSlot(s.slot)
+	c.Assert(err, IsNil)
+	// Disconnecting everything from an unknown plug returns an appropriate error
+	err = s.testRepo.Disconnect(s.plug.Snap.Name(), s.plug.Name, "", "")
+	c.Assert(err, ErrorMatches, `cannot disconnect plug "plug" from snap "consumer", no such plug`)
+}
+
 func (s *RepositorySuite) TestDisconnectFailsWhenNotConnected(c *C) {
 	err := s.testRepo.AddPlug(s.plug)
 	c.Assert(err, IsNil)
@@ -700,6 +708,30 @@ func (s *RepositorySuite) TestDisconnectFromSlot(c *C) {
 	})
 }
 
+func (s *RepositorySuite) TestDisconnectFromPlugDoesNothingWhenNotConnected(c *C) {
+	err := s.testRepo.AddPlug(s.plug)
+	c.Assert(err, IsNil)
+	// Disconnecting a plug that is not connected to anything is not an error.
+	err = s.testRepo.Disconnect(s.plug.Snap.Name(), s.plug.Name, "", "")
+	c.Assert(err, IsNil)
+}
+
+func (s *RepositorySuite) TestDisconnectFromPlug(c *C) {
+	err := s.testRepo.AddPlug(s.plug)
+	c.Assert(err, IsNil)
+	err = s.testRepo.AddSlot(s.slot)
+	c.Assert(err, IsNil)
+	err = s.testRepo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, IsNil)
+	// Disconnecting everything from a plug works OK
+	err = s.testRepo.Disconnect(s.plug.Snap.Name(), s.plug.Name, "", "")
+	c.Assert(err, IsNil)
+	c.Assert(s.testRepo.Interfaces(), DeepEquals, &Interfaces{
+		Plugs: []*Plug{{PlugInfo: s.plug.PlugInfo}},
+		Slots: []*Slot{{SlotInfo: s.slot.SlotInfo}},
+	})
+}
+
 // Tests for Repository.Interfaces()
 
 func (s *RepositorySuite) TestInterfacesSmokeTest(c *C) {
@@ -731,6 +763,57 @@ func (s *RepositorySuite) TestInterfacesSmokeTest(c *C) {
 	})
 }
 
+// Tests for Repository.Connections()
+
+func (s *RepositorySuite) TestConnections(c *C) {
+	err := s.testRepo.AddPlug(s.plug)
+	c.Assert(err, IsNil)
+	err = s.testRepo.AddSlot(s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(s.testRepo.Connections(), HasLen, 0)
+	err = s.testRepo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, IsNil)
+	conns := s.testRepo.Connections()
+	c.Assert(conns, DeepEquals, []ConnRef{{
+		PlugRef: PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: SlotRef{Snap: "producer", Name: "slot"},
+	}})
+	c.Check(conns[0].ID(), Equals, "consumer:plug producer:slot")
+}
+
+// Tests for Repository.CheckConsistency()
+
+func (s *RepositorySuite) TestCheckConsistencyOK(c *C) {
+	err := s.testRepo.AddPlug(s.plug)
+	c.Assert(err, IsNil)
+	err = s.testRepo.AddSlot(s.slot)
+	c.Assert(err, IsNil)
+	err = s.testRepo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, IsNil)
+	c.Check(s.testRepo.CheckConsistency(), HasLen, 0)
+}
+
+func (s *RepositorySuite) TestCheckConsistencyFindsDrift(c *C) {
+	err := s.testRepo.AddPlug(s.plug)
+	c.Assert(err, IsNil)
+	err = s.testRepo.AddSlot(s.slot)
+	c.Assert(err, IsNil)
+	err = s.testRepo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, IsNil)
+	// Forget the connection in the list kept by the slot.
+	s.slot.Connections = nil
+	c.Check(s.testRepo.CheckConsistency(), DeepEquals, []string{
+		"connections of slot producer:slot are [], expected [{consumer plug}]",
+	})
+	// Claim a connection on a plug that is not connected.
+	err = s.testRepo.Disconnect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, IsNil)
+	s.plug.Connections = []SlotRef{{Snap: "producer", Name: "slot"}}
+	c.Check(s.testRepo.CheckConsistency(), DeepEquals, []string{
+		"connections of plug consumer:plug are [{producer slot}], expected none",
+	})
+}
+
 // Tests for Repository.SecuritySnippetsForSnap()
 
 func (s *RepositorySuite) TestSlotSnippetsForSnapSuccess(c *C) {
@@ -22,11 +22,14 @@
 //
 // Snappy creates so-called seccomp profiles for each application (for each
 // snap) present in the system.  Upon each execution of ubuntu-core-launcher,
-// the profile is read and "compiled" to an eBPF program and injected into the
-// kernel for the duration of the execution of the process.
+// the profile is injected into the kernel for the duration of the execution of
+// the process.
 //
-// There is no binary cache for seccomp, each time the launcher starts an
-// application the profile is parsed and re-compiled.
+// Next to each profile snappy stores the BPF program compiled from it, in a
+// file with the ".bin" suffix, so that the launcher does not need to parse
+// and compile the profile each time it starts an application. The plain text
+// profile is kept for launchers that do not use the compiled program and on
+// architectures for which snappy cannot compile profiles.
 //
 // The actual profiles are stored in /var/lib/snappy/seccomp/profiles.
 // This directory is hard-coded in ubuntu-core-launcher.
@@ -37,6 +40,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/osutil"
@@ -81,6 +85,25 @@ func (b *Backend) Setup(snapInfo *snap.Info, devMode bool, repo *interfaces.Repo
 	return nil
 }
 
+// Verify checks that the seccomp profiles of a given snap are up to date.
+func (b *Backend) Verify(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (changed, extra []string, err error) {
+	snapName := snapInfo.Name()
+	snippets, err := repo.SecuritySnippetsForSnap(snapName, interfaces.SecuritySecComp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot obtain security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, devMode, snippets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapName, err)
+	}
+	glob := interfaces.SecurityTagGlob(snapName)
+	changed, extra, err = osutil.CheckDirState(dirs.SnapSeccompDir, glob, content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot check security files for snap %q: %s", snapName, err)
+	}
+	return changed, extra, nil
+}
+
 // Remove removes seccomp profiles of a given snap.
 func (b *Backend) Remove(snapName string) error {
 	glob := interfaces.SecurityTagGlob(snapName)
@@ -98,20 +121,27 @@ func (b *Backend) combineSnippets(snapInfo *snap.Info, devMode bool, snippets ma
 		if content == nil {
 			content = make(map[string]*osutil.FileState)
 		}
-		addContent(appInfo.SecurityTag(), devMode, snippets, content)
+		if err := addContent(appInfo.SecurityTag(), devMode, snippets, content); err != nil {
+			return nil, err
+		}
 	}
 
 	for _, hookInfo := range snapInfo.Hooks {
 		if content == nil {
 			content = make(map[string]*osutil.FileState)
 		}
-		addContent(hookInfo.SecurityTag(), devMode, snippets, content)
+		if err := addContent(hookInfo.SecurityTag(), devMode, snippets, content); err != nil {
+			return nil, err
+		}
 	}
 
 	return content, nil
 }
 
-func addContent(securityTag string, devMode bool, snippets map[string][][]byte, content map[string]*osutil.FileState) {
+// compiledSuffix is the suffix of the files holding compiled profiles.
+const compiledSuffix = ".bin"
+
+func addContent(securityTag string, devMode bool, snippets map[string][][]byte, content map[string]*osutil.FileState) error {
 	var buffer bytes.Buffer
 	if devMode {
 		// NOTE: This is understood by ubuntu-core-launcher
@@ -128,4 +158,18 @@ func addContent(securityTag string, devMode bool, snippets map[string][][]byte,
 		Content: buffer.Bytes(),
 		Mode:    0644,
 	}
+
+	archName := arch.UbuntuArchitecture()
+	if _, ok := archs[archName]; !ok {
+		return nil
+	}
+	prog, err := compile(buffer.Bytes(), archName)
+	if err != nil {
+		return fmt.Errorf("cannot compile seccomp profile %q: %s", securityTag, err)
+	}
+	content[securityTag+compiledSuffix] = &osutil.FileState{
+		Content: prog,
+		Mode:    0644,
+	}
+	return nil
 }
@@ -26,6 +26,7 @@ import (
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/backendtest"
@@ -68,6 +69,43 @@ func (s *backendSuite) TestInstallingSnapWritesProfiles(c *C) {
 	c.Check(err, IsNil)
 }
 
+func (s *backendSuite) TestInstallingSnapWritesCompiledProfiles(c *C) {
+	defer arch.SetArchitecture(arch.ArchitectureType(arch.UbuntuArchitecture()))
+	arch.SetArchitecture("amd64")
+	restore := seccomp.MockTemplate([]byte("read\nwrite\n"))
+	defer restore()
+	s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	data, err := ioutil.ReadFile(filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd.bin"))
+	c.Assert(err, IsNil)
+	prog, err := seccomp.Compile([]byte("read\nwrite\n"), "amd64")
+	c.Assert(err, IsNil)
+	c.Check(data, DeepEquals, prog)
+}
+
+func (s *backendSuite) TestInstallingSnapSkipsCompilationOnUnsupportedArch(c *C) {
+	defer arch.SetArchitecture(arch.ArchitectureType(arch.UbuntuArchitecture()))
+	arch.SetArchitecture("powerpc")
+	s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	_, err := os.Stat(filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd"))
+	c.Check(err, IsNil)
+	_, err = os.Stat(filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd.bin"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *backendSuite) TestInstallingSnapWithBrokenSnippetFails(c *C) {
+	defer arch.SetArchitecture(arch.ArchitectureType(arch.UbuntuArchitecture()))
+	arch.SetArchitecture("amd64")
+	s.Iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("socket AF_FOO"), nil
+	}
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(backendtest.SambaYamlV1))
+	c.Assert(err, IsNil)
+	err = s.Repo.AddSnap(snapInfo)
+	c.Assert(err, IsNil)
+	err = s.Backend.Setup(snapInfo, false, s.Repo)
+	c.Assert(err, ErrorMatches, `cannot obtain expected security files for snap "samba": cannot compile seccomp profile "snap.samba.smbd": .*`)
+}
+
 func (s *backendSuite) TestInstallingSnapWritesHookProfiles(c *C) {
 	devMode := false
 	s.InstallSnap(c, devMode, backendtest.HookYaml, 0)
@@ -212,3 +250,22 @@ func (s *backendSuite) TestCombineSnippets(c *C) {
 		s.RemoveSnap(c, snapInfo)
 	}
 }
+
+func (s *backendSuite) TestVerifyReportsDrift(c *C) {
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	verifier := s.Backend.(interfaces.SecurityBackendVerifier)
+	changed, extra, err := verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, HasLen, 0)
+	c.Check(extra, HasLen, 0)
+	// Tamper with the generated file and add an unexpected one.
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd"), []byte("tampered"), 0644)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapSeccompDir, "snap.samba.nmbd"), nil, 0644)
+	c.Assert(err, IsNil)
+	changed, extra, err = verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, DeepEquals, []string{"snap.samba.smbd"})
+	c.Check(extra, DeepEquals, []string{"snap.samba.nmbd"})
+	s.RemoveSnap(c, snapInfo)
+}
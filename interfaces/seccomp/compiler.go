@@ -0,0 +1,348 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seccomp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Classic BPF instructions used by the compiled programs.
+const (
+	bpfLdAbsW = 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfJeqK   = 0x15 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfJgeK   = 0x35 // BPF_JMP | BPF_JGE | BPF_K
+	bpfRetK   = 0x06 // BPF_RET | BPF_K
+)
+
+// Return values of seccomp filters.
+const (
+	seccompRetKill  = 0x00000000
+	seccompRetAllow = 0x7fff0000
+)
+
+// Offsets of the fields of struct seccomp_data.
+const (
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArgs = 16
+)
+
+// maxInsns is the maximum length of BPF programs accepted by the kernel.
+const maxInsns = 4096
+
+// maxArgs is the number of system call arguments visible to seccomp filters.
+const maxArgs = 6
+
+// x32SyscallBit is set in the numbers of x32 system calls on amd64. Those
+// share the audit architecture with the amd64 ones and are never allowed.
+const x32SyscallBit = 0x40000000
+
+type archInfo struct {
+	auditArch uint32
+	order     binary.ByteOrder
+}
+
+// archs describes the architectures for which profiles can be compiled,
+// indexed by their Ubuntu name.
+var archs = map[string]archInfo{
+	"amd64":   {auditArch: 0xc000003e, order: binary.LittleEndian},
+	"arm64":   {auditArch: 0xc00000b7, order: binary.LittleEndian},
+	"armhf":   {auditArch: 0x40000028, order: binary.LittleEndian},
+	"i386":    {auditArch: 0x40000003, order: binary.LittleEndian},
+	"ppc64el": {auditArch: 0xc0000015, order: binary.LittleEndian},
+	"s390x":   {auditArch: 0x80000016, order: binary.BigEndian},
+}
+
+// argConstants are the symbolic names that can be used for argument values.
+var argConstants = map[string]uint64{
+	"AF_UNSPEC":    0,
+	"AF_UNIX":      1,
+	"AF_LOCAL":     1,
+	"AF_INET":      2,
+	"AF_AX25":      3,
+	"AF_IPX":       4,
+	"AF_APPLETALK": 5,
+	"AF_X25":       9,
+	"AF_INET6":     10,
+	"AF_KEY":       15,
+	"AF_NETLINK":   16,
+	"AF_PACKET":    17,
+	"AF_CAN":       29,
+	"AF_BLUETOOTH": 31,
+	"AF_ALG":       38,
+
+	"SOCK_STREAM":    1,
+	"SOCK_DGRAM":     2,
+	"SOCK_RAW":       3,
+	"SOCK_RDM":       4,
+	"SOCK_SEQPACKET": 5,
+	"SOCK_PACKET":    10,
+
+	"NETLINK_ROUTE":          0,
+	"NETLINK_USERSOCK":       2,
+	"NETLINK_FIREWALL":       3,
+	"NETLINK_SOCK_DIAG":      4,
+	"NETLINK_NFLOG":          5,
+	"NETLINK_XFRM":           6,
+	"NETLINK_SELINUX":        7,
+	"NETLINK_ISCSI":          8,
+	"NETLINK_AUDIT":          9,
+	"NETLINK_FIB_LOOKUP":     10,
+	"NETLINK_CONNECTOR":      11,
+	"NETLINK_NETFILTER":      12,
+	"NETLINK_IP6_FW":         13,
+	"NETLINK_DNRTMSG":        14,
+	"NETLINK_KOBJECT_UEVENT": 15,
+	"NETLINK_GENERIC":        16,
+	"NETLINK_SCSITRANSPORT":  18,
+	"NETLINK_ECRYPTFS":       19,
+	"NETLINK_RDMA":           20,
+	"NETLINK_CRYPTO":         21,
+}
+
+// syscallRule allows a system call when all of its non-nil arguments match.
+type syscallRule struct {
+	args [maxArgs]*uint64
+}
+
+func (r *syscallRule) unconditional() bool {
+	for _, arg := range r.args {
+		if arg != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// profile is a parsed seccomp profile.
+type profile struct {
+	// complain is set by the @complain directive, used for snaps in
+	// developer mode.
+	complain bool
+	// unrestricted is set by the @unrestricted directive.
+	unrestricted bool
+	// syscalls lists the allowed system calls, in the order of appearance.
+	syscalls []string
+	rules    map[string][]syscallRule
+}
+
+// parseProfile parses a seccomp profile in the format understood by
+// ubuntu-core-launcher.
+//
+// Each line names an allowed system call, optionally followed by the values
+// of its arguments, in order. An argument given as "-" can have any value,
+// other arguments are either numbers or one of the names in argConstants.
+// For example "socket AF_NETLINK - NETLINK_KOBJECT_UEVENT" allows opening
+// netlink sockets of any type for receiving kernel uevents only. Lines for the
+// same system call are alternatives.
+func parseProfile(data []byte) (*profile, error) {
+	p := &profile{rules: make(map[string][]syscallRule)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexRune(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "@complain":
+			p.complain = true
+			continue
+		case "@unrestricted":
+			p.unrestricted = true
+			continue
+		}
+		if strings.HasPrefix(fields[0], "@") {
+			return nil, fmt.Errorf("unsupported directive %q", fields[0])
+		}
+		name, args := fields[0], fields[1:]
+		if len(args) > maxArgs {
+			return nil, fmt.Errorf("too many arguments for system call %q", name)
+		}
+		var rule syscallRule
+		for i, arg := range args {
+			if arg == "-" {
+				continue
+			}
+			value, err := parseArg(arg)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse argument %d of system call %q: %s", i, name, err)
+			}
+			rule.args[i] = &value
+		}
+		if _, ok := p.rules[name]; !ok {
+			p.syscalls = append(p.syscalls, name)
+		}
+		p.rules[name] = append(p.rules[name], rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func parseArg(arg string) (uint64, error) {
+	if value, ok := argConstants[arg]; ok {
+		return value, nil
+	}
+	value, err := strconv.ParseUint(arg, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", arg)
+	}
+	return value, nil
+}
+
+type bpfInsn struct {
+	code   uint16
+	jt, jf uint8
+	k      uint32
+}
+
+func stmt(code uint16, k uint32) bpfInsn {
+	return bpfInsn{code: code, k: k}
+}
+
+func jump(code uint16, k uint32, jt, jf uint8) bpfInsn {
+	return bpfInsn{code: code, jt: jt, jf: jf, k: k}
+}
+
+// compileRules returns the instructions checking the arguments of a system
+// call, to be run once the system call number matched. System calls not
+// matching any of the rules get the default action.
+func compileRules(rules []syscallRule, info archInfo, defaultAction uint32) []bpfInsn {
+	for _, rule := range rules {
+		if rule.unconditional() {
+			return []bpfInsn{stmt(bpfRetK, seccompRetAllow)}
+		}
+	}
+	// Offsets of the low and high words of an argument in seccomp_data.
+	lo, hi := uint32(0), uint32(4)
+	if info.order == binary.BigEndian {
+		lo, hi = 4, 0
+	}
+	var prog []bpfInsn
+	for _, rule := range rules {
+		var checks []bpfInsn
+		for i, arg := range rule.args {
+			if arg == nil {
+				continue
+			}
+			offset := uint32(seccompDataArgs + 8*i)
+			checks = append(checks,
+				stmt(bpfLdAbsW, offset+lo), jump(bpfJeqK, uint32(*arg), 0, 0),
+				stmt(bpfLdAbsW, offset+hi), jump(bpfJeqK, uint32(*arg>>32), 0, 0))
+		}
+		checks = append(checks, stmt(bpfRetK, seccompRetAllow))
+		// On mismatch skip to the first instruction after the rule.
+		for j := range checks {
+			if checks[j].code == bpfJeqK {
+				checks[j].jf = uint8(len(checks) - j - 1)
+			}
+		}
+		prog = append(prog, checks...)
+	}
+	return append(prog, stmt(bpfRetK, defaultAction))
+}
+
+// compile compiles a seccomp profile to a BPF program for the given
+// architecture, as expected by the SECCOMP_SET_MODE_FILTER operation of the
+// seccomp system call.
+//
+// System calls not known on the architecture are ignored.
+func compile(data []byte, archName string) ([]byte, error) {
+	info, ok := archs[archName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported architecture %q", archName)
+	}
+	p, err := parseProfile(data)
+	if err != nil {
+		return nil, err
+	}
+	var prog []bpfInsn
+	if p.complain || p.unrestricted {
+		prog = []bpfInsn{stmt(bpfRetK, seccompRetAllow)}
+	} else {
+		defaultAction := uint32(seccompRetKill)
+		prog = []bpfInsn{
+			stmt(bpfLdAbsW, seccompDataArch),
+			jump(bpfJeqK, info.auditArch, 1, 0),
+			stmt(bpfRetK, defaultAction),
+			stmt(bpfLdAbsW, seccompDataNr),
+			jump(bpfJgeK, x32SyscallBit, 0, 1),
+			stmt(bpfRetK, defaultAction),
+		}
+		numbers := syscallNumbers[archName]
+		for _, name := range p.syscalls {
+			nr, ok := numbers[name]
+			if !ok {
+				continue
+			}
+			checks := compileRules(p.rules[name], info, defaultAction)
+			if len(checks) > 255 {
+				return nil, fmt.Errorf("too many rules for system call %q", name)
+			}
+			// Arguments checks clobber the loaded system call number so
+			// they always end with a return.
+			prog = append(prog, jump(bpfJeqK, nr, 0, uint8(len(checks))))
+			prog = append(prog, checks...)
+		}
+		prog = append(prog, stmt(bpfRetK, defaultAction))
+	}
+	if len(prog) > maxInsns {
+		return nil, fmt.Errorf("program too long (%d instructions)", len(prog))
+	}
+
+	buf := make([]byte, 8*len(prog))
+	for i, insn := range prog {
+		b := buf[8*i:]
+		info.order.PutUint16(b[0:], insn.code)
+		b[2] = insn.jt
+		b[3] = insn.jf
+		info.order.PutUint32(b[4:], insn.k)
+	}
+	return buf, nil
+}
+
+// SyscallName returns the name of a system call given its number and the
+// audit architecture it was made on, as found in seccomp audit records. The
+// empty string is returned for system calls that are not known.
+func SyscallName(auditArch, nr uint32) string {
+	found := ""
+	for archName, info := range archs {
+		if info.auditArch != auditArch {
+			continue
+		}
+		// A few system calls have aliases, always pick the same name.
+		for name, number := range syscallNumbers[archName] {
+			if number == nr && (found == "" || name < found) {
+				found = name
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,169 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seccomp_test
+
+import (
+	"encoding/binary"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/seccomp"
+)
+
+type compilerSuite struct{}
+
+var _ = Suite(&compilerSuite{})
+
+const (
+	auditArchX86_64 = 0xc000003e
+	auditArchS390X  = 0x80000016
+	retKill         = 0x00000000
+	retAllow        = 0x7fff0000
+)
+
+// Some system call numbers on amd64.
+const (
+	sysRead   = 0
+	sysWrite  = 1
+	sysSocket = 41
+)
+
+// run interprets the subset of classic BPF used by compiled profiles against
+// the given seccomp_data fields.
+func run(c *C, prog []byte, order binary.ByteOrder, auditArch, nr uint32, args ...uint64) uint32 {
+	data := make([]byte, 64)
+	order.PutUint32(data[0:], nr)
+	order.PutUint32(data[4:], auditArch)
+	for i, arg := range args {
+		order.PutUint64(data[16+8*i:], arg)
+	}
+	c.Assert(len(prog)%8, Equals, 0)
+	var a uint32
+	for pc := 0; pc < len(prog)/8; pc++ {
+		insn := prog[8*pc:]
+		code := order.Uint16(insn[0:])
+		jt, jf := int(insn[2]), int(insn[3])
+		k := order.Uint32(insn[4:])
+		switch code {
+		case 0x20:
+			a = order.Uint32(data[k:])
+		case 0x15:
+			if a == k {
+				pc += jt
+			} else {
+				pc += jf
+			}
+		case 0x35:
+			if a >= k {
+				pc += jt
+			} else {
+				pc += jf
+			}
+		case 0x06:
+			return k
+		default:
+			c.Fatalf("unexpected instruction %#x", code)
+		}
+	}
+	c.Fatalf("program did not return")
+	return 0
+}
+
+func (s *compilerSuite) TestCompileAllowsListedSyscalls(c *C) {
+	prog, err := seccomp.Compile([]byte("# comment\nread\n\nno-such-syscall\n"), "amd64")
+	c.Assert(err, IsNil)
+	le := binary.LittleEndian
+	c.Check(run(c, prog, le, auditArchX86_64, sysRead), Equals, uint32(retAllow))
+	c.Check(run(c, prog, le, auditArchX86_64, sysWrite), Equals, uint32(retKill))
+	// Other architectures and x32 system calls are rejected.
+	c.Check(run(c, prog, le, 0x40000003, sysRead), Equals, uint32(retKill))
+	c.Check(run(c, prog, le, auditArchX86_64, 0x40000000|sysRead), Equals, uint32(retKill))
+}
+
+func (s *compilerSuite) TestCompileFiltersArguments(c *C) {
+	prog, err := seccomp.Compile([]byte(`
+socket AF_NETLINK - NETLINK_KOBJECT_UEVENT
+socket AF_UNIX
+write 1
+`), "amd64")
+	c.Assert(err, IsNil)
+	le := binary.LittleEndian
+	for _, t := range []struct {
+		nr     uint32
+		args   []uint64
+		result uint32
+	}{
+		{sysSocket, []uint64{16, 3, 15}, retAllow},
+		{sysSocket, []uint64{16, 2, 15}, retAllow},
+		{sysSocket, []uint64{16, 3, 0}, retKill},
+		{sysSocket, []uint64{1, 1, 0}, retAllow},
+		{sysSocket, []uint64{2, 1, 0}, retKill},
+		{sysSocket, []uint64{1 | 1<<32, 1, 0}, retKill},
+		{sysWrite, []uint64{1}, retAllow},
+		{sysWrite, []uint64{2}, retKill},
+		{sysRead, nil, retKill},
+	} {
+		c.Check(run(c, prog, le, auditArchX86_64, t.nr, t.args...), Equals, t.result, Commentf("%d %v", t.nr, t.args))
+	}
+}
+
+func (s *compilerSuite) TestCompileUnconditionalRuleWins(c *C) {
+	prog, err := seccomp.Compile([]byte("socket AF_UNIX\nsocket\n"), "amd64")
+	c.Assert(err, IsNil)
+	c.Check(run(c, prog, binary.LittleEndian, auditArchX86_64, sysSocket, 2), Equals, uint32(retAllow))
+}
+
+func (s *compilerSuite) TestCompileBigEndian(c *C) {
+	// socket is 359 on s390x
+	prog, err := seccomp.Compile([]byte("socket AF_INET\n"), "s390x")
+	c.Assert(err, IsNil)
+	be := binary.BigEndian
+	c.Check(run(c, prog, be, auditArchS390X, 359, 2), Equals, uint32(retAllow))
+	c.Check(run(c, prog, be, auditArchS390X, 359, 1), Equals, uint32(retKill))
+}
+
+func (s *compilerSuite) TestCompileComplain(c *C) {
+	for _, directive := range []string{"@complain", "@unrestricted"} {
+		prog, err := seccomp.Compile([]byte(directive+"\nread\n"), "amd64")
+		c.Assert(err, IsNil)
+		c.Check(run(c, prog, binary.LittleEndian, auditArchX86_64, sysWrite), Equals, uint32(retAllow))
+	}
+}
+
+func (s *compilerSuite) TestCompileErrors(c *C) {
+	for _, t := range []struct {
+		profile, arch, err string
+	}{
+		{"read\n", "powerpc", `unsupported architecture "powerpc"`},
+		{"@deny ptrace\n", "amd64", `unsupported directive "@deny"`},
+		{"socket AF_FOO\n", "amd64", `cannot parse argument 0 of system call "socket": invalid value "AF_FOO"`},
+		{"read 1 2 3 4 5 6 7\n", "amd64", `too many arguments for system call "read"`},
+	} {
+		_, err := seccomp.Compile([]byte(t.profile), t.arch)
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *compilerSuite) TestSyscallName(c *C) {
+	c.Check(seccomp.SyscallName(auditArchX86_64, sysSocket), Equals, "socket")
+	c.Check(seccomp.SyscallName(auditArchS390X, 359), Equals, "socket")
+	c.Check(seccomp.SyscallName(auditArchX86_64, 100000), Equals, "")
+	c.Check(seccomp.SyscallName(0x1234, sysRead), Equals, "")
+}
@@ -28,3 +28,6 @@ func MockTemplate(fakeTemplate []byte) (restore func()) {
 	defaultTemplate = fakeTemplate
 	return func() { defaultTemplate = orig }
 }
+
+// Compile exposes the profile compiler for testing.
+var Compile = compile
@@ -62,3 +62,11 @@ func (c bySlotSnapAndName) Less(i, j int) bool {
 	}
 	return c[i].Name < c[j].Name
 }
+
+type byConnRef []ConnRef
+
+func (c byConnRef) Len() int      { return len(c) }
+func (c byConnRef) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byConnRef) Less(i, j int) bool {
+	return c[i].ID() < c[j].ID()
+}
@@ -0,0 +1,202 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package systemd implements integration between snappy interfaces and
+// systemd.
+//
+// Interfaces may need something done on the system for plugs and slots to
+// work, like exporting a GPIO pin to user space. Snippets of the systemd
+// security system describe services doing that. The services of all the
+// interfaces affecting a snap are installed, enabled and started together and
+// are stopped and removed when no longer needed.
+package systemd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	sysd "github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timeout"
+)
+
+// Backend is responsible for maintaining the services needed by interfaces.
+type Backend struct{}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() string {
+	return "systemd"
+}
+
+// Setup installs and starts the services needed by the interfaces of a given
+// snap and stops and removes the ones that are no longer needed.
+//
+// Since services are not confined, devMode is ignored.
+//
+// If the method fails it should be re-tried (with a sensible strategy) by the caller.
+func (b *Backend) Setup(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) error {
+	snapName := snapInfo.Name()
+	content, err := b.expectedContent(snapName, repo)
+	if err != nil {
+		return err
+	}
+	dir := dirs.SnapServicesDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory for systemd services %q: %s", dir, err)
+	}
+	return ensureServices(snapName, content)
+}
+
+// Verify checks that the services of a given snap are up to date.
+func (b *Backend) Verify(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (changed, extra []string, err error) {
+	snapName := snapInfo.Name()
+	content, err := b.expectedContent(snapName, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	changed, extra, err = osutil.CheckDirState(dirs.SnapServicesDir, serviceGlob(snapName), content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot check systemd services for snap %q: %s", snapName, err)
+	}
+	return changed, extra, nil
+}
+
+// Remove stops and removes the services of a given snap.
+//
+// This method should be called after removing a snap.
+//
+// If the method fails it should be re-tried (with a sensible strategy) by the caller.
+func (b *Backend) Remove(snapName string) error {
+	return ensureServices(snapName, nil)
+}
+
+func (b *Backend) expectedContent(snapName string, repo *interfaces.Repository) (map[string]*osutil.FileState, error) {
+	snippets, err := repo.SecuritySnippetsForSnap(snapName, interfaces.SecuritySystemd)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain systemd security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := combineSnippets(snapName, snippets)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain expected systemd services for snap %q: %s", snapName, err)
+	}
+	return content, nil
+}
+
+func serviceGlob(snapName string) string {
+	return fmt.Sprintf("snap.%s.interface.*.service", snapName)
+}
+
+func serviceFileName(snapName, name string) string {
+	return fmt.Sprintf("snap.%s.interface.%s.service", snapName, name)
+}
+
+// combineSnippets combines security snippets collected from all the interfaces
+// affecting a given snap into a content map applicable to EnsureDirState.
+//
+// Services are not specific to applications so the services asked for the
+// different applications of the snap are merged.
+func combineSnippets(snapName string, snippets map[string][][]byte) (content map[string]*osutil.FileState, err error) {
+	securityTags := make([]string, 0, len(snippets))
+	for securityTag := range snippets {
+		securityTags = append(securityTags, securityTag)
+	}
+	sort.Strings(securityTags)
+	var combined Snippet
+	for _, securityTag := range securityTags {
+		for _, data := range snippets[securityTag] {
+			snippet, err := parseSnippet(data)
+			if err != nil {
+				return nil, err
+			}
+			if err := combined.merge(snippet); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for name, service := range combined.Services {
+		if content == nil {
+			content = make(map[string]*osutil.FileState)
+		}
+		content[serviceFileName(snapName, name)] = &osutil.FileState{Content: []byte(service.String()), Mode: 0644}
+	}
+	return content, nil
+}
+
+type dummyReporter struct{}
+
+func (dummyReporter) Notify(msg string) {}
+
+// ensureServices makes the services of a given snap match the content map,
+// stopping the services that go away and (re)starting the ones that change.
+func ensureServices(snapName string, content map[string]*osutil.FileState) error {
+	dir := dirs.SnapServicesDir
+	glob := serviceGlob(snapName)
+	systemd := sysd.New(dirs.GlobalRootDir, dummyReporter{})
+	stopTimeout := time.Duration(timeout.DefaultTimeout)
+
+	existing, err := filepath.Glob(filepath.Join(dir, glob))
+	if err != nil {
+		return fmt.Errorf("cannot synchronize systemd services for snap %q: %s", snapName, err)
+	}
+	present := make(map[string]bool, len(existing))
+	// Stop the obsolete services while systemd still knows about them.
+	for _, path := range existing {
+		name := filepath.Base(path)
+		present[name] = true
+		if _, ok := content[name]; ok {
+			continue
+		}
+		if err := systemd.Stop(name, stopTimeout); err != nil {
+			return fmt.Errorf("cannot stop service %q: %s", name, err)
+		}
+		if err := systemd.Disable(name); err != nil {
+			return fmt.Errorf("cannot disable service %q: %s", name, err)
+		}
+	}
+
+	changed, removed, errEnsure := osutil.EnsureDirState(dir, glob, content)
+	if len(changed) > 0 || len(removed) > 0 {
+		// Reload regardless of errEnsure.
+		if err := systemd.DaemonReload(); err != nil {
+			return fmt.Errorf("cannot reload systemd: %s", err)
+		}
+	}
+	if errEnsure != nil {
+		return fmt.Errorf("cannot synchronize systemd services for snap %q: %s", snapName, errEnsure)
+	}
+	for _, name := range changed {
+		if present[name] {
+			if err := systemd.Stop(name, stopTimeout); err != nil {
+				return fmt.Errorf("cannot stop service %q: %s", name, err)
+			}
+		} else if err := systemd.Enable(name); err != nil {
+			return fmt.Errorf("cannot enable service %q: %s", name, err)
+		}
+		if err := systemd.Start(name); err != nil {
+			return fmt.Errorf("cannot start service %q: %s", name, err)
+		}
+	}
+	return nil
+}
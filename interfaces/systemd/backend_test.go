@@ -0,0 +1,183 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package systemd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/backendtest"
+	"github.com/snapcore/snapd/interfaces/systemd"
+	sysd "github.com/snapcore/snapd/systemd"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	backendtest.BackendSuite
+
+	systemctlCalls [][]string
+	prevctlCmd     func(...string) ([]byte, error)
+	services       map[string]systemd.Service
+}
+
+var _ = Suite(&backendSuite{})
+
+func (s *backendSuite) SetUpTest(c *C) {
+	s.Backend = &systemd.Backend{}
+
+	s.BackendSuite.SetUpTest(c)
+
+	s.systemctlCalls = nil
+	s.prevctlCmd = sysd.SystemctlCmd
+	sysd.SystemctlCmd = func(args ...string) ([]byte, error) {
+		s.systemctlCalls = append(s.systemctlCalls, args)
+		return []byte("ActiveState=inactive\n"), nil
+	}
+	s.services = map[string]systemd.Service{
+		"foo": {Type: "oneshot", RemainAfterExit: true, ExecStart: "/bin/true"},
+	}
+	s.Iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		if securitySystem != interfaces.SecuritySystemd {
+			return nil, nil
+		}
+		snippet := &systemd.Snippet{Services: s.services}
+		return snippet.Bytes(), nil
+	}
+	err := os.MkdirAll(dirs.SnapServicesDir, 0755)
+	c.Assert(err, IsNil)
+}
+
+func (s *backendSuite) TearDownTest(c *C) {
+	sysd.SystemctlCmd = s.prevctlCmd
+	s.BackendSuite.TearDownTest(c)
+}
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.Backend.Name(), Equals, "systemd")
+}
+
+func (s *backendSuite) TestInstallingSnapWritesAndStartsServices(c *C) {
+	s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	fname := filepath.Join(dirs.SnapServicesDir, "snap.samba.interface.foo.service")
+	data, err := ioutil.ReadFile(fname)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `# This file is automatically generated.
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/bin/true
+
+[Install]
+WantedBy=multi-user.target
+`)
+	c.Check(s.systemctlCalls, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"--root", s.RootDir, "enable", "snap.samba.interface.foo.service"},
+		{"start", "snap.samba.interface.foo.service"},
+	})
+}
+
+func (s *backendSuite) TestSetupIsStable(c *C) {
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	s.systemctlCalls = nil
+	err := s.Backend.Setup(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlCalls, HasLen, 0)
+}
+
+func (s *backendSuite) TestChangedServicesAreRestarted(c *C) {
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	s.systemctlCalls = nil
+	s.services = map[string]systemd.Service{
+		"foo": {Type: "oneshot", ExecStart: "/bin/false"},
+		"bar": {Type: "oneshot", ExecStart: "/bin/true"},
+	}
+	err := s.Backend.Setup(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlCalls, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"--root", s.RootDir, "enable", "snap.samba.interface.bar.service"},
+		{"start", "snap.samba.interface.bar.service"},
+		{"stop", "snap.samba.interface.foo.service"},
+		{"show", "--property=ActiveState", "snap.samba.interface.foo.service"},
+		{"start", "snap.samba.interface.foo.service"},
+	})
+}
+
+func (s *backendSuite) TestObsoleteServicesAreStoppedAndRemoved(c *C) {
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	s.systemctlCalls = nil
+	s.services = nil
+	err := s.Backend.Setup(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlCalls, DeepEquals, [][]string{
+		{"stop", "snap.samba.interface.foo.service"},
+		{"show", "--property=ActiveState", "snap.samba.interface.foo.service"},
+		{"--root", s.RootDir, "disable", "snap.samba.interface.foo.service"},
+		{"daemon-reload"},
+	})
+	_, err = os.Stat(filepath.Join(dirs.SnapServicesDir, "snap.samba.interface.foo.service"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *backendSuite) TestRemovingSnapStopsAndRemovesServices(c *C) {
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	s.systemctlCalls = nil
+	s.RemoveSnap(c, snapInfo)
+	c.Check(s.systemctlCalls, DeepEquals, [][]string{
+		{"stop", "snap.samba.interface.foo.service"},
+		{"show", "--property=ActiveState", "snap.samba.interface.foo.service"},
+		{"--root", s.RootDir, "disable", "snap.samba.interface.foo.service"},
+		{"daemon-reload"},
+	})
+	_, err := os.Stat(filepath.Join(dirs.SnapServicesDir, "snap.samba.interface.foo.service"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *backendSuite) TestVerify(c *C) {
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	verifier := s.Backend.(interfaces.SecurityBackendVerifier)
+	changed, extra, err := verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, HasLen, 0)
+	c.Check(extra, HasLen, 0)
+
+	s.services = map[string]systemd.Service{"bar": {ExecStart: "/bin/true"}}
+	changed, extra, err = verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, DeepEquals, []string{"snap.samba.interface.bar.service"})
+	c.Check(extra, DeepEquals, []string{"snap.samba.interface.foo.service"})
+}
+
+func (s *backendSuite) TestInvalidServiceName(c *C) {
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	s.services = map[string]systemd.Service{"Foo": {ExecStart: "/bin/true"}}
+	err := s.Backend.Setup(snapInfo, false, s.Repo)
+	c.Assert(err, ErrorMatches, `cannot obtain expected systemd services for snap "samba": invalid service name "Foo"`)
+}
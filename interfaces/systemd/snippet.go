@@ -0,0 +1,106 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package systemd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+// Service describes a systemd service that an interface needs.
+type Service struct {
+	Type            string `json:"type,omitempty"`
+	RemainAfterExit bool   `json:"remain-after-exit,omitempty"`
+	ExecStart       string `json:"exec-start,omitempty"`
+	ExecStop        string `json:"exec-stop,omitempty"`
+}
+
+// String returns the content of the unit file of the service.
+func (s Service) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("# This file is automatically generated.\n")
+	buf.WriteString("[Service]\n")
+	if s.Type != "" {
+		fmt.Fprintf(&buf, "Type=%s\n", s.Type)
+	}
+	if s.RemainAfterExit {
+		buf.WriteString("RemainAfterExit=yes\n")
+	}
+	if s.ExecStart != "" {
+		fmt.Fprintf(&buf, "ExecStart=%s\n", s.ExecStart)
+	}
+	if s.ExecStop != "" {
+		fmt.Fprintf(&buf, "ExecStop=%s\n", s.ExecStop)
+	}
+	buf.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+	return buf.String()
+}
+
+// Snippet is the content of systemd security snippets, they are encoded as
+// JSON.
+//
+// Services are indexed by a name unique among the services of the snap, the
+// unit of the service foo of snap bar is named snap.bar.interface.foo.service.
+type Snippet struct {
+	Services map[string]Service `json:"services,omitempty"`
+}
+
+// Bytes returns the encoded snippet.
+func (s *Snippet) Bytes() []byte {
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(fmt.Sprintf("cannot encode systemd snippet: %s", err))
+	}
+	return data
+}
+
+var validServiceName = regexp.MustCompile("^[a-z0-9](?:-?[a-z0-9])*$")
+
+// parseSnippet decodes a snippet and checks the names of its services.
+func parseSnippet(data []byte) (*Snippet, error) {
+	var snippet Snippet
+	if err := json.Unmarshal(data, &snippet); err != nil {
+		return nil, err
+	}
+	for name := range snippet.Services {
+		if !validServiceName.MatchString(name) {
+			return nil, fmt.Errorf("invalid service name %q", name)
+		}
+	}
+	return &snippet, nil
+}
+
+// merge adds the services of another snippet. Several interfaces may ask for
+// the same service but not with different definitions.
+func (s *Snippet) merge(other *Snippet) error {
+	for name, service := range other.Services {
+		if old, ok := s.Services[name]; ok && !reflect.DeepEqual(old, service) {
+			return fmt.Errorf("conflicting definitions of service %q", name)
+		}
+		if s.Services == nil {
+			s.Services = make(map[string]Service)
+		}
+		s.Services[name] = service
+	}
+	return nil
+}
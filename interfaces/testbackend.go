@@ -33,6 +33,8 @@ type TestSecurityBackend struct {
 	SetupCallback func(snapInfo *snap.Info, developerMode bool, repo *Repository) error
 	// RemoveCallback is a callback that is optionally called in Remove
 	RemoveCallback func(snapName string) error
+	// VerifyCallback is a callback that is optionally called in Verify
+	VerifyCallback func(snapInfo *snap.Info, developerMode bool, repo *Repository) (changed, extra []string, err error)
 }
 
 // TestSetupCall stores details about calls to TestSecurityBackend.Setup
@@ -65,3 +67,11 @@ func (b *TestSecurityBackend) Remove(snapName string) error {
 	}
 	return b.RemoveCallback(snapName)
 }
+
+// Verify calls the verify callback if one is defined.
+func (b *TestSecurityBackend) Verify(snapInfo *snap.Info, devMode bool, repo *Repository) (changed, extra []string, err error) {
+	if b.VerifyCallback == nil {
+		return nil, nil, nil
+	}
+	return b.VerifyCallback(snapInfo, devMode, repo)
+}
@@ -67,6 +67,25 @@ func (b *Backend) Setup(snapInfo *snap.Info, devMode bool, repo *interfaces.Repo
 	return ensureDirState(dir, glob, content, snapName)
 }
 
+// Verify checks that the udev rules of a given snap are up to date.
+func (b *Backend) Verify(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (changed, extra []string, err error) {
+	snapName := snapInfo.Name()
+	snippets, err := repo.SecuritySnippetsForSnap(snapName, interfaces.SecurityUDev)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot obtain udev security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, snippets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot obtain expected udev rules for snap %q: %s", snapName, err)
+	}
+	glob := fmt.Sprintf("70-%s.rules", interfaces.SecurityTagGlob(snapName))
+	changed, extra, err = osutil.CheckDirState(dirs.SnapUdevRulesDir, glob, content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot check udev rules for snap %q: %s", snapName, err)
+	}
+	return changed, extra, nil
+}
+
 // Remove removes udev rules specific to a given snap.
 // If any of the rules are removed then udev database is reloaded.
 //
@@ -196,3 +196,26 @@ func (s *backendSuite) TestCombineSnippetsWithoutAnySnippets(c *C) {
 		s.RemoveSnap(c, snapInfo)
 	}
 }
+
+func (s *backendSuite) TestVerifyReportsDrift(c *C) {
+	// NOTE: Hand out a permanent snippet so that a file is generated.
+	s.Iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("dummy"), nil
+	}
+	snapInfo := s.InstallSnap(c, false, backendtest.SambaYamlV1, 0)
+	verifier := s.Backend.(interfaces.SecurityBackendVerifier)
+	changed, extra, err := verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, HasLen, 0)
+	c.Check(extra, HasLen, 0)
+	// Tamper with the generated file and add an unexpected one.
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapUdevRulesDir, "70-snap.samba.smbd.rules"), []byte("tampered"), 0644)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapUdevRulesDir, "70-snap.samba.nmbd.rules"), nil, 0644)
+	c.Assert(err, IsNil)
+	changed, extra, err = verifier.Verify(snapInfo, false, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(changed, DeepEquals, []string{"70-snap.samba.smbd.rules"})
+	c.Check(extra, DeepEquals, []string{"70-snap.samba.nmbd.rules"})
+	s.RemoveSnap(c, snapInfo)
+}
@@ -0,0 +1,305 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package udev
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// UEvent describes a single device event as reported by the kernel.
+type UEvent struct {
+	// Action is one of "add", "remove", "change", "move", ...
+	Action string
+	// DevPath is the path of the device relative to /sys.
+	DevPath string
+	// Subsystem is the kernel subsystem of the device (e.g. "tty").
+	Subsystem string
+	// DevName is the name of the device node relative to /dev, if any.
+	DevName string
+	// Env holds all the key-value pairs carried by the event.
+	Env map[string]string
+}
+
+// ParseUEvent parses a kernel uevent message as received over netlink.
+//
+// The message is made of a "action@devpath" header followed by a sequence of
+// KEY=VALUE pairs, all separated by NUL bytes.
+func ParseUEvent(msg []byte) (*UEvent, error) {
+	parts := bytes.Split(msg, []byte{0})
+	header := string(parts[0])
+	if !strings.Contains(header, "@") {
+		return nil, fmt.Errorf("cannot parse uevent: invalid header %q", header)
+	}
+	ev := &UEvent{Env: make(map[string]string)}
+	for _, part := range parts[1:] {
+		if len(part) == 0 {
+			continue
+		}
+		kv := strings.SplitN(string(part), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("cannot parse uevent: invalid entry %q", part)
+		}
+		ev.Env[kv[0]] = kv[1]
+	}
+	ev.Action = ev.Env["ACTION"]
+	ev.DevPath = ev.Env["DEVPATH"]
+	ev.Subsystem = ev.Env["SUBSYSTEM"]
+	ev.DevName = ev.Env["DEVNAME"]
+	if ev.Action == "" || ev.DevPath == "" {
+		headerParts := strings.SplitN(header, "@", 2)
+		if ev.Action == "" {
+			ev.Action = headerParts[0]
+		}
+		if ev.DevPath == "" {
+			ev.DevPath = headerParts[1]
+		}
+	}
+	return ev, nil
+}
+
+// sysfsDir returns the location of sysfs honoring the global root directory.
+func sysfsDir() string {
+	return filepath.Join(dirs.GlobalRootDir, "/sys")
+}
+
+// addUSBProperties adds the ID_VENDOR_ID, ID_MODEL_ID and ID_SERIAL_SHORT
+// properties (as udev would) by looking at the closest USB device among the
+// parents of the device described by the event.
+//
+// Existing properties are not changed.
+func addUSBProperties(ev *UEvent) {
+	root := sysfsDir()
+	for dir := filepath.Join(root, ev.DevPath); strings.HasPrefix(dir, root+"/"); dir = filepath.Dir(dir) {
+		vendor, err := ioutil.ReadFile(filepath.Join(dir, "idVendor"))
+		if err != nil {
+			continue
+		}
+		props := map[string]string{"ID_VENDOR_ID": string(vendor)}
+		if model, err := ioutil.ReadFile(filepath.Join(dir, "idProduct")); err == nil {
+			props["ID_MODEL_ID"] = string(model)
+		}
+		if serial, err := ioutil.ReadFile(filepath.Join(dir, "serial")); err == nil {
+			props["ID_SERIAL_SHORT"] = string(serial)
+		}
+		for key, value := range props {
+			if _, ok := ev.Env[key]; !ok {
+				ev.Env[key] = strings.TrimSpace(value)
+			}
+		}
+		return
+	}
+}
+
+// Monitor reports device events of a set of kernel subsystems.
+//
+// When started the monitor first reports synthetic "add" events for all the
+// devices already present in the system and then follows the events sent by
+// the kernel over a netlink socket.
+type Monitor struct {
+	fd         int
+	wakeFds    [2]int
+	subsystems []string
+	events     chan *UEvent
+	tomb       tomb.Tomb
+	closeOnce  sync.Once
+}
+
+// NewMonitor returns a running monitor for devices of the given subsystems.
+func NewMonitor(subsystems ...string) (*Monitor, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open netlink socket: %s", err)
+	}
+	// Group 1 carries the events sent by the kernel.
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: 1}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("cannot bind netlink socket: %s", err)
+	}
+	// Close closes the write end of this pipe to wake up the monitor
+	// waiting for events.
+	var wakeFds [2]int
+	if err := syscall.Pipe2(wakeFds[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("cannot create pipe: %s", err)
+	}
+	m := &Monitor{
+		fd:         fd,
+		wakeFds:    wakeFds,
+		subsystems: subsystems,
+		events:     make(chan *UEvent),
+	}
+	m.tomb.Go(m.run)
+	return m, nil
+}
+
+// Events returns the channel on which the device events are delivered.
+// The channel is closed when the monitor is closed.
+func (m *Monitor) Events() <-chan *UEvent {
+	return m.events
+}
+
+// Close stops the monitor and releases the netlink socket.
+func (m *Monitor) Close() error {
+	m.closeOnce.Do(func() {
+		m.tomb.Kill(nil)
+		syscall.Close(m.wakeFds[1])
+	})
+	return m.tomb.Wait()
+}
+
+func (m *Monitor) run() error {
+	defer close(m.events)
+	defer syscall.Close(m.wakeFds[0])
+	defer syscall.Close(m.fd)
+
+	for _, ev := range Coldplug(m.subsystems...) {
+		if !m.send(ev) {
+			return nil
+		}
+	}
+
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("cannot wait for uevents: %s", err)
+	}
+	defer syscall.Close(epfd)
+	for _, fd := range []int{m.fd, m.wakeFds[0]} {
+		ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+		if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+			return fmt.Errorf("cannot wait for uevents: %s", err)
+		}
+	}
+
+	buf := make([]byte, 16*1024)
+	ready := make([]syscall.EpollEvent, 2)
+	for {
+		_, err := syscall.EpollWait(epfd, ready, -1)
+		select {
+		case <-m.tomb.Dying():
+			return nil
+		default:
+		}
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot wait for uevents: %s", err)
+		}
+		n, _, err := syscall.Recvfrom(m.fd, buf, syscall.MSG_DONTWAIT)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot receive uevent: %s", err)
+		}
+		ev, err := ParseUEvent(buf[:n])
+		if err != nil {
+			logger.Debugf("%s", err)
+			continue
+		}
+		if !m.wanted(ev) {
+			continue
+		}
+		if ev.Action == "add" {
+			addUSBProperties(ev)
+		}
+		if !m.send(ev) {
+			return nil
+		}
+	}
+}
+
+func (m *Monitor) wanted(ev *UEvent) bool {
+	if len(m.subsystems) == 0 {
+		return true
+	}
+	for _, subsystem := range m.subsystems {
+		if ev.Subsystem == subsystem {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Monitor) send(ev *UEvent) bool {
+	select {
+	case m.events <- ev:
+		return true
+	case <-m.tomb.Dying():
+		return false
+	}
+}
+
+// Coldplug returns synthetic "add" events for all the devices of the given
+// subsystems that are currently present in the system.
+func Coldplug(subsystems ...string) []*UEvent {
+	root := sysfsDir()
+	if realRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = realRoot
+	}
+	var events []*UEvent
+	for _, subsystem := range subsystems {
+		matches, err := filepath.Glob(filepath.Join(root, "class", subsystem, "*", "uevent"))
+		if err != nil {
+			continue
+		}
+		for _, ueventFile := range matches {
+			devDir, err := filepath.EvalSymlinks(filepath.Dir(ueventFile))
+			if err != nil || !osutil.IsDirectory(devDir) {
+				continue
+			}
+			content, err := ioutil.ReadFile(ueventFile)
+			if err != nil {
+				continue
+			}
+			ev := &UEvent{
+				Action:    "add",
+				DevPath:   strings.TrimPrefix(devDir, root),
+				Subsystem: subsystem,
+				Env:       make(map[string]string),
+			}
+			for _, line := range strings.Split(string(content), "\n") {
+				kv := strings.SplitN(line, "=", 2)
+				if len(kv) == 2 {
+					ev.Env[kv[0]] = kv[1]
+				}
+			}
+			ev.DevName = ev.Env["DEVNAME"]
+			ev.Env["ACTION"] = ev.Action
+			ev.Env["DEVPATH"] = ev.DevPath
+			ev.Env["SUBSYSTEM"] = ev.Subsystem
+			addUSBProperties(ev)
+			events = append(events, ev)
+		}
+	}
+	return events
+}
@@ -0,0 +1,124 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package udev_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/udev"
+)
+
+type monitorSuite struct{}
+
+var _ = Suite(&monitorSuite{})
+
+func (s *monitorSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+const ttyUSBDevPath = "/devices/pci0000:00/usb1/1-1/1-1:1.0/ttyUSB0/tty/ttyUSB0"
+
+func (s *monitorSuite) TestParseUEvent(c *C) {
+	msg := strings.Join([]string{
+		"add@" + ttyUSBDevPath,
+		"ACTION=add",
+		"DEVPATH=" + ttyUSBDevPath,
+		"SUBSYSTEM=tty",
+		"MAJOR=188",
+		"MINOR=0",
+		"DEVNAME=ttyUSB0",
+		"SEQNUM=2302",
+		"",
+	}, "\x00")
+	ev, err := udev.ParseUEvent([]byte(msg))
+	c.Assert(err, IsNil)
+	c.Check(ev.Action, Equals, "add")
+	c.Check(ev.DevPath, Equals, ttyUSBDevPath)
+	c.Check(ev.Subsystem, Equals, "tty")
+	c.Check(ev.DevName, Equals, "ttyUSB0")
+	c.Check(ev.Env["MAJOR"], Equals, "188")
+	c.Check(ev.Env["SEQNUM"], Equals, "2302")
+}
+
+func (s *monitorSuite) TestParseUEventFallsBackToHeader(c *C) {
+	ev, err := udev.ParseUEvent([]byte("remove@/devices/foo\x00SUBSYSTEM=tty"))
+	c.Assert(err, IsNil)
+	c.Check(ev.Action, Equals, "remove")
+	c.Check(ev.DevPath, Equals, "/devices/foo")
+}
+
+func (s *monitorSuite) TestParseUEventErrors(c *C) {
+	_, err := udev.ParseUEvent([]byte("libudev\x00"))
+	c.Check(err, ErrorMatches, `cannot parse uevent: invalid header "libudev"`)
+	_, err = udev.ParseUEvent([]byte("add@/devices/foo\x00garbage"))
+	c.Check(err, ErrorMatches, `cannot parse uevent: invalid entry "garbage"`)
+}
+
+func (s *monitorSuite) TestColdplug(c *C) {
+	root := c.MkDir()
+	dirs.SetRootDir(root)
+
+	// A USB serial adapter ...
+	usbDir := filepath.Join(root, "/sys/devices/pci0000:00/usb1/1-1")
+	devDir := filepath.Join(root, "/sys", ttyUSBDevPath)
+	c.Assert(os.MkdirAll(devDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(usbDir, "idVendor"), []byte("0403\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(usbDir, "idProduct"), []byte("6001\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(usbDir, "serial"), []byte("A50285BI\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(devDir, "uevent"), []byte("MAJOR=188\nMINOR=0\nDEVNAME=ttyUSB0\n"), 0644), IsNil)
+	// ... as seen by /sys/class/tty
+	classDir := filepath.Join(root, "/sys/class/tty")
+	c.Assert(os.MkdirAll(classDir, 0755), IsNil)
+	c.Assert(os.Symlink(devDir, filepath.Join(classDir, "ttyUSB0")), IsNil)
+
+	events := udev.Coldplug("tty", "i2c-dev")
+	c.Assert(events, HasLen, 1)
+	ev := events[0]
+	c.Check(ev.Action, Equals, "add")
+	c.Check(ev.DevPath, Equals, ttyUSBDevPath)
+	c.Check(ev.Subsystem, Equals, "tty")
+	c.Check(ev.DevName, Equals, "ttyUSB0")
+	c.Check(ev.Env["ID_VENDOR_ID"], Equals, "0403")
+	c.Check(ev.Env["ID_MODEL_ID"], Equals, "6001")
+	c.Check(ev.Env["ID_SERIAL_SHORT"], Equals, "A50285BI")
+}
+
+func (s *monitorSuite) TestMonitorCloseDoesNotWait(c *C) {
+	dirs.SetRootDir(c.MkDir())
+
+	m, err := udev.NewMonitor("tty")
+	if err != nil {
+		c.Skip(err.Error())
+	}
+	start := time.Now()
+	c.Assert(m.Close(), IsNil)
+	c.Check(time.Since(start) < 500*time.Millisecond, Equals, true)
+	_, ok := <-m.Events()
+	c.Check(ok, Equals, false)
+	// closing again is harmless
+	c.Check(m.Close(), IsNil)
+}
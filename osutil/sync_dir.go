@@ -119,23 +119,74 @@ func EnsureDirState(dir, glob string, content map[string]*FileState) (changed, r
 	return changed, removed, firstErr
 }
 
-func writeFile(filePath string, fileState *FileState) error {
+// CheckDirState checks if directory content matches expectations.
+//
+// CheckDirState is the read-only counterpart of EnsureDirState. It uses the
+// same arguments but instead of changing anything it reports the files that
+// EnsureDirState would have written (because they are missing or their
+// content or permissions differ) and the files that it would have removed.
+func CheckDirState(dir, glob string, content map[string]*FileState) (changed, removed []string, err error) {
+	if _, err := filepath.Match(glob, "foo"); err != nil {
+		panic(fmt.Sprintf("CheckDirState got invalid pattern %q: %s", glob, err))
+	}
+	for baseName, fileState := range content {
+		if filepath.Base(baseName) != baseName {
+			panic(fmt.Sprintf("CheckDirState got filename %q which has a path component", baseName))
+		}
+		if ok, _ := filepath.Match(glob, baseName); !ok {
+			panic(fmt.Sprintf("CheckDirState got filename %q which doesn't match the glob pattern %q", baseName, glob))
+		}
+		same, err := sameFile(filepath.Join(dir, baseName), fileState)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !same {
+			changed = append(changed, baseName)
+		}
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, glob))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, filePath := range matches {
+		baseName := filepath.Base(filePath)
+		if content[baseName] == nil {
+			removed = append(removed, baseName)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed, nil
+}
+
+// sameFile checks if the file at the given path has the expected content and
+// permissions.
+func sameFile(filePath string, fileState *FileState) (bool, error) {
 	stat, err := os.Stat(filePath)
 	if os.IsNotExist(err) {
-		return AtomicWriteFile(filePath, fileState.Content, fileState.Mode, 0)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if stat.Mode().Perm() != fileState.Mode.Perm() || stat.Size() != int64(len(fileState.Content)) {
+		return false, nil
 	}
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(content, fileState.Content), nil
+}
+
+func writeFile(filePath string, fileState *FileState) error {
+	same, err := sameFile(filePath, fileState)
 	if err != nil {
 		return err
 	}
-	if stat.Mode().Perm() == fileState.Mode.Perm() && stat.Size() == int64(len(fileState.Content)) {
-		content, err := ioutil.ReadFile(filePath)
-		if err != nil {
-			return err
-		}
-		if bytes.Equal(content, fileState.Content) {
-			// Return a special error if the file doesn't need to be changed
-			return errSameState
-		}
+	if same {
+		// Return a special error if the file doesn't need to be changed
+		return errSameState
 	}
 	return AtomicWriteFile(filePath, fileState.Content, fileState.Mode, 0)
 }
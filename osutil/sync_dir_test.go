@@ -212,3 +212,37 @@ func (s *EnsureDirStateSuite) TestRemovesAllManagedFilesOnError(c *C) {
 	_, err = os.Stat(clash)
 	c.Assert(os.IsNotExist(err), Equals, true)
 }
+
+func (s *EnsureDirStateSuite) TestCheckDirStateReportsDrift(c *C) {
+	for _, name := range []string{"same.snap", "different.snap", "unexpected.snap", "unrelated"} {
+		err := ioutil.WriteFile(filepath.Join(s.dir, name), []byte("data"), 0600)
+		c.Assert(err, IsNil)
+	}
+	changed, removed, err := osutil.CheckDirState(s.dir, s.glob, map[string]*osutil.FileState{
+		"same.snap":      {Content: []byte("data"), Mode: 0600},
+		"different.snap": {Content: []byte("other"), Mode: 0600},
+		"missing.snap":   {Content: []byte("data"), Mode: 0600},
+	})
+	c.Assert(err, IsNil)
+	c.Check(changed, DeepEquals, []string{"different.snap", "missing.snap"})
+	c.Check(removed, DeepEquals, []string{"unexpected.snap"})
+	// Nothing was changed on disk
+	_, err = os.Stat(filepath.Join(s.dir, "missing.snap"))
+	c.Check(os.IsNotExist(err), Equals, true)
+	content, err := ioutil.ReadFile(filepath.Join(s.dir, "different.snap"))
+	c.Assert(err, IsNil)
+	c.Check(content, DeepEquals, []byte("data"))
+	_, err = os.Stat(filepath.Join(s.dir, "unexpected.snap"))
+	c.Check(err, IsNil)
+}
+
+func (s *EnsureDirStateSuite) TestCheckDirStateReportsBadPermissions(c *C) {
+	err := ioutil.WriteFile(filepath.Join(s.dir, "file.snap"), []byte("data"), 0600)
+	c.Assert(err, IsNil)
+	changed, removed, err := osutil.CheckDirState(s.dir, s.glob, map[string]*osutil.FileState{
+		"file.snap": {Content: []byte("data"), Mode: 0644},
+	})
+	c.Assert(err, IsNil)
+	c.Check(changed, DeepEquals, []string{"file.snap"})
+	c.Check(removed, HasLen, 0)
+}
@@ -23,20 +23,42 @@ package assertstate
 
 import (
 	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/store"
 
 	"github.com/snapcore/snapd/overlord/state"
 )
 
+// accountKeysRefreshInterval is how often the account-keys in the
+// assertion database are refreshed from the store, to learn about
+// their revocations and new validity periods.
+var accountKeysRefreshInterval = 24 * time.Hour
+
+// StoreService is the store functionality used by the assertion manager.
+type StoreService interface {
+	Assertion(assertType *asserts.AssertionType, primaryKey []string, user store.Authenticator) (asserts.Assertion, error)
+}
+
 // AssertManager is responsible for the enforcement of assertions in
 // system states. It manipulates the observed system state to ensure
 // nothing in it violates existing assertions, or misses required
 // ones.
 type AssertManager struct {
-	db *asserts.Database
+	state *state.State
+	db    *asserts.Database
+
+	store func() StoreService
+
+	refreshMu   sync.Mutex
+	refreshTomb *tomb.Tomb
 }
 
 func getTrustedAccountKey() string {
@@ -55,23 +77,157 @@ func Manager(s *state.State) (*AssertManager, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &AssertManager{db: db}, nil
+
+	s.Lock()
+	ReplaceDB(s, db)
+	s.Unlock()
+
+	return &AssertManager{state: s, db: db}, nil
+}
+
+// UseStore sets the function the manager calls to get the store to
+// refresh assertions from.
+func (m *AssertManager) UseStore(store func() StoreService) {
+	m.store = store
 }
 
 // Ensure implements StateManager.Ensure.
 func (m *AssertManager) Ensure() error {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	if m.refreshTomb != nil && m.refreshTomb.Alive() {
+		// still refreshing
+		return nil
+	}
+	sto, accKeys, err := m.accountKeysToRefresh()
+	if err != nil || len(accKeys) == 0 {
+		return err
+	}
+	// talking to the store can take long, do it in the background
+	m.refreshTomb = &tomb.Tomb{}
+	t := m.refreshTomb
+	t.Go(func() error {
+		m.refreshAccountKeys(t, sto, accKeys)
+		return nil
+	})
 	return nil
 }
 
+// accountKeysToRefresh returns, at most once every
+// accountKeysRefreshInterval, the account-keys in the database that
+// should be refreshed from the store, and the store itself.
+// Self-signed keys can only be trusted and are not refreshed.
+func (m *AssertManager) accountKeysToRefresh() (StoreService, []*asserts.AccountKey, error) {
+	if m.store == nil {
+		return nil, nil, nil
+	}
+
+	m.state.Lock()
+	defer m.state.Unlock()
+	var lastRefresh time.Time
+	err := m.state.Get("last-account-keys-refresh", &lastRefresh)
+	if err != nil && err != state.ErrNoState {
+		return nil, nil, err
+	}
+	if time.Now().Before(lastRefresh.Add(accountKeysRefreshInterval)) {
+		return nil, nil, nil
+	}
+	m.state.Set("last-account-keys-refresh", time.Now())
+	found, err := m.db.FindMany(asserts.AccountKeyType, map[string]string{})
+	if err == asserts.ErrNotFound {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sto := m.store()
+	if sto == nil {
+		return nil, nil, nil
+	}
+	var accKeys []*asserts.AccountKey
+	for _, a := range found {
+		accKey := a.(*asserts.AccountKey)
+		keyID, err := asserts.SignatureKeyID(accKey)
+		if err != nil || (keyID == accKey.PublicKeyID() && accKey.AuthorityID() == accKey.AccountID()) {
+			continue
+		}
+		accKeys = append(accKeys, accKey)
+	}
+	return sto, accKeys, nil
+}
+
+// refreshAccountKeys retrieves from the store newer revisions of the
+// given account-keys, which can revoke them, until the tomb is killed.
+func (m *AssertManager) refreshAccountKeys(t *tomb.Tomb, sto StoreService, accKeys []*asserts.AccountKey) {
+	for _, accKey := range accKeys {
+		select {
+		case <-t.Dying():
+			return
+		default:
+		}
+		ref := accKey.Ref()
+		newer, err := sto.Assertion(ref.Type, ref.PrimaryKey, nil)
+		if err == store.ErrAssertionNotFound {
+			continue
+		}
+		if err != nil {
+			logger.Noticef("cannot refresh %s: %v", ref, err)
+			continue
+		}
+		if newer.Revision() <= accKey.Revision() {
+			continue
+		}
+		m.state.Lock()
+		err = m.db.Add(newer)
+		m.state.Unlock()
+		if err != nil {
+			logger.Noticef("cannot refresh %s: %v", ref, err)
+		}
+	}
+}
+
 // Stop implements StateManager.Stop.
 func (m *AssertManager) Stop() {
+	m.refreshMu.Lock()
+	t := m.refreshTomb
+	m.refreshMu.Unlock()
+	if t != nil {
+		t.Kill(nil)
+		t.Wait()
+	}
 }
 
 // Wait implements StateManager.Wait.
 func (m *AssertManager) Wait() {
+	m.refreshMu.Lock()
+	t := m.refreshTomb
+	m.refreshMu.Unlock()
+	if t != nil {
+		t.Wait()
+	}
 }
 
 // DB returns the assertion database under the manager.
 func (m *AssertManager) DB() *asserts.Database {
 	return m.db
 }
+
+type cachedDBKey struct{}
+
+// ReplaceDB replaces the assertion database used by the managers
+// sharing the given state.
+func ReplaceDB(s *state.State, db *asserts.Database) {
+	s.Cache(cachedDBKey{}, db)
+}
+
+// DB returns the assertion database under the given state. It can be
+// used by other managers to consult and add assertions.
+func DB(s *state.State) *asserts.Database {
+	cached := s.Cached(cachedDBKey{})
+	if cached == nil {
+		panic("internal error: needing an assertion database before the assertion manager is initialized")
+	}
+	return cached.(*asserts.Database)
+}
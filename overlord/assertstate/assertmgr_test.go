@@ -20,12 +20,18 @@
 package assertstate_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/store"
 
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -49,3 +55,193 @@ func (ams *assertMgrSuite) TestManagerAndDB(c *C) {
 	db := mgr.DB()
 	c.Check(db, FitsTypeOf, (*asserts.Database)(nil))
 }
+
+func (ams *assertMgrSuite) TestDB(c *C) {
+	s := state.New(nil)
+	mgr, err := assertstate.Manager(s)
+	c.Assert(err, IsNil)
+
+	s.Lock()
+	defer s.Unlock()
+
+	c.Check(assertstate.DB(s), Equals, mgr.DB())
+}
+
+var (
+	rootPrivKey  = assertstest.GenerateKey(752)
+	storePrivKey = assertstest.GenerateKey(752)
+	devPrivKey   = assertstest.GenerateKey(752)
+)
+
+type fakeStore struct {
+	assertions map[string]asserts.Assertion
+	requested  []string
+	// if set, requests are announced on it and then wait for a value
+	// from it to proceed
+	block chan bool
+}
+
+func (sto *fakeStore) Assertion(assertType *asserts.AssertionType, primaryKey []string, user store.Authenticator) (asserts.Assertion, error) {
+	if sto.block != nil {
+		sto.block <- true
+		<-sto.block
+	}
+	ref := &asserts.Ref{Type: assertType, PrimaryKey: primaryKey}
+	sto.requested = append(sto.requested, ref.Unique())
+	if a := sto.assertions[ref.Unique()]; a != nil {
+		return a, nil
+	}
+	return nil, store.ErrAssertionNotFound
+}
+
+func (ams *assertMgrSuite) TestRefreshAccountKeys(c *C) {
+	storeSigning := assertstest.NewStoreStack("canonical", rootPrivKey, storePrivKey)
+	err := os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(dirs.SnapTrustedAccountKey, asserts.Encode(storeSigning.TrustedKey), 0644)
+	c.Assert(err, IsNil)
+
+	s := state.New(nil)
+	mgr, err := assertstate.Manager(s)
+	c.Assert(err, IsNil)
+
+	devKey := assertstest.NewAccountKey(storeSigning.SigningDB, "dev1", devPrivKey.PublicKey(), nil)
+	for _, a := range []asserts.Assertion{storeSigning.StoreAccountKey, devKey} {
+		err := mgr.DB().Add(a)
+		c.Assert(err, IsNil)
+	}
+
+	revokedDevKey := assertstest.NewAccountKey(storeSigning.SigningDB, "dev1", devPrivKey.PublicKey(), map[string]interface{}{
+		"revision": "1",
+		"revoked":  "true",
+	})
+	sto := &fakeStore{
+		assertions: map[string]asserts.Assertion{
+			storeSigning.StoreAccountKey.Ref().Unique(): storeSigning.StoreAccountKey,
+			revokedDevKey.Ref().Unique():                revokedDevKey,
+		},
+	}
+	mgr.UseStore(func() assertstate.StoreService {
+		return sto
+	})
+
+	err = mgr.Ensure()
+	c.Assert(err, IsNil)
+	mgr.Wait()
+
+	// the self-signed trusted key is not refreshed
+	c.Check(sto.requested, HasLen, 2)
+	a, err := devKey.Ref().Resolve(mgr.DB().Find)
+	c.Assert(err, IsNil)
+	c.Check(a.Revision(), Equals, 1)
+	c.Check(a.(*asserts.AccountKey).Revoked(), Equals, true)
+
+	// not again until the refresh interval has passed
+	sto.requested = nil
+	err = mgr.Ensure()
+	c.Assert(err, IsNil)
+	mgr.Wait()
+	c.Check(sto.requested, HasLen, 0)
+
+	restore := assertstate.MockAccountKeysRefreshInterval(0)
+	defer restore()
+	err = mgr.Ensure()
+	c.Assert(err, IsNil)
+	mgr.Wait()
+	c.Check(sto.requested, HasLen, 2)
+
+	s.Lock()
+	defer s.Unlock()
+	var lastRefresh time.Time
+	err = s.Get("last-account-keys-refresh", &lastRefresh)
+	c.Assert(err, IsNil)
+	c.Check(lastRefresh.IsZero(), Equals, false)
+}
+
+func (ams *assertMgrSuite) TestRefreshAccountKeysNoStore(c *C) {
+	s := state.New(nil)
+	mgr, err := assertstate.Manager(s)
+	c.Assert(err, IsNil)
+
+	err = mgr.Ensure()
+	c.Assert(err, IsNil)
+
+	s.Lock()
+	defer s.Unlock()
+	var lastRefresh time.Time
+	err = s.Get("last-account-keys-refresh", &lastRefresh)
+	c.Check(err, Equals, state.ErrNoState)
+}
+
+// managerWithDevKey returns a manager whose database holds the store
+// account-key and a developer account-key.
+func (ams *assertMgrSuite) managerWithDevKey(c *C) *assertstate.AssertManager {
+	storeSigning := assertstest.NewStoreStack("canonical", rootPrivKey, storePrivKey)
+	err := os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(dirs.SnapTrustedAccountKey, asserts.Encode(storeSigning.TrustedKey), 0644)
+	c.Assert(err, IsNil)
+
+	mgr, err := assertstate.Manager(state.New(nil))
+	c.Assert(err, IsNil)
+	devKey := assertstest.NewAccountKey(storeSigning.SigningDB, "dev1", devPrivKey.PublicKey(), nil)
+	for _, a := range []asserts.Assertion{storeSigning.StoreAccountKey, devKey} {
+		err := mgr.DB().Add(a)
+		c.Assert(err, IsNil)
+	}
+	return mgr
+}
+
+func (ams *assertMgrSuite) TestRefreshAccountKeysInBackground(c *C) {
+	mgr := ams.managerWithDevKey(c)
+
+	sto := &fakeStore{block: make(chan bool)}
+	mgr.UseStore(func() assertstate.StoreService {
+		return sto
+	})
+	restore := assertstate.MockAccountKeysRefreshInterval(0)
+	defer restore()
+
+	// Ensure does not wait for the store
+	err := mgr.Ensure()
+	c.Assert(err, IsNil)
+
+	for i := 0; i < 2; i++ {
+		<-sto.block
+		// and does not start another refresh while one is going on
+		err = mgr.Ensure()
+		c.Assert(err, IsNil)
+		sto.block <- true
+	}
+	mgr.Wait()
+	c.Check(sto.requested, HasLen, 2)
+}
+
+func (ams *assertMgrSuite) TestStopWaitsForRefresh(c *C) {
+	mgr := ams.managerWithDevKey(c)
+	sto := &fakeStore{block: make(chan bool)}
+	mgr.UseStore(func() assertstate.StoreService {
+		return sto
+	})
+
+	err := mgr.Ensure()
+	c.Assert(err, IsNil)
+	<-sto.block
+
+	stopped := make(chan bool)
+	go func() {
+		mgr.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		c.Fatal("Stop did not wait for the request in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	sto.block <- true
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		c.Fatal("Stop did not return")
+	}
+}
@@ -0,0 +1,206 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package devicestate implements the manager and state aspects
+// responsible for the device identity and the policies that derive
+// from its model.
+package devicestate
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+)
+
+// DeviceManager is responsible for managing the device identity and
+// the policies derived from the device model.
+type DeviceManager struct {
+	state *state.State
+}
+
+// Manager returns a new device manager.
+func Manager(s *state.State) (*DeviceManager, error) {
+	return &DeviceManager{state: s}, nil
+}
+
+// Ensure implements StateManager.Ensure.
+func (m *DeviceManager) Ensure() error {
+	return nil
+}
+
+// Wait implements StateManager.Wait.
+func (m *DeviceManager) Wait() {
+}
+
+// Stop implements StateManager.Stop.
+func (m *DeviceManager) Stop() {
+}
+
+// DeviceState holds the identity of the device as recorded in the
+// system state.
+type DeviceState struct {
+	Brand string `json:"brand,omitempty"`
+	Model string `json:"model,omitempty"`
+}
+
+// Device returns the device identity recorded in the state.
+func Device(st *state.State) (*DeviceState, error) {
+	var device DeviceState
+	err := st.Get("device", &device)
+	if err == state.ErrNoState {
+		return &device, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// SetDevice records the device identity in the state.
+func SetDevice(st *state.State, device *DeviceState) {
+	st.Set("device", device)
+}
+
+// Model returns the model assertion of the device, it returns
+// state.ErrNoState if the device has no model yet.
+func Model(st *state.State) (*asserts.Model, error) {
+	device, err := Device(st)
+	if err != nil {
+		return nil, err
+	}
+	if device.Brand == "" || device.Model == "" {
+		return nil, state.ErrNoState
+	}
+
+	a, err := assertstate.DB(st).Find(asserts.ModelType, map[string]string{
+		"series":   release.Series,
+		"brand-id": device.Brand,
+		"model":    device.Model,
+	})
+	if err == asserts.ErrNotFound {
+		return nil, state.ErrNoState
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a.(*asserts.Model), nil
+}
+
+func readSeedAssertions(assertSeedDir string) (map[string]asserts.Assertion, []asserts.Assertion, error) {
+	dc, err := ioutil.ReadDir(assertSeedDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	byRef := make(map[string]asserts.Assertion)
+	var all []asserts.Assertion
+	for _, fi := range dc {
+		fn := filepath.Join(assertSeedDir, fi.Name())
+		f, err := os.Open(fn)
+		if err != nil {
+			return nil, nil, err
+		}
+		dec := asserts.NewDecoder(f)
+		for {
+			a, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, nil, fmt.Errorf("cannot read seed assertions in %q: %v", fn, err)
+			}
+			byRef[a.Ref().Unique()] = a
+			all = append(all, a)
+		}
+		f.Close()
+	}
+	return byRef, all, nil
+}
+
+// ImportAssertionsFromSeed adds the assertions found in the seed to
+// the assertion database and records the device identity from the
+// model assertion among them. The seed is optional: if it has no
+// assertions nothing is done and nil is returned.
+// It must be called with the state locked.
+func ImportAssertionsFromSeed(st *state.State) (*asserts.Model, error) {
+	assertSeedDir := filepath.Join(dirs.SnapSeedDir, "assertions")
+	byRef, all, err := readSeedAssertions(assertSeedDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(all) == 0 {
+		return nil, nil
+	}
+
+	var model *asserts.Model
+	for _, a := range all {
+		if a.Type() != asserts.ModelType {
+			continue
+		}
+		if model != nil {
+			return nil, fmt.Errorf("cannot have multiple model assertions in the seed")
+		}
+		model = a.(*asserts.Model)
+	}
+	if model == nil {
+		return nil, fmt.Errorf("cannot find a model assertion in the seed")
+	}
+	if model.Series() != release.Series {
+		return nil, fmt.Errorf("cannot use seed model assertion for series %q on series %q", model.Series(), release.Series)
+	}
+
+	db := assertstate.DB(st)
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		a, ok := byRef[ref.Unique()]
+		if !ok {
+			return nil, fmt.Errorf("not in the seed")
+		}
+		return a, nil
+	}
+	save := func(a asserts.Assertion) error {
+		// skip what is already known, e.g. the trusted account-keys
+		if _, err := a.Ref().Resolve(db.Find); err == nil {
+			return nil
+		}
+		return db.Add(a)
+	}
+	f := asserts.NewFetcher(db, retrieve, save)
+	for _, a := range all {
+		if err := f.Save(a); err != nil {
+			return nil, fmt.Errorf("cannot add seed assertions: %v", err)
+		}
+	}
+
+	SetDevice(st, &DeviceState{
+		Brand: model.BrandID(),
+		Model: model.Model(),
+	})
+	return model, nil
+}
@@ -0,0 +1,250 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+func TestDeviceManager(t *testing.T) { TestingT(t) }
+
+type deviceMgrSuite struct {
+	state *state.State
+	mgr   *devicestate.DeviceManager
+
+	storeSigning *assertstest.StoreStack
+	brandSigning *assertstest.SigningDB
+	brandAccKey  *asserts.AccountKey
+}
+
+var _ = Suite(&deviceMgrSuite{})
+
+var (
+	rootPrivKey  = assertstest.GenerateKey(752)
+	storePrivKey = assertstest.GenerateKey(752)
+	brandPrivKey = assertstest.GenerateKey(752)
+)
+
+func (s *deviceMgrSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+
+	s.storeSigning = assertstest.NewStoreStack("canonical", rootPrivKey, storePrivKey)
+	s.brandSigning = assertstest.NewSigningDB("my-brand", brandPrivKey)
+	s.brandAccKey = assertstest.NewAccountKey(s.storeSigning.SigningDB, "my-brand", brandPrivKey.PublicKey(), nil)
+
+	s.state = state.New(nil)
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		Trusted:        []asserts.Assertion{s.storeSigning.TrustedKey},
+	})
+	c.Assert(err, IsNil)
+	s.state.Lock()
+	assertstate.ReplaceDB(s.state, db)
+	s.state.Unlock()
+
+	mgr, err := devicestate.Manager(s.state)
+	c.Assert(err, IsNil)
+	s.mgr = mgr
+}
+
+func (s *deviceMgrSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+func (s *deviceMgrSuite) makeModel(c *C, model string) *asserts.Model {
+	a, err := s.brandSigning.Sign(asserts.ModelType, map[string]interface{}{
+		"series":         "16",
+		"brand-id":       "my-brand",
+		"model":          model,
+		"class":          "generic",
+		"architecture":   "amd64",
+		"store":          "my-brand-store",
+		"gadget":         "pc",
+		"kernel":         "pc-kernel",
+		"core":           "core",
+		"allowed-modes":  "",
+		"required-snaps": []interface{}{"foo", "bar"},
+		"timestamp":      time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, IsNil)
+	return a.(*asserts.Model)
+}
+
+func (s *deviceMgrSuite) writeSeedAssertions(c *C, fn string, assertions ...asserts.Assertion) {
+	assertSeedDir := filepath.Join(dirs.SnapSeedDir, "assertions")
+	err := os.MkdirAll(assertSeedDir, 0755)
+	c.Assert(err, IsNil)
+
+	buf := bytes.NewBuffer(nil)
+	enc := asserts.NewEncoder(buf)
+	for _, a := range assertions {
+		err := enc.Encode(a)
+		c.Assert(err, IsNil)
+	}
+	err = ioutil.WriteFile(filepath.Join(assertSeedDir, fn), buf.Bytes(), 0644)
+	c.Assert(err, IsNil)
+}
+
+func (s *deviceMgrSuite) TestManager(c *C) {
+	c.Check(s.mgr.Ensure(), IsNil)
+	s.mgr.Wait()
+	s.mgr.Stop()
+}
+
+func (s *deviceMgrSuite) TestDeviceAndSetDevice(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	device, err := devicestate.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &devicestate.DeviceState{})
+
+	devicestate.SetDevice(s.state, &devicestate.DeviceState{Brand: "my-brand", Model: "my-model"})
+
+	device, err = devicestate.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &devicestate.DeviceState{Brand: "my-brand", Model: "my-model"})
+}
+
+func (s *deviceMgrSuite) TestModel(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := devicestate.Model(s.state)
+	c.Check(err, Equals, state.ErrNoState)
+
+	devicestate.SetDevice(s.state, &devicestate.DeviceState{Brand: "my-brand", Model: "my-model"})
+
+	// not in the database yet
+	_, err = devicestate.Model(s.state)
+	c.Check(err, Equals, state.ErrNoState)
+
+	db := assertstate.DB(s.state)
+	err = db.Add(s.storeSigning.StoreAccountKey)
+	c.Assert(err, IsNil)
+	err = db.Add(s.brandAccKey)
+	c.Assert(err, IsNil)
+	err = db.Add(s.makeModel(c, "my-model"))
+	c.Assert(err, IsNil)
+
+	model, err := devicestate.Model(s.state)
+	c.Assert(err, IsNil)
+	c.Check(model.BrandID(), Equals, "my-brand")
+	c.Check(model.Model(), Equals, "my-model")
+	c.Check(model.Store(), Equals, "my-brand-store")
+}
+
+func (s *deviceMgrSuite) TestImportAssertionsFromSeed(c *C) {
+	// prerequisites come after what needs them in the seed
+	s.writeSeedAssertions(c, "model", s.makeModel(c, "my-model"))
+	s.writeSeedAssertions(c, "keys", s.brandAccKey, s.storeSigning.StoreAccountKey, s.storeSigning.TrustedKey)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	model, err := devicestate.ImportAssertionsFromSeed(s.state)
+	c.Assert(err, IsNil)
+	c.Check(model.Model(), Equals, "my-model")
+
+	device, err := devicestate.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &devicestate.DeviceState{Brand: "my-brand", Model: "my-model"})
+
+	model, err = devicestate.Model(s.state)
+	c.Assert(err, IsNil)
+	c.Check(model.Model(), Equals, "my-model")
+	c.Check(model.RequiredSnaps(), DeepEquals, []string{"foo", "bar"})
+}
+
+func (s *deviceMgrSuite) TestImportAssertionsFromSeedNoSeed(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	model, err := devicestate.ImportAssertionsFromSeed(s.state)
+	c.Assert(err, IsNil)
+	c.Check(model, IsNil)
+
+	device, err := devicestate.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &devicestate.DeviceState{})
+}
+
+func (s *deviceMgrSuite) TestImportAssertionsFromSeedNoModel(c *C) {
+	s.writeSeedAssertions(c, "keys", s.brandAccKey, s.storeSigning.StoreAccountKey)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := devicestate.ImportAssertionsFromSeed(s.state)
+	c.Check(err, ErrorMatches, "cannot find a model assertion in the seed")
+}
+
+func (s *deviceMgrSuite) TestImportAssertionsFromSeedMultipleModels(c *C) {
+	s.writeSeedAssertions(c, "models", s.makeModel(c, "my-model"), s.makeModel(c, "other-model"))
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := devicestate.ImportAssertionsFromSeed(s.state)
+	c.Check(err, ErrorMatches, "cannot have multiple model assertions in the seed")
+}
+
+func (s *deviceMgrSuite) TestImportAssertionsFromSeedMissingPrerequisites(c *C) {
+	s.writeSeedAssertions(c, "model", s.makeModel(c, "my-model"))
+	s.writeSeedAssertions(c, "keys", s.storeSigning.StoreAccountKey)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := devicestate.ImportAssertionsFromSeed(s.state)
+	c.Check(err, ErrorMatches, `cannot add seed assertions: cannot fetch account-key .*: not in the seed`)
+
+	_, err = devicestate.Model(s.state)
+	c.Check(err, Equals, state.ErrNoState)
+}
+
+func (s *deviceMgrSuite) TestImportAssertionsFromSeedInvalid(c *C) {
+	assertSeedDir := filepath.Join(dirs.SnapSeedDir, "assertions")
+	err := os.MkdirAll(assertSeedDir, 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(assertSeedDir, "junk"), []byte("junk\n\n"), 0644)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err = devicestate.ImportAssertionsFromSeed(s.state)
+	c.Check(err, ErrorMatches, `cannot read seed assertions in ".*/junk": .*`)
+}
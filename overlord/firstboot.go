@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/firstboot"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -50,6 +51,16 @@ func populateStateFromInstalled() error {
 	}
 	st := ovld.State()
 
+	// the model assertion, if any, must be known before installing
+	// the seed snaps as it determines which gadget, kernel and os
+	// snaps can be installed
+	st.Lock()
+	_, err = devicestate.ImportAssertionsFromSeed(st)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
 	all, err := filepath.Glob(filepath.Join(dirs.SnapSeedDir, "snaps", "*.snap"))
 	if err != nil {
 		return err
@@ -64,8 +75,9 @@ func populateStateFromInstalled() error {
 
 		// XXX: needing to know the name here is too early
 
-		// everything will be sideloaded for now - that is
-		// ok, we will support adding assertions soon
+		// everything will be sideloaded for now, without
+		// verifying assertions - that is ok, we will support
+		// adding assertions soon
 		snapf, err := snap.Open(snapPath)
 		if err != nil {
 			return err
@@ -74,7 +86,7 @@ func populateStateFromInstalled() error {
 		if err != nil {
 			return err
 		}
-		ts, err := snapstate.InstallPath(st, info.Name(), snapPath, "", 0)
+		ts, err := snapstate.InstallPath(st, info.Name(), snapPath, "", snapstate.Dangerous)
 
 		if i > 0 {
 			ts.WaitAll(tsAll[i-1])
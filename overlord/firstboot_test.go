@@ -34,7 +34,8 @@ import (
 )
 
 type FirstBootTestSuite struct {
-	systemctl *testutil.MockCmd
+	systemctl      *testutil.MockCmd
+	restoreUEvents func()
 }
 
 var _ = Suite(&FirstBootTestSuite{})
@@ -50,12 +51,14 @@ func (s *FirstBootTestSuite) SetUpTest(c *C) {
 	c.Assert(err, IsNil)
 	os.Setenv("SNAPPY_SQUASHFS_UNPACK_FOR_TESTS", "1")
 	s.systemctl = testutil.MockCommand(c, "systemctl", "")
+	s.restoreUEvents = mockNoUEvents()
 }
 
 func (s *FirstBootTestSuite) TearDownTest(c *C) {
 	dirs.SetRootDir("/")
 	os.Unsetenv("SNAPPY_SQUASHFS_UNPACK_FOR_TESTS")
 	s.systemctl.Restore()
+	s.restoreUEvents()
 }
 
 func (s *FirstBootTestSuite) TestTwoRuns(c *C) {
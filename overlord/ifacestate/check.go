@@ -0,0 +1,256 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"sort"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Kinds of drift reported by Check.
+const (
+	// DriftRepository is reported when the repository disagrees with itself
+	// about a connection.
+	DriftRepository = "repository"
+	// DriftMissingConnection is reported for connections recorded in the
+	// state but not present in the repository.
+	DriftMissingConnection = "missing-connection"
+	// DriftUnexpectedConnection is reported for connections present in the
+	// repository but not recorded in the state.
+	DriftUnexpectedConnection = "unexpected-connection"
+	// DriftChangedFile is reported for security files that are missing or
+	// whose content differs from the one the backend would generate.
+	DriftChangedFile = "changed-file"
+	// DriftExtraFile is reported for security files that the backend would
+	// not generate.
+	DriftExtraFile = "extra-file"
+)
+
+// Drift describes a single disagreement between the connections recorded in
+// the state, the interface repository and the security files on disk.
+type Drift struct {
+	Kind    string `json:"kind"`
+	Snap    string `json:"snap,omitempty"`
+	Backend string `json:"backend,omitempty"`
+	Detail  string `json:"detail"`
+}
+
+// Check cross-checks the connections recorded in the state with the ones in
+// the repository, and the security files of all the active snaps with the
+// ones the security backends would generate.
+//
+// The state must be locked by the caller.
+func (m *InterfaceManager) Check() ([]*Drift, error) {
+	drift, err := m.checkConnections()
+	if err != nil {
+		return nil, err
+	}
+	fileDrift, err := m.checkSecurityFiles()
+	if err != nil {
+		return nil, err
+	}
+	return append(drift, fileDrift...), nil
+}
+
+// checkConnections compares the connections in the state and in the repository.
+//
+// Connections whose plug or slot is not in the repository are not reported.
+// They are kept on purpose, for instance for hotplug devices that are not
+// plugged in.
+func (m *InterfaceManager) checkConnections() ([]*Drift, error) {
+	var drift []*Drift
+	for _, problem := range m.repo.CheckConsistency() {
+		drift = append(drift, &Drift{Kind: DriftRepository, Detail: problem})
+	}
+	conns, err := getConns(m.state)
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]bool)
+	for _, conn := range m.repo.Connections() {
+		id := conn.ID()
+		present[id] = true
+		if recorded, ok := conns[id]; !ok || recorded.Undesired {
+			drift = append(drift, &Drift{Kind: DriftUnexpectedConnection, Detail: id})
+		}
+	}
+	ids := make([]string, 0, len(conns))
+	for id, conn := range conns {
+		if conn.Undesired {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if present[id] {
+			continue
+		}
+		plugRef, slotRef, err := parseConnID(id)
+		if err != nil {
+			return nil, err
+		}
+		if m.repo.Plug(plugRef.Snap, plugRef.Name) == nil || m.repo.Slot(slotRef.Snap, slotRef.Name) == nil {
+			continue
+		}
+		drift = append(drift, &Drift{Kind: DriftMissingConnection, Detail: id})
+	}
+	return drift, nil
+}
+
+// activeSnapInfos returns the infos of all active snaps, sorted by name, as
+// used for setting up their security.
+func (m *InterfaceManager) activeSnapInfos() ([]*snap.Info, error) {
+	infos, err := snapstate.ActiveInfos(m.state)
+	if err != nil {
+		return nil, err
+	}
+	for i, snapInfo := range infos {
+		// Use the info shared by the plugs and slots in the repository, if any.
+		if slots := m.repo.Slots(snapInfo.Name()); len(slots) > 0 {
+			infos[i] = slots[0].Snap
+		} else {
+			snap.AddImplicitSlots(snapInfo)
+		}
+	}
+	sort.Sort(byName(infos))
+	return infos, nil
+}
+
+type byName []*snap.Info
+
+func (infos byName) Len() int           { return len(infos) }
+func (infos byName) Swap(i, j int)      { infos[i], infos[j] = infos[j], infos[i] }
+func (infos byName) Less(i, j int) bool { return infos[i].Name() < infos[j].Name() }
+
+// checkSecurityFiles compares the security files of all the active snaps with
+// the ones the security backends would generate.
+func (m *InterfaceManager) checkSecurityFiles() ([]*Drift, error) {
+	infos, err := m.activeSnapInfos()
+	if err != nil {
+		return nil, err
+	}
+	var drift []*Drift
+	for _, snapInfo := range infos {
+		snapName := snapInfo.Name()
+		var snapState snapstate.SnapState
+		if err := snapstate.Get(m.state, snapName, &snapState); err != nil {
+			return nil, err
+		}
+		for _, backend := range securityBackends {
+			verifier, ok := backend.(interfaces.SecurityBackendVerifier)
+			if !ok {
+				continue
+			}
+			changed, extra, err := verifier.Verify(snapInfo, snapState.DevMode(), m.repo)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range changed {
+				drift = append(drift, &Drift{Kind: DriftChangedFile, Snap: snapName, Backend: backend.Name(), Detail: name})
+			}
+			for _, name := range extra {
+				drift = append(drift, &Drift{Kind: DriftExtraFile, Snap: snapName, Backend: backend.Name(), Detail: name})
+			}
+		}
+	}
+	return drift, nil
+}
+
+// Repair returns a set of tasks for repairing the drift reported by Check.
+func Repair(s *state.State) (*state.TaskSet, error) {
+	task := s.NewTask("repair-interfaces", i18n.G("Repair interface connections and security files"))
+	return state.NewTaskSet(task), nil
+}
+
+// doRepairInterfaces brings the repository in line with the connections
+// recorded in the state and re-generates the security files of all the snaps
+// affected by the difference or whose security files have drifted.
+func (m *InterfaceManager) doRepairInterfaces(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	drift, err := m.checkConnections()
+	if err != nil {
+		return err
+	}
+	affected := make(map[string]bool)
+	for _, d := range drift {
+		switch d.Kind {
+		case DriftRepository:
+			task.Logf("cannot repair repository inconsistency: %s", d.Detail)
+			continue
+		case DriftMissingConnection:
+			plugRef, slotRef, err := parseConnID(d.Detail)
+			if err != nil {
+				return err
+			}
+			if err := m.repo.Connect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name); err != nil {
+				task.Logf("cannot restore connection %s: %s", d.Detail, err)
+				continue
+			}
+			affected[plugRef.Snap] = true
+			affected[slotRef.Snap] = true
+		case DriftUnexpectedConnection:
+			plugRef, slotRef, err := parseConnID(d.Detail)
+			if err != nil {
+				return err
+			}
+			if err := m.repo.Disconnect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name); err != nil {
+				task.Logf("cannot remove connection %s: %s", d.Detail, err)
+				continue
+			}
+			affected[plugRef.Snap] = true
+			affected[slotRef.Snap] = true
+		}
+	}
+
+	fileDrift, err := m.checkSecurityFiles()
+	if err != nil {
+		return err
+	}
+	for _, d := range fileDrift {
+		affected[d.Snap] = true
+	}
+
+	infos, err := m.activeSnapInfos()
+	if err != nil {
+		return err
+	}
+	for _, snapInfo := range infos {
+		if !affected[snapInfo.Name()] {
+			continue
+		}
+		logger.Noticef("repairing security of snap %q", snapInfo.Name())
+		if err := setupSnapSecurity(task, snapInfo, m.repo); err != nil {
+			return state.Retry
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *interfaceManagerSuite) mockConnected(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test"},
+	})
+	s.state.Unlock()
+}
+
+func (s *interfaceManagerSuite) check(c *C, mgr *ifacestate.InterfaceManager) []*ifacestate.Drift {
+	s.state.Lock()
+	defer s.state.Unlock()
+	drift, err := mgr.Check()
+	c.Assert(err, IsNil)
+	return drift
+}
+
+func (s *interfaceManagerSuite) TestCheckNoDrift(c *C) {
+	s.mockConnected(c)
+	mgr := s.manager(c)
+	c.Assert(mgr.Repository().Plug("consumer", "plug").Connections, HasLen, 1)
+
+	c.Check(s.check(c, mgr), HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestCheckReportsConnectionDrift(c *C) {
+	s.mockConnected(c)
+	mgr := s.manager(c)
+	repo := mgr.Repository()
+
+	err := repo.Disconnect("consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	c.Check(s.check(c, mgr), DeepEquals, []*ifacestate.Drift{
+		{Kind: ifacestate.DriftMissingConnection, Detail: "consumer:plug producer:slot"},
+	})
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{})
+	s.state.Unlock()
+	err = repo.Connect("consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	c.Check(s.check(c, mgr), DeepEquals, []*ifacestate.Drift{
+		{Kind: ifacestate.DriftUnexpectedConnection, Detail: "consumer:plug producer:slot"},
+	})
+}
+
+func (s *interfaceManagerSuite) TestCheckIgnoresConnectionsOfMissingSlots(c *C) {
+	s.mockConnected(c)
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot":         map[string]interface{}{"interface": "test"},
+		"consumer:plug ubuntu-core:unplugged": map[string]interface{}{"interface": "test"},
+	})
+	s.state.Unlock()
+	mgr := s.manager(c)
+
+	c.Check(s.check(c, mgr), HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestCheckIgnoresUndesiredConnections(c *C) {
+	s.mockConnected(c)
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "undesired": true},
+	})
+	s.state.Unlock()
+	mgr := s.manager(c)
+	c.Assert(mgr.Repository().Plug("consumer", "plug").Connections, HasLen, 0)
+
+	c.Check(s.check(c, mgr), HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestCheckReportsFileDrift(c *C) {
+	s.mockConnected(c)
+	s.secBackend.VerifyCallback = func(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) ([]string, []string, error) {
+		if snapInfo.Name() == "consumer" {
+			return []string{"snap.consumer.app"}, []string{"snap.consumer.old"}, nil
+		}
+		return nil, nil, nil
+	}
+	mgr := s.manager(c)
+
+	c.Check(s.check(c, mgr), DeepEquals, []*ifacestate.Drift{
+		{Kind: ifacestate.DriftChangedFile, Snap: "consumer", Backend: "test", Detail: "snap.consumer.app"},
+		{Kind: ifacestate.DriftExtraFile, Snap: "consumer", Backend: "test", Detail: "snap.consumer.old"},
+	})
+}
+
+func (s *interfaceManagerSuite) TestRepair(c *C) {
+	s.mockConnected(c)
+	mgr := s.manager(c)
+	repo := mgr.Repository()
+	err := repo.Disconnect("consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	change := s.state.NewChange("repair-interfaces", "")
+	ts, err := ifacestate.Repair(s.state)
+	c.Assert(err, IsNil)
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	mgr.Ensure()
+	mgr.Wait()
+
+	s.state.Lock()
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	s.state.Unlock()
+
+	c.Check(repo.Plug("consumer", "plug").Connections, DeepEquals, []interfaces.SlotRef{{Snap: "producer", Name: "slot"}})
+	c.Assert(s.secBackend.SetupCalls, HasLen, 2)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.Name(), Equals, "consumer")
+	c.Check(s.secBackend.SetupCalls[1].SnapInfo.Name(), Equals, "producer")
+	c.Check(s.check(c, mgr), HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestRepairRegeneratesDriftedFiles(c *C) {
+	s.mockConnected(c)
+	drifted := true
+	s.secBackend.VerifyCallback = func(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) ([]string, []string, error) {
+		if drifted && snapInfo.Name() == "producer" {
+			return []string{"snap.producer.app"}, nil, nil
+		}
+		return nil, nil, nil
+	}
+	s.secBackend.SetupCallback = func(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) error {
+		drifted = false
+		return nil
+	}
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	change := s.state.NewChange("repair-interfaces", "")
+	ts, err := ifacestate.Repair(s.state)
+	c.Assert(err, IsNil)
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	mgr.Ensure()
+	mgr.Wait()
+
+	s.state.Lock()
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	s.state.Unlock()
+
+	c.Assert(s.secBackend.SetupCalls, HasLen, 1)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.Name(), Equals, "producer")
+	c.Check(s.check(c, mgr), HasLen, 0)
+}
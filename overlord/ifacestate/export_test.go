@@ -18,3 +18,12 @@
  */
 
 package ifacestate
+
+import (
+	"github.com/snapcore/snapd/interfaces/udev"
+)
+
+// HandleUEvent processes a single device event synchronously.
+func (m *InterfaceManager) HandleUEvent(ev *udev.UEvent) {
+	m.handleUEvent(ev)
+}
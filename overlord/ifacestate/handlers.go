@@ -69,6 +69,12 @@ func (m *InterfaceManager) doSetupProfiles(task *state.Task, _ *tomb.Tomb) error
 	//   - if a connection cannot be restored then remove it from the state
 	// - setup the security of all the affected snaps
 	blacklist := m.repo.AutoConnectBlacklist(snapName)
+	// Hotplug slots of the OS snap are not described by its snap.yaml and
+	// need to be carried over explicitly.
+	hotplugSlots, err := m.presentHotplugSlots(task.State(), snapName)
+	if err != nil {
+		return err
+	}
 	affectedSnaps, err := m.repo.DisconnectSnap(snapName)
 	if err != nil {
 		return err
@@ -85,6 +91,11 @@ func (m *InterfaceManager) doSetupProfiles(task *state.Task, _ *tomb.Tomb) error
 			return err
 		}
 	}
+	for _, hs := range hotplugSlots {
+		if _, err := m.addHotplugSlot(task.State(), snapInfo, hs); err != nil {
+			logger.Noticef("cannot restore hotplug slot %q: %s", hs.Name, err)
+		}
+	}
 	if err := m.reloadConnections(snapName); err != nil {
 		return err
 	}
@@ -296,21 +307,54 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	// Find out what is going to be disconnected before doing so, either side
+	// may be left unspecified to disconnect everything from the other one.
+	var affected []interfaces.ConnRef
+	for _, conn := range m.repo.Connections() {
+		if matchesConnRef(&conn, plugRef, slotRef) {
+			affected = append(affected, conn)
+		}
+	}
+	snaps := make(map[string]*snap.Info)
+	var snapNames []string
+	for _, conn := range affected {
+		for _, snapInfo := range []*snap.Info{
+			m.repo.Plug(conn.PlugRef.Snap, conn.PlugRef.Name).Snap,
+			m.repo.Slot(conn.SlotRef.Snap, conn.SlotRef.Name).Snap,
+		} {
+			if snaps[snapInfo.Name()] == nil {
+				snaps[snapInfo.Name()] = snapInfo
+				snapNames = append(snapNames, snapInfo.Name())
+			}
+		}
+	}
+
 	err = m.repo.Disconnect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name)
 	if err != nil {
 		return err
 	}
 
-	plug := m.repo.Plug(plugRef.Snap, plugRef.Name)
-	slot := m.repo.Slot(slotRef.Snap, slotRef.Name)
-	if err := setupSnapSecurity(task, plug.Snap, m.repo); err != nil {
-		return state.Retry
-	}
-	if err := setupSnapSecurity(task, slot.Snap, m.repo); err != nil {
-		return state.Retry
+	for _, snapName := range snapNames {
+		if err := setupSnapSecurity(task, snaps[snapName], m.repo); err != nil {
+			return state.Retry
+		}
 	}
 
-	delete(conns, connID(plugRef, slotRef))
+	// Remember that the user does not want these connections so that they
+	// are not established again when the snaps are refreshed.
+	for _, conn := range affected {
+		iface := m.repo.Plug(conn.PlugRef.Snap, conn.PlugRef.Name).Interface
+		conns[conn.ID()] = connState{Interface: iface, Undesired: true}
+	}
 	setConns(st, conns)
 	return nil
 }
+
+// matchesConnRef checks if a connection is matched by the given, possibly
+// partial, plug and slot references.
+func matchesConnRef(conn *interfaces.ConnRef, plugRef *interfaces.PlugRef, slotRef *interfaces.SlotRef) bool {
+	return (plugRef.Snap == "" || plugRef.Snap == conn.PlugRef.Snap) &&
+		(plugRef.Name == "" || plugRef.Name == conn.PlugRef.Name) &&
+		(slotRef.Snap == "" || slotRef.Snap == conn.SlotRef.Snap) &&
+		(slotRef.Name == "" || slotRef.Name == conn.SlotRef.Name)
+}
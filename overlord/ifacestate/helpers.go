@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/interfaces/dbus"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -88,7 +89,10 @@ func (m *InterfaceManager) reloadConnections(snapName string) error {
 	if err != nil {
 		return err
 	}
-	for id := range conns {
+	for id, conn := range conns {
+		if conn.Undesired {
+			continue
+		}
 		plugRef, slotRef, err := parseConnID(id)
 		if err != nil {
 			return err
@@ -141,6 +145,10 @@ func removeSnapSecurity(task *state.Task, snapName string) error {
 type connState struct {
 	Auto      bool   `json:"auto,omitempty"`
 	Interface string `json:"interface,omitempty"`
+	// Undesired is set for connections that were disconnected by the user.
+	// Such connections are not established again automatically until the
+	// user connects them explicitly.
+	Undesired bool `json:"undesired,omitempty"`
 }
 
 func connID(plug *interfaces.PlugRef, slot *interfaces.SlotRef) string {
@@ -181,11 +189,14 @@ func (m *InterfaceManager) autoConnect(task *state.Task, snapName string, blackl
 			continue
 		}
 		slot := candidates[0]
+		key := fmt.Sprintf("%s:%s %s:%s", snapName, plug.Name, slot.Snap.Name(), slot.Name)
+		if conns[key].Undesired {
+			continue
+		}
 		if err := m.repo.Connect(snapName, plug.Name, slot.Snap.Name(), slot.Name); err != nil {
 			task.Logf("cannot auto connect %s:%s to %s:%s: %s",
 				snapName, plug.Name, slot.Snap.Name(), slot.Name, err)
 		}
-		key := fmt.Sprintf("%s:%s %s:%s", snapName, plug.Name, slot.Snap.Name(), slot.Name)
 		conns[key] = connState{Interface: plug.Interface, Auto: true}
 	}
 	task.State().Set("conns", conns)
@@ -222,7 +233,7 @@ func setConns(st *state.State, conns map[string]connState) {
 }
 
 var securityBackends = []interfaces.SecurityBackend{
-	&seccomp.Backend{}, &dbus.Backend{}, &udev.Backend{},
+	&seccomp.Backend{}, &dbus.Backend{}, &udev.Backend{}, &systemd.Backend{},
 }
 
 func init() {
@@ -0,0 +1,429 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// UEventSource is a source of device events driving hotplugged slots.
+type UEventSource interface {
+	// Events returns a channel delivering device events. The channel is
+	// closed when the source is closed.
+	Events() <-chan *udev.UEvent
+	// Close stops the delivery of events.
+	Close() error
+}
+
+// hotplugMatcher describes which devices are offered as slots of a given
+// interface and with which attributes.
+type hotplugMatcher struct {
+	iface string
+	match func(ev *udev.UEvent) (attrs map[string]interface{}, ok bool)
+}
+
+var usbSerialDevName = regexp.MustCompile("^tty(USB|ACM)[0-9]{1,3}$")
+
+var hotplugMatchers = []hotplugMatcher{{
+	iface: "serial-port",
+	match: func(ev *udev.UEvent) (map[string]interface{}, bool) {
+		if ev.Subsystem != "tty" || !usbSerialDevName.MatchString(ev.DevName) {
+			return nil, false
+		}
+		return map[string]interface{}{"path": "/dev/" + ev.DevName}, true
+	},
+}}
+
+// hotplugSubsystems returns the kernel subsystems the matchers care about.
+func hotplugSubsystems() []string {
+	return []string{"tty"}
+}
+
+var newUEventSource = func() (UEventSource, error) {
+	return udev.NewMonitor(hotplugSubsystems()...)
+}
+
+// MockUEventSource replaces the source of device events used for hotplug.
+//
+// This function is public because it is referenced in the daemon and the
+// overlord tests.
+func MockUEventSource(f func() (UEventSource, error)) (restore func()) {
+	old := newUEventSource
+	newUEventSource = f
+	return func() { newUEventSource = old }
+}
+
+// hotplugSlot is the persistent record of a device offered as a slot.
+//
+// Records are kept after the device goes away so that the slot gets the same
+// name, and therefore the same connections, when the device comes back.
+type hotplugSlot struct {
+	Name      string                 `json:"name"`
+	Interface string                 `json:"interface"`
+	Attrs     map[string]interface{} `json:"attrs,omitempty"`
+	DevPath   string                 `json:"devpath,omitempty"`
+}
+
+func getHotplugSlots(st *state.State) (map[string]*hotplugSlot, error) {
+	var slots map[string]*hotplugSlot
+	err := st.Get("hotplug-slots", &slots)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("cannot obtain data about hotplug slots: %s", err)
+	}
+	if slots == nil {
+		slots = make(map[string]*hotplugSlot)
+	}
+	return slots, nil
+}
+
+func setHotplugSlots(st *state.State, slots map[string]*hotplugSlot) {
+	st.Set("hotplug-slots", slots)
+}
+
+// hotplugDeviceKey returns a key identifying a device across re-plugging.
+//
+// USB devices are identified by their vendor, model and serial number. Other
+// devices are identified by their position in the device tree.
+func hotplugDeviceKey(ifaceName string, ev *udev.UEvent) string {
+	vendor, model := ev.Env["ID_VENDOR_ID"], ev.Env["ID_MODEL_ID"]
+	if vendor != "" && model != "" {
+		return fmt.Sprintf("%s %s:%s:%s", ifaceName, vendor, model, ev.Env["ID_SERIAL_SHORT"])
+	}
+	return fmt.Sprintf("%s %s", ifaceName, ev.DevPath)
+}
+
+// hotplugSlotName picks a name for a new hotplug slot that is not used by any
+// other slot of the OS snap, nor reserved by another hotplug device.
+func (m *InterfaceManager) hotplugSlotName(coreName, ifaceName string, slots map[string]*hotplugSlot) string {
+	reserved := make(map[string]bool)
+	for _, hs := range slots {
+		reserved[hs.Name] = true
+	}
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("%s-%d", ifaceName, n)
+		if !reserved[name] && m.repo.Slot(coreName, name) == nil {
+			return name
+		}
+	}
+}
+
+// coreSnapInfo returns the info of the OS snap as known to the repository.
+func (m *InterfaceManager) coreSnapInfo(st *state.State) (*snap.Info, error) {
+	all, err := snapstate.All(st)
+	if err != nil {
+		return nil, err
+	}
+	// sort names to be deterministic in the unlikely case of more than one
+	var names []string
+	for name, snapst := range all {
+		if typ, err := snapst.Type(); err == nil && typ == snap.TypeOS {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("cannot find the OS snap")
+	}
+	sort.Strings(names)
+	// Use the info shared by the slots in the repository, if any.
+	if slots := m.repo.Slots(names[0]); len(slots) > 0 {
+		return slots[0].Snap, nil
+	}
+	info, err := snapstate.CurrentInfo(st, names[0])
+	if err != nil {
+		return nil, err
+	}
+	snap.AddImplicitSlots(info)
+	return info, nil
+}
+
+// startHotplug starts following device events, if not done already.
+func (m *InterfaceManager) startHotplug() {
+	if m.hotplugStarted {
+		return
+	}
+	m.hotplugStarted = true
+
+	source, err := newUEventSource()
+	if err != nil {
+		logger.Noticef("cannot monitor hotplug devices: %s", err)
+		return
+	}
+	m.uevents = source
+	m.hotplugTomb.Go(func() error {
+		for ev := range source.Events() {
+			m.handleUEvent(ev)
+		}
+		return nil
+	})
+}
+
+// stopHotplug stops following device events.
+func (m *InterfaceManager) stopHotplug() {
+	if m.uevents == nil {
+		return
+	}
+	if err := m.uevents.Close(); err != nil {
+		logger.Noticef("cannot stop monitoring hotplug devices: %s", err)
+	}
+	m.hotplugTomb.Wait()
+	m.uevents = nil
+}
+
+// handleUEvent turns a device event into a change adding or removing the
+// corresponding hotplug slot.
+func (m *InterfaceManager) handleUEvent(ev *udev.UEvent) {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	slots, err := getHotplugSlots(st)
+	if err != nil {
+		logger.Noticef("%s", err)
+		return
+	}
+
+	var key, kind, summary string
+	switch ev.Action {
+	case "add":
+		for _, matcher := range hotplugMatchers {
+			attrs, ok := matcher.match(ev)
+			if !ok {
+				continue
+			}
+			key = hotplugDeviceKey(matcher.iface, ev)
+			hs := slots[key]
+			if hs == nil {
+				coreInfo, err := m.coreSnapInfo(st)
+				if err != nil {
+					logger.Noticef("cannot add hotplug slot for device %s: %s", ev.DevPath, err)
+					return
+				}
+				hs = &hotplugSlot{
+					Name:      m.hotplugSlotName(coreInfo.Name(), matcher.iface, slots),
+					Interface: matcher.iface,
+				}
+				slots[key] = hs
+			}
+			// a device plugged into the port of a device whose removal
+			// went unnoticed takes over its devpath
+			for k, other := range slots {
+				if k != key && other.DevPath == ev.DevPath {
+					other.DevPath = ""
+				}
+			}
+			hs.Attrs = attrs
+			hs.DevPath = ev.DevPath
+			kind = "hotplug-add-slot"
+			summary = fmt.Sprintf(i18n.G("Add hotplug slot %q for device %s"), hs.Name, ev.DevPath)
+			break
+		}
+	case "remove":
+		coreInfo, err := m.coreSnapInfo(st)
+		if err != nil {
+			logger.Noticef("cannot remove hotplug slot for device %s: %s", ev.DevPath, err)
+			return
+		}
+		for k, hs := range slots {
+			if hs.DevPath != ev.DevPath || m.repo.Slot(coreInfo.Name(), hs.Name) == nil {
+				continue
+			}
+			// the devpath identifies the port, not the device; forget it
+			// so that a different device plugged in there later is not
+			// mistaken for this one
+			hs.DevPath = ""
+			key = k
+			kind = "hotplug-remove-slot"
+			summary = fmt.Sprintf(i18n.G("Remove hotplug slot %q for device %s"), hs.Name, ev.DevPath)
+			break
+		}
+	}
+	if kind == "" {
+		return
+	}
+	setHotplugSlots(st, slots)
+
+	task := st.NewTask(kind, summary)
+	task.Set("device-key", key)
+	chg := st.NewChange(kind, summary)
+	chg.AddTask(task)
+	st.EnsureBefore(0)
+}
+
+func getHotplugSlot(task *state.Task) (*hotplugSlot, error) {
+	var key string
+	if err := task.Get("device-key", &key); err != nil {
+		return nil, err
+	}
+	slots, err := getHotplugSlots(task.State())
+	if err != nil {
+		return nil, err
+	}
+	hs := slots[key]
+	if hs == nil {
+		return nil, fmt.Errorf("cannot find hotplug device %q", key)
+	}
+	return hs, nil
+}
+
+// addHotplugSlot adds the hotplug slot to the repository and restores its
+// connections. The return value is the list of snaps affected by the
+// restored connections.
+func (m *InterfaceManager) addHotplugSlot(st *state.State, coreInfo *snap.Info, hs *hotplugSlot) ([]string, error) {
+	coreName := coreInfo.Name()
+	if m.repo.Slot(coreName, hs.Name) == nil {
+		slot := &interfaces.Slot{SlotInfo: &snap.SlotInfo{
+			Snap:      coreInfo,
+			Name:      hs.Name,
+			Interface: hs.Interface,
+			Attrs:     hs.Attrs,
+		}}
+		if err := m.repo.AddSlot(slot); err != nil {
+			return nil, err
+		}
+	}
+	conns, err := getConns(st)
+	if err != nil {
+		return nil, err
+	}
+	var affected []string
+	for id, conn := range conns {
+		if conn.Undesired {
+			continue
+		}
+		plugRef, slotRef, err := parseConnID(id)
+		if err != nil {
+			return nil, err
+		}
+		if slotRef.Snap != coreName || slotRef.Name != hs.Name {
+			continue
+		}
+		if err := m.repo.Connect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name); err != nil {
+			logger.Noticef("%s", err)
+			continue
+		}
+		affected = append(affected, plugRef.Snap)
+	}
+	return affected, nil
+}
+
+func (m *InterfaceManager) doHotplugAddSlot(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	hs, err := getHotplugSlot(task)
+	if err != nil {
+		return err
+	}
+	coreInfo, err := m.coreSnapInfo(st)
+	if err != nil {
+		return err
+	}
+	affected, err := m.addHotplugSlot(st, coreInfo, hs)
+	if err != nil {
+		return err
+	}
+	return m.setupAffectedSnaps(task, coreInfo, affected)
+}
+
+func (m *InterfaceManager) doHotplugRemoveSlot(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	hs, err := getHotplugSlot(task)
+	if err != nil {
+		return err
+	}
+	coreInfo, err := m.coreSnapInfo(st)
+	if err != nil {
+		return err
+	}
+	coreName := coreInfo.Name()
+	slot := m.repo.Slot(coreName, hs.Name)
+	if slot == nil {
+		return nil
+	}
+	// Connections are kept in the state so that they are restored when the
+	// device is plugged in again.
+	var affected []string
+	for _, plugRef := range slot.Connections {
+		affected = append(affected, plugRef.Snap)
+	}
+	if err := m.repo.Disconnect("", "", coreName, hs.Name); err != nil {
+		return err
+	}
+	if err := m.repo.RemoveSlot(coreName, hs.Name); err != nil {
+		return err
+	}
+	return m.setupAffectedSnaps(task, coreInfo, affected)
+}
+
+// setupAffectedSnaps sets up the security of the OS snap and of the given
+// snaps so that the generated profiles and rules reflect the hotplug change.
+func (m *InterfaceManager) setupAffectedSnaps(task *state.Task, coreInfo *snap.Info, affected []string) error {
+	if err := setupSnapSecurity(task, coreInfo, m.repo); err != nil {
+		return state.Retry
+	}
+	seen := make(map[string]bool)
+	for _, snapName := range affected {
+		if seen[snapName] || snapName == coreInfo.Name() {
+			continue
+		}
+		seen[snapName] = true
+		snapInfo, err := snapstate.CurrentInfo(task.State(), snapName)
+		if err != nil {
+			return err
+		}
+		snap.AddImplicitSlots(snapInfo)
+		if err := setupSnapSecurity(task, snapInfo, m.repo); err != nil {
+			return state.Retry
+		}
+	}
+	return nil
+}
+
+// presentHotplugSlots returns the records of the hotplug slots of the given
+// snap that are currently in the repository.
+func (m *InterfaceManager) presentHotplugSlots(st *state.State, snapName string) ([]*hotplugSlot, error) {
+	slots, err := getHotplugSlots(st)
+	if err != nil {
+		return nil, err
+	}
+	var present []*hotplugSlot
+	for _, hs := range slots {
+		if m.repo.Slot(snapName, hs.Name) != nil {
+			present = append(present, hs)
+		}
+	}
+	return present, nil
+}
@@ -0,0 +1,273 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type fakeUEventSource struct {
+	events chan *udev.UEvent
+	closed bool
+}
+
+func newFakeUEventSource() *fakeUEventSource {
+	return &fakeUEventSource{events: make(chan *udev.UEvent)}
+}
+
+func (s *fakeUEventSource) Events() <-chan *udev.UEvent {
+	return s.events
+}
+
+func (s *fakeUEventSource) Close() error {
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	return nil
+}
+
+var serialConsumerYaml = `
+name: serial-consumer
+version: 1
+apps:
+ app:
+  command: foo
+plugs:
+ serial-port:
+`
+
+func (s *interfaceManagerSuite) mockCoreSnap(c *C) *snap.Info {
+	sideInfo := &snap.SideInfo{}
+	snapInfo := snaptest.MockSnap(c, osSnapYaml, sideInfo)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, snapInfo.Name(), &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{sideInfo},
+		SnapType: "os",
+	})
+	return snapInfo
+}
+
+func usbSerialEvent(action string) *udev.UEvent {
+	return &udev.UEvent{
+		Action:    action,
+		DevPath:   "/devices/pci0000:00/usb1/1-1/1-1:1.0/ttyUSB0/tty/ttyUSB0",
+		Subsystem: "tty",
+		DevName:   "ttyUSB0",
+		Env: map[string]string{
+			"ID_VENDOR_ID":    "0403",
+			"ID_MODEL_ID":     "6001",
+			"ID_SERIAL_SHORT": "A50285BI",
+		},
+	}
+}
+
+func (s *interfaceManagerSuite) settle(c *C, mgr *ifacestate.InterfaceManager) {
+	mgr.Ensure()
+	mgr.Wait()
+}
+
+func (s *interfaceManagerSuite) TestHotplugAddsSlot(c *C) {
+	s.mockCoreSnap(c)
+	mgr := s.manager(c)
+
+	mgr.HandleUEvent(usbSerialEvent("add"))
+	s.settle(c, mgr)
+
+	slot := mgr.Repository().Slot("ubuntu-core", "serial-port-1")
+	c.Assert(slot, NotNil)
+	c.Check(slot.Interface, Equals, "serial-port")
+	c.Check(slot.Attrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB0"})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var hotplugSlots map[string]interface{}
+	err := s.state.Get("hotplug-slots", &hotplugSlots)
+	c.Assert(err, IsNil)
+	c.Check(hotplugSlots, DeepEquals, map[string]interface{}{
+		"serial-port 0403:6001:A50285BI": map[string]interface{}{
+			"name":      "serial-port-1",
+			"interface": "serial-port",
+			"attrs":     map[string]interface{}{"path": "/dev/ttyUSB0"},
+			"devpath":   "/devices/pci0000:00/usb1/1-1/1-1:1.0/ttyUSB0/tty/ttyUSB0",
+		},
+	})
+	c.Check(s.state.Changes(), HasLen, 1)
+	c.Check(s.state.Changes()[0].Status(), Equals, state.DoneStatus)
+}
+
+func (s *interfaceManagerSuite) TestHotplugIgnoresUnmatchedDevices(c *C) {
+	s.mockCoreSnap(c)
+	mgr := s.manager(c)
+
+	ev := usbSerialEvent("add")
+	ev.DevName = "tty1"
+	mgr.HandleUEvent(ev)
+	s.settle(c, mgr)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestHotplugNamesAreStableAndConnectionsRestored(c *C) {
+	s.mockCoreSnap(c)
+	s.mockSnap(c, serialConsumerYaml)
+	mgr := s.manager(c)
+	repo := mgr.Repository()
+
+	mgr.HandleUEvent(usbSerialEvent("add"))
+	s.settle(c, mgr)
+	c.Assert(repo.Slot("ubuntu-core", "serial-port-1"), NotNil)
+
+	// A second, different device gets a different name.
+	other := usbSerialEvent("add")
+	other.DevName = "ttyUSB1"
+	other.DevPath = "/devices/pci0000:00/usb1/1-2/1-2:1.0/ttyUSB1/tty/ttyUSB1"
+	other.Env["ID_SERIAL_SHORT"] = "B60396CJ"
+	mgr.HandleUEvent(other)
+	s.settle(c, mgr)
+	c.Assert(repo.Slot("ubuntu-core", "serial-port-2"), NotNil)
+
+	// Connect the first device.
+	s.state.Lock()
+	chg := s.state.NewChange("connect", "")
+	ts, err := ifacestate.Connect(s.state, "serial-consumer", "serial-port", "ubuntu-core", "serial-port-1")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	s.state.Unlock()
+	s.settle(c, mgr)
+	c.Assert(repo.Plug("serial-consumer", "serial-port").Connections, HasLen, 1)
+
+	// Unplugging the device removes the slot and the connection.
+	s.secBackend.SetupCalls = nil
+	mgr.HandleUEvent(usbSerialEvent("remove"))
+	s.settle(c, mgr)
+	c.Check(repo.Slot("ubuntu-core", "serial-port-1"), IsNil)
+	c.Check(repo.Plug("serial-consumer", "serial-port").Connections, HasLen, 0)
+	c.Assert(s.secBackend.SetupCalls, HasLen, 2)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.Name(), Equals, "ubuntu-core")
+	c.Check(s.secBackend.SetupCalls[1].SnapInfo.Name(), Equals, "serial-consumer")
+
+	// Plugging it back, even on another port, restores the slot under the
+	// same name and with the same connection.
+	back := usbSerialEvent("add")
+	back.DevName = "ttyUSB3"
+	back.DevPath = "/devices/pci0000:00/usb1/1-4/1-4:1.0/ttyUSB3/tty/ttyUSB3"
+	mgr.HandleUEvent(back)
+	s.settle(c, mgr)
+	slot := repo.Slot("ubuntu-core", "serial-port-1")
+	c.Assert(slot, NotNil)
+	c.Check(slot.Attrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB3"})
+	c.Check(slot.Connections, DeepEquals, []interfaces.PlugRef{{Snap: "serial-consumer", Name: "serial-port"}})
+}
+
+func (s *interfaceManagerSuite) TestHotplugDevicesSharingAPort(c *C) {
+	s.mockCoreSnap(c)
+	mgr := s.manager(c)
+	repo := mgr.Repository()
+
+	mgr.HandleUEvent(usbSerialEvent("add"))
+	s.settle(c, mgr)
+	mgr.HandleUEvent(usbSerialEvent("remove"))
+	s.settle(c, mgr)
+	c.Assert(repo.Slot("ubuntu-core", "serial-port-1"), IsNil)
+
+	// A different device plugged into the same port.
+	other := usbSerialEvent("add")
+	other.Env["ID_SERIAL_SHORT"] = "B60396CJ"
+	mgr.HandleUEvent(other)
+	s.settle(c, mgr)
+	c.Assert(repo.Slot("ubuntu-core", "serial-port-2"), NotNil)
+
+	// Unplugging it removes its own slot.
+	mgr.HandleUEvent(usbSerialEvent("remove"))
+	s.settle(c, mgr)
+	c.Check(repo.Slot("ubuntu-core", "serial-port-1"), IsNil)
+	c.Check(repo.Slot("ubuntu-core", "serial-port-2"), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var hotplugSlots map[string]map[string]interface{}
+	err := s.state.Get("hotplug-slots", &hotplugSlots)
+	c.Assert(err, IsNil)
+	c.Check(hotplugSlots, HasLen, 2)
+	for _, hs := range hotplugSlots {
+		c.Check(hs["devpath"], IsNil)
+	}
+	for _, chg := range s.state.Changes() {
+		c.Check(chg.Status(), Equals, state.DoneStatus)
+	}
+}
+
+func (s *interfaceManagerSuite) TestHotplugEventsFromSource(c *C) {
+	s.mockCoreSnap(c)
+	mgr := s.manager(c)
+	// The event source is started by the first Ensure.
+	s.settle(c, mgr)
+
+	s.uevents.events <- usbSerialEvent("add")
+
+	for i := 0; i < 100; i++ {
+		s.state.Lock()
+		n := len(s.state.Changes())
+		s.state.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.settle(c, mgr)
+	c.Check(mgr.Repository().Slot("ubuntu-core", "serial-port-1"), NotNil)
+
+	mgr.Stop()
+	c.Check(s.uevents.closed, Equals, true)
+}
+
+func (s *interfaceManagerSuite) TestHotplugSlotsSurviveOSSnapRefresh(c *C) {
+	coreInfo := s.mockCoreSnap(c)
+	mgr := s.manager(c)
+
+	mgr.HandleUEvent(usbSerialEvent("add"))
+	s.settle(c, mgr)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: coreInfo.Name(), Revision: coreInfo.Revision})
+	s.settle(c, mgr)
+
+	s.state.Lock()
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	s.state.Unlock()
+	c.Check(mgr.Repository().Slot("ubuntu-core", "serial-port-1"), NotNil)
+}
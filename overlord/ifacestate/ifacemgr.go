@@ -24,6 +24,8 @@ package ifacestate
 import (
 	"fmt"
 
+	"gopkg.in/tomb.v2"
+
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/overlord/state"
@@ -36,6 +38,10 @@ type InterfaceManager struct {
 	state  *state.State
 	runner *state.TaskRunner
 	repo   *interfaces.Repository
+
+	hotplugStarted bool
+	hotplugTomb    tomb.Tomb
+	uevents        UEventSource
 }
 
 // Manager returns a new InterfaceManager.
@@ -55,6 +61,9 @@ func Manager(s *state.State, extra []interfaces.Interface) (*InterfaceManager, e
 	runner.AddHandler("setup-profiles", m.doSetupProfiles, m.doRemoveProfiles)
 	runner.AddHandler("remove-profiles", m.doRemoveProfiles, m.doSetupProfiles)
 	runner.AddHandler("discard-conns", m.doDiscardConns, m.undoDiscardConns)
+	runner.AddHandler("hotplug-add-slot", m.doHotplugAddSlot, nil)
+	runner.AddHandler("hotplug-remove-slot", m.doHotplugRemoveSlot, nil)
+	runner.AddHandler("repair-interfaces", m.doRepairInterfaces, nil)
 	return m, nil
 }
 
@@ -73,19 +82,58 @@ func Connect(s *state.State, plugSnap, plugName, slotSnap, slotName string) (*st
 }
 
 // Disconnect returns a set of tasks for  disconnecting an interface.
+//
+// The plug or the slot side can be left empty to disconnect everything from
+// the other side, as supported by Repository.Disconnect.
 func Disconnect(s *state.State, plugSnap, plugName, slotSnap, slotName string) (*state.TaskSet, error) {
 	// TODO: Remove the intent-to-connect from the state so that we no longer
 	// automatically try to reconnect on reboot.
-	summary := fmt.Sprintf(i18n.G("Disconnect %s:%s from %s:%s"),
-		plugSnap, plugName, slotSnap, slotName)
+	var summary string
+	switch {
+	case plugSnap == "" && plugName == "" && slotName == "":
+		summary = fmt.Sprintf(i18n.G("Disconnect all plugs from snap %s"), slotSnap)
+	case plugSnap == "" && plugName == "":
+		summary = fmt.Sprintf(i18n.G("Disconnect all plugs from %s:%s"), slotSnap, slotName)
+	case slotSnap == "" && slotName == "":
+		summary = fmt.Sprintf(i18n.G("Disconnect %s:%s from all slots"), plugSnap, plugName)
+	default:
+		summary = fmt.Sprintf(i18n.G("Disconnect %s:%s from %s:%s"),
+			plugSnap, plugName, slotSnap, slotName)
+	}
 	task := s.NewTask("disconnect", summary)
 	task.Set("slot", interfaces.SlotRef{Snap: slotSnap, Name: slotName})
 	task.Set("plug", interfaces.PlugRef{Snap: plugSnap, Name: plugName})
 	return state.NewTaskSet(task), nil
 }
 
+// ConnectionState describes a connection as recorded by the interface manager.
+type ConnectionState struct {
+	Interface string
+	// Auto is set for connections made automatically rather than on request.
+	Auto bool
+}
+
+// ConnectionStates returns the connections recorded in the state, indexed by
+// connection identifier (see interfaces.ConnRef.ID). Connections disconnected
+// by the user are not included.
+func ConnectionStates(st *state.State) (map[string]ConnectionState, error) {
+	conns, err := getConns(st)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]ConnectionState, len(conns))
+	for id, conn := range conns {
+		if conn.Undesired {
+			continue
+		}
+		result[id] = ConnectionState{Interface: conn.Interface, Auto: conn.Auto}
+	}
+	return result, nil
+}
+
 // Ensure implements StateManager.Ensure.
 func (m *InterfaceManager) Ensure() error {
+	m.startHotplug()
 	m.runner.Ensure()
 	return nil
 }
@@ -97,6 +145,7 @@ func (m *InterfaceManager) Wait() {
 
 // Stop implements StateManager.Stop.
 func (m *InterfaceManager) Stop() {
+	m.stopHotplug()
 	m.runner.Stop()
 
 }
@@ -41,6 +41,8 @@ type interfaceManagerSuite struct {
 	extraIfaces     []interfaces.Interface
 	secBackend      *interfaces.TestSecurityBackend
 	restoreBackends func()
+	restoreUEvents  func()
+	uevents         *fakeUEventSource
 }
 
 var _ = Suite(&interfaceManagerSuite{})
@@ -53,6 +55,10 @@ func (s *interfaceManagerSuite) SetUpTest(c *C) {
 	s.extraIfaces = nil
 	s.secBackend = &interfaces.TestSecurityBackend{}
 	s.restoreBackends = ifacestate.MockSecurityBackends([]interfaces.SecurityBackend{s.secBackend})
+	s.uevents = newFakeUEventSource()
+	s.restoreUEvents = ifacestate.MockUEventSource(func() (ifacestate.UEventSource, error) {
+		return s.uevents, nil
+	})
 }
 
 func (s *interfaceManagerSuite) TearDownTest(c *C) {
@@ -61,6 +67,7 @@ func (s *interfaceManagerSuite) TearDownTest(c *C) {
 	}
 	dirs.SetRootDir("")
 	s.restoreBackends()
+	s.restoreUEvents()
 }
 
 func (s *interfaceManagerSuite) manager(c *C) *ifacestate.InterfaceManager {
@@ -376,6 +383,56 @@ func (s *interfaceManagerSuite) TestDoSetupSnapSecuirtyAutoConnects(c *C) {
 	c.Check(plug.Connections, HasLen, 1)
 }
 
+// The setup-profiles task will not auto-connect a plug that the user has
+// disconnected, even if the snap was refreshed in the meantime.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityHonorsUndesiredConnection(c *C) {
+	// Add an OS snap.
+	s.mockSnap(c, osSnapYaml)
+
+	// Remember that the user disconnected the "network" plug before.
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"snap:network ubuntu-core:network": map[string]interface{}{
+			"interface": "network", "undesired": true,
+		},
+	})
+	s.state.Unlock()
+
+	// Initialize the manager. This registers the OS snap.
+	mgr := s.manager(c)
+
+	// Add a sample snap with a "network" plug which would be auto-connected.
+	snapInfo := s.mockSnap(c, sampleSnapYaml)
+
+	// Run the setup-snap-security task and let it finish.
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// Ensure that the task succeeded.
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	// Ensure that the connection is still remembered as undesired.
+	var conns map[string]interface{}
+	err := s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"snap:network ubuntu-core:network": map[string]interface{}{
+			"interface": "network", "undesired": true,
+		},
+	})
+
+	// Ensure that "network" is really disconnected.
+	plug := mgr.Repository().Plug("snap", "network")
+	c.Assert(plug, Not(IsNil))
+	c.Check(plug.Connections, HasLen, 0)
+}
+
 // The setup-profiles task will only touch connection state for the task it
 // operates on or auto-connects to and will leave other state intact.
 func (s *interfaceManagerSuite) TestDoSetupSnapSecuirtyKeepsExistingConnectionState(c *C) {
@@ -927,7 +984,181 @@ func (s *interfaceManagerSuite) TestDisconnectTracksConnectionsInState(c *C) {
 	var conns map[string]interface{}
 	err = s.state.Get("conns", &conns)
 	c.Assert(err, IsNil)
-	c.Check(conns, DeepEquals, map[string]interface{}{})
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test", "undesired": true,
+		},
+	})
+}
+
+func (s *interfaceManagerSuite) TestConnectClearsUndesiredConnection(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "undesired": true},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.Repository().Plug("consumer", "plug").Connections, HasLen, 0)
+
+	s.state.Lock()
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	change := s.state.NewChange("connect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	var conns map[string]interface{}
+	err = s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test"},
+	})
+}
+
+func (s *interfaceManagerSuite) TestDisconnectTaskSummaries(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, t := range []struct {
+		plugSnap, plugName, slotSnap, slotName string
+		summary                                string
+	}{
+		{"consumer", "plug", "producer", "slot", "Disconnect consumer:plug from producer:slot"},
+		{"consumer", "plug", "", "", "Disconnect consumer:plug from all slots"},
+		{"", "", "producer", "slot", "Disconnect all plugs from producer:slot"},
+		{"", "", "producer", "", "Disconnect all plugs from snap producer"},
+	} {
+		ts, err := ifacestate.Disconnect(s.state, t.plugSnap, t.plugName, t.slotSnap, t.slotName)
+		c.Assert(err, IsNil)
+		c.Check(ts.Tasks()[0].Summary(), Equals, t.summary)
+	}
+}
+
+var otherProducerYaml = `
+name: other-producer
+version: 1
+slots:
+ slot:
+  interface: test
+`
+
+func (s *interfaceManagerSuite) TestDisconnectEverythingFromPlug(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	s.mockSnap(c, otherProducerYaml)
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot":       map[string]interface{}{"interface": "test"},
+		"consumer:plug other-producer:slot": map[string]interface{}{"interface": "test", "auto": true},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	repo := mgr.Repository()
+	c.Assert(repo.Plug("consumer", "plug").Connections, HasLen, 2)
+
+	s.state.Lock()
+	ts, err := ifacestate.Disconnect(s.state, "consumer", "plug", "", "")
+	c.Assert(err, IsNil)
+	change := s.state.NewChange("disconnect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	c.Check(repo.Plug("consumer", "plug").Connections, HasLen, 0)
+	c.Check(repo.Slot("producer", "slot").Connections, HasLen, 0)
+	c.Check(repo.Slot("other-producer", "slot").Connections, HasLen, 0)
+
+	// Each affected snap had its security set up once.
+	c.Assert(s.secBackend.SetupCalls, HasLen, 3)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.Name(), Equals, "consumer")
+	c.Check(s.secBackend.SetupCalls[1].SnapInfo.Name(), Equals, "other-producer")
+	c.Check(s.secBackend.SetupCalls[2].SnapInfo.Name(), Equals, "producer")
+
+	var conns map[string]interface{}
+	err = s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot":       map[string]interface{}{"interface": "test", "undesired": true},
+		"consumer:plug other-producer:slot": map[string]interface{}{"interface": "test", "undesired": true},
+	})
+}
+
+func (s *interfaceManagerSuite) TestDisconnectEverythingFromSlot(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test"},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	ts, err := ifacestate.Disconnect(s.state, "", "", "producer", "slot")
+	c.Assert(err, IsNil)
+	change := s.state.NewChange("disconnect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	c.Check(mgr.Repository().Slot("producer", "slot").Connections, HasLen, 0)
+	var conns map[string]interface{}
+	err = s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "undesired": true},
+	})
+}
+
+func (s *interfaceManagerSuite) TestManagerSkipsUndesiredConnections(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "undesired": true},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	c.Check(mgr.Repository().Plug("consumer", "plug").Connections, HasLen, 0)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	conns, err := ifacestate.ConnectionStates(s.state)
+	c.Assert(err, IsNil)
+	c.Check(conns, HasLen, 0)
 }
 
 func (s *interfaceManagerSuite) TestManagerReloadsConnections(c *C) {
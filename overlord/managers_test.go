@@ -22,17 +22,23 @@ package overlord_test
 // test the various managers and their operation together through overlord
 
 import (
+	"crypto"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/boot/boottest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
@@ -43,18 +49,29 @@ import (
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/snap/squashfs"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 )
 
+var (
+	rootPrivKey  = assertstest.GenerateKey(752)
+	storePrivKey = assertstest.GenerateKey(752)
+)
+
 type mgrsSuite struct {
 	tempdir string
 
+	storeSigning    *assertstest.StoreStack
+	serveAssertions map[string]asserts.Assertion
+
 	aa         *testutil.MockCmd
 	udev       *testutil.MockCmd
 	prevctlCmd func(...string) ([]byte, error)
 
+	restoreUEvents func()
+
 	o *overlord.Overlord
 }
 
@@ -77,6 +94,16 @@ func (ms *mgrsSuite) SetUpTest(c *C) {
 	}
 	ms.aa = testutil.MockCommand(c, "apparmor_parser", "")
 	ms.udev = testutil.MockCommand(c, "udevadm", "")
+	ms.restoreUEvents = mockNoUEvents()
+
+	ms.storeSigning = assertstest.NewStoreStack("can0nical", rootPrivKey, storePrivKey)
+	ms.serveAssertions = make(map[string]asserts.Assertion)
+	ms.serve(ms.storeSigning.StoreAccountKey)
+	ms.serve(assertstest.NewAccount(ms.storeSigning.SigningDB, "devdevdev", nil))
+	err = os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(dirs.SnapTrustedAccountKey, asserts.Encode(ms.storeSigning.TrustedKey), 0644)
+	c.Assert(err, IsNil)
 
 	o, err := overlord.New()
 	c.Assert(err, IsNil)
@@ -89,6 +116,60 @@ func (ms *mgrsSuite) TearDownTest(c *C) {
 	systemd.SystemctlCmd = ms.prevctlCmd
 	ms.udev.Restore()
 	ms.aa.Restore()
+	ms.restoreUEvents()
+}
+
+// serve makes the fake store serve the assertion.
+func (ms *mgrsSuite) serve(a asserts.Assertion) {
+	assertType := a.Type()
+	primaryKey := make([]string, len(assertType.PrimaryKey))
+	for i, k := range assertType.PrimaryKey {
+		primaryKey[i] = a.HeaderString(k)
+	}
+	ms.serveAssertions[path.Join(assertType.Name, path.Join(primaryKey...))] = a
+}
+
+func (ms *mgrsSuite) serveSnapAssertions(c *C, snapPath, revno string) {
+	size, hashDigest, err := squashfs.New(snapPath).HashDigest(crypto.SHA512)
+	c.Assert(err, IsNil)
+	digest, err := asserts.EncodeDigest(crypto.SHA512, hashDigest)
+	c.Assert(err, IsNil)
+
+	now := time.Now().Format(time.RFC3339)
+	snapDecl, err := ms.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "idididididididididididididididid",
+		"snap-name":    "foo",
+		"publisher-id": "devdevdev",
+		"gates":        "",
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, IsNil)
+	ms.serve(snapDecl)
+	snapRev, err := ms.storeSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"series":        "16",
+		"snap-id":       "idididididididididididididididid",
+		"snap-digest":   digest,
+		"snap-size":     fmt.Sprintf("%d", size),
+		"snap-revision": revno,
+		"developer-id":  "devdevdev",
+		"timestamp":     now,
+	}, nil)
+	c.Assert(err, IsNil)
+	ms.serve(snapRev)
+}
+
+func (ms *mgrsSuite) serveAssertion(w http.ResponseWriter, r *http.Request) {
+	a := ms.serveAssertions[strings.TrimPrefix(r.URL.Path, "/assertions/")]
+	if a == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"status": 404}`)
+		return
+	}
+	w.Header().Set("Content-Type", asserts.MediaType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(asserts.Encode(a))
 }
 
 func makeTestSnap(c *C, snapYamlContent string) string {
@@ -243,9 +324,14 @@ apps:
 	snapPath := makeTestSnap(c, strings.Replace(snapYamlContent, "@VERSION@", ver, -1))
 	snapR, err := os.Open(snapPath)
 	c.Assert(err, IsNil)
+	ms.serveSnapAssertions(c, snapPath, revno)
 
 	var baseURL string
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/assertions/") {
+			ms.serveAssertion(w, r)
+			return
+		}
 		switch r.URL.Path {
 		case "/search":
 			w.WriteHeader(http.StatusOK)
@@ -267,8 +353,11 @@ apps:
 
 	searchURL, err := url.Parse(baseURL + "/search")
 	c.Assert(err, IsNil)
+	assertionsURL, err := url.Parse(baseURL + "/assertions/")
+	c.Assert(err, IsNil)
 	storeCfg := store.SnapUbuntuStoreConfig{
-		SearchURI: searchURL,
+		SearchURI:     searchURL,
+		AssertionsURI: assertionsURL,
 	}
 
 	mStore := store.NewUbuntuStoreSnapRepository(&storeCfg, "")
@@ -301,6 +390,14 @@ apps:
 	c.Check(info.Description(), Equals, "this is a description")
 	c.Check(info.Developer, Equals, "bar")
 
+	// the assertions about the snap were added to the database
+	db := ms.o.AssertManager().DB()
+	_, err = db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "idididididididididididididididid",
+	})
+	c.Check(err, IsNil)
+
 	// check service was setup properly
 	svcFile := filepath.Join(dirs.SnapServicesDir, "snap.foo.svc.service")
 	c.Assert(osutil.FileExists(svcFile), Equals, true)
@@ -316,6 +413,7 @@ apps:
 	snapPath = makeTestSnap(c, strings.Replace(snapYamlContent, "@VERSION@", ver, -1))
 	snapR, err = os.Open(snapPath)
 	c.Assert(err, IsNil)
+	ms.serveSnapAssertions(c, snapPath, revno)
 
 	ts, err = snapstate.Update(st, "foo", "stable", 0, 0)
 	c.Assert(err, IsNil)
@@ -369,7 +467,7 @@ type: os
 	st.Lock()
 	defer st.Unlock()
 
-	ts, err := snapstate.InstallPath(st, "core", snapPath, "", 0)
+	ts, err := snapstate.InstallPath(st, "core", snapPath, "", snapstate.Dangerous)
 	c.Assert(err, IsNil)
 	chg := st.NewChange("install-snap", "...")
 	chg.AddAll(ts)
@@ -411,7 +509,7 @@ type: kernel`
 	st.Lock()
 	defer st.Unlock()
 
-	ts, err := snapstate.InstallPath(st, "krnl", snapPath, "", 0)
+	ts, err := snapstate.InstallPath(st, "krnl", snapPath, "", snapstate.Dangerous)
 	c.Assert(err, IsNil)
 	chg := st.NewChange("install-snap", "...")
 	chg.AddAll(ts)
@@ -34,7 +34,9 @@ import (
 	"github.com/snapcore/snapd/osutil"
 
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 )
@@ -62,6 +64,8 @@ type Overlord struct {
 	snapMgr   *snapstate.SnapManager
 	assertMgr *assertstate.AssertManager
 	ifaceMgr  *ifacestate.InterfaceManager
+	svcMgr    *servicestate.ServiceManager
+	deviceMgr *devicestate.DeviceManager
 }
 
 // New creates a new Overlord with all its state managers.
@@ -82,19 +86,25 @@ func New() (*Overlord, error) {
 
 	o.stateEng = NewStateEngine(s)
 
-	snapMgr, err := snapstate.Manager(s)
+	// the assertion manager is created first as the other managers
+	// can need the assertion database, e.g. to get the device model
+	assertMgr, err := assertstate.Manager(s)
 	if err != nil {
 		return nil, err
 	}
-	o.snapMgr = snapMgr
-	o.stateEng.AddManager(o.snapMgr)
+	o.assertMgr = assertMgr
 
-	assertMgr, err := assertstate.Manager(s)
+	snapMgr, err := snapstate.Manager(s)
 	if err != nil {
 		return nil, err
 	}
-	o.assertMgr = assertMgr
+	o.snapMgr = snapMgr
+	o.stateEng.AddManager(o.snapMgr)
+
 	o.stateEng.AddManager(o.assertMgr)
+	assertMgr.UseStore(func() assertstate.StoreService {
+		return o.snapMgr.Store()
+	})
 
 	ifaceMgr, err := ifacestate.Manager(s, nil)
 	if err != nil {
@@ -103,6 +113,20 @@ func New() (*Overlord, error) {
 	o.ifaceMgr = ifaceMgr
 	o.stateEng.AddManager(o.ifaceMgr)
 
+	svcMgr, err := servicestate.Manager(s)
+	if err != nil {
+		return nil, err
+	}
+	o.svcMgr = svcMgr
+	o.stateEng.AddManager(o.svcMgr)
+
+	deviceMgr, err := devicestate.Manager(s)
+	if err != nil {
+		return nil, err
+	}
+	o.deviceMgr = deviceMgr
+	o.stateEng.AddManager(o.deviceMgr)
+
 	return o, nil
 }
 
@@ -279,3 +303,15 @@ func (o *Overlord) AssertManager() *assertstate.AssertManager {
 func (o *Overlord) InterfaceManager() *ifacestate.InterfaceManager {
 	return o.ifaceMgr
 }
+
+// ServiceManager returns the service manager controlling the services
+// of snaps under the overlord.
+func (o *Overlord) ServiceManager() *servicestate.ServiceManager {
+	return o.svcMgr
+}
+
+// DeviceManager returns the device manager enforcing the device model
+// policies under the overlord.
+func (o *Overlord) DeviceManager() *devicestate.DeviceManager {
+	return o.deviceMgr
+}
@@ -34,15 +34,32 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/testutil"
 
 	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
 func TestOverlord(t *testing.T) { TestingT(t) }
 
-type overlordSuite struct{}
+// noUEvents is a source of device events that never reports any, used so
+// that the tests do not follow the devices of the host.
+type noUEvents chan *udev.UEvent
+
+func (s noUEvents) Events() <-chan *udev.UEvent { return s }
+func (s noUEvents) Close() error                { close(s); return nil }
+
+func mockNoUEvents() (restore func()) {
+	return ifacestate.MockUEventSource(func() (ifacestate.UEventSource, error) {
+		return make(noUEvents), nil
+	})
+}
+
+type overlordSuite struct {
+	restoreUEvents func()
+}
 
 var _ = Suite(&overlordSuite{})
 
@@ -50,10 +67,12 @@ func (ovs *overlordSuite) SetUpTest(c *C) {
 	tmpdir := c.MkDir()
 	dirs.SetRootDir(tmpdir)
 	dirs.SnapStateFile = filepath.Join(tmpdir, "test.json")
+	ovs.restoreUEvents = mockNoUEvents()
 }
 
 func (ovs *overlordSuite) TearDownTest(c *C) {
 	dirs.SetRootDir("/")
+	ovs.restoreUEvents()
 }
 
 func (ovs *overlordSuite) TestNew(c *C) {
@@ -67,6 +86,8 @@ func (ovs *overlordSuite) TestNew(c *C) {
 	c.Check(o.SnapManager(), NotNil)
 	c.Check(o.AssertManager(), NotNil)
 	c.Check(o.InterfaceManager(), NotNil)
+	c.Check(o.ServiceManager(), NotNil)
+	c.Check(o.DeviceManager(), NotNil)
 
 	s := o.State()
 	c.Check(s, NotNil)
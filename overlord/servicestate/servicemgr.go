@@ -0,0 +1,97 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package servicestate implements the manager and state aspects
+// responsible for controlling the services of installed snaps.
+package servicestate
+
+import (
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+// ServiceManager is responsible for starting, stopping, enabling and
+// disabling the services of installed snaps on request.
+type ServiceManager struct {
+	state  *state.State
+	runner *state.TaskRunner
+}
+
+// Manager returns a new ServiceManager.
+func Manager(s *state.State) (*ServiceManager, error) {
+	runner := state.NewTaskRunner(s)
+	m := &ServiceManager{
+		state:  s,
+		runner: runner,
+	}
+	runner.AddHandler("service-control", m.doServiceControl, nil)
+	return m, nil
+}
+
+// Ensure implements StateManager.Ensure.
+func (m *ServiceManager) Ensure() error {
+	m.runner.Ensure()
+	return nil
+}
+
+// Wait implements StateManager.Wait.
+func (m *ServiceManager) Wait() {
+	m.runner.Wait()
+}
+
+// Stop implements StateManager.Stop.
+func (m *ServiceManager) Stop() {
+	m.runner.Stop()
+}
+
+var controlServices = func(apps []*snap.AppInfo, action string, meter progress.Meter) error {
+	return wrappers.ControlServices(apps, action, meter)
+}
+
+func (m *ServiceManager) doServiceControl(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	var action serviceAction
+	err := t.Get("service-action", &action)
+	if err != nil {
+		st.Unlock()
+		return err
+	}
+	info, err := snapstate.CurrentInfo(st, action.SnapName)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	apps, err := appsByName(info, action.AppNames)
+	if err != nil {
+		return err
+	}
+	for _, verb := range action.Actions {
+		if err := controlServices(apps, verb, &progress.NullProgress{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
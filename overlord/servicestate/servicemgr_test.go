@@ -0,0 +1,230 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"errors"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+func TestServiceManager(t *testing.T) { TestingT(t) }
+
+type serviceManagerSuite struct {
+	state   *state.State
+	mgr     *servicestate.ServiceManager
+	calls   []string
+	failOn  string
+	restore func()
+}
+
+var _ = Suite(&serviceManagerSuite{})
+
+const fooYaml = `name: foo
+version: 1
+apps:
+ cli:
+  command: bin/cli
+ web:
+  command: bin/web
+  daemon: simple
+ worker:
+  command: bin/worker
+  daemon: forking
+`
+
+const barYaml = `name: bar
+version: 1
+apps:
+ svc:
+  command: bin/svc
+  daemon: simple
+`
+
+func (s *serviceManagerSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	s.state = state.New(nil)
+	mgr, err := servicestate.Manager(s.state)
+	c.Assert(err, IsNil)
+	s.mgr = mgr
+	s.calls = nil
+	s.failOn = ""
+	s.restore = servicestate.MockControlServices(func(apps []*snap.AppInfo, action string, meter progress.Meter) error {
+		for _, app := range apps {
+			s.calls = append(s.calls, action+" "+app.Snap.Name()+"."+app.Name)
+		}
+		if action == s.failOn {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	s.mockSnap(c, fooYaml)
+	s.mockSnap(c, barYaml)
+}
+
+func (s *serviceManagerSuite) TearDownTest(c *C) {
+	s.mgr.Stop()
+	s.restore()
+	dirs.SetRootDir("")
+}
+
+func (s *serviceManagerSuite) mockSnap(c *C, yamlText string) {
+	sideInfo := &snap.SideInfo{Revision: snap.R(1)}
+	info := snaptest.MockSnap(c, yamlText, sideInfo)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.Set(s.state, info.Name(), &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{sideInfo},
+	})
+}
+
+func appNames(apps []*snap.AppInfo) []string {
+	names := make([]string, len(apps))
+	for i, app := range apps {
+		names[i] = app.Snap.Name() + "." + app.Name
+	}
+	return names
+}
+
+func (s *serviceManagerSuite) TestAppInfos(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	apps, err := servicestate.AppInfos(s.state, nil, false)
+	c.Assert(err, IsNil)
+	c.Check(appNames(apps), DeepEquals, []string{"bar.svc", "foo.cli", "foo.web", "foo.worker"})
+
+	apps, err = servicestate.AppInfos(s.state, nil, true)
+	c.Assert(err, IsNil)
+	c.Check(appNames(apps), DeepEquals, []string{"bar.svc", "foo.web", "foo.worker"})
+
+	apps, err = servicestate.AppInfos(s.state, []string{"foo", "foo.web"}, true)
+	c.Assert(err, IsNil)
+	c.Check(appNames(apps), DeepEquals, []string{"foo.web", "foo.worker"})
+
+	apps, err = servicestate.AppInfos(s.state, []string{"foo.cli", "bar"}, false)
+	c.Assert(err, IsNil)
+	c.Check(appNames(apps), DeepEquals, []string{"bar.svc", "foo.cli"})
+}
+
+func (s *serviceManagerSuite) TestAppInfosErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := servicestate.AppInfos(s.state, []string{"baz"}, false)
+	c.Check(err, ErrorMatches, `cannot find snap "baz"`)
+
+	_, err = servicestate.AppInfos(s.state, []string{"foo.nope"}, false)
+	c.Check(err, ErrorMatches, `snap "foo" has no app "nope"`)
+
+	_, err = servicestate.AppInfos(s.state, []string{"foo.cli"}, true)
+	c.Check(err, ErrorMatches, `app "foo.cli" is not a service`)
+}
+
+func (s *serviceManagerSuite) TestControlTasks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	apps, err := servicestate.AppInfos(s.state, nil, false)
+	c.Assert(err, IsNil)
+
+	ts, err := servicestate.Control(s.state, apps, &servicestate.Instruction{Action: "restart"})
+	c.Assert(err, IsNil)
+	tasks := ts.Tasks()
+	c.Assert(tasks, HasLen, 2)
+	c.Check(tasks[0].Kind(), Equals, "service-control")
+	c.Check(tasks[0].Summary(), Equals, `Restart services of snap "bar": svc`)
+	c.Check(tasks[1].Summary(), Equals, `Restart services of snap "foo": web, worker`)
+}
+
+func (s *serviceManagerSuite) TestControlErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	apps, err := servicestate.AppInfos(s.state, []string{"foo"}, false)
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		inst *servicestate.Instruction
+		err  string
+	}{
+		{&servicestate.Instruction{Action: "frobnicate"}, `unknown service action "frobnicate"`},
+		{&servicestate.Instruction{Action: "start", Disable: true}, `cannot disable services while starting them`},
+		{&servicestate.Instruction{Action: "stop", Enable: true}, `cannot enable services while stopping them`},
+		{&servicestate.Instruction{Action: "restart", Enable: true}, `cannot enable or disable services on restart`},
+	} {
+		_, err := servicestate.Control(s.state, apps, t.inst)
+		c.Check(err, ErrorMatches, t.err)
+	}
+
+	_, err = servicestate.Control(s.state, apps[:1], &servicestate.Instruction{Action: "start"})
+	c.Check(err, ErrorMatches, `no services selected`)
+}
+
+func (s *serviceManagerSuite) runControl(c *C, names []string, inst *servicestate.Instruction) *state.Change {
+	s.state.Lock()
+	apps, err := servicestate.AppInfos(s.state, names, true)
+	c.Assert(err, IsNil)
+	ts, err := servicestate.Control(s.state, apps, inst)
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("service-control", "...")
+	chg.AddAll(ts)
+	s.state.Unlock()
+
+	s.mgr.Ensure()
+	s.mgr.Wait()
+
+	return chg
+}
+
+func (s *serviceManagerSuite) TestDoServiceControl(c *C) {
+	chg := s.runControl(c, []string{"foo"}, &servicestate.Instruction{Action: "start", Enable: true})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.calls, DeepEquals, []string{
+		"enable foo.web", "enable foo.worker",
+		"start foo.web", "start foo.worker",
+	})
+}
+
+func (s *serviceManagerSuite) TestDoServiceControlError(c *C) {
+	s.failOn = "stop"
+	chg := s.runControl(c, []string{"bar.svc"}, &servicestate.Instruction{Action: "stop", Disable: true})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*boom.*`)
+	c.Check(s.calls, DeepEquals, []string{"disable bar.svc", "stop bar.svc"})
+}
@@ -0,0 +1,213 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Instruction describes an action to perform on some services.
+type Instruction struct {
+	// Action is one of "start", "stop", "restart", "enable" or "disable".
+	Action string
+	// Enable also enables the services when starting them.
+	Enable bool
+	// Disable also disables the services when stopping them.
+	Disable bool
+}
+
+// serviceAction is the task data of a service-control task.
+type serviceAction struct {
+	SnapName string   `json:"snap-name"`
+	AppNames []string `json:"app-names"`
+	Actions  []string `json:"actions"`
+}
+
+func (inst *Instruction) actions() ([]string, error) {
+	switch inst.Action {
+	case "start":
+		if inst.Disable {
+			return nil, fmt.Errorf("cannot disable services while starting them")
+		}
+		if inst.Enable {
+			return []string{"enable", "start"}, nil
+		}
+	case "stop":
+		if inst.Enable {
+			return nil, fmt.Errorf("cannot enable services while stopping them")
+		}
+		if inst.Disable {
+			return []string{"disable", "stop"}, nil
+		}
+	case "restart", "enable", "disable":
+		if inst.Enable || inst.Disable {
+			return nil, fmt.Errorf("cannot enable or disable services on %s", inst.Action)
+		}
+	default:
+		return nil, fmt.Errorf("unknown service action %q", inst.Action)
+	}
+	return []string{inst.Action}, nil
+}
+
+var actionSummaries = map[string]string{
+	"start":   i18n.G("Start services of snap %q: %s"),
+	"stop":    i18n.G("Stop services of snap %q: %s"),
+	"restart": i18n.G("Restart services of snap %q: %s"),
+	"enable":  i18n.G("Enable services of snap %q: %s"),
+	"disable": i18n.G("Disable services of snap %q: %s"),
+}
+
+// Control returns a set of tasks performing the instruction on the given
+// apps, with one task for the services of each snap. Apps that are not
+// services are ignored.
+func Control(st *state.State, apps []*snap.AppInfo, inst *Instruction) (*state.TaskSet, error) {
+	actions, err := inst.actions()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapNames []string
+	appNames := make(map[string][]string)
+	for _, app := range apps {
+		if app.Daemon == "" {
+			continue
+		}
+		snapName := app.Snap.Name()
+		if _, ok := appNames[snapName]; !ok {
+			snapNames = append(snapNames, snapName)
+		}
+		appNames[snapName] = append(appNames[snapName], app.Name)
+	}
+	if len(snapNames) == 0 {
+		return nil, fmt.Errorf("no services selected")
+	}
+
+	var tasks []*state.Task
+	for _, snapName := range snapNames {
+		summary := fmt.Sprintf(actionSummaries[inst.Action], snapName, strings.Join(appNames[snapName], ", "))
+		t := st.NewTask("service-control", summary)
+		t.Set("service-action", serviceAction{
+			SnapName: snapName,
+			AppNames: appNames[snapName],
+			Actions:  actions,
+		})
+		tasks = append(tasks, t)
+	}
+	return state.NewTaskSet(tasks...), nil
+}
+
+type appsByFullName []*snap.AppInfo
+
+func (a appsByFullName) Len() int      { return len(a) }
+func (a appsByFullName) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a appsByFullName) Less(i, j int) bool {
+	if a[i].Snap.Name() != a[j].Snap.Name() {
+		return a[i].Snap.Name() < a[j].Snap.Name()
+	}
+	return a[i].Name < a[j].Name
+}
+
+func appsByName(info *snap.Info, names []string) ([]*snap.AppInfo, error) {
+	apps := make([]*snap.AppInfo, 0, len(names))
+	for _, name := range names {
+		app, ok := info.Apps[name]
+		if !ok {
+			return nil, fmt.Errorf("snap %q has no app %q", info.Name(), name)
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+// AppInfos returns the apps selected by names, sorted by snap and app
+// name. Each name is either a snap name, selecting all the apps of the
+// snap, or a <snap>.<app> pair; no names select the apps of all active
+// snaps. With servicesOnly, only services are returned and naming an app
+// that is not a service is an error.
+func AppInfos(st *state.State, names []string, servicesOnly bool) ([]*snap.AppInfo, error) {
+	var apps []*snap.AppInfo
+	addApp := func(app *snap.AppInfo) {
+		if servicesOnly && app.Daemon == "" {
+			return
+		}
+		apps = append(apps, app)
+	}
+
+	if len(names) == 0 {
+		infos, err := snapstate.ActiveInfos(st)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			for _, app := range info.Apps {
+				addApp(app)
+			}
+		}
+		sort.Sort(appsByFullName(apps))
+		return apps, nil
+	}
+
+	seen := make(map[*snap.AppInfo]bool)
+	infos := make(map[string]*snap.Info)
+	for _, name := range names {
+		parts := strings.SplitN(name, ".", 2)
+		snapName := parts[0]
+		info, ok := infos[snapName]
+		if !ok {
+			var err error
+			info, err = snapstate.CurrentInfo(st, snapName)
+			if err != nil {
+				return nil, err
+			}
+			infos[snapName] = info
+		}
+
+		if len(parts) == 1 {
+			for _, app := range info.Apps {
+				if !seen[app] {
+					seen[app] = true
+					addApp(app)
+				}
+			}
+			continue
+		}
+
+		app, ok := info.Apps[parts[1]]
+		if !ok {
+			return nil, fmt.Errorf("snap %q has no app %q", snapName, parts[1])
+		}
+		if servicesOnly && app.Daemon == "" {
+			return nil, fmt.Errorf("app %q is not a service", name)
+		}
+		if !seen[app] {
+			seen[app] = true
+			addApp(app)
+		}
+	}
+	sort.Sort(appsByFullName(apps))
+	return apps, nil
+}
@@ -20,12 +20,14 @@
 package snapstate
 
 import (
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
 )
 
-// A StoreService can find, list available updates and download snaps.
+// A StoreService can find, list available updates and download snaps,
+// and retrieve the assertions about them.
 type StoreService interface {
 	Snap(name, channel string, devmode bool, auther store.Authenticator) (*snap.Info, error)
 	Find(query, channel string, auther store.Authenticator) ([]*snap.Info, error)
@@ -33,6 +35,8 @@ type StoreService interface {
 	SuggestedCurrency() string
 
 	Download(*snap.Info, progress.Meter, store.Authenticator) (string, error)
+
+	Assertion(assertType *asserts.AssertionType, primaryKey []string, auther store.Authenticator) (asserts.Assertion, error)
 }
 
 type managerBackend interface {
@@ -50,6 +54,10 @@ type managerBackend interface {
 	RemoveSnapData(info *snap.Info) error
 	RemoveSnapCommonData(info *snap.Info) error
 
+	// refresh related
+	RunningApps(info *snap.Info) (map[string][]int, error)
+	KillApps(info *snap.Info, meter progress.Meter) error
+
 	// testing helpers
 	CurrentInfo(cur *snap.Info)
 	Candidate(sideInfo *snap.SideInfo)
@@ -19,7 +19,30 @@
 
 package backend
 
+import (
+	"syscall"
+	"time"
+)
+
 var (
 	AddMountUnit    = addMountUnit
 	RemoveMountUnit = removeMountUnit
 )
+
+func MockProcDir(dir string) (restore func()) {
+	old := procDir
+	procDir = dir
+	return func() { procDir = old }
+}
+
+func MockKillWait(wait time.Duration) (restore func()) {
+	old := killWait
+	killWait = wait
+	return func() { killWait = old }
+}
+
+func MockSyscallKill(f func(pid int, sig syscall.Signal) error) (restore func()) {
+	old := syscallKill
+	syscallKill = f
+	return func() { syscallKill = old }
+}
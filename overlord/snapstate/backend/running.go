@@ -0,0 +1,145 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+)
+
+var (
+	// procDir is where the processes of the system are listed
+	procDir = "/proc"
+	// wait this long for apps to exit between TERM and KILL
+	killWait = 5 * time.Second
+
+	syscallKill = syscall.Kill
+)
+
+// appLabel returns the security tag in the apparmor label of the given
+// process, or an empty string if it has none.
+func appLabel(pid string) string {
+	label, err := ioutil.ReadFile(filepath.Join(procDir, pid, "attr", "current"))
+	if err != nil {
+		return ""
+	}
+	// labels look like "snap.foo.app (enforce)"
+	fields := strings.Fields(string(label))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// RunningApps returns the ids of the running processes of the apps of the
+// snap, by app name, found through the security tag they are confined
+// with. Services are left out as unlinking the snap stops them anyway.
+//
+// The security tag is read from the apparmor label of the processes, so
+// on systems without apparmor no app is ever found running and refreshes
+// are not postponed.
+func (b Backend) RunningApps(info *snap.Info) (map[string][]int, error) {
+	apps := make(map[string]string)
+	for _, app := range info.Apps {
+		if app.Daemon == "" {
+			apps[app.SecurityTag()] = app.Name
+		}
+	}
+	if len(apps) == 0 {
+		return nil, nil
+	}
+
+	entries, err := ioutil.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var running map[string][]int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		// processes may exit at any time, skip them if they do
+		appName, ok := apps[appLabel(entry.Name())]
+		if !ok {
+			continue
+		}
+		if running == nil {
+			running = make(map[string][]int)
+		}
+		running[appName] = append(running[appName], pid)
+	}
+	for _, pids := range running {
+		sort.Ints(pids)
+	}
+
+	return running, nil
+}
+
+// KillApps terminates the running apps of the snap, killing the ones
+// that do not exit in time.
+func (b Backend) KillApps(info *snap.Info, meter progress.Meter) error {
+	signal := func(sig syscall.Signal) (int, error) {
+		running, err := b.RunningApps(info)
+		if err != nil {
+			return 0, err
+		}
+		n := 0
+		for _, pids := range running {
+			for _, pid := range pids {
+				if err := syscallKill(pid, sig); err != nil && err != syscall.ESRCH {
+					return 0, err
+				}
+				n++
+			}
+		}
+		return n, nil
+	}
+
+	n, err := signal(syscall.SIGTERM)
+	if err != nil || n == 0 {
+		return err
+	}
+
+	giveup := time.Now().Add(killWait)
+	for time.Now().Before(giveup) {
+		running, err := b.RunningApps(info)
+		if err != nil {
+			return err
+		}
+		if len(running) == 0 {
+			return nil
+		}
+		time.Sleep(killWait / 10)
+	}
+
+	meter.Notify("Some apps of the snap refused to exit, killing.")
+	_, err = signal(syscall.SIGKILL)
+	return err
+}
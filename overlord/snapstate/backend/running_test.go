@@ -0,0 +1,138 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate/backend"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+)
+
+type runningSuite struct {
+	be      backend.Backend
+	procDir string
+	info    *snap.Info
+	restore func()
+}
+
+var _ = Suite(&runningSuite{})
+
+func (s *runningSuite) SetUpTest(c *C) {
+	s.procDir = c.MkDir()
+	s.restore = backend.MockProcDir(s.procDir)
+
+	var err error
+	s.info, err = snap.InfoFromSnapYaml([]byte(`name: kiosk
+version: 1.0
+apps:
+ browser:
+  command: browser
+ helper:
+  command: helper
+ server:
+  command: server
+  daemon: simple
+`))
+	c.Assert(err, IsNil)
+}
+
+func (s *runningSuite) TearDownTest(c *C) {
+	s.restore()
+}
+
+func (s *runningSuite) addProcess(c *C, pid, label string) {
+	attrDir := filepath.Join(s.procDir, pid, "attr")
+	c.Assert(os.MkdirAll(attrDir, 0755), IsNil)
+	if label != "" {
+		c.Assert(ioutil.WriteFile(filepath.Join(attrDir, "current"), []byte(label+"\n"), 0644), IsNil)
+	}
+}
+
+func (s *runningSuite) TestRunningApps(c *C) {
+	s.addProcess(c, "1", "")
+	s.addProcess(c, "42", "snap.kiosk.browser (enforce)")
+	s.addProcess(c, "7", "snap.kiosk.browser (enforce)")
+	s.addProcess(c, "8", "snap.kiosk.helper (complain)")
+	s.addProcess(c, "9", "snap.kiosk.server (enforce)")
+	s.addProcess(c, "10", "snap.other.browser (enforce)")
+	s.addProcess(c, "11", "unconfined")
+	c.Assert(os.MkdirAll(filepath.Join(s.procDir, "self"), 0755), IsNil)
+
+	running, err := s.be.RunningApps(s.info)
+	c.Assert(err, IsNil)
+	c.Check(running, DeepEquals, map[string][]int{
+		"browser": {7, 42},
+		"helper":  {8},
+	})
+}
+
+func (s *runningSuite) TestRunningAppsNone(c *C) {
+	s.addProcess(c, "9", "snap.kiosk.server (enforce)")
+
+	running, err := s.be.RunningApps(s.info)
+	c.Assert(err, IsNil)
+	c.Check(running, HasLen, 0)
+}
+
+func (s *runningSuite) TestKillApps(c *C) {
+	s.addProcess(c, "42", "snap.kiosk.browser (enforce)")
+	s.addProcess(c, "8", "snap.kiosk.helper (enforce)")
+
+	var killed []int
+	restore := backend.MockSyscallKill(func(pid int, sig syscall.Signal) error {
+		c.Check(sig, Equals, syscall.SIGTERM)
+		killed = append(killed, pid)
+		// the process exits
+		return os.RemoveAll(filepath.Join(s.procDir, strconv.Itoa(pid)))
+	})
+	defer restore()
+
+	err := s.be.KillApps(s.info, &progress.NullProgress{})
+	c.Assert(err, IsNil)
+	c.Check(killed, HasLen, 2)
+}
+
+func (s *runningSuite) TestKillAppsRefusingToExit(c *C) {
+	restoreWait := backend.MockKillWait(20 * time.Millisecond)
+	defer restoreWait()
+
+	s.addProcess(c, "42", "snap.kiosk.browser (enforce)")
+
+	var signals []syscall.Signal
+	restore := backend.MockSyscallKill(func(pid int, sig syscall.Signal) error {
+		c.Check(pid, Equals, 42)
+		signals = append(signals, sig)
+		return nil
+	})
+	defer restore()
+
+	err := s.be.KillApps(s.info, &progress.NullProgress{})
+	c.Assert(err, IsNil)
+	c.Check(signals, DeepEquals, []syscall.Signal{syscall.SIGTERM, syscall.SIGKILL})
+}
@@ -21,8 +21,13 @@ package snapstate_test
 
 import (
 	"errors"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -52,6 +57,19 @@ type fakeStore struct {
 	fakeBackend         *fakeSnappyBackend
 	fakeCurrentProgress int
 	fakeTotalProgress   int
+
+	storeSigning *assertstest.StoreStack
+	// downloaded snaps by the digest of their file
+	downloaded map[string]*snap.Info
+	// tamperSnapRevision and tamperSnapDeclaration, if set, can modify
+	// the headers of the assertions before they get signed
+	tamperSnapRevision    func(headers map[string]interface{})
+	tamperSnapDeclaration func(headers map[string]interface{})
+	// wrongAssertionType, if set, makes the store return an account
+	// assertion for any snap assertion
+	wrongAssertionType bool
+	// validations served by the store
+	validations []asserts.Assertion
 }
 
 func (f *fakeStore) Snap(name, channel string, devmode bool, auther store.Authenticator) (*snap.Info, error) {
@@ -101,13 +119,93 @@ func (f *fakeStore) Download(snapInfo *snap.Info, pb progress.Meter, auther stor
 	pb.SetTotal(float64(f.fakeTotalProgress))
 	pb.Set(float64(f.fakeCurrentProgress))
 
-	return "downloaded-snap-path", nil
+	path := "downloaded-snap-path"
+	digest, _, err := fakeSnapDigest(path)
+	if err != nil {
+		return "", err
+	}
+	if f.downloaded == nil {
+		f.downloaded = make(map[string]*snap.Info)
+	}
+	f.downloaded[digest] = snapInfo
+
+	return path, nil
+}
+
+const fakeSnapSize = 5
+
+func fakeSnapDigest(snapPath string) (string, uint64, error) {
+	return "sha512-digest-of-" + filepath.Base(snapPath), fakeSnapSize, nil
+}
+
+// Assertion signs on demand the assertions about the downloaded snaps.
+func (f *fakeStore) Assertion(assertType *asserts.AssertionType, primaryKey []string, auther store.Authenticator) (asserts.Assertion, error) {
+	storeSigning := f.storeSigning
+	now := time.Now().Format(time.RFC3339)
+	switch assertType {
+	case asserts.AccountKeyType:
+		if primaryKey[0] == storeSigning.AuthorityID && primaryKey[1] == storeSigning.KeyID {
+			return storeSigning.StoreAccountKey, nil
+		}
+	case asserts.AccountType:
+		return assertstest.NewAccount(storeSigning.SigningDB, primaryKey[0], nil), nil
+	case asserts.SnapDeclarationType:
+		if f.wrongAssertionType {
+			return assertstest.NewAccount(storeSigning.SigningDB, "devdevdev", nil), nil
+		}
+		for _, info := range f.downloaded {
+			if info.SnapID == primaryKey[1] {
+				headers := map[string]interface{}{
+					"series":       primaryKey[0],
+					"snap-id":      primaryKey[1],
+					"snap-name":    info.Name(),
+					"publisher-id": "devdevdev",
+					"gates":        "",
+					"timestamp":    now,
+				}
+				if f.tamperSnapDeclaration != nil {
+					f.tamperSnapDeclaration(headers)
+				}
+				return storeSigning.Sign(asserts.SnapDeclarationType, headers, nil)
+			}
+		}
+	case asserts.SnapRevisionType:
+		if f.wrongAssertionType {
+			return assertstest.NewAccount(storeSigning.SigningDB, "devdevdev", nil), nil
+		}
+		info := f.downloaded[primaryKey[2]]
+		if info != nil && info.SnapID == primaryKey[1] {
+			headers := map[string]interface{}{
+				"series":        primaryKey[0],
+				"snap-id":       primaryKey[1],
+				"snap-digest":   primaryKey[2],
+				"snap-size":     strconv.Itoa(fakeSnapSize),
+				"snap-revision": info.Revision.String(),
+				"developer-id":  "devdevdev",
+				"timestamp":     now,
+			}
+			if f.tamperSnapRevision != nil {
+				f.tamperSnapRevision(headers)
+			}
+			return storeSigning.Sign(asserts.SnapRevisionType, headers, nil)
+		}
+	case asserts.ValidationType:
+		ref := &asserts.Ref{Type: assertType, PrimaryKey: primaryKey}
+		for _, a := range f.validations {
+			if a.Ref().Unique() == ref.Unique() {
+				return a, nil
+			}
+		}
+	}
+	return nil, store.ErrAssertionNotFound
 }
 
 type fakeSnappyBackend struct {
 	ops []fakeOp
 
 	linkSnapFailTrigger string
+
+	runningApps map[string][]int
 }
 
 func (f *fakeSnappyBackend) OpenSnapFile(snapFilePath string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
@@ -241,6 +339,19 @@ func (f *fakeSnappyBackend) RemoveSnapCommonData(info *snap.Info) error {
 	return nil
 }
 
+func (f *fakeSnappyBackend) RunningApps(info *snap.Info) (map[string][]int, error) {
+	return f.runningApps, nil
+}
+
+func (f *fakeSnappyBackend) KillApps(info *snap.Info, meter progress.Meter) error {
+	f.ops = append(f.ops, fakeOp{
+		op:   "kill-apps",
+		name: info.MountDir(),
+	})
+	f.runningApps = nil
+	return nil
+}
+
 func (f *fakeSnappyBackend) Candidate(sideInfo *snap.SideInfo) {
 	var sinfo snap.SideInfo
 	if sideInfo != nil {
@@ -25,6 +25,7 @@ import (
 
 	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/firstboot"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
@@ -53,6 +54,38 @@ func checkAssumes(s *snap.Info) error {
 
 var openSnapFile = backend.OpenSnapFile
 
+// checkModelSnapType ensures that a gadget, kernel or os snap is the
+// one named by the device model, if there is one.
+func checkModelSnapType(st *state.State, s *snap.Info) error {
+	if s.Type != snap.TypeGadget && s.Type != snap.TypeKernel && s.Type != snap.TypeOS {
+		return nil
+	}
+
+	st.Lock()
+	defer st.Unlock()
+	model, err := devicestate.Model(st)
+	if err == state.ErrNoState {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var expected string
+	switch s.Type {
+	case snap.TypeGadget:
+		expected = model.Gadget()
+	case snap.TypeKernel:
+		expected = model.Kernel()
+	case snap.TypeOS:
+		expected = model.Core()
+	}
+	if s.Name() != expected {
+		return fmt.Errorf("cannot install %s snap %q, the device model requires %q", s.Type, s.Name(), expected)
+	}
+	return nil
+}
+
 // checkSnap ensures that the snap can be installed.
 func checkSnap(st *state.State, snapFilePath string, curInfo *snap.Info, flags Flags) error {
 	// XXX: actually verify snap before using content from it unless dev-mode
@@ -73,6 +106,11 @@ func checkSnap(st *state.State, snapFilePath string, curInfo *snap.Info, flags F
 		return err
 	}
 
+	err = checkModelSnapType(st, s)
+	if err != nil {
+		return err
+	}
+
 	if s.Type != snap.TypeGadget {
 		return nil
 	}
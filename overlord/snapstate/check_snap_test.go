@@ -28,7 +28,10 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/arch"
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
@@ -247,3 +250,68 @@ version: 1
 	st.Lock()
 	c.Check(err, ErrorMatches, "cannot install a gadget snap on classic")
 }
+
+func (s *checkSnapSuite) TestCheckSnapModelSnapTypes(c *C) {
+	reset := release.MockOnClassic(false)
+	defer reset()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	storeSigning := assertstest.NewStoreStack("can0nical", rootPrivKey, storePrivKey)
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		Trusted:        []asserts.Assertion{storeSigning.TrustedKey},
+	})
+	c.Assert(err, IsNil)
+	assertstate.ReplaceDB(st, db)
+	setModel(c, st, storeSigning, nil)
+
+	// the current gadget, for the gadget specific checks
+	si := &snap.SideInfo{Revision: snap.R(2)}
+	snaptest.MockSnap(c, `
+name: pc
+type: gadget
+version: 1
+`, si)
+	snapstate.Set(st, "pc", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+	})
+
+	tests := []struct {
+		name, snapType, expectedErr string
+	}{
+		{"pc", "gadget", ""},
+		{"pc-kernel", "kernel", ""},
+		{"core", "os", ""},
+		{"some-app", "app", ""},
+		{"other-gadget", "gadget", `cannot install gadget snap "other-gadget", the device model requires "pc"`},
+		{"other-kernel", "kernel", `cannot install kernel snap "other-kernel", the device model requires "pc-kernel"`},
+		{"ubuntu-core", "os", `cannot install os snap "ubuntu-core", the device model requires "core"`},
+	}
+
+	for _, test := range tests {
+		yaml := fmt.Sprintf("name: %s\ntype: %s\nversion: 1\n", test.name, test.snapType)
+		info, err := snap.InfoFromSnapYaml([]byte(yaml))
+		c.Assert(err, IsNil)
+
+		var openSnapFile = func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
+			return info, nil, nil
+		}
+		restore := snapstate.MockOpenSnapFile(openSnapFile)
+
+		st.Unlock()
+		err = snapstate.CheckSnap(st, "snap-path", nil, 0)
+		st.Lock()
+		restore()
+
+		if test.expectedErr == "" {
+			c.Check(err, IsNil, Commentf("snap %q", test.name))
+		} else {
+			c.Check(err, ErrorMatches, test.expectedErr)
+		}
+	}
+}
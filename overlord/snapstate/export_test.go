@@ -21,6 +21,7 @@ package snapstate
 
 import (
 	"errors"
+	"time"
 
 	"gopkg.in/tomb.v2"
 
@@ -28,6 +29,8 @@ import (
 	"github.com/snapcore/snapd/snap"
 )
 
+var DeviceStoreID = deviceStoreID
+
 type ManagerBackend managerBackend
 
 func SetSnapManagerBackend(s *SnapManager, b ManagerBackend) {
@@ -77,9 +80,23 @@ func MockOpenSnapFile(mock func(path string, si *snap.SideInfo) (*snap.Info, sna
 	return func() { openSnapFile = prevOpenSnapFile }
 }
 
+func MockSnapDigest(mock func(snapPath string) (string, uint64, error)) (restore func()) {
+	prevSnapDigest := snapDigest
+	snapDigest = mock
+	return func() { snapDigest = prevSnapDigest }
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() { timeNow = old }
+}
+
 var (
 	CheckSnap = checkSnap
 	CanRemove = canRemove
+
+	MaxRefreshInhibition = maxRefreshInhibition
 )
 
 // flagscompat
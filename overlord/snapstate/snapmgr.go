@@ -23,11 +23,18 @@ package snapstate
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
@@ -42,8 +49,14 @@ type SnapManager struct {
 	store   StoreService
 
 	runner *state.TaskRunner
+
+	refreshInhibited RefreshInhibitedFunc
 }
 
+// RefreshInhibitedFunc is called, with the state locked, with the names of
+// the running apps of a snap whose refresh gets postponed until they exit.
+type RefreshInhibitedFunc func(snapName string, apps []string)
+
 // SnapSetupFlags are flags stored in SnapSetup to control snap manager tasks.
 type SnapSetupFlags Flags
 
@@ -112,6 +125,19 @@ type SnapState struct {
 	Flags     SnapStateFlags   `json:"flags,omitempty"`
 	// incremented revision used for local installs
 	LocalRevision snap.Revision `json:"local-revision,omitempty"`
+	// when the refresh of the snap was first postponed because of its
+	// running apps
+	RefreshInhibitedSince *time.Time `json:"refresh-inhibited-since,omitempty"`
+	// the refresh postponed until the running apps exit, if any
+	RefreshPending *PendingRefresh `json:"refresh-pending,omitempty"`
+}
+
+// PendingRefresh holds the parameters of a refresh postponed until the
+// running apps of the snap exit.
+type PendingRefresh struct {
+	Channel string `json:"channel,omitempty"`
+	UserID  int    `json:"user-id,omitempty"`
+	Flags   Flags  `json:"flags,omitempty"`
 }
 
 // Type returns the type of the snap or an error.
@@ -166,14 +192,32 @@ func (snapst *SnapState) SetTryMode(active bool) {
 	}
 }
 
+// deviceStoreID returns the id of the store to use, as set by the
+// device model if there is one. It must be called with the state locked.
+func deviceStoreID(s *state.State) (string, error) {
+	storeID := ""
+	model, err := devicestate.Model(s)
+	if err == nil {
+		storeID = model.Store()
+	} else if err != state.ErrNoState {
+		return "", err
+	}
+	// the environment can still override the store, mostly for testing
+	if cand := os.Getenv("UBUNTU_STORE_ID"); cand != "" {
+		storeID = cand
+	}
+	return storeID, nil
+}
+
 // Manager returns a new snap manager.
 func Manager(s *state.State) (*SnapManager, error) {
 	runner := state.NewTaskRunner(s)
 
-	storeID := ""
-	// TODO: set the store-id here from the model information
-	if cand := os.Getenv("UBUNTU_STORE_ID"); cand != "" {
-		storeID = cand
+	s.Lock()
+	storeID, err := deviceStoreID(s)
+	s.Unlock()
+	if err != nil {
+		return nil, err
 	}
 	store := store.NewUbuntuStoreSnapRepository(nil, storeID)
 	// TODO: if needed we could also put the store on the state using
@@ -194,6 +238,7 @@ func Manager(s *state.State) (*SnapManager, error) {
 	// install/update related
 	runner.AddHandler("prepare-snap", m.doPrepareSnap, m.undoPrepareSnap)
 	runner.AddHandler("download-snap", m.doDownloadSnap, m.undoPrepareSnap)
+	runner.AddHandler("validate-snap", m.doValidateSnap, m.undoValidateSnap)
 	runner.AddHandler("mount-snap", m.doMountSnap, m.undoMountSnap)
 	runner.AddHandler("unlink-current-snap", m.doUnlinkCurrentSnap, m.undoUnlinkCurrentSnap)
 	runner.AddHandler("copy-snap-data", m.doCopySnapData, m.undoCopySnapData)
@@ -214,9 +259,19 @@ func Manager(s *state.State) (*SnapManager, error) {
 		return fmt.Errorf("fake-install-snap-error errored")
 	}, nil)
 
+	s.Lock()
+	s.Cache(cachedSnapManagerKey{}, m)
+	s.Unlock()
+
 	return m, nil
 }
 
+// NotifyRefreshInhibited registers f to be called when the refresh of a
+// snap starts being postponed because some of its apps are running.
+func (m *SnapManager) NotifyRefreshInhibited(f RefreshInhibitedFunc) {
+	m.refreshInhibited = f
+}
+
 // Store returns the store service used by the manager.
 func (m *SnapManager) Store() StoreService {
 	return m.store
@@ -299,15 +354,11 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
 
 	meter := &TaskProgressAdapter{task: t}
 
-	var auther store.Authenticator
-	if ss.UserID > 0 {
-		st.Lock()
-		user, err := auth.User(st, ss.UserID)
-		st.Unlock()
-		if err != nil {
-			return err
-		}
-		auther = user.Authenticator()
+	st.Lock()
+	auther, err := userAuthenticator(st, ss.UserID)
+	st.Unlock()
+	if err != nil {
+		return err
 	}
 
 	storeInfo, err := m.store.Snap(ss.Name, ss.Channel, ss.DevMode(), auther)
@@ -319,6 +370,18 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	if snapst.CurrentSideInfo() != nil {
+		st.Lock()
+		err = m.fetchGatingValidations(st, ss.UserID, ss.Name, storeInfo.SnapID, storeInfo.Revision)
+		if err == nil {
+			err = checkGatingValidations(st, ss.Name, storeInfo.SnapID, storeInfo.Revision)
+		}
+		st.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
 	downloadedSnapFile, err := m.store.Download(storeInfo, meter, auther)
 	if err != nil {
 		return err
@@ -337,6 +400,57 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
 	return nil
 }
 
+// undoValidateSnap has nothing to undo, the assertions added to the
+// database stay valid; it makes sure the undo of the task waits for the
+// undo of the following ones.
+func (m *SnapManager) undoValidateSnap(t *state.Task, _ *tomb.Tomb) error {
+	return nil
+}
+
+// userAuthenticator returns the authenticator for the store requests of
+// the user with the given id, if any. It must be called with the state
+// locked.
+func userAuthenticator(st *state.State, userID int) (store.Authenticator, error) {
+	if userID <= 0 {
+		return nil, nil
+	}
+	user, err := auth.User(st, userID)
+	if err != nil {
+		return nil, err
+	}
+	return user.Authenticator(), nil
+}
+
+func (m *SnapManager) doValidateSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	ss, snapst, err := snapSetupAndState(t)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	local := ss.Revision.Local()
+	if local && ss.Flags&(Dangerous|DevMode) != 0 {
+		// the snap file is installed as is, with a local revision
+		return nil
+	}
+
+	digest, size, err := snapDigest(ss.SnapPath)
+	if err != nil {
+		return err
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	db := assertstate.DB(st)
+	if local {
+		return m.validateLocalSnap(t, db, ss, snapst, digest, size)
+	}
+	return m.validateStoreSnap(t, db, ss, snapst, digest, size)
+}
+
 func (m *SnapManager) doUnlinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	// invoked only if snap has a current active revision
 
@@ -437,10 +551,48 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 	return nil
 }
 
+// ensurePendingRefreshes starts the refreshes postponed while the apps of
+// their snaps were running, once the apps exited or the refreshes waited
+// for maxRefreshInhibition.
+func (m *SnapManager) ensurePendingRefreshes() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	snapStates, err := All(m.state)
+	if err != nil {
+		return err
+	}
+	for name, snapst := range snapStates {
+		pending := snapst.RefreshPending
+		if pending == nil || checkChangeConflict(m.state, name) != nil {
+			continue
+		}
+		ts, err := Update(m.state, name, pending.Channel, pending.UserID, pending.Flags)
+		if _, ok := err.(*RefreshPostponedError); ok {
+			continue
+		}
+		if err != nil {
+			logger.Noticef("Cannot refresh snap %q: %v", name, err)
+			snapst.RefreshPending = nil
+			Set(m.state, name, snapst)
+			continue
+		}
+		msg := fmt.Sprintf(i18n.G("Refresh %q snap"), name)
+		if pending.Channel != "stable" && pending.Channel != "" {
+			msg = fmt.Sprintf(i18n.G("Refresh %q snap from %q channel"), name, pending.Channel)
+		}
+		chg := m.state.NewChange("refresh-snap", msg)
+		chg.AddAll(ts)
+		chg.Set("snap-names", []string{name})
+	}
+	return nil
+}
+
 // Ensure implements StateManager.Ensure.
 func (m *SnapManager) Ensure() error {
+	err := m.ensurePendingRefreshes()
 	m.runner.Ensure()
-	return nil
+	return err
 }
 
 // Wait implements StateManager.Wait.
@@ -478,6 +630,22 @@ func TaskSnapSetup(t *state.Task) (*SnapSetup, error) {
 	return &ss, nil
 }
 
+// setTaskSnapSetup stores the SnapSetup back where the task holds or
+// refers to it.
+func setTaskSnapSetup(t *state.Task, ss *SnapSetup) error {
+	var id string
+	err := t.Get("snap-setup-task", &id)
+	if err == state.ErrNoState {
+		t.Set("snap-setup", ss)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	t.State().Task(id).Set("snap-setup", ss)
+	return nil
+}
+
 func snapSetupAndState(t *state.Task) (*SnapSetup, *SnapState, error) {
 	ss, err := TaskSnapSetup(t)
 	if err != nil {
@@ -579,6 +747,19 @@ func (m *SnapManager) doUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	// apps running from the current revision break when it goes away,
+	// wait for the ones started since the refresh was asked for to exit
+	apps, err := appsBlockingRefresh(st, ss.Name, snapst, Flags(ss.Flags))
+	if err != nil {
+		return err
+	}
+	if len(apps) > 0 {
+		return state.Retry
+	}
+	if err := m.killRunningApps(t, ss, oldInfo); err != nil {
+		return err
+	}
+
 	snapst.Active = false
 
 	pb := &TaskProgressAdapter{task: t}
@@ -594,6 +775,47 @@ func (m *SnapManager) doUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	return nil
 }
 
+// maxRefreshInhibition is how long the refresh of a snap is postponed at
+// most while its apps are running.
+var maxRefreshInhibition = 14 * 24 * time.Hour
+
+var timeNow = time.Now
+
+type cachedSnapManagerKey struct{}
+
+// cachedSnapManager returns the snap manager sharing the given state, if
+// there is one.
+func cachedSnapManager(st *state.State) *SnapManager {
+	m, _ := st.Cached(cachedSnapManagerKey{}).(*SnapManager)
+	return m
+}
+
+// killRunningApps kills the running apps of the snap being refreshed if
+// asked to. It must be called with the state locked.
+func (m *SnapManager) killRunningApps(t *state.Task, ss *SnapSetup, info *snap.Info) error {
+	if ss.Flags&KillApps == 0 {
+		return nil
+	}
+	st := t.State()
+
+	running, err := m.backend.RunningApps(info)
+	if err != nil || len(running) == 0 {
+		return err
+	}
+	apps := make([]string, 0, len(running))
+	for app := range running {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	t.Logf("Killing running apps of snap %q: %s", ss.Name, strings.Join(apps, ", "))
+	pb := &TaskProgressAdapter{task: t}
+	st.Unlock() // pb itself will ask for locking
+	err = m.backend.KillApps(info, pb)
+	st.Lock()
+	return err
+}
+
 func (m *SnapManager) undoCopySnapData(t *state.Task, _ *tomb.Tomb) error {
 	t.State().Lock()
 	ss, snapst, err := snapSetupAndState(t)
@@ -671,6 +893,8 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	}
 	oldTryMode := snapst.TryMode()
 	snapst.SetTryMode(ss.TryMode())
+	// the refresh is not postponed any longer
+	snapst.RefreshInhibitedSince = nil
 
 	newInfo, err := readInfo(ss.Name, cand)
 	if err != nil {
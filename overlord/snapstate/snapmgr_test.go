@@ -20,15 +20,23 @@
 package snapstate_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
@@ -38,6 +46,12 @@ import (
 
 func TestSnapManager(t *testing.T) { TestingT(t) }
 
+var (
+	rootPrivKey  = assertstest.GenerateKey(752)
+	storePrivKey = assertstest.GenerateKey(752)
+	brandPrivKey = assertstest.GenerateKey(752)
+)
+
 type snapmgrTestSuite struct {
 	state   *state.State
 	snapmgr *snapstate.SnapManager
@@ -45,6 +59,9 @@ type snapmgrTestSuite struct {
 	fakeBackend *fakeSnappyBackend
 	fakeStore   *fakeStore
 
+	storeSigning *assertstest.StoreStack
+	db           *asserts.Database
+
 	user *auth.UserState
 
 	reset func()
@@ -63,13 +80,25 @@ var _ = Suite(&snapmgrTestSuite{})
 func (s *snapmgrTestSuite) SetUpTest(c *C) {
 	s.fakeBackend = &fakeSnappyBackend{}
 	s.state = state.New(nil)
+	s.storeSigning = assertstest.NewStoreStack("can0nical", rootPrivKey, storePrivKey)
 	s.fakeStore = &fakeStore{
 		fakeCurrentProgress: 75,
 		fakeTotalProgress:   100,
 		fakeBackend:         s.fakeBackend,
+		storeSigning:        s.storeSigning,
 	}
 
-	var err error
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		Trusted:        []asserts.Assertion{s.storeSigning.TrustedKey},
+	})
+	c.Assert(err, IsNil)
+	s.db = db
+	s.state.Lock()
+	assertstate.ReplaceDB(s.state, db)
+	s.state.Unlock()
+
 	s.snapmgr, err = snapstate.Manager(s.state)
 	c.Assert(err, IsNil)
 	s.snapmgr.AddForeignTaskHandlers(s.fakeBackend)
@@ -79,8 +108,10 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 
 	restore1 := snapstate.MockReadInfo(s.fakeBackend.ReadInfo)
 	restore2 := snapstate.MockOpenSnapFile(s.fakeBackend.OpenSnapFile)
+	restore3 := snapstate.MockSnapDigest(fakeSnapDigest)
 
 	s.reset = func() {
+		restore3()
 		restore2()
 		restore1()
 	}
@@ -104,7 +135,7 @@ func (s *snapmgrTestSuite) TestStore(c *C) {
 
 func verifyInstallUpdateTasks(c *C, curActive bool, ts *state.TaskSet, st *state.State) {
 	i := 0
-	n := 5
+	n := 6
 	if curActive {
 		n++
 	}
@@ -113,6 +144,8 @@ func verifyInstallUpdateTasks(c *C, curActive bool, ts *state.TaskSet, st *state
 	c.Assert(st.NumTask(), Equals, n)
 	c.Assert(ts.Tasks()[i].Kind(), Equals, "download-snap")
 	i++
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "validate-snap")
+	i++
 	c.Assert(ts.Tasks()[i].Kind(), Equals, "mount-snap")
 	i++
 	if curActive {
@@ -383,6 +416,219 @@ func (s *snapmgrTestSuite) TestInstallRunThrough(c *C) {
 		SnapID:       "snapIDsnapidsnapidsnapidsnapidsn",
 		Revision:     snap.R(11),
 	})
+
+	// the assertions about the snap were added to the database
+	snapRev, err := s.db.Find(asserts.SnapRevisionType, map[string]string{
+		"series":      "16",
+		"snap-id":     "snapIDsnapidsnapidsnapidsnapidsn",
+		"snap-digest": "sha512-digest-of-downloaded-snap-path",
+	})
+	c.Assert(err, IsNil)
+	c.Check(snapRev.(*asserts.SnapRevision).SnapRevision(), Equals, uint64(11))
+	snapDecl, err := s.db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "snapIDsnapidsnapidsnapidsnapidsn",
+	})
+	c.Assert(err, IsNil)
+	c.Check(snapDecl.(*asserts.SnapDeclaration).SnapName(), Equals, "some-snap")
+	_, err = s.db.Find(asserts.AccountType, map[string]string{
+		"account-id": "devdevdev",
+	})
+	c.Check(err, IsNil)
+}
+
+func (s *snapmgrTestSuite) testInstallValidateSnapFails(c *C, digest string, size uint64, errMatch string) {
+	restore := snapstate.MockSnapDigest(func(snapPath string) (string, uint64, error) {
+		return digest, size, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(s.state, "some-snap", "some-channel", s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, errMatch)
+	for _, op := range s.fakeBackend.ops {
+		c.Check(op.op, Not(Equals), "setup-snap")
+	}
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Check(err, Equals, state.ErrNoState)
+}
+
+func (s *snapmgrTestSuite) TestInstallValidateSnapDigestMismatch(c *C) {
+	s.testInstallValidateSnapFails(c, "sha512-tampered", fakeSnapSize, `(?s).*cannot find signatures with metadata for snap "some-snap": .*`)
+}
+
+func (s *snapmgrTestSuite) TestInstallValidateSnapSizeMismatch(c *C) {
+	s.testInstallValidateSnapFails(c, "sha512-digest-of-downloaded-snap-path", fakeSnapSize+1, `(?s).*snap "some-snap" file does not have expected size according to signatures: 6 != 5.*`)
+}
+
+func (s *snapmgrTestSuite) TestInstallValidateSnapSignedForOtherDigest(c *C) {
+	// a correctly signed snap-revision of the same size but for
+	// another file
+	s.fakeStore.tamperSnapRevision = func(headers map[string]interface{}) {
+		headers["snap-digest"] = "sha512-other"
+	}
+	s.testInstallValidateSnapFails(c, "sha512-digest-of-downloaded-snap-path", fakeSnapSize, `(?s).*snap "some-snap" file does not have expected digest according to signatures: sha512-digest-of-downloaded-snap-path != sha512-other.*`)
+}
+
+func (s *snapmgrTestSuite) TestInstallValidateSnapSignedForOtherSnapID(c *C) {
+	tamper := func(headers map[string]interface{}) {
+		headers["snap-id"] = "other-snap-id"
+	}
+	s.fakeStore.tamperSnapRevision = tamper
+	s.fakeStore.tamperSnapDeclaration = tamper
+	s.testInstallValidateSnapFails(c, "sha512-digest-of-downloaded-snap-path", fakeSnapSize, `(?s).*snap "some-snap" does not have expected snap-id according to signatures: "snapIDsnapidsnapidsnapidsnapidsn" != "other-snap-id".*`)
+}
+
+func (s *snapmgrTestSuite) TestInstallValidateSnapWrongAssertionType(c *C) {
+	s.fakeStore.wrongAssertionType = true
+	s.testInstallValidateSnapFails(c, "sha512-digest-of-downloaded-snap-path", fakeSnapSize, `(?s).*cannot verify snap "some-snap": store returned account assertion instead of snap-revision.*`)
+}
+
+var gatingDevPrivKey = assertstest.GenerateKey(752)
+
+// mockGatingSnap installs some-snap at revision 7 and a gating-snap
+// that gates it, with their snap-declarations in the database.
+func (s *snapmgrTestSuite) mockGatingSnap(c *C) {
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{{
+			OfficialName: "some-snap",
+			SnapID:       "snapIDsnapidsnapidsnapidsnapidsn",
+			Revision:     snap.R(7),
+		}},
+	})
+	snapstate.Set(s.state, "gating-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{{
+			OfficialName: "gating-snap",
+			SnapID:       "gating-snap-id",
+			Revision:     snap.R(1),
+		}},
+	})
+
+	now := time.Now().Format(time.RFC3339)
+	snapDecl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "snapIDsnapidsnapidsnapidsnapidsn",
+		"snap-name":    "some-snap",
+		"publisher-id": "devdevdev",
+		"gates":        "",
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, IsNil)
+	gatingDecl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "gating-snap-id",
+		"snap-name":    "gating-snap",
+		"publisher-id": "gatingdev",
+		"gates":        "snapIDsnapidsnapidsnapidsnapidsn",
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, IsNil)
+	devKey := assertstest.NewAccountKey(s.storeSigning.SigningDB, "gatingdev", gatingDevPrivKey.PublicKey(), nil)
+	for _, a := range []asserts.Assertion{s.storeSigning.StoreAccountKey, snapDecl, gatingDecl, devKey} {
+		err := s.db.Add(a)
+		c.Assert(err, IsNil)
+	}
+}
+
+// gatingValidation returns a validation by gating-snap of the given
+// revision of some-snap.
+func (s *snapmgrTestSuite) gatingValidation(c *C, approvedRevision string, revision int, revoked bool) asserts.Assertion {
+	devSigning := assertstest.NewSigningDB("gatingdev", gatingDevPrivKey)
+	validation, err := devSigning.Sign(asserts.ValidationType, map[string]interface{}{
+		"series":            "16",
+		"snap-id":           "gating-snap-id",
+		"approved-snap-id":  "snapIDsnapidsnapidsnapidsnapidsn",
+		"approved-revision": approvedRevision,
+		"revoked":           strconv.FormatBool(revoked),
+		"revision":          strconv.Itoa(revision),
+		"timestamp":         time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, IsNil)
+	return validation
+}
+
+// testUpdateGated refreshes some-snap, gated by gating-snap, to revision
+// 11 with the given validations in the database and in the store.
+func (s *snapmgrTestSuite) testUpdateGated(c *C, known, inStore []asserts.Assertion) (*state.Change, error) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.mockGatingSnap(c)
+	for _, a := range known {
+		err := s.db.Add(a)
+		c.Assert(err, IsNil)
+	}
+	s.fakeStore.validations = inStore
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	return chg, chg.Err()
+}
+
+func (s *snapmgrTestSuite) TestUpdateGatedApproved(c *C) {
+	validation := s.gatingValidation(c, "11", 0, false)
+	chg, err := s.testUpdateGated(c, nil, []asserts.Assertion{validation})
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.CurrentSideInfo().Revision, Equals, snap.R(11))
+
+	// the validation was fetched from the store
+	_, err = validation.Ref().Resolve(s.db.Find)
+	c.Check(err, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateGatedNotApproved(c *C) {
+	_, err := s.testUpdateGated(c, nil, []asserts.Assertion{s.gatingValidation(c, "9", 0, false)})
+	c.Assert(err, ErrorMatches, `(?s).*cannot refresh "some-snap" to revision 11: no validation by "gating-snap".*`)
+	c.Check(s.fakeStore.downloads, HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestUpdateGatedNoValidations(c *C) {
+	_, err := s.testUpdateGated(c, nil, nil)
+	c.Assert(err, ErrorMatches, `(?s).*cannot refresh "some-snap" to revision 11: no validation by "gating-snap".*`)
+	c.Check(s.fakeStore.downloads, HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestUpdateGatedRevoked(c *C) {
+	_, err := s.testUpdateGated(c, nil, []asserts.Assertion{s.gatingValidation(c, "11", 0, true)})
+	c.Assert(err, ErrorMatches, `(?s).*cannot refresh "some-snap" to revision 11: no validation by "gating-snap".*`)
+}
+
+func (s *snapmgrTestSuite) TestUpdateGatedRevokedSinceKnown(c *C) {
+	known := []asserts.Assertion{s.gatingValidation(c, "11", 0, false)}
+	inStore := []asserts.Assertion{s.gatingValidation(c, "11", 1, true)}
+	_, err := s.testUpdateGated(c, known, inStore)
+	c.Assert(err, ErrorMatches, `(?s).*cannot refresh "some-snap" to revision 11: no validation by "gating-snap".*`)
 }
 
 func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
@@ -511,6 +757,223 @@ func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 	})
 }
 
+func (s *snapmgrTestSuite) TestUpdatePostponedWhileAppsRun(c *C) {
+	si := snap.SideInfo{
+		OfficialName: "some-snap",
+		Revision:     snap.R(7),
+	}
+	s.fakeBackend.runningApps = map[string][]int{"kiosk": {42}}
+
+	var notified []string
+	s.snapmgr.NotifyRefreshInhibited(func(snapName string, apps []string) {
+		notified = append(notified, snapName+": "+strings.Join(apps, ","))
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si},
+	})
+
+	// the refresh is postponed while the app runs
+	for i := 0; i < 2; i++ {
+		_, err := snapstate.Update(s.state, "some-snap", "some-channel", s.user.ID, 0)
+		c.Assert(err, FitsTypeOf, &snapstate.RefreshPostponedError{})
+		c.Assert(err, ErrorMatches, `refresh of snap "some-snap" postponed until its apps exit: kiosk`)
+	}
+	c.Check(notified, DeepEquals, []string{"some-snap: kiosk"})
+	var snapst snapstate.SnapState
+	err := snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.RefreshInhibitedSince, NotNil)
+	c.Check(snapst.RefreshPending, DeepEquals, &snapstate.PendingRefresh{
+		Channel: "some-channel",
+		UserID:  s.user.ID,
+	})
+
+	// without getting in the way of other operations on the snap
+	_, err = snapstate.Remove(s.state, "some-snap")
+	c.Check(err, IsNil)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 0)
+
+	// and done by the snap manager once it exited
+	s.fakeBackend.runningApps = nil
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(s.state.Changes(), HasLen, 1)
+	chg := s.state.Changes()[0]
+	c.Check(chg.Kind(), Equals, "refresh-snap")
+	c.Check(chg.Summary(), Equals, `Refresh "some-snap" snap from "some-channel" channel`)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.fakeStore.downloads, DeepEquals, []fakeDownload{{
+		macaroon: s.user.Macaroon,
+		name:     "some-snap",
+		channel:  "some-channel",
+	}})
+	c.Check(notified, HasLen, 1)
+	var refreshed snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &refreshed)
+	c.Assert(err, IsNil)
+	c.Check(refreshed.CurrentSideInfo().Revision, Equals, snap.R(11))
+	c.Check(refreshed.RefreshInhibitedSince, IsNil)
+	c.Check(refreshed.RefreshPending, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdatePostponedAtMost(c *C) {
+	si := snap.SideInfo{
+		OfficialName: "some-snap",
+		Revision:     snap.R(7),
+	}
+	s.fakeBackend.runningApps = map[string][]int{"kiosk": {42}}
+
+	now := time.Now()
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si},
+	})
+
+	_, err := snapstate.Update(s.state, "some-snap", "some-channel", s.user.ID, 0)
+	c.Assert(err, FitsTypeOf, &snapstate.RefreshPostponedError{})
+
+	// the apps still run past the maximum postponement
+	now = now.Add(snapstate.MaxRefreshInhibition)
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(s.state.Changes(), HasLen, 1)
+	c.Check(s.state.Changes()[0].Status(), Equals, state.DoneStatus)
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.CurrentSideInfo().Revision, Equals, snap.R(11))
+	c.Check(snapst.RefreshInhibitedSince, IsNil)
+	c.Check(snapst.RefreshPending, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateWaitsForAppsStartedMeanwhile(c *C) {
+	si := snap.SideInfo{
+		OfficialName: "some-snap",
+		Revision:     snap.R(7),
+	}
+
+	var notified []string
+	s.snapmgr.NotifyRefreshInhibited(func(snapName string, apps []string) {
+		notified = append(notified, snapName+": "+strings.Join(apps, ","))
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si},
+	})
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	// an app is started before the current revision is unlinked
+	s.fakeBackend.runningApps = map[string][]int{"kiosk": {42}}
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Check(chg.Status(), Equals, state.DoingStatus)
+	c.Check(notified, DeepEquals, []string{"some-snap: kiosk"})
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.CurrentSideInfo().Revision, Equals, snap.R(7))
+	c.Check(snapst.Active, Equals, true)
+
+	s.fakeBackend.runningApps = nil
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	var refreshed snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &refreshed)
+	c.Assert(err, IsNil)
+	c.Check(refreshed.CurrentSideInfo().Revision, Equals, snap.R(11))
+	c.Check(refreshed.RefreshInhibitedSince, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestInstallPathRefusedWhileAppsRun(c *C) {
+	s.fakeBackend.runningApps = map[string][]int{"kiosk": {42}}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{OfficialName: "some-snap", Revision: snap.R(7)}},
+	})
+
+	_, err := snapstate.InstallPath(s.state, "some-snap", "/path/to/some-snap.snap", "", snapstate.Dangerous)
+	c.Assert(err, ErrorMatches, `cannot refresh snap "some-snap" from a file while its apps are running: kiosk`)
+
+	_, err = snapstate.InstallPath(s.state, "some-snap", "/path/to/some-snap.snap", "", snapstate.Dangerous|snapstate.KillApps)
+	c.Assert(err, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateKillApps(c *C) {
+	si := snap.SideInfo{
+		OfficialName: "some-snap",
+		Revision:     snap.R(7),
+	}
+	s.fakeBackend.runningApps = map[string][]int{"kiosk": {42}, "browser": {43}}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si},
+	})
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", s.user.ID, snapstate.KillApps)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.fakeBackend.ops[5], DeepEquals, fakeOp{op: "kill-apps", name: "/snap/some-snap/7"})
+	c.Check(s.fakeBackend.ops[6], DeepEquals, fakeOp{op: "unlink-snap", name: "/snap/some-snap/7"})
+	c.Check(ts.Tasks()[3].Log()[0], Matches, `.* Killing running apps of snap "some-snap": browser, kiosk`)
+
+	// the flag is not kept for the snap
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Flags, Equals, snapstate.SnapStateFlags(0))
+}
+
 func (s *snapmgrTestSuite) TestUpdateUndoRunThrough(c *C) {
 	si := snap.SideInfo{
 		OfficialName: "some-snap",
@@ -828,7 +1291,7 @@ func (s *snapmgrTestSuite) TestInstallFirstLocalRunThrough(c *C) {
 	mockSnap := makeTestSnap(c, `name: mock
 version: 1.0`)
 	chg := s.state.NewChange("install", "install a local snap")
-	ts, err := snapstate.InstallPath(s.state, "mock", mockSnap, "", 0)
+	ts, err := snapstate.InstallPath(s.state, "mock", mockSnap, "", snapstate.Dangerous)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -860,6 +1323,7 @@ version: 1.0`)
 		Name:     "mock",
 		Revision: snap.R(-1),
 		SnapPath: mockSnap,
+		Flags:    snapstate.Dangerous,
 	})
 
 	// verify snaps in the system state
@@ -893,7 +1357,7 @@ func (s *snapmgrTestSuite) TestInstallSubsequentLocalRunThrough(c *C) {
 	mockSnap := makeTestSnap(c, `name: mock
 version: 1.0`)
 	chg := s.state.NewChange("install", "install a local snap")
-	ts, err := snapstate.InstallPath(s.state, "mock", mockSnap, "", 0)
+	ts, err := snapstate.InstallPath(s.state, "mock", mockSnap, "", snapstate.Dangerous)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -936,6 +1400,7 @@ version: 1.0`)
 		Name:     "mock",
 		Revision: snap.R(-3),
 		SnapPath: mockSnap,
+		Flags:    snapstate.Dangerous,
 	})
 
 	// verify snaps in the system state
@@ -970,7 +1435,7 @@ func (s *snapmgrTestSuite) TestInstallOldSubsequentLocalRunThrough(c *C) {
 	mockSnap := makeTestSnap(c, `name: mock
 version: 1.0`)
 	chg := s.state.NewChange("install", "install a local snap")
-	ts, err := snapstate.InstallPath(s.state, "mock", mockSnap, "", 0)
+	ts, err := snapstate.InstallPath(s.state, "mock", mockSnap, "", snapstate.Dangerous)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -1004,6 +1469,139 @@ version: 1.0`)
 	c.Assert(snapst.LocalRevision, Equals, snap.R(-1))
 }
 
+func (s *snapmgrTestSuite) installPath(c *C, snapPath string, flags snapstate.Flags) *state.Change {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install", "install a local snap")
+	ts, err := snapstate.InstallPath(s.state, "some-snap", snapPath, "", flags)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	return chg
+}
+
+func (s *snapmgrTestSuite) TestInstallPathUnassertedRefused(c *C) {
+	chg := s.installPath(c, "/path/to/some-snap.snap", 0)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot find signatures with metadata for snap "some-snap" \("/path/to/some-snap.snap"\).*`)
+}
+
+func (s *snapmgrTestSuite) TestInstallPathDangerous(c *C) {
+	snapstate.MockSnapDigest(func(snapPath string) (string, uint64, error) {
+		c.Fatalf("unexpected digest computation")
+		return "", 0, nil
+	})
+
+	chg := s.installPath(c, "/path/to/some-snap.snap", snapstate.Dangerous)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+	var snapst snapstate.SnapState
+	err := snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.CurrentSideInfo(), DeepEquals, &snap.SideInfo{Revision: snap.R(-1)})
+}
+
+func (s *snapmgrTestSuite) TestInstallPathAsserted(c *C) {
+	now := time.Now().Format(time.RFC3339)
+	snapDecl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "snapIDsnapidsnapidsnapidsnapidsn",
+		"snap-name":    "some-snap",
+		"publisher-id": "devdevdev",
+		"gates":        "",
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, IsNil)
+	snapRev, err := s.storeSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"series":        "16",
+		"snap-id":       "snapIDsnapidsnapidsnapidsnapidsn",
+		"snap-digest":   "sha512-digest-of-some-snap.snap",
+		"snap-size":     "5",
+		"snap-revision": "42",
+		"developer-id":  "devdevdev",
+		"timestamp":     now,
+	}, nil)
+	c.Assert(err, IsNil)
+	for _, a := range []asserts.Assertion{s.storeSigning.StoreAccountKey, snapDecl, snapRev} {
+		err := s.db.Add(a)
+		c.Assert(err, IsNil)
+	}
+
+	chg := s.installPath(c, "/path/to/some-snap.snap", 0)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("install failed with: %v", chg.Err()))
+
+	// the snap got the asserted identity and revision
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.CurrentSideInfo(), DeepEquals, &snap.SideInfo{
+		OfficialName: "some-snap",
+		SnapID:       "snapIDsnapidsnapidsnapidsnapidsn",
+		Revision:     snap.R(42),
+	})
+	c.Check(s.fakeBackend.ops[2], DeepEquals, fakeOp{
+		op:    "setup-snap",
+		name:  "/path/to/some-snap.snap",
+		revno: snap.R(42),
+	})
+}
+
+func (s *snapmgrTestSuite) testInstallPathGated(c *C, known []asserts.Assertion) *state.Change {
+	s.state.Lock()
+	s.mockGatingSnap(c)
+	snapRev, err := s.storeSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"series":        "16",
+		"snap-id":       "snapIDsnapidsnapidsnapidsnapidsn",
+		"snap-digest":   "sha512-digest-of-some-snap.snap",
+		"snap-size":     "5",
+		"snap-revision": "11",
+		"developer-id":  "devdevdev",
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, IsNil)
+	for _, a := range append([]asserts.Assertion{snapRev}, known...) {
+		err := s.db.Add(a)
+		c.Assert(err, IsNil)
+	}
+	s.state.Unlock()
+
+	return s.installPath(c, "/path/to/some-snap.snap", 0)
+}
+
+func (s *snapmgrTestSuite) TestInstallPathGatedApproved(c *C) {
+	chg := s.testInstallPathGated(c, []asserts.Assertion{s.gatingValidation(c, "11", 0, false)})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("install failed with: %v", chg.Err()))
+}
+
+func (s *snapmgrTestSuite) TestInstallPathGatedNotApproved(c *C) {
+	chg := s.testInstallPathGated(c, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot refresh "some-snap" to revision 11: no validation by "gating-snap".*`)
+}
+
 func (s *snapmgrTestSuite) TestRemoveRunThrough(c *C) {
 	si := snap.SideInfo{
 		OfficialName: "some-snap",
@@ -1216,6 +1814,88 @@ func (s *snapmgrTestSuite) TestRemoveRefused(c *C) {
 	c.Check(err, ErrorMatches, `snap "gadget" is not removable`)
 }
 
+// setModel adds to the assertion database of the locked state a
+// "my-brand" model with the given headers, together with the brand
+// account-key signed using storeSigning, and makes it the device model.
+func setModel(c *C, st *state.State, storeSigning *assertstest.StoreStack, extraHeaders map[string]interface{}) {
+	db := assertstate.DB(st)
+	brandAccKey := assertstest.NewAccountKey(storeSigning.SigningDB, "my-brand", brandPrivKey.PublicKey(), nil)
+	err := db.Add(storeSigning.StoreAccountKey)
+	c.Assert(err, IsNil)
+	err = db.Add(brandAccKey)
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"series":         "16",
+		"brand-id":       "my-brand",
+		"model":          "my-model",
+		"class":          "generic",
+		"architecture":   "amd64",
+		"store":          "my-brand-store",
+		"gadget":         "pc",
+		"kernel":         "pc-kernel",
+		"core":           "core",
+		"allowed-modes":  "",
+		"required-snaps": "",
+		"timestamp":      time.Now().Format(time.RFC3339),
+	}
+	for k, v := range extraHeaders {
+		headers[k] = v
+	}
+	model, err := assertstest.NewSigningDB("my-brand", brandPrivKey).Sign(asserts.ModelType, headers, nil)
+	c.Assert(err, IsNil)
+	err = db.Add(model)
+	c.Assert(err, IsNil)
+
+	devicestate.SetDevice(st, &devicestate.DeviceState{Brand: "my-brand", Model: "my-model"})
+}
+
+func (s *snapmgrTestSuite) TestRemoveRequiredByModelRefused(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	setModel(c, s.state, s.storeSigning, map[string]interface{}{
+		"required-snaps": []interface{}{"some-snap"},
+	})
+
+	for _, name := range []string{"some-snap", "pc", "pc-kernel", "core"} {
+		// not active, so this is not refused by canRemove
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Sequence: []*snap.SideInfo{{OfficialName: name, Revision: snap.R(7)}},
+		})
+
+		_, err := snapstate.Remove(s.state, name)
+		c.Check(err, ErrorMatches, fmt.Sprintf(`snap %q is required by the device model and cannot be removed`, name))
+	}
+
+	snapstate.Set(s.state, "other-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{{OfficialName: "other-snap", Revision: snap.R(7)}},
+	})
+	_, err := snapstate.Remove(s.state, "other-snap")
+	c.Check(err, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestDeviceStoreID(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	storeID, err := snapstate.DeviceStoreID(s.state)
+	c.Assert(err, IsNil)
+	c.Check(storeID, Equals, "")
+
+	setModel(c, s.state, s.storeSigning, nil)
+
+	storeID, err = snapstate.DeviceStoreID(s.state)
+	c.Assert(err, IsNil)
+	c.Check(storeID, Equals, "my-brand-store")
+
+	os.Setenv("UBUNTU_STORE_ID", "override-store")
+	defer os.Unsetenv("UBUNTU_STORE_ID")
+	storeID, err = snapstate.DeviceStoreID(s.state)
+	c.Assert(err, IsNil)
+	c.Check(storeID, Equals, "override-store")
+}
+
 type snapmgrQuerySuite struct {
 	st *state.State
 }
@@ -23,9 +23,13 @@ package snapstate
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
@@ -52,6 +56,13 @@ const (
 	// for use in the interim time while we have the backward compatible
 	// support
 	firstInterimUsableFlagValue
+
+	// KillApps kills the running apps of a snap being refreshed instead
+	// of postponing the refresh until they exit.
+	KillApps = firstInterimUsableFlagValue
+	// Dangerous allows installing a snap file without the assertions
+	// that verify it.
+	Dangerous = firstInterimUsableFlagValue << 1
 	// if we need flags for just SnapSetup it may be easier
 	// to start a new sequence from the other end with:
 	// 0x40000000 >> iota
@@ -81,10 +92,19 @@ func doInstall(s *state.State, curActive bool, ss *SnapSetup) (*state.TaskSet, e
 		tasks = append(tasks, t)
 	}
 
+	premount := prepare
+	if !ss.TryMode() {
+		// validate the snap file against its assertions
+		validate := s.NewTask("validate-snap", fmt.Sprintf(i18n.G("Verify snap %q"), ss.Name))
+		addTask(validate)
+		validate.WaitFor(prepare)
+		premount = validate
+	}
+
 	// mount
 	mount := s.NewTask("mount-snap", fmt.Sprintf(i18n.G("Mount snap %q"), ss.Name))
 	addTask(mount)
-	mount.WaitFor(prepare)
+	mount.WaitFor(premount)
 	precopy := mount
 
 	if curActive {
@@ -130,6 +150,66 @@ func checkChangeConflict(s *state.State, snapName string) error {
 	return nil
 }
 
+// RefreshPostponedError is returned when the refresh of a snap is
+// postponed until its running apps exit.
+type RefreshPostponedError struct {
+	Snap string
+	Apps []string
+}
+
+func (e *RefreshPostponedError) Error() string {
+	return fmt.Sprintf("refresh of snap %q postponed until its apps exit: %s", e.Snap, strings.Join(e.Apps, ", "))
+}
+
+// appsBlockingRefresh returns the running apps of the snap its refresh
+// must wait for, unless asked to kill them, for at most
+// maxRefreshInhibition since the refresh first had to wait. It records in
+// snapst when that was, and must be called with the state locked.
+func appsBlockingRefresh(st *state.State, name string, snapst *SnapState, flags Flags) ([]string, error) {
+	m := cachedSnapManager(st)
+	if m == nil || !snapst.Active || flags&KillApps != 0 {
+		return nil, nil
+	}
+	info, err := readInfo(name, snapst.CurrentSideInfo())
+	if err != nil {
+		return nil, err
+	}
+	running, err := m.backend.RunningApps(info)
+	if err != nil {
+		return nil, err
+	}
+
+	since := snapst.RefreshInhibitedSince
+	if len(running) == 0 {
+		if since != nil {
+			snapst.RefreshInhibitedSince = nil
+			Set(st, name, snapst)
+		}
+		return nil, nil
+	}
+	// since is kept until the new revision is linked, for the refresh not
+	// to wait again once started
+	if since != nil && timeNow().Sub(*since) >= maxRefreshInhibition {
+		logger.Noticef("Refreshing snap %q although its apps are running: postponed since %s", name, since.Format(time.RFC3339))
+		return nil, nil
+	}
+
+	apps := make([]string, 0, len(running))
+	for app := range running {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	if since == nil {
+		now := timeNow()
+		snapst.RefreshInhibitedSince = &now
+		Set(st, name, snapst)
+		if m.refreshInhibited != nil {
+			m.refreshInhibited(name, apps)
+		}
+	}
+	return apps, nil
+}
+
 // Install returns a set of tasks for installing snap.
 // Note that the state must be locked by the caller.
 func Install(s *state.State, name, channel string, userID int, flags Flags) (*state.TaskSet, error) {
@@ -161,6 +241,16 @@ func InstallPath(s *state.State, name, path, channel string, flags Flags) (*stat
 		return nil, err
 	}
 
+	// the snap file is not kept around for postponing its install until
+	// the running apps exit, so refuse it instead
+	apps, err := appsBlockingRefresh(s, name, &snapst, flags)
+	if err != nil {
+		return nil, err
+	}
+	if len(apps) > 0 {
+		return nil, fmt.Errorf("cannot refresh snap %q from a file while its apps are running: %s", name, strings.Join(apps, ", "))
+	}
+
 	ss := &SnapSetup{
 		Name:     name,
 		SnapPath: path,
@@ -195,6 +285,25 @@ func Update(s *state.State, name, channel string, userID int, flags Flags) (*sta
 		channel = snapst.Channel
 	}
 
+	if err := checkChangeConflict(s, name); err != nil {
+		return nil, err
+	}
+
+	// the refresh is started again by the snap manager once the apps exit
+	apps, err := appsBlockingRefresh(s, name, &snapst, flags)
+	if err != nil {
+		return nil, err
+	}
+	if len(apps) > 0 {
+		snapst.RefreshPending = &PendingRefresh{
+			Channel: channel,
+			UserID:  userID,
+			Flags:   flags,
+		}
+		Set(s, name, &snapst)
+		return nil, &RefreshPostponedError{Snap: name, Apps: apps}
+	}
+
 	ss := &SnapSetup{
 		Name:    name,
 		Channel: channel,
@@ -202,7 +311,15 @@ func Update(s *state.State, name, channel string, userID int, flags Flags) (*sta
 		Flags:   SnapSetupFlags(flags),
 	}
 
-	return doInstall(s, snapst.Active, ss)
+	ts, err := doInstall(s, snapst.Active, ss)
+	if err != nil {
+		return nil, err
+	}
+	if snapst.RefreshPending != nil {
+		snapst.RefreshPending = nil
+		Set(s, name, &snapst)
+	}
+	return ts, nil
 }
 
 func removeInactiveRevision(s *state.State, name string, revision snap.Revision) *state.TaskSet {
@@ -239,6 +356,27 @@ func canRemove(s *snap.Info, active bool) bool {
 	return true
 }
 
+// requiredByModel returns whether the device model, if there is one,
+// requires the snap, either directly or as its gadget, kernel or core.
+func requiredByModel(s *state.State, name string) (bool, error) {
+	model, err := devicestate.Model(s)
+	if err == state.ErrNoState {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if name == model.Gadget() || name == model.Kernel() || name == model.Core() {
+		return true, nil
+	}
+	for _, required := range model.RequiredSnaps() {
+		if name == required {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Remove returns a set of tasks for removing snap.
 // Note that the state must be locked by the caller.
 func Remove(s *state.State, name string) (*state.TaskSet, error) {
@@ -269,6 +407,13 @@ func Remove(s *state.State, name string) (*state.TaskSet, error) {
 	if !canRemove(info, active) {
 		return nil, fmt.Errorf("snap %q is not removable", name)
 	}
+	required, err := requiredByModel(s, name)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		return nil, fmt.Errorf("snap %q is required by the device model and cannot be removed", name)
+	}
 
 	// main/current SnapSetup
 	ss := SnapSetup{
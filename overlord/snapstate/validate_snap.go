@@ -0,0 +1,326 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"crypto"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/squashfs"
+	"github.com/snapcore/snapd/store"
+)
+
+// snapDigestImpl computes the digest and size of the snap file at
+// snapPath, in the form used by snap-revision assertions.
+func snapDigestImpl(snapPath string) (digest string, size uint64, err error) {
+	size, hashDigest, err := squashfs.New(snapPath).HashDigest(crypto.SHA512)
+	if err != nil {
+		return "", 0, fmt.Errorf("cannot compute digest of snap file %q: %v", snapPath, err)
+	}
+	digest, err = asserts.EncodeDigest(crypto.SHA512, hashDigest)
+	if err != nil {
+		return "", 0, err
+	}
+	return digest, size, nil
+}
+
+var snapDigest = snapDigestImpl
+
+// saveSnapAssert adds the assertion to the database unless the same
+// or a more recent revision of it is already present.
+func saveSnapAssert(db *asserts.Database, a asserts.Assertion) error {
+	err := db.Add(a)
+	if revErr, ok := err.(*asserts.RevisionError); ok && revErr.Current >= revErr.Used {
+		return nil
+	}
+	return err
+}
+
+// checkSnapAsserts checks that the snap-revision and snap-declaration
+// assertions match the snap file and each other.
+func checkSnapAsserts(name, digest string, size uint64, snapRev *asserts.SnapRevision, snapDecl *asserts.SnapDeclaration) error {
+	if snapRev.SnapDigest() != digest {
+		return fmt.Errorf("snap %q file does not have expected digest according to signatures: %s != %s", name, digest, snapRev.SnapDigest())
+	}
+	if snapRev.SnapID() != snapDecl.SnapID() {
+		return fmt.Errorf("snap %q has inconsistent signatures: snap-revision is for snap-id %q, snap-declaration for %q", name, snapRev.SnapID(), snapDecl.SnapID())
+	}
+	if snapRev.SnapSize() != size {
+		return fmt.Errorf("snap %q file does not have expected size according to signatures: %d != %d", name, size, snapRev.SnapSize())
+	}
+	if snapDecl.SnapName() != name {
+		return fmt.Errorf("snap %q does not have expected name according to signatures: %q", name, snapDecl.SnapName())
+	}
+	return nil
+}
+
+// validateStoreSnap fetches the assertions about the snap file
+// downloaded from the store, checks them against it and adds them to the
+// assertion database. It must be called with the state locked.
+func (m *SnapManager) validateStoreSnap(t *state.Task, db *asserts.Database, ss *SnapSetup, snapst *SnapState, digest string, size uint64) error {
+	st := t.State()
+
+	if snapst.Candidate == nil || snapst.Candidate.SnapID == "" {
+		return fmt.Errorf("cannot verify snap %q: missing snap-id", ss.Name)
+	}
+	snapID := snapst.Candidate.SnapID
+
+	auther, err := userAuthenticator(st, ss.UserID)
+	if err != nil {
+		return err
+	}
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		st.Unlock()
+		defer st.Lock()
+		return m.store.Assertion(ref.Type, ref.PrimaryKey, auther)
+	}
+
+	a, err := retrieve(&asserts.Ref{Type: asserts.SnapRevisionType, PrimaryKey: []string{release.Series, snapID, digest}})
+	if err != nil {
+		return fmt.Errorf("cannot find signatures with metadata for snap %q: %v", ss.Name, err)
+	}
+	snapRev, ok := a.(*asserts.SnapRevision)
+	if !ok {
+		return fmt.Errorf("cannot verify snap %q: store returned %s assertion instead of snap-revision", ss.Name, a.Type().Name)
+	}
+	a, err = retrieve(&asserts.Ref{Type: asserts.SnapDeclarationType, PrimaryKey: []string{release.Series, snapID}})
+	if err != nil {
+		return fmt.Errorf("cannot find signatures with metadata for snap %q: %v", ss.Name, err)
+	}
+	snapDecl, ok := a.(*asserts.SnapDeclaration)
+	if !ok {
+		return fmt.Errorf("cannot verify snap %q: store returned %s assertion instead of snap-declaration", ss.Name, a.Type().Name)
+	}
+
+	if err := checkSnapAsserts(ss.Name, digest, size, snapRev, snapDecl); err != nil {
+		return err
+	}
+	if snapRev.SnapID() != snapID {
+		return fmt.Errorf("snap %q does not have expected snap-id according to signatures: %q != %q", ss.Name, snapID, snapRev.SnapID())
+	}
+	if snap.R(int(snapRev.SnapRevision())) != ss.Revision {
+		return fmt.Errorf("snap %q does not have expected revision according to signatures: %s != %d", ss.Name, ss.Revision, snapRev.SnapRevision())
+	}
+
+	save := func(a asserts.Assertion) error {
+		return saveSnapAssert(db, a)
+	}
+	f := asserts.NewFetcher(db, retrieve, save)
+	for _, a := range []asserts.Assertion{snapDecl, snapRev} {
+		if err := f.Save(a); err != nil {
+			return fmt.Errorf("cannot verify snap %q: %v", ss.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateLocalSnap checks the snap file being installed from the local
+// system against the assertions about it found in the assertion
+// database. When they are present the snap takes the identity and
+// revision they assert. It must be called with the state locked.
+func (m *SnapManager) validateLocalSnap(t *state.Task, db *asserts.Database, ss *SnapSetup, snapst *SnapState, digest string, size uint64) error {
+	revs, err := db.FindMany(asserts.SnapRevisionType, map[string]string{
+		"series":      release.Series,
+		"snap-digest": digest,
+	})
+	if err == asserts.ErrNotFound {
+		return fmt.Errorf("cannot find signatures with metadata for snap %q (%q)", ss.Name, ss.SnapPath)
+	}
+	if err != nil {
+		return err
+	}
+	snapRev, ok := revs[0].(*asserts.SnapRevision)
+	if !ok {
+		return fmt.Errorf("internal error: unexpected %s assertion found for snap %q", revs[0].Type().Name, ss.Name)
+	}
+
+	a, err := db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  release.Series,
+		"snap-id": snapRev.SnapID(),
+	})
+	if err == asserts.ErrNotFound {
+		return fmt.Errorf("cannot find signatures with metadata for snap %q (%q): missing snap-declaration", ss.Name, ss.SnapPath)
+	}
+	if err != nil {
+		return err
+	}
+	snapDecl, ok := a.(*asserts.SnapDeclaration)
+	if !ok {
+		return fmt.Errorf("internal error: unexpected %s assertion found for snap %q", a.Type().Name, ss.Name)
+	}
+
+	if err := checkSnapAsserts(ss.Name, digest, size, snapRev, snapDecl); err != nil {
+		return err
+	}
+
+	revision := snap.R(int(snapRev.SnapRevision()))
+	if err := checkRevisionIsNew(ss.Name, snapst, revision); err != nil {
+		return err
+	}
+	if snapst.CurrentSideInfo() != nil {
+		if err := checkGatingValidations(t.State(), ss.Name, snapDecl.SnapID(), revision); err != nil {
+			return err
+		}
+	}
+
+	ss.Revision = revision
+	if err := setTaskSnapSetup(t, ss); err != nil {
+		return err
+	}
+	snapst.Candidate = &snap.SideInfo{
+		OfficialName: snapDecl.SnapName(),
+		SnapID:       snapDecl.SnapID(),
+		Revision:     revision,
+	}
+	Set(t.State(), ss.Name, snapst)
+	return nil
+}
+
+// gatingSnaps returns the names, by snap-id, of the installed snaps
+// whose snap-declarations say that they gate the snap with snapID. It
+// must be called with the state locked.
+func gatingSnaps(st *state.State, snapID string) (map[string]string, error) {
+	snapStates, err := All(st)
+	if err != nil {
+		return nil, err
+	}
+	db := assertstate.DB(st)
+	gating := make(map[string]string)
+	for snapName, snapst := range snapStates {
+		si := snapst.CurrentSideInfo()
+		if si == nil || si.SnapID == "" || si.SnapID == snapID {
+			continue
+		}
+		a, err := db.Find(asserts.SnapDeclarationType, map[string]string{
+			"series":  release.Series,
+			"snap-id": si.SnapID,
+		})
+		if err == asserts.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapDecl, ok := a.(*asserts.SnapDeclaration)
+		if !ok {
+			return nil, fmt.Errorf("internal error: unexpected %s assertion found for snap %q", a.Type().Name, snapName)
+		}
+		for _, gated := range snapDecl.Gates() {
+			if gated == snapID {
+				gating[si.SnapID] = snapName
+				break
+			}
+		}
+	}
+	return gating, nil
+}
+
+// fetchGatingValidations retrieves from the store the validations by
+// the installed snaps gating the snap with snapID of its given revision,
+// and adds them to the assertion database. Validations the store does
+// not have are skipped. It must be called with the state locked.
+func (m *SnapManager) fetchGatingValidations(st *state.State, userID int, name, snapID string, revision snap.Revision) error {
+	if snapID == "" {
+		return nil
+	}
+	gating, err := gatingSnaps(st, snapID)
+	if err != nil || len(gating) == 0 {
+		return err
+	}
+
+	auther, err := userAuthenticator(st, userID)
+	if err != nil {
+		return err
+	}
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		st.Unlock()
+		defer st.Lock()
+		return m.store.Assertion(ref.Type, ref.PrimaryKey, auther)
+	}
+	db := assertstate.DB(st)
+	save := func(a asserts.Assertion) error {
+		return saveSnapAssert(db, a)
+	}
+	f := asserts.NewFetcher(db, retrieve, save)
+
+	gatingIDs := make([]string, 0, len(gating))
+	for gatingID := range gating {
+		gatingIDs = append(gatingIDs, gatingID)
+	}
+	sort.Strings(gatingIDs)
+	for _, gatingID := range gatingIDs {
+		// always ask the store, which knows about revocations
+		a, err := retrieve(&asserts.Ref{Type: asserts.ValidationType, PrimaryKey: []string{release.Series, gatingID, snapID, revision.String()}})
+		if err == store.ErrAssertionNotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot fetch validation by %q of snap %q: %v", gating[gatingID], name, err)
+		}
+		if err := f.Save(a); err != nil {
+			return fmt.Errorf("cannot fetch validation by %q of snap %q: %v", gating[gatingID], name, err)
+		}
+	}
+	return nil
+}
+
+// checkGatingValidations checks that the refresh to revision of the
+// snap with snapID is approved by validations, found in the assertion
+// database, of all the installed snaps gating it. It must be called with
+// the state locked.
+func checkGatingValidations(st *state.State, name, snapID string, revision snap.Revision) error {
+	if snapID == "" {
+		return nil
+	}
+	gating, err := gatingSnaps(st, snapID)
+	if err != nil {
+		return err
+	}
+
+	db := assertstate.DB(st)
+	var refusing []string
+	for gatingID, gatingName := range gating {
+		a, err := db.Find(asserts.ValidationType, map[string]string{
+			"series":            release.Series,
+			"snap-id":           gatingID,
+			"approved-snap-id":  snapID,
+			"approved-revision": revision.String(),
+		})
+		if err != nil && err != asserts.ErrNotFound {
+			return err
+		}
+		if val, ok := a.(*asserts.Validation); ok && !val.Revoked() {
+			continue
+		}
+		refusing = append(refusing, fmt.Sprintf("%q", gatingName))
+	}
+	if len(refusing) > 0 {
+		sort.Strings(refusing)
+		return fmt.Errorf("cannot refresh %q to revision %s: no validation by %s", name, revision, strings.Join(refusing, ", "))
+	}
+	return nil
+}
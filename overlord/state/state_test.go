@@ -195,6 +195,11 @@ func (ss *stateSuite) TestImplicitCheckpointAndRead(c *C) {
 	err = st2.Get("mgr2", &mSt2B)
 	c.Assert(err, IsNil)
 	c.Check(&mSt2B, DeepEquals, mSt2)
+
+	// the cache of a read state is usable
+	type key1 struct{}
+	st2.Cache(key1{}, "value1")
+	c.Check(st2.Cached(key1{}), Equals, "value1")
 }
 
 func (ss *stateSuite) TestImplicitCheckpointRetry(c *C) {
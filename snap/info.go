@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/snapcore/snapd/dirs"
@@ -125,6 +126,10 @@ type Info struct {
 	Plugs            map[string]*PlugInfo
 	Slots            map[string]*SlotInfo
 
+	// Resources limits the resources used by all the services of the snap
+	// together.
+	Resources *Resources
+
 	// The information in all the remaining fields is not sourced from the snap blob itself.
 	SideInfo
 
@@ -201,6 +206,19 @@ func (s *Info) NeedsDevMode() bool {
 	return s.Confinement == DevmodeConfinement
 }
 
+// SliceName returns the name of the systemd slice grouping the services of
+// the snap.
+func (s *Info) SliceName() string {
+	// Dashes separate the levels of the slice hierarchy so they must be
+	// escaped, like systemd-escape does.
+	return "snap." + strings.Replace(s.Name(), "-", `\x2d`, -1) + ".slice"
+}
+
+// SliceFile returns the systemd slice file path for the services of the snap.
+func (s *Info) SliceFile() string {
+	return filepath.Join(dirs.SnapServicesDir, s.SliceName())
+}
+
 // sanity check that Info is a PlaceInfo
 var _ PlaceInfo = (*Info)(nil)
 
@@ -244,6 +262,20 @@ type AppInfo struct {
 	SocketMode   string
 	ListenStream string
 
+	// Sockets and Timer activate the service on demand, instead of
+	// having it started on boot.
+	Sockets map[string]*SocketInfo
+	Timer   *TimerInfo
+
+	// Before and After name the other services of the snap that this
+	// service must be started before or after, respectively.
+	Before []string
+	After  []string
+
+	// Autostart names the desktop file, in meta/gui, of an app started
+	// when users log into a desktop session.
+	Autostart string
+
 	// TODO: this should go away once we have more plumbing and can change
 	// things vs refactor
 	// https://github.com/snapcore/snapd/pull/794#discussion_r58688496
@@ -253,6 +285,49 @@ type AppInfo struct {
 	Slots map[string]*SlotInfo
 
 	Environment map[string]string
+
+	// Resources limits the resources used by the service.
+	Resources *Resources
+}
+
+// Resources describes limits on the system resources used by services,
+// enforced through systemd.
+type Resources struct {
+	// MemoryLimit is the maximum amount of memory, in bytes or with one of
+	// the K, M, G or T suffixes.
+	MemoryLimit string `yaml:"memory-limit,omitempty" json:"memory-limit,omitempty"`
+	// CPUQuota is the maximum share of the time of one CPU, as a percentage
+	// like "50%". Quotas above 100% allow using more than one CPU.
+	CPUQuota string `yaml:"cpu-quota,omitempty" json:"cpu-quota,omitempty"`
+	// CPUWeight is the relative share of CPU time given when CPUs are
+	// contended, between 2 and 262144. The default weight is 1024.
+	CPUWeight int `yaml:"cpu-weight,omitempty" json:"cpu-weight,omitempty"`
+	// TasksMax is the maximum number of processes and threads.
+	TasksMax int `yaml:"tasks-max,omitempty" json:"tasks-max,omitempty"`
+}
+
+// SocketInfo provides information about a socket activating a service.
+type SocketInfo struct {
+	App *AppInfo
+
+	Name string
+	// ListenStream and ListenDatagram are the address of a stream or a
+	// datagram socket, only one of which is set. The address is either
+	// a path under $SNAP_DATA or $SNAP_COMMON, an abstract socket name
+	// starting with "@", or a port with an optional host.
+	ListenStream   string
+	ListenDatagram string
+	// SocketMode is the octal file mode of a socket in the file system.
+	SocketMode string
+}
+
+// TimerInfo provides information about a timer activating a service.
+type TimerInfo struct {
+	App *AppInfo
+
+	// Timer is the schedule of the timer, in the calendar event format
+	// of systemd.time(7).
+	Timer string
 }
 
 // HookInfo provides information about a hook.
@@ -314,6 +389,86 @@ func (app *AppInfo) ServiceSocketFile() string {
 	return filepath.Join(dirs.SnapServicesDir, app.SecurityTag()+".socket")
 }
 
+// IsActivated returns whether the service is started on demand by its
+// sockets or its timer, rather than on boot.
+func (app *AppInfo) IsActivated() bool {
+	return len(app.Sockets) > 0 || app.Timer != nil
+}
+
+// File returns the systemd socket file path for the socket.
+func (socket *SocketInfo) File() string {
+	return filepath.Join(dirs.SnapServicesDir, socket.App.SecurityTag()+"."+socket.Name+".socket")
+}
+
+// File returns the systemd timer file path for the timer.
+func (timer *TimerInfo) File() string {
+	return filepath.Join(dirs.SnapServicesDir, timer.App.SecurityTag()+".timer")
+}
+
+// SortServices returns the given apps ordered so that every service comes
+// after the services it must be started after, and before the ones it
+// must be started before. Orderings on apps not in the list are ignored;
+// otherwise apps are sorted by name. It fails if the ordering has a cycle.
+func SortServices(apps []*AppInfo) ([]*AppInfo, error) {
+	byName := make(map[string]*AppInfo, len(apps))
+	for _, app := range apps {
+		byName[app.Name] = app
+	}
+
+	// successors[a] lists the apps that must come after a
+	successors := make(map[string][]string)
+	predecessors := make(map[string]int, len(apps))
+	addEdge := func(from, to string) {
+		if byName[from] == nil || byName[to] == nil {
+			return
+		}
+		successors[from] = append(successors[from], to)
+		predecessors[to]++
+	}
+	for _, app := range apps {
+		for _, other := range app.After {
+			addEdge(other, app.Name)
+		}
+		for _, other := range app.Before {
+			addEdge(app.Name, other)
+		}
+	}
+
+	var ready []string
+	for name := range byName {
+		if predecessors[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	sorted := make([]*AppInfo, 0, len(apps))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, byName[name])
+		for _, next := range successors[name] {
+			predecessors[next]--
+			if predecessors[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+
+	if len(sorted) < len(byName) {
+		var cycle []string
+		for name := range byName {
+			if predecessors[name] > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("applications are part of a before/after cycle: %s", strings.Join(cycle, ", "))
+	}
+
+	return sorted, nil
+}
+
 func copyEnv(in map[string]string) map[string]string {
 	out := make(map[string]string)
 	for k, v := range in {
@@ -47,6 +47,7 @@ type snapYaml struct {
 	Slots            map[string]interface{} `yaml:"slots,omitempty"`
 	Apps             map[string]appYaml     `yaml:"apps,omitempty"`
 	Hooks            map[string]hookYaml    `yaml:"hooks,omitempty"`
+	Resources        *Resources             `yaml:"resources,omitempty"`
 }
 
 type plugYaml struct {
@@ -83,6 +84,22 @@ type appYaml struct {
 	Socket       bool   `yaml:"socket,omitempty"`
 	ListenStream string `yaml:"listen-stream,omitempty"`
 	SocketMode   string `yaml:"socket-mode,omitempty"`
+
+	Sockets map[string]socketYaml `yaml:"sockets,omitempty"`
+	Timer   string                `yaml:"timer,omitempty"`
+
+	Before []string `yaml:"before,omitempty"`
+	After  []string `yaml:"after,omitempty"`
+
+	Autostart string `yaml:"autostart,omitempty"`
+
+	Resources *Resources `yaml:"resources,omitempty"`
+}
+
+type socketYaml struct {
+	ListenStream   string `yaml:"listen-stream,omitempty"`
+	ListenDatagram string `yaml:"listen-datagram,omitempty"`
+	SocketMode     string `yaml:"socket-mode,omitempty"`
 }
 
 type hookYaml struct {
@@ -174,6 +191,7 @@ func infoSkeletonFromSnapYaml(y snapYaml) *Info {
 		Plugs:               make(map[string]*PlugInfo),
 		Slots:               make(map[string]*SlotInfo),
 		Environment:         y.Environment,
+		Resources:           y.Resources,
 	}
 
 	sort.Strings(snap.Assumes)
@@ -249,6 +267,28 @@ func setAppsFromSnapYaml(y snapYaml, snap *Info) {
 			ListenStream:    yApp.ListenStream,
 			BusName:         yApp.BusName,
 			Environment:     yApp.Environment,
+			Resources:       yApp.Resources,
+			Before:          yApp.Before,
+			After:           yApp.After,
+			Autostart:       yApp.Autostart,
+		}
+		if len(yApp.Sockets) > 0 {
+			app.Sockets = make(map[string]*SocketInfo, len(yApp.Sockets))
+		}
+		for socketName, ySocket := range yApp.Sockets {
+			app.Sockets[socketName] = &SocketInfo{
+				App:            app,
+				Name:           socketName,
+				ListenStream:   ySocket.ListenStream,
+				ListenDatagram: ySocket.ListenDatagram,
+				SocketMode:     ySocket.SocketMode,
+			}
+		}
+		if yApp.Timer != "" {
+			app.Timer = &TimerInfo{
+				App:   app,
+				Timer: yApp.Timer,
+			}
 		}
 		if len(y.Plugs) > 0 || len(yApp.PlugNames) > 0 {
 			app.Plugs = make(map[string]*PlugInfo)
@@ -1201,6 +1201,37 @@ apps:
 	})
 }
 
+func (s *YamlSuite) TestSnapYamlResources(c *C) {
+	y := []byte(`
+name: foo
+version: 1.0
+resources:
+ memory-limit: 1G
+ tasks-max: 512
+apps:
+ svc:
+   command: svc1
+   daemon: simple
+   resources:
+     memory-limit: 256M
+     cpu-quota: 50%
+     cpu-weight: 512
+     tasks-max: 64
+ app:
+   command: app
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+	c.Check(info.Resources, DeepEquals, &snap.Resources{MemoryLimit: "1G", TasksMax: 512})
+	c.Check(info.Apps["svc"].Resources, DeepEquals, &snap.Resources{
+		MemoryLimit: "256M",
+		CPUQuota:    "50%",
+		CPUWeight:   512,
+		TasksMax:    64,
+	})
+	c.Check(info.Apps["app"].Resources, IsNil)
+}
+
 func (s *YamlSuite) TestSnapYamlGlobalEnvironment(c *C) {
 	y := []byte(`
 name: foo
@@ -1234,3 +1265,86 @@ apps:
 		"k2": "v2",
 	})
 }
+
+func (s *InfoSnapYamlTestSuite) TestSnapYamlSocketsAndTimer(c *C) {
+	y := []byte(`name: foo
+version: 1.0
+apps:
+  web:
+    command: web
+    daemon: simple
+    sockets:
+      http:
+        listen-stream: $SNAP_DATA/http.sock
+        socket-mode: 0660
+      stats:
+        listen-datagram: 127.0.0.1:9000
+  backup:
+    command: backup
+    daemon: oneshot
+    timer: "*-*-* 03:00"
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	web := info.Apps["web"]
+	c.Check(web.Sockets, DeepEquals, map[string]*snap.SocketInfo{
+		"http": {
+			App:          web,
+			Name:         "http",
+			ListenStream: "$SNAP_DATA/http.sock",
+			SocketMode:   "0660",
+		},
+		"stats": {
+			App:            web,
+			Name:           "stats",
+			ListenDatagram: "127.0.0.1:9000",
+		},
+	})
+	c.Check(web.Timer, IsNil)
+	c.Check(web.IsActivated(), Equals, true)
+
+	backup := info.Apps["backup"]
+	c.Check(backup.Sockets, IsNil)
+	c.Check(backup.Timer, DeepEquals, &snap.TimerInfo{App: backup, Timer: "*-*-* 03:00"})
+	c.Check(backup.IsActivated(), Equals, true)
+}
+
+func (s *InfoSnapYamlTestSuite) TestSnapYamlBeforeAfter(c *C) {
+	y := []byte(`name: foo
+version: 1.0
+apps:
+  db:
+    command: db
+    daemon: simple
+    before: [web]
+  web:
+    command: web
+    daemon: simple
+    after: [db, cache]
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	c.Check(info.Apps["db"].Before, DeepEquals, []string{"web"})
+	c.Check(info.Apps["db"].After, IsNil)
+	c.Check(info.Apps["web"].Before, IsNil)
+	c.Check(info.Apps["web"].After, DeepEquals, []string{"db", "cache"})
+}
+
+func (s *InfoSnapYamlTestSuite) TestSnapYamlAutostart(c *C) {
+	y := []byte(`name: foo
+version: 1.0
+apps:
+  browser:
+    command: browser
+    autostart: browser.desktop
+  tool:
+    command: tool
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	c.Check(info.Apps["browser"].Autostart, Equals, "browser.desktop")
+	c.Check(info.Apps["tool"].Autostart, Equals, "")
+}
@@ -73,6 +73,29 @@ func (s *infoSuite) TestAppInfoSecurityTag(c *C) {
 	c.Check(appInfo.SecurityTag(), Equals, "snap.http.GET")
 }
 
+func (s *infoSuite) TestSliceFile(c *C) {
+	info := &snap.Info{SuggestedName: "foo"}
+	c.Check(info.SliceName(), Equals, "snap.foo.slice")
+	c.Check(info.SliceFile(), Equals, filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"))
+
+	// Dashes would make the slice part of the hierarchy of snap.foo.slice.
+	info = &snap.Info{SuggestedName: "foo-bar"}
+	c.Check(info.SliceName(), Equals, `snap.foo\x2dbar.slice`)
+}
+
+func (s *infoSuite) TestSocketAndTimerFiles(c *C) {
+	info := &snap.Info{SuggestedName: "foo"}
+	app := &snap.AppInfo{Snap: info, Name: "web"}
+	socket := &snap.SocketInfo{App: app, Name: "http"}
+	timer := &snap.TimerInfo{App: app, Timer: "daily"}
+	c.Check(socket.File(), Equals, filepath.Join(dirs.SnapServicesDir, "snap.foo.web.http.socket"))
+	c.Check(timer.File(), Equals, filepath.Join(dirs.SnapServicesDir, "snap.foo.web.timer"))
+
+	c.Check(app.IsActivated(), Equals, false)
+	app.Timer = timer
+	c.Check(app.IsActivated(), Equals, true)
+}
+
 func (s *infoSuite) TestAppInfoWrapperPath(c *C) {
 	info, err := snap.InfoFromSnapYaml([]byte(`name: foo
 apps:
@@ -417,6 +440,21 @@ hooks:
 	})
 }
 
+func (s *infoSuite) TestSortServices(c *C) {
+	db := &snap.AppInfo{Name: "db"}
+	cache := &snap.AppInfo{Name: "cache", Before: []string{"web"}}
+	web := &snap.AppInfo{Name: "web", After: []string{"db", "gone"}}
+	alone := &snap.AppInfo{Name: "alone"}
+
+	sorted, err := snap.SortServices([]*snap.AppInfo{web, db, alone, cache})
+	c.Assert(err, IsNil)
+	c.Check(sorted, DeepEquals, []*snap.AppInfo{alone, cache, db, web})
+
+	db.After = []string{"web"}
+	_, err = snap.SortServices([]*snap.AppInfo{web, db, alone, cache})
+	c.Check(err, ErrorMatches, `applications are part of a before/after cycle: db, web`)
+}
+
 func verifyImplicitHook(c *C, info *snap.Info, hookName string) {
 	hook := info.Hooks[hookName]
 	c.Assert(hook, NotNil, Commentf("Expected hooks to contain %q", hookName))
@@ -22,6 +22,8 @@ package snap
 import (
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
 // Regular expression describing correct identifiers.
@@ -76,6 +78,10 @@ func Validate(info *Info) error {
 		return err
 	}
 
+	if err := ValidateResources(info.Resources); err != nil {
+		return err
+	}
+
 	// validate app entries
 	for _, app := range info.Apps {
 		err := ValidateApp(app)
@@ -84,6 +90,10 @@ func Validate(info *Info) error {
 		}
 	}
 
+	if err := validateAppOrder(info); err != nil {
+		return err
+	}
+
 	// validate hook entries
 	for _, hook := range info.Hooks {
 		err := ValidateHook(hook)
@@ -94,6 +104,40 @@ func Validate(info *Info) error {
 	return nil
 }
 
+// validateAppOrder checks that the before and after orderings of the
+// services of the snap refer to other services and have no cycles.
+func validateAppOrder(info *Info) error {
+	var services []*AppInfo
+	for _, app := range info.Apps {
+		if len(app.Before) == 0 && len(app.After) == 0 {
+			if app.Daemon != "" {
+				services = append(services, app)
+			}
+			continue
+		}
+		if app.Daemon == "" {
+			return fmt.Errorf("cannot define before or after for application %q: not a service", app.Name)
+		}
+		services = append(services, app)
+		for _, names := range [][]string{app.Before, app.After} {
+			for _, name := range names {
+				other, ok := info.Apps[name]
+				switch {
+				case !ok:
+					return fmt.Errorf("cannot order application %q: unknown application %q", app.Name, name)
+				case other == app:
+					return fmt.Errorf("cannot order application %q with itself", app.Name)
+				case other.Daemon == "":
+					return fmt.Errorf("cannot order application %q with %q: not a service", app.Name, name)
+				}
+			}
+		}
+	}
+
+	_, err := SortServices(services)
+	return err
+}
+
 func validateField(name, cont string, whitelist *regexp.Regexp) error {
 	if !whitelist.MatchString(cont) {
 		return fmt.Errorf("app description field '%s' contains illegal %q (legal: '%s')", name, cont, whitelist)
@@ -121,6 +165,43 @@ func ValidateApp(app *AppInfo) error {
 		return fmt.Errorf("cannot have %q as app name - use letters, digits, and dash as separator", app.Name)
 	}
 
+	if app.Resources != nil && app.Daemon == "" {
+		return fmt.Errorf(`"resources" field can only be used by services`)
+	}
+	if err := ValidateResources(app.Resources); err != nil {
+		return err
+	}
+
+	if len(app.Sockets) > 0 {
+		if app.Daemon == "" {
+			return fmt.Errorf(`"sockets" field can only be used by services`)
+		}
+		if app.Socket || app.ListenStream != "" {
+			return fmt.Errorf(`"sockets" field cannot be used together with "socket" or "listen-stream"`)
+		}
+	}
+	for _, socket := range app.Sockets {
+		if err := validateSocket(socket); err != nil {
+			return err
+		}
+	}
+	if app.Autostart != "" {
+		if app.Daemon != "" {
+			return fmt.Errorf(`"autostart" field cannot be used by services`)
+		}
+		if !validAutostart.MatchString(app.Autostart) {
+			return fmt.Errorf(`"autostart" field contains invalid value %q`, app.Autostart)
+		}
+	}
+	if app.Timer != nil {
+		if app.Daemon == "" {
+			return fmt.Errorf(`"timer" field can only be used by services`)
+		}
+		if !validTimer.MatchString(app.Timer.Timer) {
+			return fmt.Errorf(`"timer" field contains invalid value %q`, app.Timer.Timer)
+		}
+	}
+
 	// Validate the rest of the app info
 	checks := map[string]string{
 		"command":           app.Command,
@@ -138,3 +219,91 @@ func ValidateApp(app *AppInfo) error {
 	}
 	return nil
 }
+
+var validSocketName = regexp.MustCompile(`^[a-z0-9](?:-?[a-z0-9])*$`)
+var validSocketMode = regexp.MustCompile(`^0?[0-7]{3}$`)
+var validSocketPath = regexp.MustCompile(`^\$SNAP_(?:DATA|COMMON)/[A-Za-z0-9/._-]+$`)
+var validSocketPort = regexp.MustCompile(`^(?:(?:[0-9]{1,3}\.){3}[0-9]{1,3}:|\[[0-9a-fA-F:]+\]:)?([0-9]{1,5})$`)
+
+// validTimer is a loose check of systemd calendar events, which
+// systemd itself validates in full.
+var validTimer = regexp.MustCompile(`^[A-Za-z0-9*][A-Za-z0-9 *,:./~-]*$`)
+
+// validAutostart matches the name of a desktop file in meta/gui
+var validAutostart = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*\.desktop$`)
+
+func validateSocket(socket *SocketInfo) error {
+	if !validSocketName.MatchString(socket.Name) {
+		return fmt.Errorf("invalid socket name %q", socket.Name)
+	}
+
+	address := socket.ListenStream
+	if (socket.ListenStream == "") == (socket.ListenDatagram == "") {
+		return fmt.Errorf(`socket %q must define exactly one of "listen-stream" or "listen-datagram"`, socket.Name)
+	}
+	if address == "" {
+		address = socket.ListenDatagram
+	}
+
+	isPath := false
+	switch {
+	case strings.HasPrefix(address, "@"):
+		// abstract sockets are namespaced by the name of the snap
+		snapName := socket.App.Snap.Name()
+		if address != "@"+snapName && !strings.HasPrefix(address, "@"+snapName+"_") {
+			return fmt.Errorf("socket %q must use an abstract name starting with %q, not %q", socket.Name, "@"+snapName, address)
+		}
+	case validSocketPath.MatchString(address):
+		if strings.Contains(address, "/../") || strings.HasSuffix(address, "/..") {
+			return fmt.Errorf("socket %q has invalid path %q", socket.Name, address)
+		}
+		isPath = true
+	default:
+		m := validSocketPort.FindStringSubmatch(address)
+		if m == nil {
+			return fmt.Errorf("socket %q has invalid address %q (use a path under $SNAP_DATA or $SNAP_COMMON, an abstract name or a port)", socket.Name, address)
+		}
+		if port, _ := strconv.Atoi(m[1]); port < 1 || port > 65535 {
+			return fmt.Errorf("socket %q has invalid port %s", socket.Name, m[1])
+		}
+	}
+
+	if socket.SocketMode != "" {
+		if !isPath {
+			return fmt.Errorf("socket %q can only have a mode when in the file system", socket.Name)
+		}
+		if !validSocketMode.MatchString(socket.SocketMode) {
+			return fmt.Errorf("socket %q has invalid mode %q", socket.Name, socket.SocketMode)
+		}
+	}
+	return nil
+}
+
+var validMemoryLimit = regexp.MustCompile(`^[1-9][0-9]*[KMGT]?$`)
+var validCPUQuota = regexp.MustCompile(`^[1-9][0-9]*%$`)
+
+// Bounds of CPU weights, as accepted by systemd.
+const (
+	minCPUWeight = 2
+	maxCPUWeight = 262144
+)
+
+// ValidateResources verifies the resource limits of a snap or of a service.
+func ValidateResources(res *Resources) error {
+	if res == nil {
+		return nil
+	}
+	if res.MemoryLimit != "" && !validMemoryLimit.MatchString(res.MemoryLimit) {
+		return fmt.Errorf(`"memory-limit" field contains invalid value %q`, res.MemoryLimit)
+	}
+	if res.CPUQuota != "" && !validCPUQuota.MatchString(res.CPUQuota) {
+		return fmt.Errorf(`"cpu-quota" field contains invalid value %q`, res.CPUQuota)
+	}
+	if res.CPUWeight != 0 && (res.CPUWeight < minCPUWeight || res.CPUWeight > maxCPUWeight) {
+		return fmt.Errorf(`"cpu-weight" field must be between %d and %d, not %d`, minCPUWeight, maxCPUWeight, res.CPUWeight)
+	}
+	if res.TasksMax < 0 {
+		return fmt.Errorf(`"tasks-max" field contains invalid value %d`, res.TasksMax)
+	}
+	return nil
+}
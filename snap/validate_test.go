@@ -20,6 +20,8 @@
 package snap_test
 
 import (
+	"fmt"
+
 	. "gopkg.in/check.v1"
 
 	. "github.com/snapcore/snapd/snap"
@@ -150,6 +152,128 @@ func (s *ValidateSuite) TestAppDaemonValue(c *C) {
 	c.Check(ValidateApp(&AppInfo{Name: "foo", Daemon: "nono"}), ErrorMatches, `"daemon" field contains invalid value "nono"`)
 }
 
+func (s *ValidateSuite) TestAppResources(c *C) {
+	res := &Resources{MemoryLimit: "512M", CPUQuota: "150%", CPUWeight: 100, TasksMax: 32}
+	c.Check(ValidateApp(&AppInfo{Name: "foo", Daemon: "simple", Resources: res}), IsNil)
+	c.Check(ValidateApp(&AppInfo{Name: "foo", Resources: res}), ErrorMatches, `"resources" field can only be used by services`)
+}
+
+func (s *ValidateSuite) TestValidateResources(c *C) {
+	c.Check(ValidateResources(nil), IsNil)
+	c.Check(ValidateResources(&Resources{}), IsNil)
+	for _, limit := range []string{"1", "4096", "64K", "512M", "2G", "1T"} {
+		c.Check(ValidateResources(&Resources{MemoryLimit: limit}), IsNil)
+	}
+	for _, limit := range []string{"0", "-1", "M", "512MB", "1.5G", "512m"} {
+		c.Check(ValidateResources(&Resources{MemoryLimit: limit}), ErrorMatches, `"memory-limit" field contains invalid value ".*"`)
+	}
+	for _, quota := range []string{"1%", "50%", "200%"} {
+		c.Check(ValidateResources(&Resources{CPUQuota: quota}), IsNil)
+	}
+	for _, quota := range []string{"0%", "50", "0.5", "%"} {
+		c.Check(ValidateResources(&Resources{CPUQuota: quota}), ErrorMatches, `"cpu-quota" field contains invalid value ".*"`)
+	}
+	c.Check(ValidateResources(&Resources{CPUWeight: 2}), IsNil)
+	c.Check(ValidateResources(&Resources{CPUWeight: 262144}), IsNil)
+	c.Check(ValidateResources(&Resources{CPUWeight: 1}), ErrorMatches, `"cpu-weight" field must be between 2 and 262144, not 1`)
+	c.Check(ValidateResources(&Resources{CPUWeight: 262145}), ErrorMatches, `"cpu-weight" field must be between 2 and 262144, not 262145`)
+	c.Check(ValidateResources(&Resources{TasksMax: -1}), ErrorMatches, `"tasks-max" field contains invalid value -1`)
+}
+
+func (s *ValidateSuite) TestAppSockets(c *C) {
+	info := &Info{SuggestedName: "foo"}
+	app := &AppInfo{Snap: info, Name: "web", Daemon: "simple"}
+	app.Sockets = map[string]*SocketInfo{
+		"http": {App: app, Name: "http", ListenStream: "8080"},
+	}
+	c.Check(ValidateApp(app), IsNil)
+
+	app.ListenStream = "/var/run/foo.sock"
+	c.Check(ValidateApp(app), ErrorMatches, `"sockets" field cannot be used together with "socket" or "listen-stream"`)
+
+	app.ListenStream = ""
+	app.Daemon = ""
+	c.Check(ValidateApp(app), ErrorMatches, `"sockets" field can only be used by services`)
+}
+
+func (s *ValidateSuite) TestValidateSocket(c *C) {
+	info := &Info{SuggestedName: "foo"}
+	app := &AppInfo{Snap: info, Name: "web", Daemon: "simple"}
+	check := func(socket *SocketInfo) error {
+		socket.App = app
+		if socket.Name == "" {
+			socket.Name = "sock"
+		}
+		app.Sockets = map[string]*SocketInfo{socket.Name: socket}
+		return ValidateApp(app)
+	}
+
+	for _, good := range []*SocketInfo{
+		{ListenStream: "80"},
+		{ListenStream: "127.0.0.1:8080"},
+		{ListenStream: "[::1]:8080"},
+		{ListenDatagram: "65535"},
+		{ListenStream: "@foo"},
+		{ListenDatagram: "@foo_stats"},
+		{ListenStream: "$SNAP_DATA/foo.sock", SocketMode: "0660"},
+		{ListenStream: "$SNAP_COMMON/run/foo.sock", SocketMode: "600"},
+		{Name: "a-1", ListenStream: "80"},
+	} {
+		c.Check(check(good), IsNil, Commentf("%#v", good))
+	}
+
+	for _, t := range []struct {
+		socket *SocketInfo
+		err    string
+	}{
+		{&SocketInfo{Name: "Bad_Name", ListenStream: "80"}, `invalid socket name "Bad_Name"`},
+		{&SocketInfo{}, `socket "sock" must define exactly one of "listen-stream" or "listen-datagram"`},
+		{&SocketInfo{ListenStream: "80", ListenDatagram: "81"}, `socket "sock" must define exactly one of "listen-stream" or "listen-datagram"`},
+		{&SocketInfo{ListenStream: "@bar"}, `socket "sock" must use an abstract name starting with "@foo", not "@bar"`},
+		{&SocketInfo{ListenStream: "@foobar"}, `socket "sock" must use an abstract name starting with "@foo", not "@foobar"`},
+		{&SocketInfo{ListenStream: "/run/foo.sock"}, `socket "sock" has invalid address "/run/foo.sock" .*`},
+		{&SocketInfo{ListenStream: "$SNAP_DATA/../foo.sock"}, `socket "sock" has invalid path "\$SNAP_DATA/../foo.sock"`},
+		{&SocketInfo{ListenStream: "0"}, `socket "sock" has invalid port 0`},
+		{&SocketInfo{ListenStream: "70000"}, `socket "sock" has invalid port 70000`},
+		{&SocketInfo{ListenStream: "80", SocketMode: "0660"}, `socket "sock" can only have a mode when in the file system`},
+		{&SocketInfo{ListenStream: "$SNAP_DATA/foo.sock", SocketMode: "rw"}, `socket "sock" has invalid mode "rw"`},
+	} {
+		c.Check(check(t.socket), ErrorMatches, t.err)
+	}
+}
+
+func (s *ValidateSuite) TestAppTimer(c *C) {
+	app := &AppInfo{Name: "backup", Daemon: "oneshot"}
+	for _, timer := range []string{"daily", "*-*-* 03:00", "Mon,Fri 10:00", "*:0/15", "2016-12-24 18:00:00"} {
+		app.Timer = &TimerInfo{App: app, Timer: timer}
+		c.Check(ValidateApp(app), IsNil)
+	}
+	for _, timer := range []string{"", " daily", "daily; rm -rf /", "03:00\n"} {
+		app.Timer = &TimerInfo{App: app, Timer: timer}
+		c.Check(ValidateApp(app), ErrorMatches, `"timer" field contains invalid value .*`)
+	}
+
+	app.Timer = &TimerInfo{App: app, Timer: "daily"}
+	app.Daemon = ""
+	c.Check(ValidateApp(app), ErrorMatches, `"timer" field can only be used by services`)
+}
+
+func (s *ValidateSuite) TestAppAutostart(c *C) {
+	app := &AppInfo{Name: "browser"}
+	for _, autostart := range []string{"browser.desktop", "org.example.Browser.desktop", "my_browser-2.desktop"} {
+		app.Autostart = autostart
+		c.Check(ValidateApp(app), IsNil)
+	}
+	for _, autostart := range []string{"browser", ".desktop", "../browser.desktop", "gui/browser.desktop", "browser.desktop\n"} {
+		app.Autostart = autostart
+		c.Check(ValidateApp(app), ErrorMatches, `"autostart" field contains invalid value .*`)
+	}
+
+	app.Autostart = "browser.desktop"
+	app.Daemon = "simple"
+	c.Check(ValidateApp(app), ErrorMatches, `"autostart" field cannot be used by services`)
+}
+
 func (s *ValidateSuite) TestAppWhitelistError(c *C) {
 	err := ValidateApp(&AppInfo{Name: "foo", Command: "x\n"})
 	c.Assert(err, NotNil)
@@ -185,6 +309,18 @@ apps:
 	c.Check(err, NotNil)
 }
 
+func (s *ValidateSuite) TestIllegalSnapResources(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+resources:
+  memory-limit: lots
+`))
+	c.Assert(err, IsNil)
+
+	err = Validate(info)
+	c.Check(err, ErrorMatches, `"memory-limit" field contains invalid value "lots"`)
+}
+
 func (s *ValidateSuite) TestIllegalSnapName(c *C) {
 	info, err := InfoFromSnapYaml([]byte(`name: foo.something
 version: 1.0
@@ -235,3 +371,85 @@ hooks:
 	err = Validate(info)
 	c.Check(err, ErrorMatches, `invalid hook name: "abc123"`)
 }
+
+func (s *ValidateSuite) TestAppOrder(c *C) {
+	const yamlFmt = `name: foo
+version: 1.0
+apps:
+  db:
+    command: db
+    daemon: simple
+  web:
+    command: web
+    daemon: simple
+    %s
+  tool:
+    command: tool
+`
+	for _, t := range []struct {
+		order string
+		err   string
+	}{
+		{"after: [db]", ""},
+		{"before: [db]", ""},
+		{"after: [nope]", `cannot order application "web": unknown application "nope"`},
+		{"after: [web]", `cannot order application "web" with itself`},
+		{"before: [tool]", `cannot order application "web" with "tool": not a service`},
+	} {
+		info, err := InfoFromSnapYaml([]byte(fmt.Sprintf(yamlFmt, t.order)))
+		c.Assert(err, IsNil)
+		err = Validate(info)
+		if t.err == "" {
+			c.Check(err, IsNil, Commentf(t.order))
+		} else {
+			c.Check(err, ErrorMatches, t.err, Commentf(t.order))
+		}
+	}
+}
+
+func (s *ValidateSuite) TestAppOrderNotService(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+apps:
+  db:
+    command: db
+    daemon: simple
+  tool:
+    command: tool
+    after: [db]
+`))
+	c.Assert(err, IsNil)
+
+	err = Validate(info)
+	c.Check(err, ErrorMatches, `cannot define before or after for application "tool": not a service`)
+}
+
+func (s *ValidateSuite) TestAppOrderCycle(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+apps:
+  db:
+    command: db
+    daemon: simple
+    after: [web]
+  cache:
+    command: cache
+    daemon: simple
+    before: [db]
+  web:
+    command: web
+    daemon: simple
+    after: [cache]
+    before: [db]
+  other:
+    command: other
+    daemon: simple
+    before: [web]
+`))
+	c.Assert(err, IsNil)
+	c.Assert(Validate(info), IsNil)
+
+	info.Apps["cache"].After = []string{"db"}
+	err = Validate(info)
+	c.Check(err, ErrorMatches, `applications are part of a before/after cycle: cache, db, web`)
+}
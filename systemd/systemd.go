@@ -29,6 +29,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/snapcore/snapd/dirs"
@@ -81,6 +82,55 @@ func jctl(svcs []string) ([]byte, error) {
 // JournalctlCmd is called from Logs to run journalctl; exported for testing.
 var JournalctlCmd = jctl
 
+// journalReader reads the output of a running journalctl, killing it on Close.
+type journalReader struct {
+	io.ReadCloser
+	cmd  *exec.Cmd
+	once sync.Once
+}
+
+func (r *journalReader) Close() error {
+	r.once.Do(func() {
+		r.cmd.Process.Kill()
+		r.ReadCloser.Close()
+		r.cmd.Wait()
+	})
+	return nil
+}
+
+// jctlStream starts journalctl to get the last n (or all, if n is
+// negative) JSON logs of the given services, following new ones if asked
+// to.
+func jctlStream(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+	cmd := []string{"journalctl", "-o", "json", "--no-pager"}
+	if n < 0 {
+		cmd = append(cmd, "--no-tail")
+	} else {
+		cmd = append(cmd, "-n", strconv.Itoa(n))
+	}
+	if follow {
+		cmd = append(cmd, "-f")
+	}
+	for i := range svcs {
+		cmd = append(cmd, "-u", svcs[i])
+	}
+
+	c := exec.Command(cmd[0], cmd[1:]...)
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		return nil, &Error{cmd: cmd, msg: []byte(err.Error())}
+	}
+
+	return &journalReader{ReadCloser: stdout, cmd: c}, nil
+}
+
+// JournalctlStreamCmd is called from LogReader to run journalctl; exported
+// for testing.
+var JournalctlStreamCmd = jctlStream
+
 // Systemd exposes a minimal interface to manage systemd via the systemctl command.
 type Systemd interface {
 	DaemonReload() error
@@ -93,6 +143,7 @@ type Systemd interface {
 	Status(service string) (string, error)
 	ServiceStatus(service string) (*ServiceStatus, error)
 	Logs(services []string) ([]Log, error)
+	LogReader(services []string, n int, follow bool) (io.ReadCloser, error)
 	WriteMountUnitFile(name, what, where string) (string, error)
 }
 
@@ -153,6 +204,9 @@ const (
 	// the default target for systemd units that we generate
 	SocketsTarget = "sockets.target"
 
+	// the default target for systemd timers that we generate
+	TimersTarget = "timers.target"
+
 	// the location to put system services
 	snapServicesDir = "/etc/systemd/system"
 )
@@ -227,6 +281,13 @@ func (*systemd) Logs(serviceNames []string) ([]Log, error) {
 	return logs, nil
 }
 
+// LogReader returns a reader of the last n (or all, if n is negative)
+// journal entries of the given services as a stream of JSON objects.
+// With follow, new entries keep being returned until the reader is closed.
+func (*systemd) LogReader(serviceNames []string, n int, follow bool) (io.ReadCloser, error) {
+	return JournalctlStreamCmd(serviceNames, n, follow)
+}
+
 var statusregex = regexp.MustCompile(`(?m)^(?:(.*?)=(.*))?$`)
 
 func (s *systemd) Status(serviceName string) (string, error) {
@@ -401,6 +462,15 @@ func (l Log) SID() string {
 	return "-"
 }
 
+// PID is the process identifier of the Log, if any; otherwise, "-".
+func (l Log) PID() string {
+	if pid, ok := l["_PID"].(string); ok {
+		return pid
+	}
+
+	return "-"
+}
+
 func (l Log) String() string {
 	return fmt.Sprintf("%s %s %s", l.Timestamp(), l.SID(), l.Message())
 }
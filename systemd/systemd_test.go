@@ -21,9 +21,11 @@ package systemd_test
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -254,6 +256,54 @@ func (s *SystemdTestSuite) TestLogs(c *C) {
 	c.Check(s.j, Equals, 1)
 }
 
+func (s *SystemdTestSuite) TestLogReader(c *C) {
+	var args []interface{}
+	JournalctlStreamCmd = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+		args = []interface{}{svcs, n, follow}
+		return ioutil.NopCloser(strings.NewReader(`{"a": 1}`)), nil
+	}
+	defer func() { JournalctlStreamCmd = JctlStream }()
+
+	r, err := New("", s.rep).LogReader([]string{"foo"}, 10, true)
+	c.Assert(err, IsNil)
+	out, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, `{"a": 1}`)
+	c.Check(args, DeepEquals, []interface{}{[]string{"foo"}, 10, true})
+}
+
+func (s *SystemdTestSuite) TestJctlStream(c *C) {
+	binDir := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(binDir, "journalctl"), []byte("#!/bin/sh\necho \"$@\"\n"), 0755)
+	c.Assert(err, IsNil)
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", binDir+":"+oldPath)
+	defer os.Setenv("PATH", oldPath)
+
+	for _, t := range []struct {
+		n      int
+		follow bool
+		args   string
+	}{
+		{10, false, "-o json --no-pager -n 10 -u foo -u bar\n"},
+		{-1, true, "-o json --no-pager --no-tail -f -u foo -u bar\n"},
+	} {
+		r, err := JctlStream([]string{"foo", "bar"}, t.n, t.follow)
+		c.Assert(err, IsNil)
+		out, err := ioutil.ReadAll(r)
+		c.Assert(err, IsNil)
+		c.Check(string(out), Equals, t.args)
+		c.Check(r.Close(), IsNil)
+		// closing again is harmless
+		c.Check(r.Close(), IsNil)
+	}
+}
+
+func (s *SystemdTestSuite) TestLogPID(c *C) {
+	c.Check(Log{}.PID(), Equals, "-")
+	c.Check(Log{"_PID": "42"}.PID(), Equals, "42")
+}
+
 func (s *SystemdTestSuite) TestLogString(c *C) {
 	c.Check(Log{}.String(), Equals, "-(no timestamp!)- - -")
 	c.Check(Log{
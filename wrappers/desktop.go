@@ -22,13 +22,16 @@ package wrappers
 import (
 	"bufio"
 	"bytes"
+	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
 )
@@ -163,12 +166,220 @@ func sanitizeDesktopFile(s *snap.Info, rawcontent []byte) []byte {
 	return []byte(strings.Join(newContent, "\n"))
 }
 
+// updateDesktopDatabase refreshes the caches of desktop files, MIME types
+// and icons, for the tools that are installed.
+var updateDesktopDatabase = func() error {
+	for _, cmd := range [][]string{
+		{"update-desktop-database", dirs.SnapDesktopFilesDir},
+		{"update-mime-database", dirs.SnapMimeDir},
+		{"gtk-update-icon-cache", "--force", "--ignore-theme-index", filepath.Join(dirs.SnapDesktopIconsDir, "hicolor")},
+	} {
+		// the directory to refresh comes last
+		if !osutil.IsDirectory(cmd[len(cmd)-1]) {
+			continue
+		}
+		if _, err := exec.LookPath(cmd[0]); err != nil {
+			continue
+		}
+		if output, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("cannot run %s: %s\n%s", cmd[0], err, output)
+		}
+	}
+	return nil
+}
+
+// updateDesktopCaches refreshes the caches of desktop files, MIME types and
+// icons. This is best effort: the files are in place either way, so failing
+// to refresh the caches is only logged.
+func updateDesktopCaches() {
+	if err := updateDesktopDatabase(); err != nil {
+		logger.Noticef("Cannot update the desktop database: %v", err)
+	}
+}
+
+// iconPrefix is what the file names of the icons exported by the snap
+// start with, so that they cannot clash with those of other snaps.
+func iconPrefix(s *snap.Info) string {
+	return fmt.Sprintf("snap.%s.", s.Name())
+}
+
+// addSnapIcons exports the icons in meta/gui/icons into a theme directory,
+// keeping their hicolor-style layout.
+func addSnapIcons(s *snap.Info) error {
+	iconsDir := filepath.Join(s.MountDir(), "meta", "gui", "icons")
+	if !osutil.IsDirectory(iconsDir) {
+		return nil
+	}
+
+	prefix := iconPrefix(s)
+	return filepath.Walk(iconsDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		if !strings.HasPrefix(fi.Name(), prefix) {
+			logger.Noticef("Not exporting icon %q of snap %q: its name must start with %q", fi.Name(), s.Name(), prefix)
+			return nil
+		}
+		rel, err := filepath.Rel(iconsDir, path)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dirs.SnapDesktopIconsDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		return osutil.AtomicWriteFile(dst, content, 0644, 0)
+	})
+}
+
+// mimeInfo is a shared MIME-info package, reduced to what snaps may
+// declare about their MIME types.
+type mimeInfo struct {
+	XMLName xml.Name   `xml:"http://www.freedesktop.org/standards/shared-mime-info mime-info"`
+	Types   []mimeType `xml:"mime-type"`
+}
+
+type mimeType struct {
+	Type       string         `xml:"type,attr"`
+	Comments   []mimeComment  `xml:"comment"`
+	Globs      []mimeGlob     `xml:"glob"`
+	SubClassOf []mimeSubClass `xml:"sub-class-of"`
+}
+
+type mimeComment struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+type mimeGlob struct {
+	Pattern string `xml:"pattern,attr"`
+}
+
+type mimeSubClass struct {
+	Type string `xml:"type,attr"`
+}
+
+// mimeTypePrefix is what the subtypes of the MIME types declared by the
+// snap start with, so that they cannot redefine the MIME types of the
+// system or of other snaps.
+func mimeTypePrefix(s *snap.Info) string {
+	return fmt.Sprintf("x-snap.%s.", s.Name())
+}
+
+// sanitizeMimeInfo keeps the MIME types of the snap from the given shared
+// MIME-info package, with only their descriptions, glob patterns and parent
+// types. Aliases, magic rules and glob weights are dropped, as they would
+// let the snap take over the files of other MIME types.
+func sanitizeMimeInfo(s *snap.Info, rawcontent []byte) ([]byte, error) {
+	var info mimeInfo
+	if err := xml.Unmarshal(rawcontent, &info); err != nil {
+		return nil, err
+	}
+
+	prefix := mimeTypePrefix(s)
+	var types []mimeType
+	for _, mt := range info.Types {
+		slash := strings.IndexByte(mt.Type, '/')
+		if slash < 0 || !strings.HasPrefix(mt.Type[slash+1:], prefix) {
+			logger.Noticef("Not exporting MIME type %q of snap %q: its subtype must start with %q", mt.Type, s.Name(), prefix)
+			continue
+		}
+		types = append(types, mt)
+	}
+	if len(types) == 0 {
+		return nil, nil
+	}
+	info.Types = types
+
+	content, err := xml.MarshalIndent(&info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+// addSnapMimeTypes registers the MIME types of the snap from the shared
+// MIME-info packages in meta/gui/mime.
+func addSnapMimeTypes(s *snap.Info) error {
+	mimeFiles, err := filepath.Glob(filepath.Join(s.MountDir(), "meta", "gui", "mime", "*.xml"))
+	if err != nil {
+		return fmt.Errorf("cannot get MIME types for %v: %s", s.Name(), err)
+	}
+	if len(mimeFiles) == 0 {
+		return nil
+	}
+
+	packagesDir := filepath.Join(dirs.SnapMimeDir, "packages")
+	if err := os.MkdirAll(packagesDir, 0755); err != nil {
+		return err
+	}
+	for _, mf := range mimeFiles {
+		rawcontent, err := ioutil.ReadFile(mf)
+		if err != nil {
+			return err
+		}
+		content, err := sanitizeMimeInfo(s, rawcontent)
+		if err != nil {
+			return fmt.Errorf("cannot read MIME types of snap %q from %q: %s", s.Name(), filepath.Base(mf), err)
+		}
+		if content == nil {
+			continue
+		}
+		dst := filepath.Join(packagesDir, fmt.Sprintf("%s_%s", s.Name(), filepath.Base(mf)))
+		if err := osutil.AtomicWriteFile(dst, content, 0644, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addSnapAutostartFiles puts in place XDG autostart files for the apps of
+// the snap started on login, from the desktop files they name.
+func addSnapAutostartFiles(s *snap.Info) error {
+	for _, app := range s.Apps {
+		if app.Autostart == "" {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(s.MountDir(), "meta", "gui", app.Autostart))
+		if err != nil {
+			return fmt.Errorf("cannot read autostart desktop file of app %q: %s", app.Name, err)
+		}
+
+		if err := os.MkdirAll(dirs.SnapAutostartDir, 0755); err != nil {
+			return err
+		}
+		dst := filepath.Join(dirs.SnapAutostartDir, fmt.Sprintf("%s_%s.desktop", s.Name(), app.Name))
+		if err := osutil.AtomicWriteFile(dst, sanitizeDesktopFile(s, content), 0644, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // AddSnapDesktopFiles puts in place the desktop files for the applications from the snap.
-func AddSnapDesktopFiles(s *snap.Info) error {
+//
+// The icons, MIME types and autostart entries of the snap are added as
+// well; on error, none of them are left behind.
+func AddSnapDesktopFiles(s *snap.Info) (err error) {
 	if err := os.MkdirAll(dirs.SnapDesktopFilesDir, 0755); err != nil {
 		return err
 	}
 
+	defer func() {
+		if err != nil {
+			if e := RemoveSnapDesktopFiles(s); e != nil {
+				logger.Noticef("Cannot remove desktop files of %q while undoing: %v", s.Name(), e)
+			}
+		}
+	}()
+
 	baseDir := s.MountDir()
 
 	desktopFiles, err := filepath.Glob(filepath.Join(baseDir, "meta", "gui", "*.desktop"))
@@ -190,19 +401,58 @@ func AddSnapDesktopFiles(s *snap.Info) error {
 		}
 	}
 
+	if err := addSnapIcons(s); err != nil {
+		return err
+	}
+	if err := addSnapMimeTypes(s); err != nil {
+		return err
+	}
+	if err := addSnapAutostartFiles(s); err != nil {
+		return err
+	}
+
+	updateDesktopCaches()
 	return nil
 }
 
 // RemoveSnapDesktopFiles removes the added desktop files for the applications in the snap.
+//
+// The icons, MIME types and autostart entries of the snap are removed as well.
 func RemoveSnapDesktopFiles(s *snap.Info) error {
-	glob := filepath.Join(dirs.SnapDesktopFilesDir, s.Name()+"_*.desktop")
-	activeDesktopFiles, err := filepath.Glob(glob)
+	var files []string
+	for _, glob := range []string{
+		filepath.Join(dirs.SnapDesktopFilesDir, s.Name()+"_*.desktop"),
+		filepath.Join(dirs.SnapMimeDir, "packages", s.Name()+"_*.xml"),
+		filepath.Join(dirs.SnapAutostartDir, s.Name()+"_*.desktop"),
+	} {
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return fmt.Errorf("cannot get desktop files for %v: %s", glob, err)
+		}
+		files = append(files, matches...)
+	}
+
+	prefix := iconPrefix(s)
+	err := filepath.Walk(dirs.SnapDesktopIconsDir, func(path string, fi os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() && strings.HasPrefix(fi.Name(), prefix) {
+			files = append(files, path)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("cannot get desktop files for %v: %s", glob, err)
+		return fmt.Errorf("cannot get icons for %v: %s", s.Name(), err)
 	}
-	for _, f := range activeDesktopFiles {
+
+	for _, f := range files {
 		os.Remove(f)
 	}
 
+	updateDesktopCaches()
 	return nil
 }
@@ -20,6 +20,7 @@
 package wrappers_test
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -35,6 +36,9 @@ import (
 
 type desktopSuite struct {
 	tempdir string
+
+	updates int
+	restore func()
 }
 
 var _ = Suite(&desktopSuite{})
@@ -42,9 +46,16 @@ var _ = Suite(&desktopSuite{})
 func (s *desktopSuite) SetUpTest(c *C) {
 	s.tempdir = c.MkDir()
 	dirs.SetRootDir(s.tempdir)
+
+	s.updates = 0
+	s.restore = wrappers.MockUpdateDesktopDatabase(func() error {
+		s.updates++
+		return nil
+	})
 }
 
 func (s *desktopSuite) TearDownTest(c *C) {
+	s.restore()
 	dirs.SetRootDir("")
 }
 
@@ -92,8 +103,191 @@ func (s *desktopSuite) TestRemovePackageDesktopFiles(c *C) {
 	c.Assert(osutil.FileExists(mockDesktopFilePath), Equals, false)
 }
 
+const desktopIntegrationYaml = `
+name: foo
+version: 1.0
+apps:
+ browser:
+  command: browser
+  autostart: browser.desktop
+ tool:
+  command: tool
+`
+
+const fooMimeInfo = `<?xml version="1.0" encoding="UTF-8"?>
+<mime-info xmlns="http://www.freedesktop.org/standards/shared-mime-info">
+  <mime-type type="application/x-snap.foo.document">
+    <comment>Foo document</comment>
+    <comment xml:lang="de">Foo-Dokument</comment>
+    <glob pattern="*.foo" weight="100"/>
+    <magic priority="100"><match type="string" offset="0" value="%PDF-"/></magic>
+    <alias type="text/html"/>
+    <sub-class-of type="text/plain"/>
+  </mime-type>
+  <mime-type type="text/html">
+    <glob pattern="*.html" weight="100"/>
+  </mime-type>
+</mime-info>
+`
+
+const systemMimeInfo = `<?xml version="1.0" encoding="UTF-8"?>
+<mime-info xmlns="http://www.freedesktop.org/standards/shared-mime-info">
+  <mime-type type="application/pdf">
+    <glob pattern="*.pdf"/>
+  </mime-type>
+</mime-info>
+`
+
+func (s *desktopSuite) mockDesktopIntegration(c *C) *snap.Info {
+	info := snaptest.MockSnap(c, desktopIntegrationYaml, &snap.SideInfo{Revision: snap.R(11)})
+	guiDir := filepath.Join(info.MountDir(), "meta", "gui")
+	for name, content := range map[string]string{
+		"browser.desktop": "[Desktop Entry]\nName=Browser\nExec=foo.browser %U\nX-Unknown=1\n",
+		"icons/hicolor/48x48/apps/snap.foo.browser.png":    "png",
+		"icons/hicolor/scalable/apps/snap.foo.browser.svg": "svg",
+		"icons/hicolor/48x48/apps/browser.png":             "not exported",
+		"mime/foo-documents.xml":                           fooMimeInfo,
+		"mime/system-types.xml":                            systemMimeInfo,
+	} {
+		path := filepath.Join(guiDir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+	}
+	return info
+}
+
+func (s *desktopSuite) TestAddSnapDesktopIntegration(c *C) {
+	info := s.mockDesktopIntegration(c)
+
+	err := wrappers.AddSnapDesktopFiles(info)
+	c.Assert(err, IsNil)
+
+	for _, path := range []string{
+		filepath.Join(dirs.SnapDesktopFilesDir, "foo_browser.desktop"),
+		filepath.Join(dirs.SnapDesktopIconsDir, "hicolor/48x48/apps/snap.foo.browser.png"),
+		filepath.Join(dirs.SnapDesktopIconsDir, "hicolor/scalable/apps/snap.foo.browser.svg"),
+		filepath.Join(dirs.SnapMimeDir, "packages", "foo_foo-documents.xml"),
+	} {
+		c.Check(osutil.FileExists(path), Equals, true, Commentf(path))
+	}
+	// icons without the snap prefix could clash with other snaps
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapDesktopIconsDir, "hicolor/48x48/apps/browser.png")), Equals, false)
+	// and so could MIME types outside of its namespace
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapMimeDir, "packages", "foo_system-types.xml")), Equals, false)
+
+	content, err := ioutil.ReadFile(filepath.Join(dirs.SnapAutostartDir, "foo_browser.desktop"))
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "[Desktop Entry]\nName=Browser\nExec="+filepath.Join(dirs.SnapBinariesDir, "foo.browser")+" %U")
+
+	c.Check(s.updates, Equals, 1)
+}
+
+func (s *desktopSuite) TestDesktopDatabaseUpdateIsBestEffort(c *C) {
+	restore := wrappers.MockUpdateDesktopDatabase(func() error {
+		s.updates++
+		return errors.New("cannot run update-desktop-database: exit status 1")
+	})
+	defer restore()
+	info := s.mockDesktopIntegration(c)
+
+	err := wrappers.AddSnapDesktopFiles(info)
+	c.Assert(err, IsNil)
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapDesktopFilesDir, "foo_browser.desktop")), Equals, true)
+
+	err = wrappers.RemoveSnapDesktopFiles(info)
+	c.Assert(err, IsNil)
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapDesktopFilesDir, "foo_browser.desktop")), Equals, false)
+	c.Check(s.updates, Equals, 2)
+}
+
+func (s *desktopSuite) TestAddSnapDesktopIntegrationMissingAutostart(c *C) {
+	info := s.mockDesktopIntegration(c)
+	err := os.Remove(filepath.Join(info.MountDir(), "meta", "gui", "browser.desktop"))
+	c.Assert(err, IsNil)
+
+	err = wrappers.AddSnapDesktopFiles(info)
+	c.Assert(err, ErrorMatches, `cannot read autostart desktop file of app "browser": .*`)
+
+	// nothing is left behind
+	for _, dir := range []string{dirs.SnapDesktopFilesDir, dirs.SnapMimeDir, dirs.SnapDesktopIconsDir} {
+		var files []string
+		filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err == nil && fi.Mode().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		c.Check(files, HasLen, 0, Commentf(dir))
+	}
+}
+
+func (s *desktopSuite) TestRemoveSnapDesktopIntegration(c *C) {
+	info := s.mockDesktopIntegration(c)
+	err := wrappers.AddSnapDesktopFiles(info)
+	c.Assert(err, IsNil)
+
+	// another snap's icon stays
+	otherIcon := filepath.Join(dirs.SnapDesktopIconsDir, "hicolor/48x48/apps/snap.foo-bar.app.png")
+	c.Assert(ioutil.WriteFile(otherIcon, nil, 0644), IsNil)
+
+	err = wrappers.RemoveSnapDesktopFiles(info)
+	c.Assert(err, IsNil)
+
+	for _, path := range []string{
+		filepath.Join(dirs.SnapDesktopFilesDir, "foo_browser.desktop"),
+		filepath.Join(dirs.SnapDesktopIconsDir, "hicolor/48x48/apps/snap.foo.browser.png"),
+		filepath.Join(dirs.SnapDesktopIconsDir, "hicolor/scalable/apps/snap.foo.browser.svg"),
+		filepath.Join(dirs.SnapMimeDir, "packages", "foo_foo-documents.xml"),
+		filepath.Join(dirs.SnapAutostartDir, "foo_browser.desktop"),
+	} {
+		c.Check(osutil.FileExists(path), Equals, false, Commentf(path))
+	}
+	c.Check(osutil.FileExists(otherIcon), Equals, true)
+	c.Check(s.updates, Equals, 2)
+}
+
 // sanitize
 
+func (s *sanitizeDesktopFileSuite) TestSanitizeMimeInfo(c *C) {
+	info := &snap.Info{SideInfo: snap.SideInfo{OfficialName: "foo", Revision: snap.R(12)}}
+
+	content, err := wrappers.SanitizeMimeInfo(info, []byte(fooMimeInfo))
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, `<?xml version="1.0" encoding="UTF-8"?>
+<mime-info xmlns="http://www.freedesktop.org/standards/shared-mime-info">
+  <mime-type type="application/x-snap.foo.document">
+    <comment>Foo document</comment>
+    <comment xml:lang="de">Foo-Dokument</comment>
+    <glob pattern="*.foo"></glob>
+    <sub-class-of type="text/plain"></sub-class-of>
+  </mime-type>
+</mime-info>
+`)
+}
+
+func (s *sanitizeDesktopFileSuite) TestSanitizeMimeInfoNothingLeft(c *C) {
+	info := &snap.Info{SideInfo: snap.SideInfo{OfficialName: "foo", Revision: snap.R(12)}}
+
+	content, err := wrappers.SanitizeMimeInfo(info, []byte(systemMimeInfo))
+	c.Assert(err, IsNil)
+	c.Check(content, IsNil)
+
+	// the MIME types of other snaps are not the snap's either
+	info = &snap.Info{SideInfo: snap.SideInfo{OfficialName: "fo", Revision: snap.R(12)}}
+	content, err = wrappers.SanitizeMimeInfo(info, []byte(fooMimeInfo))
+	c.Assert(err, IsNil)
+	c.Check(content, IsNil)
+}
+
+func (s *sanitizeDesktopFileSuite) TestSanitizeMimeInfoInvalid(c *C) {
+	info := &snap.Info{SideInfo: snap.SideInfo{OfficialName: "foo", Revision: snap.R(12)}}
+
+	_, err := wrappers.SanitizeMimeInfo(info, []byte("<mime-info>"))
+	c.Check(err, NotNil)
+	_, err = wrappers.SanitizeMimeInfo(info, []byte(`<other xmlns="http://www.freedesktop.org/standards/shared-mime-info"/>`))
+	c.Check(err, NotNil)
+}
+
 type sanitizeDesktopFileSuite struct{}
 
 var _ = Suite(&sanitizeDesktopFileSuite{})
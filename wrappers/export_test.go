@@ -31,11 +31,15 @@ var (
 	// services
 	GenerateSnapServiceFile = generateSnapServiceFile
 	GenerateSnapSocketFile  = generateSnapSocketFile
+	GenSliceFile            = genSliceFile
+	GenSocketUnitFile       = genSocketUnitFile
+	GenTimerFile            = genTimerFile
 
 	// desktop
 	SanitizeDesktopFile = sanitizeDesktopFile
 	RewriteExecLine     = rewriteExecLine
 	TrimLang            = trimLang
+	SanitizeMimeInfo    = sanitizeMimeInfo
 )
 
 func MockKillWait(wait time.Duration) (restore func()) {
@@ -45,3 +49,11 @@ func MockKillWait(wait time.Duration) (restore func()) {
 		killWait = oldKillWait
 	}
 }
+
+func MockUpdateDesktopDatabase(f func() error) (restore func()) {
+	old := updateDesktopDatabase
+	updateDesktopDatabase = f
+	return func() {
+		updateDesktopDatabase = old
+	}
+}
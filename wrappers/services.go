@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -74,20 +75,117 @@ func generateSnapSocketFile(app *snap.AppInfo) (string, error) {
 	return genSocketFile(app), nil
 }
 
-// AddSnapServices adds and starts service units for the applications from the snap which are services.
-func AddSnapServices(s *snap.Info, inter interacter) error {
+// sortedServices returns the apps of the snap which are services, in the
+// order they must be started in.
+func sortedServices(s *snap.Info) ([]*snap.AppInfo, error) {
+	var apps []*snap.AppInfo
 	for _, app := range s.Apps {
-		if app.Daemon == "" {
-			continue
+		if app.Daemon != "" {
+			apps = append(apps, app)
+		}
+	}
+	return snap.SortServices(apps)
+}
+
+// sortedSockets returns the sockets of the app, sorted by name.
+func sortedSockets(app *snap.AppInfo) []*snap.SocketInfo {
+	var names []string
+	for name := range app.Sockets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sockets := make([]*snap.SocketInfo, len(names))
+	for i, name := range names {
+		sockets[i] = app.Sockets[name]
+	}
+	return sockets
+}
+
+// activationUnits returns the names of the units that start the service:
+// its sockets and its timer if it has any, or the service itself and its
+// legacy socket otherwise.
+func activationUnits(app *snap.AppInfo) []string {
+	var units []string
+	if !app.IsActivated() {
+		units = append(units, filepath.Base(app.ServiceFile()))
+		if app.Socket {
+			units = append(units, filepath.Base(app.ServiceSocketFile()))
+		}
+		return units
+	}
+	for _, socket := range sortedSockets(app) {
+		units = append(units, filepath.Base(socket.File()))
+	}
+	if app.Timer != nil {
+		units = append(units, filepath.Base(app.Timer.File()))
+	}
+	return units
+}
+
+// AddSnapServices adds and starts service units for the applications from the snap which are services.
+//
+// The services are grouped in a slice limiting the resources they use
+// together, and started honouring their before and after orderings.
+// Services with sockets or a timer are not started directly but activated
+// by those. Either all the units are added and started or, on error, none
+// are left behind.
+func AddSnapServices(s *snap.Info, inter interacter) (err error) {
+	apps, err := sortedServices(s)
+	if err != nil {
+		return err
+	}
+	if len(apps) == 0 {
+		return nil
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, inter)
+	var written, enabled, started []string
+	defer func() {
+		if err == nil {
+			return
 		}
+		for i := len(started) - 1; i >= 0; i-- {
+			if e := sysd.Stop(started[i], time.Duration(timeout.DefaultTimeout)); e != nil {
+				logger.Noticef("Failed to stop %q while undoing: %v", started[i], e)
+			}
+		}
+		for _, name := range enabled {
+			if e := sysd.Disable(name); e != nil {
+				logger.Noticef("Failed to disable %q while undoing: %v", name, e)
+			}
+		}
+		for _, path := range written {
+			if e := os.Remove(path); e != nil && !os.IsNotExist(e) {
+				logger.Noticef("Failed to remove %q while undoing: %v", path, e)
+			}
+		}
+		if len(written) > 0 {
+			if e := sysd.DaemonReload(); e != nil {
+				logger.Noticef("Failed to reload systemd while undoing: %v", e)
+			}
+		}
+	}()
+
+	write := func(path, content string) error {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := osutil.AtomicWriteFile(path, []byte(content), 0644, 0); err != nil {
+			return err
+		}
+		written = append(written, path)
+		return nil
+	}
+
+	if err := write(s.SliceFile(), genSliceFile(s)); err != nil {
+		return err
+	}
+
+	for _, app := range apps {
 		// Generate service file
 		content, err := generateSnapServiceFile(app)
 		if err != nil {
 			return err
 		}
-		svcFilePath := app.ServiceFile()
-		os.MkdirAll(filepath.Dir(svcFilePath), 0755)
-		if err := osutil.AtomicWriteFile(svcFilePath, []byte(content), 0644, 0); err != nil {
+		if err := write(app.ServiceFile(), content); err != nil {
 			return err
 		}
 		// Generate systemd socket file if needed
@@ -96,71 +194,91 @@ func AddSnapServices(s *snap.Info, inter interacter) error {
 			if err != nil {
 				return err
 			}
-			svcSocketFilePath := app.ServiceSocketFile()
-			os.MkdirAll(filepath.Dir(svcSocketFilePath), 0755)
-			if err := osutil.AtomicWriteFile(svcSocketFilePath, []byte(content), 0644, 0); err != nil {
+			if err := write(app.ServiceSocketFile(), content); err != nil {
 				return err
 			}
 		}
-		// daemon-reload and enable plus start
-		serviceName := filepath.Base(app.ServiceFile())
-		sysd := systemd.New(dirs.GlobalRootDir, inter)
-
-		if err := sysd.DaemonReload(); err != nil {
-			return err
+		for _, socket := range sortedSockets(app) {
+			if err := write(socket.File(), genSocketUnitFile(socket)); err != nil {
+				return err
+			}
 		}
-
-		// enable the service
-		if err := sysd.Enable(serviceName); err != nil {
-			return err
+		if app.Timer != nil {
+			if err := write(app.Timer.File(), genTimerFile(app.Timer)); err != nil {
+				return err
+			}
 		}
+	}
 
-		if err := sysd.Start(serviceName); err != nil {
-			return err
-		}
+	if err := sysd.DaemonReload(); err != nil {
+		return err
+	}
 
-		if app.Socket {
-			socketName := filepath.Base(app.ServiceSocketFile())
-			// enable the socket
-			if err := sysd.Enable(socketName); err != nil {
+	// enable plus start
+	for _, app := range apps {
+		for _, unit := range activationUnits(app) {
+			if err := sysd.Enable(unit); err != nil {
 				return err
 			}
+			enabled = append(enabled, unit)
 
-			if err := sysd.Start(socketName); err != nil {
+			if err := sysd.Start(unit); err != nil {
 				return err
 			}
+			started = append(started, unit)
 		}
 	}
 
 	return nil
 }
 
+// stopUnit stops the given unit of the app, killing its processes when
+// it refuses to stop in time.
+func stopUnit(sysd systemd.Systemd, unit string, app *snap.AppInfo, inter interacter) error {
+	if err := sysd.Stop(unit, serviceStopTimeout(app)); err != nil {
+		if !systemd.IsTimeout(err) {
+			return err
+		}
+		inter.Notify(fmt.Sprintf("%s refused to stop, killing.", unit))
+		// ignore errors for kill; nothing we'd do differently at this point
+		sysd.Kill(unit, "TERM")
+		time.Sleep(killWait)
+		sysd.Kill(unit, "KILL")
+	}
+	return nil
+}
+
 // RemoveSnapServices stops and removes service units for the applications from the snap which are services.
+//
+// The services are stopped in the reverse of the order they are started in.
 func RemoveSnapServices(s *snap.Info, inter interacter) error {
 	sysd := systemd.New(dirs.GlobalRootDir, inter)
 
-	nservices := 0
+	apps, err := sortedServices(s)
+	if err != nil {
+		return err
+	}
+	for i := len(apps) - 1; i >= 0; i-- {
+		app := apps[i]
+		serviceName := filepath.Base(app.ServiceFile())
 
-	for _, app := range s.Apps {
-		if app.Daemon == "" {
-			continue
+		// stop whatever activates the service before the service itself
+		if app.IsActivated() {
+			for _, unit := range activationUnits(app) {
+				if err := sysd.Disable(unit); err != nil {
+					return err
+				}
+				if err := stopUnit(sysd, unit, app, inter); err != nil {
+					return err
+				}
+			}
 		}
-		nservices++
 
-		serviceName := filepath.Base(app.ServiceFile())
 		if err := sysd.Disable(serviceName); err != nil {
 			return err
 		}
-		tout := serviceStopTimeout(app)
-		if err := sysd.Stop(serviceName, tout); err != nil {
-			if !systemd.IsTimeout(err) {
-				return err
-			}
-			inter.Notify(fmt.Sprintf("%s refused to stop, killing.", serviceName))
-			// ignore errors for kill; nothing we'd do differently at this point
-			sysd.Kill(serviceName, "TERM")
-			time.Sleep(killWait)
-			sysd.Kill(serviceName, "KILL")
+		if err := stopUnit(sysd, serviceName, app, inter); err != nil {
+			return err
 		}
 
 		if err := os.Remove(app.ServiceFile()); err != nil && !os.IsNotExist(err) {
@@ -170,10 +288,26 @@ func RemoveSnapServices(s *snap.Info, inter interacter) error {
 		if err := os.Remove(app.ServiceSocketFile()); err != nil && !os.IsNotExist(err) {
 			logger.Noticef("Failed to remove socket file for %q: %v", serviceName, err)
 		}
+
+		for _, socket := range app.Sockets {
+			if err := os.Remove(socket.File()); err != nil && !os.IsNotExist(err) {
+				logger.Noticef("Failed to remove socket file for %q: %v", serviceName, err)
+			}
+		}
+
+		if app.Timer != nil {
+			if err := os.Remove(app.Timer.File()); err != nil && !os.IsNotExist(err) {
+				logger.Noticef("Failed to remove timer file for %q: %v", serviceName, err)
+			}
+		}
 	}
 
 	// only reload if we actually had services
-	if nservices > 0 {
+	if len(apps) > 0 {
+		if err := os.Remove(s.SliceFile()); err != nil && !os.IsNotExist(err) {
+			logger.Noticef("Failed to remove slice file for %q: %v", s.Name(), err)
+		}
+
 		if err := sysd.DaemonReload(); err != nil {
 			return err
 		}
@@ -182,12 +316,71 @@ func RemoveSnapServices(s *snap.Info, inter interacter) error {
 	return nil
 }
 
+// controlledUnits returns the units that control the service: whatever
+// activates it followed by the service itself.
+func controlledUnits(app *snap.AppInfo) []string {
+	serviceName := filepath.Base(app.ServiceFile())
+	var units []string
+	for _, unit := range activationUnits(app) {
+		if unit != serviceName {
+			units = append(units, unit)
+		}
+	}
+	return append(units, serviceName)
+}
+
+// ControlServices performs the given action on the services among apps,
+// in order. The action is one of "start", "stop", "restart", "enable" or
+// "disable"; apps that are not services are skipped.
+//
+// The action also applies to the sockets and the timer activating a
+// service, which are acted upon before the service itself. Enabling an
+// activated service only enables what activates it, as when the snap is
+// installed.
+func ControlServices(apps []*snap.AppInfo, action string, inter interacter) error {
+	sysd := systemd.New(dirs.GlobalRootDir, inter)
+
+	for _, app := range apps {
+		if app.Daemon == "" {
+			continue
+		}
+
+		units := controlledUnits(app)
+		if action == "enable" {
+			units = activationUnits(app)
+		}
+		for _, unit := range units {
+			var err error
+			switch action {
+			case "start":
+				err = sysd.Start(unit)
+			case "stop":
+				err = sysd.Stop(unit, serviceStopTimeout(app))
+			case "restart":
+				err = sysd.Restart(unit, serviceStopTimeout(app))
+			case "enable":
+				err = sysd.Enable(unit)
+			case "disable":
+				err = sysd.Disable(unit)
+			default:
+				return fmt.Errorf("unknown service action %q", action)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func genServiceFile(appInfo *snap.AppInfo) string {
 	serviceTemplate := `[Unit]
 # Auto-generated, DO NO EDIT
 Description=Service for snap application {{.App.Snap.Name}}.{{.App.Name}}
-After=snapd.frameworks.target{{ if .App.Socket }} {{.SocketFileName}}{{end}}
-Requires=snapd.frameworks.target{{ if .App.Socket }} {{.SocketFileName}}{{end}}
+After=snapd.frameworks.target{{range .SocketFileNames}} {{.}}{{end}}{{range .AfterServices}} {{.}}{{end}}
+Requires=snapd.frameworks.target{{range .SocketFileNames}} {{.}}{{end}}{{if .BeforeServices}}
+Before={{range $i, $name := .BeforeServices}}{{if $i}} {{end}}{{$name}}{{end}}{{end}}
 X-Snappy=yes
 
 [Service]
@@ -200,7 +393,8 @@ Environment={{.EnvVars}}
 {{if .StopTimeout}}TimeoutStopSec={{.StopTimeout.Seconds}}{{end}}
 Type={{.App.Daemon}}
 {{if .App.BusName}}BusName={{.App.BusName}}{{end}}
-
+Slice={{.App.Snap.SliceName}}
+{{.Resources}}
 [Install]
 WantedBy={{.ServiceTargetUnit}}
 `
@@ -211,31 +405,40 @@ WantedBy={{.ServiceTargetUnit}}
 	if restartCond == "" {
 		restartCond = systemd.RestartOnFailure.String()
 	}
-	socketFileName := ""
+	var socketFileNames []string
 	if appInfo.Socket {
-		socketFileName = filepath.Base(appInfo.ServiceSocketFile())
+		socketFileNames = append(socketFileNames, filepath.Base(appInfo.ServiceSocketFile()))
+	}
+	for _, socket := range sortedSockets(appInfo) {
+		socketFileNames = append(socketFileNames, filepath.Base(socket.File()))
 	}
 
 	wrapperData := struct {
 		App *snap.AppInfo
 
-		SocketFileName    string
+		SocketFileNames   []string
+		AfterServices     []string
+		BeforeServices    []string
 		Restart           string
 		StopTimeout       time.Duration
 		ServiceTargetUnit string
 
-		Home    string
-		EnvVars string
+		Home      string
+		EnvVars   string
+		Resources string
 	}{
 		App: appInfo,
 
-		SocketFileName:    socketFileName,
+		SocketFileNames:   socketFileNames,
+		AfterServices:     serviceFileNames(appInfo.Snap, appInfo.After),
+		BeforeServices:    serviceFileNames(appInfo.Snap, appInfo.Before),
 		Restart:           restartCond,
 		StopTimeout:       serviceStopTimeout(appInfo),
 		ServiceTargetUnit: systemd.ServicesTarget,
 
 		// systemd runs as PID 1 so %h will not work.
-		Home: "/root",
+		Home:      "/root",
+		Resources: resourceProperties(appInfo.Resources),
 	}
 	allVars := snapenv.Basic(appInfo.Snap)
 	allVars = append(allVars, snapenv.User(appInfo.Snap, "/root")...)
@@ -249,6 +452,67 @@ WantedBy={{.ServiceTargetUnit}}
 	return templateOut.String()
 }
 
+// serviceFileNames returns the names of the service units of the given
+// apps of the snap.
+func serviceFileNames(s *snap.Info, appNames []string) []string {
+	var names []string
+	for _, appName := range appNames {
+		if app, ok := s.Apps[appName]; ok {
+			names = append(names, filepath.Base(app.ServiceFile()))
+		}
+	}
+	return names
+}
+
+// resourceProperties returns the systemd properties enforcing the given
+// resource limits, one per line.
+func resourceProperties(res *snap.Resources) string {
+	if res == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	if res.MemoryLimit != "" {
+		fmt.Fprintf(&buf, "MemoryLimit=%s\n", res.MemoryLimit)
+	}
+	if res.CPUQuota != "" {
+		fmt.Fprintf(&buf, "CPUQuota=%s\n", res.CPUQuota)
+	}
+	if res.CPUWeight != 0 {
+		fmt.Fprintf(&buf, "CPUShares=%d\n", res.CPUWeight)
+	}
+	if res.TasksMax != 0 {
+		fmt.Fprintf(&buf, "TasksMax=%d\n", res.TasksMax)
+	}
+	return buf.String()
+}
+
+func genSliceFile(s *snap.Info) string {
+	sliceTemplate := `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Slice for the services of snap {{.Snap.Name}}
+X-Snappy=yes
+
+[Slice]
+{{.Resources}}`
+	var templateOut bytes.Buffer
+	t := template.Must(template.New("slice").Parse(sliceTemplate))
+
+	wrapperData := struct {
+		Snap      *snap.Info
+		Resources string
+	}{
+		Snap:      s,
+		Resources: resourceProperties(s.Resources),
+	}
+
+	if err := t.Execute(&templateOut, wrapperData); err != nil {
+		// this can never happen, except we forget a variable
+		logger.Panicf("Unable to execute template: %v", err)
+	}
+
+	return templateOut.String()
+}
+
 func genSocketFile(appInfo *snap.AppInfo) string {
 	serviceTemplate := `[Unit]
 # Auto-generated, DO NO EDIT
@@ -283,3 +547,106 @@ WantedBy={{.SocketTargetUnit}}
 
 	return templateOut.String()
 }
+
+// socketAddress returns the address of the socket as systemd expects it,
+// with $SNAP_DATA and $SNAP_COMMON expanded.
+func socketAddress(socket *snap.SocketInfo) string {
+	address := socket.ListenStream
+	if address == "" {
+		address = socket.ListenDatagram
+	}
+	switch {
+	case strings.HasPrefix(address, "$SNAP_DATA/"):
+		return socket.App.Snap.DataDir() + strings.TrimPrefix(address, "$SNAP_DATA")
+	case strings.HasPrefix(address, "$SNAP_COMMON/"):
+		return socket.App.Snap.CommonDataDir() + strings.TrimPrefix(address, "$SNAP_COMMON")
+	}
+	return address
+}
+
+func genSocketUnitFile(socket *snap.SocketInfo) string {
+	socketTemplate := `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Socket {{.Socket.Name}} for snap application {{.Socket.App.Snap.Name}}.{{.Socket.App.Name}}
+PartOf={{.ServiceFileName}}
+X-Snappy=yes
+
+[Socket]
+Service={{.ServiceFileName}}
+FileDescriptorName={{.Socket.Name}}
+{{if .Socket.ListenStream}}ListenStream={{.Address}}{{else}}ListenDatagram={{.Address}}{{end}}
+{{if .SocketMode}}SocketMode={{.SocketMode}}{{end}}
+
+[Install]
+WantedBy={{.SocketTargetUnit}}
+`
+	var templateOut bytes.Buffer
+	t := template.Must(template.New("socket").Parse(socketTemplate))
+
+	address := socketAddress(socket)
+	socketMode := ""
+	if strings.HasPrefix(address, "/") {
+		// lp: #1515709, systemd will default to 0666 if no socket mode
+		// is specified
+		socketMode = socket.SocketMode
+		if socketMode == "" {
+			socketMode = "0660"
+		}
+	}
+
+	wrapperData := struct {
+		Socket           *snap.SocketInfo
+		Address          string
+		SocketMode       string
+		ServiceFileName  string
+		SocketTargetUnit string
+	}{
+		Socket:           socket,
+		Address:          address,
+		SocketMode:       socketMode,
+		ServiceFileName:  filepath.Base(socket.App.ServiceFile()),
+		SocketTargetUnit: systemd.SocketsTarget,
+	}
+
+	if err := t.Execute(&templateOut, wrapperData); err != nil {
+		// this can never happen, except we forget a variable
+		logger.Panicf("Unable to execute template: %v", err)
+	}
+
+	return templateOut.String()
+}
+
+func genTimerFile(timer *snap.TimerInfo) string {
+	timerTemplate := `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Timer for snap application {{.Timer.App.Snap.Name}}.{{.Timer.App.Name}}
+PartOf={{.ServiceFileName}}
+X-Snappy=yes
+
+[Timer]
+Unit={{.ServiceFileName}}
+OnCalendar={{.Timer.Timer}}
+
+[Install]
+WantedBy={{.TimerTargetUnit}}
+`
+	var templateOut bytes.Buffer
+	t := template.Must(template.New("timer").Parse(timerTemplate))
+
+	wrapperData := struct {
+		Timer           *snap.TimerInfo
+		ServiceFileName string
+		TimerTargetUnit string
+	}{
+		Timer:           timer,
+		ServiceFileName: filepath.Base(timer.App.ServiceFile()),
+		TimerTargetUnit: systemd.TimersTarget,
+	}
+
+	if err := t.Execute(&templateOut, wrapperData); err != nil {
+		// this can never happen, except we forget a variable
+		logger.Panicf("Unable to execute template: %v", err)
+	}
+
+	return templateOut.String()
+}
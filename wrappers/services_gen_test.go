@@ -50,6 +50,7 @@ ExecStop=/usr/bin/ubuntu-core-launcher snap.snap.app snap.snap.app /snap/snap/44
 ExecStopPost=/usr/bin/ubuntu-core-launcher snap.snap.app snap.snap.app /snap/snap/44/bin/stop --post
 TimeoutStopSec=10
 %[2]s
+Slice=snap.snap.slice
 
 [Install]
 WantedBy=multi-user.target
@@ -76,6 +77,7 @@ ExecStop=/usr/bin/ubuntu-core-launcher snap.xkcd-webserver.xkcd-webserver snap.x
 ExecStopPost=/usr/bin/ubuntu-core-launcher snap.xkcd-webserver.xkcd-webserver snap.xkcd-webserver.xkcd-webserver /snap/xkcd-webserver/44/bin/foo post-stop
 TimeoutStopSec=30
 %[2]s
+Slice=snap.xkcd\x2dwebserver.slice
 
 [Install]
 WantedBy=multi-user.target
@@ -106,6 +108,69 @@ apps:
 	c.Check(generatedWrapper, Equals, expectedAppService)
 }
 
+func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileResources(c *C) {
+	yamlText := `
+name: snap
+version: 1.0
+apps:
+    app:
+        command: bin/start
+        daemon: simple
+        resources:
+            memory-limit: 256M
+            cpu-quota: 50%
+            cpu-weight: 512
+            tasks-max: 64
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yamlText))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+	app := info.Apps["app"]
+
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app)
+	c.Assert(err, IsNil)
+	c.Check(generatedWrapper, Matches, `(?ms).*
+Type=simple
+
+Slice=snap.snap.slice
+MemoryLimit=256M
+CPUQuota=50%
+CPUShares=512
+TasksMax=64
+
+\[Install\]
+.*`)
+}
+
+func (s *servicesWrapperGenSuite) TestGenSliceFile(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`
+name: snap
+version: 1.0
+resources:
+    memory-limit: 1G
+    tasks-max: 512
+`))
+	c.Assert(err, IsNil)
+	c.Check(wrappers.GenSliceFile(info), Equals, `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Slice for the services of snap snap
+X-Snappy=yes
+
+[Slice]
+MemoryLimit=1G
+TasksMax=512
+`)
+
+	info.Resources = nil
+	c.Check(wrappers.GenSliceFile(info), Equals, `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Slice for the services of snap snap
+X-Snappy=yes
+
+[Slice]
+`)
+}
+
 func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileRestart(c *C) {
 	yamlTextTemplate := `
 name: snap
@@ -289,3 +354,112 @@ func (s *servicesWrapperGenSuite) TestGenerateSnapSocketFileMode(c *C) {
 	c.Assert(content, Matches, "(?ms).*SocketMode=0600")
 
 }
+
+func (s *servicesWrapperGenSuite) TestGenSocketUnitFile(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: xkcd-webserver
+version: 0.3.4
+apps:
+ xkcd-webserver:
+  command: bin/foo start
+  daemon: simple
+  sockets:
+   http:
+    listen-stream: 8080
+   control:
+    listen-stream: $SNAP_DATA/control.sock
+    socket-mode: 0600
+   events:
+    listen-datagram: "@xkcd-webserver"
+`))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+	app := info.Apps["xkcd-webserver"]
+
+	c.Check(wrappers.GenSocketUnitFile(app.Sockets["http"]), Equals, `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Socket http for snap application xkcd-webserver.xkcd-webserver
+PartOf=snap.xkcd-webserver.xkcd-webserver.service
+X-Snappy=yes
+
+[Socket]
+Service=snap.xkcd-webserver.xkcd-webserver.service
+FileDescriptorName=http
+ListenStream=8080
+
+
+[Install]
+WantedBy=sockets.target
+`)
+	c.Check(wrappers.GenSocketUnitFile(app.Sockets["control"]), Matches, "(?ms).*^ListenStream=/var/snap/xkcd-webserver/44/control.sock\nSocketMode=0600$.*")
+	c.Check(wrappers.GenSocketUnitFile(app.Sockets["events"]), Matches, "(?ms).*^ListenDatagram=@xkcd-webserver\n\n.*")
+
+	// path sockets default to 0660
+	app.Sockets["control"].SocketMode = ""
+	c.Check(wrappers.GenSocketUnitFile(app.Sockets["control"]), Matches, "(?ms).*^SocketMode=0660$.*")
+
+	// the service depends on all its sockets
+	content, err := wrappers.GenerateSnapServiceFile(app)
+	c.Assert(err, IsNil)
+	c.Check(content, Matches, "(?ms).*^After=snapd.frameworks.target snap.xkcd-webserver.xkcd-webserver.control.socket snap.xkcd-webserver.xkcd-webserver.events.socket snap.xkcd-webserver.xkcd-webserver.http.socket$.*")
+}
+
+func (s *servicesWrapperGenSuite) TestGenTimerFile(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+apps:
+ cleanup:
+  command: bin/cleanup
+  daemon: oneshot
+  timer: "*-*-* 03:00"
+`))
+	c.Assert(err, IsNil)
+
+	c.Check(wrappers.GenTimerFile(info.Apps["cleanup"].Timer), Equals, `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Timer for snap application foo.cleanup
+PartOf=snap.foo.cleanup.service
+X-Snappy=yes
+
+[Timer]
+Unit=snap.foo.cleanup.service
+OnCalendar=*-*-* 03:00
+
+[Install]
+WantedBy=timers.target
+`)
+}
+
+func (s *servicesWrapperGenSuite) TestGenServiceFileBeforeAfter(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+apps:
+ db:
+  command: bin/db
+  daemon: simple
+ cache:
+  command: bin/cache
+  daemon: simple
+ web:
+  command: bin/web
+  daemon: simple
+  after: [db, cache]
+  before: [proxy]
+ proxy:
+  command: bin/proxy
+  daemon: simple
+`))
+	c.Assert(err, IsNil)
+
+	content, err := wrappers.GenerateSnapServiceFile(info.Apps["web"])
+	c.Assert(err, IsNil)
+	c.Check(content, Matches, `(?ms).*^After=snapd.frameworks.target snap.foo.db.service snap.foo.cache.service
+Requires=snapd.frameworks.target
+Before=snap.foo.proxy.service
+X-Snappy=yes$.*`)
+
+	content, err = wrappers.GenerateSnapServiceFile(info.Apps["db"])
+	c.Assert(err, IsNil)
+	c.Check(content, Matches, `(?ms).*^After=snapd.frameworks.target
+Requires=snapd.frameworks.target
+X-Snappy=yes$.*`)
+}
@@ -84,6 +84,12 @@ func (s *servicesTestSuite) TestAddSnapServicesAndRemove(c *C) {
 		c.Check(string(content), Matches, "(?ms).*^"+regexp.QuoteMeta(expected)) // check.v1 adds ^ and $ around the regexp provided
 	}
 
+	c.Check(string(content), Matches, "(?ms).*^Slice=snap.hello\\\\x2dsnap.slice$.*")
+
+	// The services of the snap are grouped in a slice.
+	sliceFile := filepath.Join(s.tempdir, `/etc/systemd/system/snap.hello\x2dsnap.slice`)
+	c.Check(osutil.FileExists(sliceFile), Equals, true)
+
 	c.Assert(sysdLog, HasLen, 3)
 	c.Check(sysdLog[0], DeepEquals, []string{"daemon-reload"})
 	c.Check(sysdLog[1], DeepEquals, []string{"--root", dirs.GlobalRootDir, "enable", filepath.Base(svcFile)})
@@ -95,6 +101,7 @@ func (s *servicesTestSuite) TestAddSnapServicesAndRemove(c *C) {
 	c.Assert(err, IsNil)
 
 	c.Check(osutil.FileExists(svcFile), Equals, false)
+	c.Check(osutil.FileExists(sliceFile), Equals, false)
 
 	c.Assert(sysdLog, HasLen, 4)
 	c.Check(sysdLog[0], DeepEquals, []string{"--root", dirs.GlobalRootDir, "disable", filepath.Base(svcFile)})
@@ -139,3 +146,288 @@ apps:
 
 	c.Check(sysdLog[len(sysdLog)-1], DeepEquals, []string{"daemon-reload"})
 }
+
+const packageActivated = `name: hello-snap
+version: 1.0
+apps:
+ web:
+  command: bin/web
+  daemon: simple
+  sockets:
+   http:
+    listen-stream: 8080
+   control:
+    listen-stream: $SNAP_DATA/control.sock
+ cleanup:
+  command: bin/cleanup
+  daemon: oneshot
+  timer: daily
+`
+
+func (s *servicesTestSuite) TestAddSnapServicesActivated(c *C) {
+	var sysdLog [][]string
+	systemd.SystemctlCmd = func(cmd ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, cmd)
+		return []byte("ActiveState=inactive\n"), nil
+	}
+
+	info := snaptest.MockSnap(c, packageActivated, &snap.SideInfo{Revision: snap.R(12)})
+
+	err := wrappers.AddSnapServices(info, nil)
+	c.Assert(err, IsNil)
+
+	units := []string{
+		"snap.hello-snap.cleanup.service",
+		"snap.hello-snap.cleanup.timer",
+		"snap.hello-snap.web.service",
+		"snap.hello-snap.web.control.socket",
+		"snap.hello-snap.web.http.socket",
+	}
+	for _, unit := range units {
+		c.Check(osutil.FileExists(filepath.Join(dirs.SnapServicesDir, unit)), Equals, true, Commentf(unit))
+	}
+
+	// only the sockets and the timer are enabled and started
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.cleanup.timer"},
+		{"start", "snap.hello-snap.cleanup.timer"},
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.web.control.socket"},
+		{"start", "snap.hello-snap.web.control.socket"},
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.web.http.socket"},
+		{"start", "snap.hello-snap.web.http.socket"},
+	})
+
+	sysdLog = nil
+	err = wrappers.RemoveSnapServices(info, &progress.NullProgress{})
+	c.Assert(err, IsNil)
+
+	for _, unit := range units {
+		c.Check(osutil.FileExists(filepath.Join(dirs.SnapServicesDir, unit)), Equals, false, Commentf(unit))
+	}
+
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.web.control.socket"},
+		{"stop", "snap.hello-snap.web.control.socket"},
+		{"show", "--property=ActiveState", "snap.hello-snap.web.control.socket"},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.web.http.socket"},
+		{"stop", "snap.hello-snap.web.http.socket"},
+		{"show", "--property=ActiveState", "snap.hello-snap.web.http.socket"},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.web.service"},
+		{"stop", "snap.hello-snap.web.service"},
+		{"show", "--property=ActiveState", "snap.hello-snap.web.service"},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.cleanup.timer"},
+		{"stop", "snap.hello-snap.cleanup.timer"},
+		{"show", "--property=ActiveState", "snap.hello-snap.cleanup.timer"},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.cleanup.service"},
+		{"stop", "snap.hello-snap.cleanup.service"},
+		{"show", "--property=ActiveState", "snap.hello-snap.cleanup.service"},
+		{"daemon-reload"},
+	})
+}
+
+func (s *servicesTestSuite) TestAddSnapServicesUndoesOnError(c *C) {
+	var sysdLog [][]string
+	systemd.SystemctlCmd = func(cmd ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, cmd)
+		if cmd[0] == "start" && cmd[1] == "snap.hello-snap.web.control.socket" {
+			return nil, fmt.Errorf("failed")
+		}
+		return []byte("ActiveState=inactive\n"), nil
+	}
+
+	info := snaptest.MockSnap(c, packageActivated, &snap.SideInfo{Revision: snap.R(12)})
+
+	err := wrappers.AddSnapServices(info, nil)
+	c.Assert(err, ErrorMatches, ".*failed.*")
+
+	// nothing is left behind
+	files, err := filepath.Glob(filepath.Join(dirs.SnapServicesDir, "snap.hello*"))
+	c.Assert(err, IsNil)
+	c.Check(files, HasLen, 0)
+
+	c.Check(sysdLog[len(sysdLog)-5:], DeepEquals, [][]string{
+		{"stop", "snap.hello-snap.cleanup.timer"},
+		{"show", "--property=ActiveState", "snap.hello-snap.cleanup.timer"},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.cleanup.timer"},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.web.control.socket"},
+		{"daemon-reload"},
+	})
+}
+
+func (s *servicesTestSuite) TestAddAndRemoveSnapServicesInOrder(c *C) {
+	var sysdLog [][]string
+	systemd.SystemctlCmd = func(cmd ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, cmd)
+		return []byte("ActiveState=inactive\n"), nil
+	}
+
+	info := snaptest.MockSnap(c, `name: stack
+version: 1.0
+apps:
+ web:
+  command: bin/web
+  daemon: simple
+  after: [db]
+ db:
+  command: bin/db
+  daemon: simple
+ cache:
+  command: bin/cache
+  daemon: simple
+  after: [web]
+  before: [admin]
+ admin:
+  command: bin/admin
+  daemon: simple
+`, &snap.SideInfo{Revision: snap.R(1)})
+
+	err := wrappers.AddSnapServices(info, nil)
+	c.Assert(err, IsNil)
+
+	var started []string
+	for _, cmd := range sysdLog {
+		if cmd[0] == "start" {
+			started = append(started, cmd[1])
+		}
+	}
+	c.Check(started, DeepEquals, []string{
+		"snap.stack.db.service",
+		"snap.stack.web.service",
+		"snap.stack.cache.service",
+		"snap.stack.admin.service",
+	})
+
+	sysdLog = nil
+	err = wrappers.RemoveSnapServices(info, &progress.NullProgress{})
+	c.Assert(err, IsNil)
+
+	var stopped []string
+	for _, cmd := range sysdLog {
+		if cmd[0] == "stop" {
+			stopped = append(stopped, cmd[1])
+		}
+	}
+	c.Check(stopped, DeepEquals, []string{
+		"snap.stack.admin.service",
+		"snap.stack.cache.service",
+		"snap.stack.web.service",
+		"snap.stack.db.service",
+	})
+}
+
+func (s *servicesTestSuite) TestAddSnapServicesCycle(c *C) {
+	info := snaptest.MockSnap(c, `name: stack
+version: 1.0
+apps:
+ web:
+  command: bin/web
+  daemon: simple
+  after: [db]
+ db:
+  command: bin/db
+  daemon: simple
+  after: [web]
+`, &snap.SideInfo{Revision: snap.R(1)})
+
+	err := wrappers.AddSnapServices(info, nil)
+	c.Assert(err, ErrorMatches, `applications are part of a before/after cycle: db, web`)
+
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapServicesDir, "snap.stack.web.service")), Equals, false)
+}
+
+func (s *servicesTestSuite) TestControlServices(c *C) {
+	var sysdLog [][]string
+	systemd.SystemctlCmd = func(cmd ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, cmd)
+		return []byte("ActiveState=inactive\n"), nil
+	}
+
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	apps := []*snap.AppInfo{info.Apps["hello"], info.Apps["svc1"]}
+	svcFName := "snap.hello-snap.svc1.service"
+
+	for action, expected := range map[string][]string{
+		"start":   {"start", svcFName},
+		"enable":  {"--root", s.tempdir, "enable", svcFName},
+		"disable": {"--root", s.tempdir, "disable", svcFName},
+	} {
+		sysdLog = nil
+		err := wrappers.ControlServices(apps, action, &progress.NullProgress{})
+		c.Assert(err, IsNil)
+		c.Check(sysdLog, DeepEquals, [][]string{expected})
+	}
+
+	sysdLog = nil
+	err := wrappers.ControlServices(apps, "restart", &progress.NullProgress{})
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"stop", svcFName},
+		{"show", "--property=ActiveState", svcFName},
+		{"start", svcFName},
+	})
+
+	err = wrappers.ControlServices(apps, "frobnicate", &progress.NullProgress{})
+	c.Check(err, ErrorMatches, `unknown service action "frobnicate"`)
+}
+
+func (s *servicesTestSuite) TestControlServicesActivated(c *C) {
+	var sysdLog [][]string
+	systemd.SystemctlCmd = func(cmd ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, cmd)
+		return []byte("ActiveState=inactive\n"), nil
+	}
+
+	info := snaptest.MockSnap(c, packageActivated, &snap.SideInfo{Revision: snap.R(12)})
+	apps := []*snap.AppInfo{info.Apps["web"], info.Apps["cleanup"]}
+	web := []string{
+		"snap.hello-snap.web.control.socket",
+		"snap.hello-snap.web.http.socket",
+		"snap.hello-snap.web.service",
+	}
+	cleanup := []string{
+		"snap.hello-snap.cleanup.timer",
+		"snap.hello-snap.cleanup.service",
+	}
+	units := append(append([]string(nil), web...), cleanup...)
+
+	for action, cmd := range map[string][]string{
+		"start":   {"start"},
+		"disable": {"--root", s.tempdir, "disable"},
+	} {
+		var expected [][]string
+		for _, unit := range units {
+			expected = append(expected, append(append([]string(nil), cmd...), unit))
+		}
+		sysdLog = nil
+		err := wrappers.ControlServices(apps, action, &progress.NullProgress{})
+		c.Assert(err, IsNil)
+		c.Check(sysdLog, DeepEquals, expected, Commentf(action))
+	}
+
+	// the services themselves are not enabled, only what activates them
+	sysdLog = nil
+	err := wrappers.ControlServices(apps, "enable", &progress.NullProgress{})
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"--root", s.tempdir, "enable", "snap.hello-snap.web.control.socket"},
+		{"--root", s.tempdir, "enable", "snap.hello-snap.web.http.socket"},
+		{"--root", s.tempdir, "enable", "snap.hello-snap.cleanup.timer"},
+	})
+
+	for _, action := range []string{"stop", "restart"} {
+		var expected [][]string
+		for _, unit := range units {
+			expected = append(expected,
+				[]string{"stop", unit},
+				[]string{"show", "--property=ActiveState", unit})
+			if action == "restart" {
+				expected = append(expected, []string{"start", unit})
+			}
+		}
+		sysdLog = nil
+		err := wrappers.ControlServices(apps, action, &progress.NullProgress{})
+		c.Assert(err, IsNil)
+		c.Check(sysdLog, DeepEquals, expected, Commentf(action))
+	}
+}